@@ -0,0 +1,136 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/archive.go
+
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// archiveMagic starts every export archive, so import can refuse anything
+// else before writing a single key.
+const archiveMagic = "KVARCHIVE1\n"
+
+// importBatchBytes is roughly how much value data import sends per
+// BatchPut, kept well under gRPC's default 4MB message limit.
+const importBatchBytes = 1 << 20
+
+// exportArchive writes every key under prefix and its value to w. After
+// archiveMagic each record is the key and then the value, each preceded by
+// its length as a uvarint, so keys and values may hold any bytes. Keys
+// deleted between listing and reading them are skipped.
+func exportArchive(ctx context.Context, kv shared.KV, prefix string, w io.Writer) (int, error) {
+    keys, err := kv.List(ctx, prefix)
+    if err != nil {
+        return 0, fmt.Errorf("listing keys: %w", err)
+    }
+
+    bw := bufio.NewWriter(w)
+    if _, err := bw.WriteString(archiveMagic); err != nil {
+        return 0, err
+    }
+    exported := 0
+    for _, key := range keys {
+        value, err := kv.Get(ctx, key)
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            continue
+        }
+        if err != nil {
+            return exported, fmt.Errorf("reading %q: %w", key, err)
+        }
+        if err := writeArchiveField(bw, []byte(key)); err != nil {
+            return exported, err
+        }
+        if err := writeArchiveField(bw, value); err != nil {
+            return exported, err
+        }
+        exported++
+    }
+    return exported, bw.Flush()
+}
+
+func writeArchiveField(w *bufio.Writer, data []byte) error {
+    if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(data)))); err != nil {
+        return err
+    }
+    _, err := w.Write(data)
+    return err
+}
+
+// importArchive replays an archive written by exportArchive with BatchPut,
+// overwriting keys that already exist. A damaged archive is reported
+// before the batch holding the damage is sent, but earlier batches stay
+// written.
+func importArchive(ctx context.Context, kv shared.KV, r io.Reader) (int, error) {
+    br := bufio.NewReader(r)
+    magic := make([]byte, len(archiveMagic))
+    if _, err := io.ReadFull(br, magic); err != nil || string(magic) != archiveMagic {
+        return 0, errors.New("not a KV export archive")
+    }
+
+    imported := 0
+    batch := map[string][]byte{}
+    batchBytes := 0
+    flush := func() error {
+        if len(batch) == 0 {
+            return nil
+        }
+        if err := kv.BatchPut(ctx, batch); err != nil {
+            return err
+        }
+        imported += len(batch)
+        batch = map[string][]byte{}
+        batchBytes = 0
+        return nil
+    }
+
+    for record := 1; ; record++ {
+        key, err := readArchiveField(br)
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return imported, fmt.Errorf("record %d: reading key: %w", record, err)
+        }
+        value, err := readArchiveField(br)
+        if err != nil {
+            if err == io.EOF {
+                err = io.ErrUnexpectedEOF
+            }
+            return imported, fmt.Errorf("record %d: reading value of %q: %w", record, key, err)
+        }
+        batch[string(key)] = value
+        batchBytes += len(key) + len(value)
+        if batchBytes >= importBatchBytes {
+            if err := flush(); err != nil {
+                return imported, err
+            }
+        }
+    }
+    return imported, flush()
+}
+
+// readArchiveField reads one length-prefixed field, returning io.EOF only
+// when the archive ends cleanly before it.
+func readArchiveField(r *bufio.Reader) ([]byte, error) {
+    length, err := binary.ReadUvarint(r)
+    if err != nil {
+        return nil, err
+    }
+    // Don't trust the length for the allocation; a damaged archive could
+    // claim any size
+    data, err := io.ReadAll(io.LimitReader(r, int64(length)))
+    if err != nil {
+        return nil, err
+    }
+    if uint64(len(data)) != length {
+        return nil, io.ErrUnexpectedEOF
+    }
+    return data, nil
+}
@@ -0,0 +1,66 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/archive_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+    values := map[string][]byte{
+        "plain":           []byte("hello"),
+        "empty":           {},
+        "binary":          {0, 1, 2, 0xff, '\n', 0},
+        "spaces and ünï": []byte("value with\nnewlines"),
+        "large":           bytes.Repeat([]byte("x"), importBatchBytes+1),
+    }
+    source := &mapKV{data: map[string][]byte{}}
+    for key, value := range values {
+        source.data[key] = value
+    }
+
+    archive := filepath.Join(t.TempDir(), "kv.archive")
+    if err := runCommand(t, source, nil, "export", archive); err != nil {
+        t.Fatalf("export failed: %v", err)
+    }
+
+    // Import into a wiped store
+    restored := &mapKV{data: map[string][]byte{}}
+    if err := runCommand(t, restored, nil, "import", archive); err != nil {
+        t.Fatalf("import failed: %v", err)
+    }
+    if !reflect.DeepEqual(restored.data, values) {
+        t.Fatalf("import restored %d keys that differ from the %d exported", len(restored.data), len(values))
+    }
+}
+
+func TestImportRejectsDamagedArchive(t *testing.T) {
+    var archive bytes.Buffer
+    source := &mapKV{data: map[string][]byte{"k": []byte("value")}}
+    if _, err := exportArchive(context.Background(), source, "", &archive); err != nil {
+        t.Fatalf("exportArchive failed: %v", err)
+    }
+
+    dir := t.TempDir()
+    for name, data := range map[string][]byte{
+        "not-an-archive": []byte("k=value\n"),
+        "truncated":      archive.Bytes()[:archive.Len()-2],
+    } {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, data, 0644); err != nil {
+            t.Fatalf("WriteFile failed: %v", err)
+        }
+        restored := &mapKV{data: map[string][]byte{}}
+        if err := runCommand(t, restored, nil, "import", path); err == nil {
+            t.Fatalf("import of a %s file succeeded", name)
+        }
+        if len(restored.data) != 0 {
+            t.Fatalf("import of a %s file wrote %d keys", name, len(restored.data))
+        }
+    }
+}
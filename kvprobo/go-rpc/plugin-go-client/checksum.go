@@ -0,0 +1,61 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/checksum.go
+
+package main
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    "github.com/hashicorp/go-plugin"
+)
+
+// errChecksumMismatch is returned when the plugin executable doesn't hash
+// to PLUGIN_SERVER_SHA256.
+var errChecksumMismatch = errors.New("plugin executable checksum mismatch")
+
+// pluginChecksumFromEnv reads PLUGIN_SERVER_SHA256, the hex SHA-256 the
+// plugin executable must hash to, as sha256sum prints it. It returns nil
+// when unset.
+func pluginChecksumFromEnv() ([]byte, error) {
+    value := strings.TrimSpace(os.Getenv("PLUGIN_SERVER_SHA256"))
+    if value == "" {
+        return nil, nil
+    }
+    sum, err := hex.DecodeString(value)
+    if err != nil || len(sum) != sha256.Size {
+        return nil, fmt.Errorf("invalid PLUGIN_SERVER_SHA256 %q: want the 64 hex digits sha256sum prints", value)
+    }
+    return sum, nil
+}
+
+// verifyPluginChecksum hashes the executable at path and fails with
+// errChecksumMismatch unless it matches want.
+func verifyPluginChecksum(path string, want []byte) error {
+    f, err := os.Open(path)
+    if err != nil {
+        return fmt.Errorf("hashing plugin executable: %w", err)
+    }
+    defer f.Close()
+
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil {
+        return fmt.Errorf("hashing plugin executable: %w", err)
+    }
+    if got := h.Sum(nil); !bytes.Equal(got, want) {
+        return fmt.Errorf("%w: %s has SHA-256 %x, but PLUGIN_SERVER_SHA256 is %x; refusing to launch it", errChecksumMismatch, path, got, want)
+    }
+    return nil
+}
+
+// pluginSecureConfig makes go-plugin check the checksum again as it
+// launches the executable, narrowing the window in which it could be
+// swapped after verifyPluginChecksum.
+func pluginSecureConfig(want []byte) *plugin.SecureConfig {
+    return &plugin.SecureConfig{Checksum: want, Hash: sha256.New()}
+}
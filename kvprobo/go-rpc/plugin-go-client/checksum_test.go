@@ -0,0 +1,66 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/checksum_test.go
+
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "os"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+)
+
+// launchChecked starts the test binary as a plugin that go-plugin verifies
+// against want, returning the launch error.
+func launchChecked(t *testing.T, want []byte) error {
+    t.Helper()
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), true, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=serve")
+    config.SecureConfig = pluginSecureConfig(want)
+    client := plugin.NewClient(config)
+    defer client.Kill()
+    _, err := client.Start()
+    return err
+}
+
+func TestPluginChecksum(t *testing.T) {
+    binary, err := os.ReadFile(os.Args[0])
+    if err != nil {
+        t.Fatalf("ReadFile failed: %v", err)
+    }
+    sum := sha256.Sum256(binary)
+
+    t.Setenv("PLUGIN_SERVER_SHA256", hex.EncodeToString(sum[:]))
+    want, err := pluginChecksumFromEnv()
+    if err != nil {
+        t.Fatalf("pluginChecksumFromEnv() failed: %v", err)
+    }
+    if err := verifyPluginChecksum(os.Args[0], want); err != nil {
+        t.Fatalf("verifyPluginChecksum() with the right hash = %v", err)
+    }
+    if err := launchChecked(t, want); err != nil {
+        t.Fatalf("launching with the right hash failed: %v", err)
+    }
+
+    wrong := sha256.Sum256([]byte("tampered"))
+    if err := verifyPluginChecksum(os.Args[0], wrong[:]); !errors.Is(err, errChecksumMismatch) {
+        t.Fatalf("verifyPluginChecksum() with the wrong hash = %v, want errChecksumMismatch", err)
+    }
+    if err := launchChecked(t, wrong[:]); err == nil {
+        t.Fatalf("launching with the wrong hash succeeded")
+    }
+
+    for _, value := range []string{"abc", "zz" + hex.EncodeToString(sum[1:])} {
+        t.Setenv("PLUGIN_SERVER_SHA256", value)
+        if _, err := pluginChecksumFromEnv(); err == nil {
+            t.Fatalf("pluginChecksumFromEnv() accepted %q", value)
+        }
+    }
+    t.Setenv("PLUGIN_SERVER_SHA256", "")
+    if want, err := pluginChecksumFromEnv(); want != nil || err != nil {
+        t.Fatalf("pluginChecksumFromEnv() unset = %x, %v, want nothing", want, err)
+    }
+}
@@ -0,0 +1,132 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/commands.go
+
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// usageError reports arguments a subcommand can't take, after the
+// command's usage line.
+type usageError struct {
+    usage  string
+    reason string
+}
+
+func (e *usageError) Error() string {
+    return e.usage + ": " + e.reason
+}
+
+// command is one subcommand's flag set, with the synopsis shown by its
+// help and usage errors.
+type command struct {
+    flags    *flag.FlagSet
+    synopsis string
+    summary  string
+}
+
+// newCommand returns the command name, taking the arguments synopsis shows,
+// such as "[--stream] key", and described by summary in its help.
+func newCommand(name, synopsis, summary string) *command {
+    flags := flag.NewFlagSet(name, flag.ContinueOnError)
+    // parse returns the errors and prints the help itself
+    flags.SetOutput(io.Discard)
+    flags.Usage = func() {}
+    return &command{flags: flags, synopsis: synopsis, summary: summary}
+}
+
+// usage returns the command's usage line.
+func (c *command) usage() string {
+    return strings.TrimSpace(fmt.Sprintf("usage: %s %s %s", os.Args[0], c.flags.Name(), c.synopsis))
+}
+
+// usageError returns a usageError saying why the arguments were refused.
+func (c *command) usageError(format string, args ...any) error {
+    return &usageError{usage: c.usage(), reason: fmt.Sprintf(format, args...)}
+}
+
+// isSet reports whether the flag name was given, even with its default value.
+func (c *command) isSet(name string) bool {
+    set := false
+    c.flags.Visit(func(f *flag.Flag) {
+        if f.Name == name {
+            set = true
+        }
+    })
+    return set
+}
+
+// parse parses args, the words after the subcommand, and returns the
+// positional arguments. Flags may come before, between or after them;
+// "--" ends the flags, and "-" or a negative number is an argument rather
+// than a flag. -h or --help prints the command's help to out and returns
+// flag.ErrHelp.
+func (c *command) parse(args []string, out io.Writer) ([]string, error) {
+    var flagArgs, positional []string
+    for i := 0; i < len(args); i++ {
+        arg := args[i]
+        if arg == "--" {
+            positional = append(positional, args[i+1:]...)
+            break
+        }
+        if !isFlagArg(arg) {
+            positional = append(positional, arg)
+            continue
+        }
+        flagArgs = append(flagArgs, arg)
+        // The value of a non-boolean flag may be the next word
+        name := strings.TrimLeft(arg, "-")
+        if !strings.Contains(name, "=") && takesValue(c.flags.Lookup(name)) && i+1 < len(args) {
+            i++
+            flagArgs = append(flagArgs, args[i])
+        }
+    }
+
+    if err := c.flags.Parse(flagArgs); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            c.printHelp(out)
+            return nil, err
+        }
+        return nil, c.usageError("%v", err)
+    }
+    return positional, nil
+}
+
+// printHelp writes the usage line, summary and flags to out.
+func (c *command) printHelp(out io.Writer) {
+    fmt.Fprintf(out, "%s\n\n%s\n", c.usage(), c.summary)
+    hasFlags := false
+    c.flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+    if !hasFlags {
+        return
+    }
+    fmt.Fprintln(out, "\nflags:")
+    c.flags.SetOutput(out)
+    c.flags.PrintDefaults()
+    c.flags.SetOutput(io.Discard)
+}
+
+// isFlagArg reports whether arg is written as a flag.
+func isFlagArg(arg string) bool {
+    if len(arg) < 2 || arg[0] != '-' {
+        return false
+    }
+    _, err := strconv.ParseFloat(arg, 64)
+    return err != nil
+}
+
+// takesValue reports whether f is a flag that needs a value, which an
+// unknown flag doesn't.
+func takesValue(f *flag.Flag) bool {
+    if f == nil {
+        return false
+    }
+    b, ok := f.Value.(interface{ IsBoolFlag() bool })
+    return !ok || !b.IsBoolFlag()
+}
@@ -0,0 +1,103 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/commands_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "flag"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestCommandHelp(t *testing.T) {
+    for _, tt := range []struct {
+        args []string
+        want []string
+    }{
+        {[]string{"get", "-h"}, []string{"get [--stream]", "Print the value stored under key.", "-output-file path", "-if-modified-after time"}},
+        {[]string{"put", "--help"}, []string{"put key value", "-stdin", "-value-file path"}},
+        {[]string{"delete", "k", "-h"}, []string{"delete key", "Delete key."}},
+    } {
+        var stdout bytes.Buffer
+        session := newKVSession(&mapKV{data: map[string][]byte{}}, shared.ProtocolVersion, hclog.NewNullLogger())
+        session.stdout = &stdout
+        if err := session.Execute(context.Background(), tt.args); !errors.Is(err, flag.ErrHelp) {
+            t.Fatalf("%v = %v, want flag.ErrHelp", tt.args, err)
+        }
+        for _, want := range tt.want {
+            if !strings.Contains(stdout.String(), want) {
+                t.Fatalf("%v printed %q, which doesn't contain %q", tt.args, stdout.String(), want)
+            }
+        }
+        if tt.args[0] == "delete" && strings.Contains(stdout.String(), "flags:") {
+            t.Fatalf("help for delete, which has no flags, lists some:\n%s", stdout.String())
+        }
+    }
+}
+
+func TestCommandUsageErrors(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    for _, tt := range []struct {
+        args []string
+        want string
+    }{
+        {[]string{"get", "--bogus", "k"}, "flag provided but not defined: -bogus"},
+        {[]string{"get", "k", "--output-file"}, "flag needs an argument: -output-file"},
+        {[]string{"put", "--stdin=maybe", "k"}, "invalid boolean value"},
+        {[]string{"list", "--page-size", "many"}, "invalid value \"many\" for flag -page-size"},
+        {[]string{"delete"}, "want one key, got 0 arguments"},
+    } {
+        err := runCommand(t, kv, nil, tt.args...)
+        var usage *usageError
+        if !errors.As(err, &usage) {
+            t.Fatalf("%v = %v, want a usage error", tt.args, err)
+        }
+        if !strings.Contains(err.Error(), tt.want) || !strings.HasPrefix(err.Error(), "usage: ") {
+            t.Fatalf("%v = %q, want the usage line and %q", tt.args, err, tt.want)
+        }
+    }
+    if len(kv.data) != 0 {
+        t.Fatalf("refused commands stored %q", kv.data)
+    }
+}
+
+func TestCommandFlagPlacement(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    valueFile := filepath.Join(t.TempDir(), "value")
+    if err := os.WriteFile(valueFile, []byte("from file"), 0644); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    // Flags after the key, a value that looks like a number, and one after --
+    for _, args := range [][]string{
+        {"put", "neg", "-5"},
+        {"put", "dash", "--", "-x"},
+        {"put", "k", "--value-file=" + valueFile},
+    } {
+        if err := runCommand(t, kv, nil, args...); err != nil {
+            t.Fatalf("%v failed: %v", args, err)
+        }
+    }
+    for key, want := range map[string]string{"neg": "-5", "dash": "-x", "k": "from file"} {
+        if got := string(kv.data[key]); got != want {
+            t.Fatalf("%s = %q, want %q", key, got, want)
+        }
+    }
+
+    var stdout bytes.Buffer
+    session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+    session.stdout = &stdout
+    if err := session.Execute(context.Background(), []string{"list", "n", "--page-size", "1"}); err != nil {
+        t.Fatalf("list with a trailing --page-size failed: %v", err)
+    }
+    if stdout.String() != "neg\n" {
+        t.Fatalf("list n --page-size 1 printed %q, want neg", stdout.String())
+    }
+}
@@ -0,0 +1,108 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/crash.go
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os/exec"
+    "strings"
+    "sync"
+    "time"
+
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+)
+
+// stderrTailLines is how many of the plugin's last stderr lines a crash
+// or startup failure report includes.
+const stderrTailLines = 20
+
+// crashReapTimeout is how long to wait for go-plugin to notice a plugin
+// process has died after its connection drops.
+const crashReapTimeout = 2 * time.Second
+
+// errPluginTerminated is returned in place of the transport error a
+// command gets when the plugin process dies under it.
+var errPluginTerminated = errors.New("plugin process terminated unexpectedly")
+
+// stderrTail keeps the last lines of the plugin's stderr, which go-plugin
+// otherwise only logs, so a crash or startup failure report can show them.
+type stderrTail struct {
+    mu      sync.Mutex
+    lines   []string
+    partial strings.Builder
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    for _, b := range p {
+        if b != '\n' {
+            t.partial.WriteByte(b)
+            continue
+        }
+        t.lines = append(t.lines, t.partial.String())
+        t.partial.Reset()
+        if len(t.lines) > stderrTailLines {
+            t.lines = t.lines[len(t.lines)-stderrTailLines:]
+        }
+    }
+    return len(p), nil
+}
+
+// String returns the kept lines, including any unfinished last line.
+func (t *stderrTail) String() string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    lines := t.lines
+    if t.partial.Len() > 0 {
+        lines = append(lines[:len(lines):len(lines)], t.partial.String())
+    }
+    return strings.Join(lines, "\n")
+}
+
+// withStderrTail adds what the plugin last wrote to stderr, if anything, to
+// err.
+func withStderrTail(err error, tail *stderrTail) error {
+    if output := tail.String(); output != "" {
+        return fmt.Errorf("%w; its last stderr output was:\n%s", err, output)
+    }
+    return err
+}
+
+// exitWatcher is the part of plugin.Client crash reports need.
+type exitWatcher interface {
+    Exited() bool
+}
+
+// crashDiagnoser explains errors caused by the plugin process dying.
+type crashDiagnoser struct {
+    client exitWatcher
+    cmd    *exec.Cmd
+    stderr *stderrTail
+}
+
+// diagnose replaces err with a report of how the plugin exited and what it
+// last wrote to stderr if err is a lost connection and the plugin process
+// is gone. Any other error is returned unchanged.
+func (d *crashDiagnoser) diagnose(err error) error {
+    if err == nil || status.Code(err) != codes.Unavailable {
+        return err
+    }
+    deadline := time.Now().Add(crashReapTimeout)
+    for !d.client.Exited() {
+        if time.Now().After(deadline) {
+            return err
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    exitStatus := "exit status unknown"
+    if d.cmd != nil && d.cmd.ProcessState != nil {
+        exitStatus = d.cmd.ProcessState.String()
+    }
+    return withStderrTail(fmt.Errorf("%w (%s)", errPluginTerminated, exitStatus), d.stderr)
+}
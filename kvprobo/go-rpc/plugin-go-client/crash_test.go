@@ -0,0 +1,73 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/crash_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// crashingKV panics in Get, taking the plugin process down mid-request.
+type crashingKV struct {
+    shared.KV
+}
+
+func (crashingKV) Get(ctx context.Context, key string) ([]byte, error) {
+    panic("disk on fire")
+}
+
+func (c crashingKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := c.Get(ctx, key)
+    return value, shared.DefaultContentType, err
+}
+
+func TestPluginCrashIsDiagnosed(t *testing.T) {
+    pluginStderr := &stderrTail{}
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=crash")
+    config.Stderr = pluginStderr
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        t.Fatalf("Client() failed: %v", err)
+    }
+    raw, err := rpcClient.Dispense(shared.PluginName)
+    if err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+
+    crash := &crashDiagnoser{client: client, cmd: config.Cmd, stderr: pluginStderr}
+    _, err = raw.(shared.KV).Get(context.Background(), "k")
+    err = crash.diagnose(err)
+    if !errors.Is(err, errPluginTerminated) {
+        t.Fatalf("Get against a crashing plugin = %v, want errPluginTerminated", err)
+    }
+    for _, want := range []string{"plugin process terminated unexpectedly (exit status 2)", "panic: disk on fire"} {
+        if !strings.Contains(err.Error(), want) {
+            t.Fatalf("crash report %q doesn't mention %q", err, want)
+        }
+    }
+}
+
+func TestStderrTailKeepsLastLines(t *testing.T) {
+    var tail stderrTail
+    for i := 0; i < stderrTailLines+5; i++ {
+        tail.Write([]byte("line\n"))
+    }
+    tail.Write([]byte("last, unfinished"))
+
+    lines := strings.Split(tail.String(), "\n")
+    if len(lines) != stderrTailLines+1 || lines[len(lines)-1] != "last, unfinished" {
+        t.Fatalf("tail kept %d lines ending %q, want %d ending with the unfinished line", len(lines), lines[len(lines)-1], stderrTailLines+1)
+    }
+}
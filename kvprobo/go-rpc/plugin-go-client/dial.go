@@ -0,0 +1,55 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/dial.go
+
+package main
+
+import (
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "net"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// checkDialTLSEnv requires manual mTLS when PLUGIN_KV_DIAL_ADDR is set.
+// AutoMTLS certificates are exchanged while go-plugin launches the plugin,
+// which never happens for a server that is already running.
+func checkDialTLSEnv(dialAddr string, autoMTLS bool) error {
+    if dialAddr != "" && autoMTLS {
+        return errors.New("PLUGIN_KV_DIAL_ADDR is set, but AutoMTLS only works for a plugin the client launches; set PLUGIN_AUTO_MTLS=false and the manual mTLS certificates")
+    }
+    return nil
+}
+
+// dialKV connects straight to a KV server already listening on addr, a TCP
+// host:port, instead of launching the plugin. There is no go-plugin
+// handshake, so the server is assumed to speak shared.ProtocolVersion, and
+// its certificate is verified against the host in addr rather than
+// localhost. A nil tlsConfig, which only --insecure allows, dials without
+// TLS. Closing the returned client closes the connection.
+func dialKV(addr string, tlsConfig *tls.Config, dialOptions []grpc.DialOption, logger hclog.Logger) (*shared.GRPCClient, error) {
+    host, _, err := net.SplitHostPort(addr)
+    if err != nil {
+        return nil, fmt.Errorf("invalid PLUGIN_KV_DIAL_ADDR %q: %w", addr, err)
+    }
+    creds := insecure.NewCredentials()
+    if tlsConfig != nil {
+        tlsConfig = tlsConfig.Clone()
+        tlsConfig.ServerName = host
+        creds = credentials.NewTLS(tlsConfig)
+    }
+
+    logger.Debug("🔌 dialling standalone server", "address", addr, "server_name", host, "tls", tlsConfig != nil)
+    dialOptions = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, dialOptions...)
+    conn, err := grpc.NewClient(addr, dialOptions...)
+    if err != nil {
+        return nil, err
+    }
+    logger.Debug("🔌✅ standalone server connection configured", "target", conn.Target())
+    return shared.NewGRPCClient(conn, logger.Named("🔌🌐 kv-grpc-client")), nil
+}
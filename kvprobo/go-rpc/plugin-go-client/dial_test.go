@@ -0,0 +1,108 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/dial_test.go
+
+package main
+
+import (
+    "context"
+    "net"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// serveStandalone runs the KV gRPC service over mTLS on a TCP port, the way
+// a server run as a daemon would, and returns its address.
+func serveStandalone(t *testing.T, kv shared.KV) string {
+    t.Helper()
+    tlsConfig, err := shared.ServerTLSConfigFromEnv(shared.TLSPolicy{}, nil)
+    if err != nil {
+        t.Fatalf("ServerTLSConfigFromEnv failed: %v", err)
+    }
+    server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+    plugin := &shared.KVGRPCPlugin{Impl: kv, Logger: hclog.NewNullLogger()}
+    if err := plugin.GRPCServer(nil, server); err != nil {
+        t.Fatalf("GRPCServer failed: %v", err)
+    }
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %v", err)
+    }
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+    return listener.Addr().String()
+}
+
+func TestDialStandaloneServer(t *testing.T) {
+    ca := newTestCA(t)
+    clientCert, clientKey := ca.issue(t)
+    serverCert, serverKey := ca.issue(t)
+    t.Setenv("PLUGIN_CA_CERT", string(ca.certPEM))
+    t.Setenv("PLUGIN_CLIENT_CERT", clientCert)
+    t.Setenv("PLUGIN_CLIENT_KEY", clientKey)
+    t.Setenv("PLUGIN_SERVER_CERT", serverCert)
+    t.Setenv("PLUGIN_SERVER_KEY", serverKey)
+
+    _, port, err := net.SplitHostPort(serveStandalone(t, &recordingKV{}))
+    if err != nil {
+        t.Fatalf("SplitHostPort failed: %v", err)
+    }
+    tlsConfig, err := shared.ClientTLSConfigFromEnv(shared.TLSPolicy{}, nil)
+    if err != nil {
+        t.Fatalf("ClientTLSConfigFromEnv failed: %v", err)
+    }
+    // The test certificates name localhost, not 127.0.0.1
+    kv, err := dialKV(net.JoinHostPort("localhost", port), tlsConfig, nil, hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("dialKV failed: %v", err)
+    }
+    defer kv.Close()
+
+    ctx := context.Background()
+    if err := kv.Put(ctx, "greeting", []byte("hello")); err != nil {
+        t.Fatalf("Put over TCP failed: %v", err)
+    }
+    if value, err := kv.Get(ctx, "greeting"); err != nil || string(value) != "hello" {
+        t.Fatalf("Get over TCP = %q, %v; want hello", value, err)
+    }
+}
+
+func TestDialInsecureServer(t *testing.T) {
+    server := grpc.NewServer()
+    plugin := &shared.KVGRPCPlugin{Impl: &recordingKV{}, Logger: hclog.NewNullLogger()}
+    if err := plugin.GRPCServer(nil, server); err != nil {
+        t.Fatalf("GRPCServer failed: %v", err)
+    }
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %v", err)
+    }
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    // What --insecure leaves run with: no TLS config at all
+    kv, err := dialKV(listener.Addr().String(), nil, nil, hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("dialKV failed: %v", err)
+    }
+    defer kv.Close()
+    if err := kv.Put(context.Background(), "k", []byte("plain")); err != nil {
+        t.Fatalf("Put over plaintext failed: %v", err)
+    }
+}
+
+func TestCheckDialTLSEnv(t *testing.T) {
+    if err := checkDialTLSEnv("", true); err != nil {
+        t.Fatalf("checkDialTLSEnv without an address = %v", err)
+    }
+    if err := checkDialTLSEnv("localhost:5000", false); err != nil {
+        t.Fatalf("checkDialTLSEnv with manual mTLS = %v", err)
+    }
+    if err := checkDialTLSEnv("localhost:5000", true); err == nil {
+        t.Fatalf("checkDialTLSEnv accepted AutoMTLS for a dialled server")
+    }
+}
@@ -0,0 +1,71 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/discover.go
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// pluginBinaryName is the executable discovery looks for when
+// PLUGIN_SERVER_PATH is unset.
+const pluginBinaryName = "kv-plugin-server"
+
+// errPluginNotFound is returned when discovery finds no plugin binary.
+var errPluginNotFound = errors.New("plugin executable not found")
+
+// discoverPluginBinary looks for pluginBinaryName in the directories listed
+// in PLUGIN_SERVER_SEARCH_PATH, then those in PATH, and returns its absolute
+// path. Finding different binaries in more than one place is an error, as
+// picking one could launch a stale build; PLUGIN_SERVER_PATH settles it.
+func discoverPluginBinary(logger hclog.Logger) (string, error) {
+    dirs := filepath.SplitList(os.Getenv("PLUGIN_SERVER_SEARCH_PATH"))
+    dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+
+    var found []string
+    var foundInfo []os.FileInfo
+candidates:
+    for _, dir := range dirs {
+        if dir == "" {
+            continue
+        }
+        path, err := exec.LookPath(filepath.Join(dir, pluginBinaryName))
+        if err != nil {
+            continue
+        }
+        if path, err = filepath.Abs(path); err != nil {
+            continue
+        }
+        info, err := os.Stat(path)
+        if err != nil {
+            continue
+        }
+        // The same file reached through a repeated directory or a symlink
+        // isn't ambiguous
+        for _, seen := range foundInfo {
+            if os.SameFile(seen, info) {
+                continue candidates
+            }
+        }
+        logger.Debug("🔍 found plugin candidate", "path", path)
+        found = append(found, path)
+        foundInfo = append(foundInfo, info)
+    }
+
+    switch len(found) {
+    case 0:
+        return "", fmt.Errorf("%w: set PLUGIN_SERVER_PATH, or put %s in PLUGIN_SERVER_SEARCH_PATH or PATH", errPluginNotFound, pluginBinaryName)
+    case 1:
+        logger.Info("🔍✅ discovered plugin executable", "path", found[0])
+        return found[0], nil
+    default:
+        return "", fmt.Errorf("found %d different %s executables (%s); set PLUGIN_SERVER_PATH to choose one",
+            len(found), pluginBinaryName, strings.Join(found, ", "))
+    }
+}
@@ -0,0 +1,56 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/discover_test.go
+
+package main
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// fakePluginBinary creates an executable named pluginBinaryName in a new
+// directory and returns the directory.
+func fakePluginBinary(t *testing.T) string {
+    t.Helper()
+    dir := t.TempDir()
+    if err := os.WriteFile(filepath.Join(dir, pluginBinaryName), []byte("#!/bin/sh\n"), 0755); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    return dir
+}
+
+func TestDiscoverPluginBinary(t *testing.T) {
+    logger := hclog.NewNullLogger()
+    empty := t.TempDir()
+    onPath := fakePluginBinary(t)
+
+    t.Setenv("PLUGIN_SERVER_SEARCH_PATH", "")
+    t.Setenv("PATH", strings.Join([]string{empty, onPath, onPath}, string(os.PathListSeparator)))
+    path, err := discoverPluginBinary(logger)
+    if err != nil || path != filepath.Join(onPath, pluginBinaryName) {
+        t.Fatalf("discoverPluginBinary() = %q, %v, want the binary on PATH", path, err)
+    }
+
+    // A search list entry is found as well, and two different binaries are ambiguous
+    searched := fakePluginBinary(t)
+    t.Setenv("PLUGIN_SERVER_SEARCH_PATH", searched)
+    if _, err := discoverPluginBinary(logger); err == nil || !strings.Contains(err.Error(), "PLUGIN_SERVER_PATH") {
+        t.Fatalf("discoverPluginBinary() with two binaries = %v, want an error naming PLUGIN_SERVER_PATH", err)
+    }
+    t.Setenv("PATH", empty)
+    if path, err := discoverPluginBinary(logger); err != nil || path != filepath.Join(searched, pluginBinaryName) {
+        t.Fatalf("discoverPluginBinary() = %q, %v, want the binary in the search list", path, err)
+    }
+
+    // A file that isn't executable doesn't count
+    if err := os.Chmod(filepath.Join(searched, pluginBinaryName), 0644); err != nil {
+        t.Fatalf("Chmod failed: %v", err)
+    }
+    if _, err := discoverPluginBinary(logger); !errors.Is(err, errPluginNotFound) {
+        t.Fatalf("discoverPluginBinary() with no executable = %v, want errPluginNotFound", err)
+    }
+}
@@ -0,0 +1,42 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/events.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// eventSinkFromEnv reads PLUGIN_KV_EVENT_SINK. When it is true the client
+// asks the plugin to call back with every change it makes, which is most
+// useful in the repl where the plugin stays up between commands.
+func eventSinkFromEnv() (bool, error) {
+    value := os.Getenv("PLUGIN_KV_EVENT_SINK")
+    if value == "" {
+        return false, nil
+    }
+    enabled, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid PLUGIN_KV_EVENT_SINK %q: %w", value, err)
+    }
+    return enabled, nil
+}
+
+// loggingSink logs each event the plugin pushes to the host.
+type loggingSink struct {
+    logger hclog.Logger
+}
+
+func (s *loggingSink) Notify(ctx context.Context, event shared.Event) error {
+    s.logger.Info("🌐📣 event from plugin",
+        "op", event.Op,
+        "key", event.Key,
+        "value_size", len(event.Value))
+    return nil
+}
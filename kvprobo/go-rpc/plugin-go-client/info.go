@@ -0,0 +1,66 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/info.go
+
+package main
+
+import (
+    "context"
+    "crypto/x509"
+    "encoding/json"
+    "fmt"
+    "io"
+    "text/tabwriter"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// connectionInfo describes how the client reached the plugin, as printed by
+// the info command.
+type connectionInfo struct {
+    Network  string `json:"network"`
+    Address  string `json:"address"`
+    Protocol string `json:"protocol"`
+    Version  int    `json:"version"`
+    Secure   bool   `json:"secure"`
+    // The server certificate fields are empty without TLS.
+    ServerCertFingerprint string `json:"server_cert_fingerprint,omitempty"`
+    ServerCertSubject     string `json:"server_cert_subject,omitempty"`
+}
+
+// peerCertifier is the part of shared.GRPCClient the info command needs.
+type peerCertifier interface {
+    PeerCertificate(ctx context.Context) (*x509.Certificate, error)
+}
+
+// describeConnection fills in the certificate p's plugin presents.
+func describeConnection(ctx context.Context, p peerCertifier, info connectionInfo) (connectionInfo, error) {
+    cert, err := p.PeerCertificate(ctx)
+    if err != nil {
+        return info, err
+    }
+    if cert != nil {
+        info.ServerCertFingerprint = shared.CertificateFingerprint(cert)
+        info.ServerCertSubject = cert.Subject.String()
+    }
+    return info, nil
+}
+
+// printConnectionInfo writes info to out as JSON or an aligned table.
+func printConnectionInfo(out io.Writer, info connectionInfo, asJSON bool) error {
+    if asJSON {
+        encoder := json.NewEncoder(out)
+        encoder.SetIndent("", "  ")
+        return encoder.Encode(info)
+    }
+
+    w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+    fmt.Fprintf(w, "network:\t%s\n", info.Network)
+    fmt.Fprintf(w, "address:\t%s\n", info.Address)
+    fmt.Fprintf(w, "protocol:\t%s\n", info.Protocol)
+    fmt.Fprintf(w, "version:\t%d\n", info.Version)
+    fmt.Fprintf(w, "secure:\t%t\n", info.Secure)
+    if info.ServerCertFingerprint != "" {
+        fmt.Fprintf(w, "server cert fingerprint:\t%s\n", info.ServerCertFingerprint)
+        fmt.Fprintf(w, "server cert subject:\t%s\n", info.ServerCertSubject)
+    }
+    return w.Flush()
+}
@@ -0,0 +1,69 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/info_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestInfoReportsNegotiatedConnection(t *testing.T) {
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), true, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=serve")
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        t.Fatalf("Client() failed: %v", err)
+    }
+    raw, err := rpcClient.Dispense(shared.PluginName)
+    if err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+
+    addr := client.ReattachConfig().Addr
+    session := newKVSession(raw.(shared.KV), client.NegotiatedVersion(), hclog.NewNullLogger())
+    session.conn = connectionInfo{
+        Network:  addr.Network(),
+        Address:  addr.String(),
+        Protocol: string(client.Protocol()),
+        Version:  client.NegotiatedVersion(),
+        Secure:   true,
+    }
+
+    var stdout bytes.Buffer
+    session.stdout = &stdout
+    if err := session.Execute(context.Background(), []string{"info", "--json"}); err != nil {
+        t.Fatalf("info --json failed: %v", err)
+    }
+    var info connectionInfo
+    if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+        t.Fatalf("info --json printed %q, which isn't JSON: %v", stdout.String(), err)
+    }
+    if info.Version != shared.ProtocolVersion || info.Protocol != "grpc" {
+        t.Fatalf("info reported protocol %q version %d, want grpc version %d", info.Protocol, info.Version, shared.ProtocolVersion)
+    }
+    if !info.Secure || info.ServerCertFingerprint == "" || info.ServerCertSubject == "" {
+        t.Fatalf("info over AutoMTLS = %+v, want the server certificate's fingerprint and subject", info)
+    }
+
+    stdout.Reset()
+    if err := session.Execute(context.Background(), []string{"info"}); err != nil {
+        t.Fatalf("info failed: %v", err)
+    }
+    for _, want := range []string{"version:", "server cert fingerprint:", info.ServerCertFingerprint} {
+        if !strings.Contains(stdout.String(), want) {
+            t.Fatalf("info table %q doesn't contain %q", stdout.String(), want)
+        }
+    }
+}
@@ -0,0 +1,140 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/launch.go
+
+package main
+
+import (
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "math/rand/v2"
+    "net"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// startRetryBaseDelay and startRetryMaxDelay bound the wait before each
+// relaunch. Like RPC retries, the delay doubles on every attempt and a
+// random part of its upper half is dropped.
+var (
+    startRetryBaseDelay = 500 * time.Millisecond
+    startRetryMaxDelay  = 5 * time.Second
+)
+
+// startRetriesFromEnv reads PLUGIN_KV_START_RETRIES, how many times to
+// relaunch a plugin that fails to start. It returns 0 when unset.
+func startRetriesFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_START_RETRIES")
+    if value == "" {
+        return 0, nil
+    }
+    retries, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_START_RETRIES %q: %w", value, err)
+    }
+    if retries < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_START_RETRIES must not be negative, got %d", retries)
+    }
+    return retries, nil
+}
+
+// launchedPlugin is a running plugin process and its RPC connection.
+type launchedPlugin struct {
+    client    *plugin.Client
+    config    *plugin.ClientConfig
+    rpcClient plugin.ClientProtocol
+    addr      net.Addr
+    stderr    *stderrTail
+}
+
+// pluginLauncher launches the plugin and connects to it.
+type pluginLauncher struct {
+    // newConfig returns the configuration for one launch. Neither a
+    // plugin.Client nor its exec.Cmd can be started twice, so every
+    // attempt needs a fresh one.
+    newConfig func() *plugin.ClientConfig
+    autoMTLS  bool
+    tlsPolicy shared.TLSPolicy
+    retries   int
+    logger    hclog.Logger
+}
+
+// launch starts the plugin, relaunching it up to retries times, with
+// backoff, if it fails to start or to accept the connection. A handshake
+// that can never succeed, such as a version or checksum mismatch, isn't
+// retried.
+func (l *pluginLauncher) launch() (*launchedPlugin, error) {
+    delay := startRetryBaseDelay
+    for attempt := 0; ; attempt++ {
+        launched, err := l.start(l.newConfig())
+        if err == nil {
+            return launched, nil
+        }
+        if attempt >= l.retries || errors.Is(err, shared.ErrVersionMismatch) || errors.Is(err, plugin.ErrChecksumsDoNotMatch) {
+            return nil, err
+        }
+
+        wait := delay/2 + rand.N(delay/2+1)
+        l.logger.Warn("🔌🔄 plugin failed to start, relaunching",
+            "attempt", attempt+1,
+            "retries", l.retries,
+            "wait", wait,
+            "error", err)
+        time.Sleep(wait)
+        delay = min(delay*2, startRetryMaxDelay)
+    }
+}
+
+// start makes one attempt at launching the plugin and connecting to it. A
+// plugin that fails either step is killed, and the error ends with what it
+// last wrote to stderr, which usually says why it failed.
+func (l *pluginLauncher) start(config *plugin.ClientConfig) (*launchedPlugin, error) {
+    logger := l.logger
+    stderr := &stderrTail{}
+    config.Stderr = stderr
+    logger.Debug("🔌 creating new plugin client")
+    client := plugin.NewClient(config)
+
+    // Launch the plugin before connecting. Under AutoMTLS go-plugin builds
+    // its TLS config during the launch, and the policy has to be applied to
+    // it before the connection is dialled
+    logger.Debug("🔌 launching plugin")
+    rpcAddr, err := client.Start()
+    if err != nil {
+        // Killing waits for the plugin's stderr to be read to the end
+        client.Kill()
+        err = shared.HandshakeError(err)
+        logger.Error("🔌❌ failed to launch plugin", "error", err)
+        return nil, withStderrTail(fmt.Errorf("error launching plugin: %w", err), stderr)
+    }
+    if config.TLSConfig != nil {
+        if l.autoMTLS {
+            l.tlsPolicy.Apply(config.TLSConfig)
+        }
+        logger.Debug("🔐 TLS policy applied", "min_version", tls.VersionName(config.TLSConfig.MinVersion))
+    }
+    // A Unix socket's address is a path, so name the host the plugin's
+    // certificate is verified against rather than skipping verification
+    if shared.SetUnixSocketServerName(config.TLSConfig, rpcAddr) {
+        logger.Debug("🔐 verifying the plugin's Unix socket certificate", "server_name", config.TLSConfig.ServerName)
+    }
+
+    // Connect via RPC
+    logger.Debug("🤝 attempting to establish RPC connection")
+    rpcClient, err := client.Client()
+    if err != nil {
+        client.Kill()
+        err = shared.HandshakeError(err)
+        logger.Error("🤝❌ failed to create RPC client",
+            "error", err,
+            "error_type", fmt.Sprintf("%T", err))
+        return nil, withStderrTail(fmt.Errorf("error creating RPC client: %w", err), stderr)
+    }
+    logger.Debug("🤝✅ RPC connection established")
+    return &launchedPlugin{client: client, config: config, rpcClient: rpcClient, addr: rpcAddr, stderr: stderr}, nil
+}
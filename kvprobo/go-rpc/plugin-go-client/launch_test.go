@@ -0,0 +1,95 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/launch_test.go
+
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+)
+
+func TestLaunchRetriesFailedStart(t *testing.T) {
+    baseDelay := startRetryBaseDelay
+    startRetryBaseDelay = time.Millisecond
+    t.Cleanup(func() { startRetryBaseDelay = baseDelay })
+
+    marker := filepath.Join(t.TempDir(), "failed-once")
+    launches := 0
+    launcher := &pluginLauncher{
+        newConfig: func() *plugin.ClientConfig {
+            launches++
+            config := newClientConfig(os.Args[0], hclog.NewNullLogger(), true, nil)
+            config.Cmd.Env = append(os.Environ(), testPluginEnv+"=flaky-start", flakyStartMarkerEnv+"="+marker)
+            return config
+        },
+        autoMTLS: true,
+        logger:   hclog.NewNullLogger(),
+    }
+
+    // Without retries the failed first launch is final
+    if launched, err := launcher.launch(); err == nil {
+        launched.client.Kill()
+        t.Fatalf("launch without retries succeeded despite the failed start")
+    }
+    if err := os.Remove(marker); err != nil {
+        t.Fatalf("the plugin didn't fail its first start: %v", err)
+    }
+
+    launches = 0
+    launcher.retries = 2
+    launched, err := launcher.launch()
+    if err != nil {
+        t.Fatalf("launch with retries failed: %v", err)
+    }
+    defer launched.client.Kill()
+    if launches != 2 {
+        t.Fatalf("launch took %d attempts, want 2", launches)
+    }
+    if err := launched.rpcClient.Ping(); err != nil {
+        t.Fatalf("Ping of the relaunched plugin failed: %v", err)
+    }
+}
+
+func TestLaunchFailureIncludesStderr(t *testing.T) {
+    launcher := &pluginLauncher{
+        newConfig: func() *plugin.ClientConfig {
+            config := newClientConfig(os.Args[0], hclog.NewNullLogger(), true, nil)
+            config.Cmd.Env = append(os.Environ(), testPluginEnv+"=fail-start")
+            return config
+        },
+        autoMTLS: true,
+        logger:   hclog.NewNullLogger(),
+    }
+    launched, err := launcher.launch()
+    if err == nil {
+        launched.client.Kill()
+        t.Fatalf("launching a plugin that exits at startup succeeded")
+    }
+    if !strings.Contains(err.Error(), "opening data directory: permission denied") {
+        t.Fatalf("launch error %q doesn't include the plugin's stderr", err)
+    }
+}
+
+func TestStartRetriesFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value   string
+        want    int
+        wantErr bool
+    }{
+        {"", 0, false},
+        {"3", 3, false},
+        {"-1", 0, true},
+        {"a few", 0, true},
+    } {
+        t.Setenv("PLUGIN_KV_START_RETRIES", tt.value)
+        got, err := startRetriesFromEnv()
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Fatalf("startRetriesFromEnv() with %q = %d, %v, want %d and error %t", tt.value, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
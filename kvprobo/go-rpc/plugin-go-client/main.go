@@ -4,21 +4,46 @@
 package main
 
 import (
+    "bufio"
+    "context"
+    "errors"
+    "flag"
     "fmt"
+    "io"
     "os"
     "os/exec"
+    "os/signal"
+    "sort"
     "strconv"
+    "strings"
+    "syscall"
     "time"
 
-    //"crypto/tls"
+    "crypto/tls"
     "crypto/x509"
     "encoding/pem"
 
     "github.com/hashicorp/go-hclog"
     "github.com/hashicorp/go-plugin"
     "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+    "go.opentelemetry.io/otel"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
 )
 
+// exitCodeKeyNotFound is the exit status when get targets a missing key, so
+// scripts can tell it apart from other failures.
+const exitCodeKeyNotFound = 3
+
+// errKeyAbsent is returned by the exists command so the process exits
+// non-zero without printing an error.
+var errKeyAbsent = errors.New("key absent")
+
+// defaultStartTimeout is how long the plugin gets to complete its handshake
+// when PLUGIN_START_TIMEOUT is unset.
+const defaultStartTimeout = 5 * time.Second
+
 // DisplayCertificate logs the certificate details.
 func displayCertificate(cert *x509.Certificate) {
     fmt.Println("📜 Received Certificate:")
@@ -28,21 +53,91 @@ func displayCertificate(cert *x509.Certificate) {
     fmt.Printf("   📆 Valid From: %s\n", cert.NotBefore)
     fmt.Printf("   📆 Valid To: %s\n", cert.NotAfter)
     fmt.Printf("   🌐 DNS Names: %v\n", cert.DNSNames)
+    fmt.Printf("   🧬 SHA-256 Fingerprint: %s\n", shared.CertificateFingerprint(cert))
 
-    // PEM encode the certificate for debugging
+    // Only the size of the PEM goes to the output
     pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
-    fmt.Println("   🔐 PEM Encoded Certificate:")
-    fmt.Println(string(pemBytes))
+    fmt.Println("   🔐 PEM Encoded Certificate: " + shared.RedactValue(string(pemBytes)))
+}
+
+// checkClientTLSEnv rejects the manual mTLS variables under AutoMTLS, where
+// go-plugin generates the certificates itself, and requires a client
+// certificate and a way to verify the plugin when AutoMTLS is off. A
+// certificate pin is refused under AutoMTLS too, since the plugin gets a
+// new certificate every time it starts. insecure, set by --insecure, is the
+// only way to go without TLS, and needs AutoMTLS off and no certificates.
+func checkClientTLSEnv(autoMTLS, insecure bool) error {
+    clientCert := os.Getenv("PLUGIN_CLIENT_CERT")
+    serverCert := os.Getenv("PLUGIN_SERVER_CERT")
+    caCert := os.Getenv("PLUGIN_CA_CERT")
+    if insecure {
+        if autoMTLS {
+            return errors.New("--insecure disables TLS, but AutoMTLS is enabled; set PLUGIN_AUTO_MTLS=false as well")
+        }
+        if clientCert != "" || serverCert != "" || caCert != "" || os.Getenv("PLUGIN_SERVER_CERT_PIN") != "" {
+            return errors.New("--insecure disables TLS, but TLS certificates are configured; unset them or drop --insecure")
+        }
+        return nil
+    }
+    if autoMTLS {
+        if clientCert != "" || serverCert != "" || caCert != "" {
+            return errors.New("AutoMTLS is enabled, but PLUGIN_CLIENT_CERT, PLUGIN_SERVER_CERT or PLUGIN_CA_CERT is set; unset them or set PLUGIN_AUTO_MTLS=false")
+        }
+        if os.Getenv("PLUGIN_SERVER_CERT_PIN") != "" {
+            return errors.New("AutoMTLS is enabled, but PLUGIN_SERVER_CERT_PIN is set; AutoMTLS certificates change on every start, so pinning needs PLUGIN_AUTO_MTLS=false")
+        }
+        return nil
+    }
+    if clientCert == "" || (serverCert == "" && caCert == "") {
+        return errors.New("PLUGIN_AUTO_MTLS=false requires PLUGIN_CLIENT_CERT and either PLUGIN_SERVER_CERT or PLUGIN_CA_CERT; refusing to connect without TLS (pass --insecure to do so deliberately)")
+    }
+    return nil
+}
+
+// startTimeoutFromEnv reads PLUGIN_START_TIMEOUT as a Go duration. An
+// unset, invalid or non-positive value falls back to defaultStartTimeout.
+func startTimeoutFromEnv(logger hclog.Logger) time.Duration {
+    value := os.Getenv("PLUGIN_START_TIMEOUT")
+    if value == "" {
+        return defaultStartTimeout
+    }
+    timeout, err := time.ParseDuration(value)
+    if err == nil && timeout <= 0 {
+        err = errors.New("must be positive")
+    }
+    if err != nil {
+        logger.Warn("⏱️⚠️ invalid PLUGIN_START_TIMEOUT value, using the default",
+            "value", value,
+            "default", defaultStartTimeout,
+            "error", err)
+        return defaultStartTimeout
+    }
+    return timeout
+}
+
+// newClientConfig describes how to launch and connect to the plugin at
+// pluginPath.
+func newClientConfig(pluginPath string, logger hclog.Logger, autoMTLS bool, dialOptions []grpc.DialOption) *plugin.ClientConfig {
+    return &plugin.ClientConfig{
+        HandshakeConfig:   shared.Handshake,
+        VersionedPlugins:  shared.VersionedPlugins(&shared.KVGRPCPlugin{Logger: logger}),
+        Cmd:              exec.Command(pluginPath),
+        Logger:           logger,
+        AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+        StartTimeout:     startTimeoutFromEnv(logger),
+        Managed:         true,
+        AutoMTLS:        autoMTLS,
+        GRPCDialOptions: dialOptions,
+    }
 }
 
 func run() error {
     // Create logger with more verbose debugging
-    logger := hclog.New(&hclog.LoggerOptions{
-        Name:       "🌐 kv-client",
-        Level:      hclog.Trace,
-        Output:     os.Stderr,
-        JSONFormat: false,
-    })
+    logger, err := shared.NewLoggerFromEnv("🌐 kv-client", os.Stderr, shared.DefaultLogLevel)
+    if err != nil {
+        logger.Error("📝❌ invalid logging setting", "error", err)
+        return err
+    }
 
     // Display environment variables based on the toggle and filter
     shared.DisplayFilteredEnv(logger, []string{
@@ -53,114 +148,206 @@ func run() error {
 
     logger.Info("🚀 starting KV client application")
 
-    // Validate environment variables
+    // Validate environment variables. A standalone server is dialled
+    // rather than launched, so it needs no plugin executable
+    dialAddr := os.Getenv("PLUGIN_KV_DIAL_ADDR")
     pluginPath := os.Getenv("PLUGIN_SERVER_PATH")
-    if pluginPath == "" {
-        logger.Error("🔍❌ PLUGIN_SERVER_PATH environment variable must be set")
-        return fmt.Errorf("PLUGIN_SERVER_PATH environment variable must be set")
-    }
-    logger.Debug("🔍✅ found PLUGIN_SERVER_PATH path", "path", pluginPath)
+    if dialAddr == "" {
+        if pluginPath == "" {
+            discovered, err := discoverPluginBinary(logger)
+            if err != nil {
+                logger.Error("🔍❌ no plugin executable to launch", "error", err)
+                return err
+            }
+            pluginPath = discovered
+        } else {
+            logger.Debug("🔍✅ found PLUGIN_SERVER_PATH path", "path", pluginPath)
+        }
 
-    // Verify plugin executable exists
-    if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
-        logger.Error("🔍❌ plugin executable not found", "path", pluginPath)
-        return fmt.Errorf("plugin executable not found at: %s", pluginPath)
+        // Verify plugin executable exists
+        if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+            logger.Error("🔍❌ plugin executable not found", "path", pluginPath)
+            return fmt.Errorf("plugin executable not found at: %s", pluginPath)
+        }
+        logger.Debug("🔍✅ verified plugin executable exists")
     }
-    logger.Debug("🔍✅ verified plugin executable exists")
 
-    // Check if AutoMTLS should be enabled
-    autoMTLS := true // Default to secure mode
-    if envAutoMTLS := os.Getenv("PLUGIN_AUTO_MTLS"); envAutoMTLS != "" {
-        var err error
-        autoMTLS, err = strconv.ParseBool(envAutoMTLS)
-        if err != nil {
-            logger.Warn("🔐⚠️ invalid PLUGIN_AUTO_MTLS value, defaulting to enabled", 
-                "value", envAutoMTLS,
-                "error", err)
+    // Refuse to launch a plugin executable that has been tampered with
+    pluginChecksum, err := pluginChecksumFromEnv()
+    if err != nil {
+        logger.Error("🔏❌ invalid plugin checksum setting", "error", err)
+        return err
+    }
+    if pluginChecksum != nil && dialAddr == "" {
+        if err := verifyPluginChecksum(pluginPath, pluginChecksum); err != nil {
+            logger.Error("🔏❌ plugin executable failed verification", "error", err)
+            return err
         }
+        logger.Debug("🔏✅ plugin executable matches PLUGIN_SERVER_SHA256", "path", pluginPath)
     }
 
-    // Validate certificates if AutoMTLS is enabled
+    // AutoMTLS and manually supplied certificates are mutually exclusive,
+    // and turning AutoMTLS off must not silently fall back to plaintext;
+    // that takes --insecure
+    args, insecure := extractFlag(os.Args[1:], "--insecure")
+    autoMTLS := shared.AutoMTLSFromEnv(logger)
+    if err := checkClientTLSEnv(autoMTLS, insecure); err != nil {
+        logger.Error("🔐❌ invalid TLS configuration", "error", err)
+        return err
+    }
+    if err := checkDialTLSEnv(dialAddr, autoMTLS); err != nil {
+        logger.Error("🔐❌ invalid TLS configuration", "error", err)
+        return err
+    }
+    tlsPolicy, err := shared.TLSPolicyFromEnv()
+    if err != nil {
+        logger.Error("🔐❌ invalid TLS policy", "error", err)
+        return err
+    }
+    var tlsConfig *tls.Config
     if autoMTLS {
         logger.Info("🔐 AutoMTLS is enabled. Proceeding with TLS setup...")
-
-        clientCert := os.Getenv("PLUGIN_CLIENT_CERT")
-        serverCert := os.Getenv("PLUGIN_SERVER_CERT")
-
-        if clientCert != "" || serverCert != "" {
-            logger.Error("❌🔒 AutoMTLS is enabled, but PLUGIN_CLIENT_CERT and/or PLUGIN_SERVER_CERT are set, which is not allowed")
-        }
+    } else if insecure {
+        logger.Warn("🔓⚠️ TLS is DISABLED by --insecure: the plugin is not authenticated and keys and values travel in plaintext")
     } else {
-        logger.Info("🚫 AutoMTLS is disabled. Skipping TLS setup.")
+        logger.Info("🔐 AutoMTLS is disabled. Using the provided certificates.")
+        tlsConfig, err = shared.ClientTLSConfigFromEnv(tlsPolicy, logger)
+        if err != nil {
+            logger.Error("🔐❌ invalid client certificate", "error", err)
+            return err
+        }
     }
 
-    config := &plugin.ClientConfig{
-        HandshakeConfig:   shared.Handshake,
-        Plugins: map[string]plugin.Plugin{
-            "kv_grpc": &shared.KVGRPCPlugin{},
-        },
-        Cmd:              exec.Command(pluginPath),
-        Logger:           logger,
-        AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-        StartTimeout:     5 * time.Second,
-        Managed:         true,
-        AutoMTLS:        autoMTLS,
+    // Retry reads that fail while the plugin is still coming up; each
+    // attempt is logged separately
+    maxRetries, err := shared.MaxRetriesFromEnv()
+    if err != nil {
+        logger.Error("🔄❌ invalid retry setting", "error", err)
+        return err
+    }
+    dialOptions := []grpc.DialOption{
+        grpc.WithChainUnaryInterceptor(
+            shared.RetryUnaryClientInterceptor(maxRetries, logger.Named("rpc")),
+            shared.LoggingUnaryClientInterceptor(logger.Named("rpc")),
+        ),
+    }
+
+    // Compress request payloads if asked to
+    compressor, err := shared.CompressionFromEnv()
+    if err != nil {
+        logger.Error("🗜️❌ invalid compression setting", "error", err)
+        return err
+    }
+    if compressor != "" {
+        logger.Debug("🗜️ compressing requests", "compressor", compressor)
+        dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(grpc.UseCompressor(compressor)))
     }
 
-    logger.Debug("🔧✅ plugin client configuration complete",
-        "timeout", config.StartTimeout,
-        "managed", config.Managed,
-        "auto_mtls", autoMTLS)
+    // Detect a dead plugin on an idle connection
+    keepaliveInterval, err := shared.KeepaliveIntervalFromEnv()
+    if err != nil {
+        logger.Error("💓❌ invalid keepalive setting", "error", err)
+        return err
+    }
+    dialOptions = append(dialOptions, shared.KeepaliveDialOptions(keepaliveInterval)...)
+    logger.Debug("💓 keepalive configured", "interval", keepaliveInterval)
 
-    // Create plugin client
-    logger.Debug("🔌 creating new plugin client")
-    client := plugin.NewClient(config)
+    // Trace RPCs when a collector is configured; the plugin joins our trace
+    tracing, err := shared.TracingFromEnv()
+    if err != nil {
+        logger.Error("🔭❌ invalid tracing setting", "error", err)
+        return err
+    }
+    stopTracing, err := tracing.Start(context.Background(), "kv-go-client")
+    if err != nil {
+        logger.Error("🔭❌ failed to start tracing", "error", err)
+        return err
+    }
     defer func() {
-        logger.Debug("🧹 cleaning up plugin client")
-        client.Kill()
+        flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        if err := stopTracing(flushCtx); err != nil {
+            logger.Warn("🔭⚠️ failed to flush traces", "error", err)
+        }
     }()
+    dialOptions = append(dialOptions, tracing.DialOptions()...)
 
-    // Connect via RPC
-    logger.Debug("🤝 attempting to establish RPC connection")
-    rpcClient, err := client.Client()
-    if err != nil {
-        logger.Error("🤝❌ failed to create RPC client",
-            "error", err,
-            "error_type", fmt.Sprintf("%T", err))
-        return fmt.Errorf("error creating RPC client: %w", err)
+    // A standalone server is already running, so skip go-plugin entirely
+    if dialAddr != "" {
+        kv, err := dialKV(dialAddr, tlsConfig, dialOptions, logger)
+        if err != nil {
+            logger.Error("🔌❌ failed to dial server", "address", dialAddr, "error", err)
+            return fmt.Errorf("error dialling %s: %w", dialAddr, err)
+        }
+        defer kv.Close()
+        info := connectionInfo{
+            Network:  "tcp",
+            Address:  dialAddr,
+            Protocol: string(plugin.ProtocolGRPC),
+            Version:  shared.ProtocolVersion,
+            Secure:   tlsConfig != nil,
+        }
+        return runCommands(kv, args, info, nil, logger)
     }
-    logger.Debug("🤝✅ RPC connection established")
 
-    // Get the RPC address
-    logger.Debug("🔌 starting RPC client")
-    rpcAddr, err := client.Start()
+    // Relaunch a plugin with a flaky cold start instead of giving up at once
+    startRetries, err := startRetriesFromEnv()
     if err != nil {
-        logger.Error("🔌❌ failed to start RPC client", "error", err)
-        return fmt.Errorf("error starting RPC client: %w", err)
+        logger.Error("🔌❌ invalid start retry setting", "error", err)
+        return err
     }
 
-// Get protocol info
-protocol := client.Protocol()
-version := client.NegotiatedVersion()
+    newConfig := func() *plugin.ClientConfig {
+        config := newClientConfig(pluginPath, logger, autoMTLS, dialOptions)
+        // The launch fills in the TLS config, so each attempt needs its own
+        if tlsConfig != nil {
+            config.TLSConfig = tlsConfig.Clone()
+        }
+        if pluginChecksum != nil {
+            config.SecureConfig = pluginSecureConfig(pluginChecksum)
+        }
+        if insecure {
+            // The plugin refuses to serve without TLS unless it's told to as well
+            config.Cmd.Env = append(os.Environ(), "PLUGIN_KV_ALLOW_INSECURE=true")
+        }
+        logger.Debug("🔧✅ plugin client configuration complete",
+            "timeout", config.StartTimeout,
+            "managed", config.Managed,
+            "auto_mtls", autoMTLS)
+        return config
+    }
+    launcher := &pluginLauncher{
+        newConfig: newConfig,
+        autoMTLS:  autoMTLS,
+        tlsPolicy: tlsPolicy,
+        retries:   startRetries,
+        logger:    logger,
+    }
 
-logger.Debug("🔌✅ RPC client started successfully",
-    "network", rpcAddr.Network(),
-    "address", rpcAddr.String(),
-    "protocol", protocol,
-    "version", version,
-    "secure", autoMTLS)
-/*
-    // Adjust TLS config for Unix sockets if needed
-    if rpcAddr.Network() == "unix" && tlsConfig != nil {
-        logger.Debug("🔧 adjusting TLS config for Unix socket")
-        tlsConfig.InsecureSkipVerify = true
-        tlsConfig.ServerName = ""
+    launched, err := launcher.launch()
+    if err != nil {
+        return err
     }
-    */
+    client, config, rpcClient, rpcAddr := launched.client, launched.config, launched.rpcClient, launched.addr
+    defer func() {
+        logger.Debug("🧹 cleaning up plugin client")
+        client.Kill()
+    }()
+
+    // Get protocol info
+    protocol := client.Protocol()
+    version := client.NegotiatedVersion()
+
+    logger.Debug("🔌✅ RPC client started successfully",
+        "network", rpcAddr.Network(),
+        "address", rpcAddr.String(),
+        "protocol", protocol,
+        "version", version,
+        "secure", autoMTLS)
 
     // Request the plugin
     logger.Debug("🔌 attempting to dispense plugin")
-    raw, err := rpcClient.Dispense("kv_grpc")
+    raw, err := rpcClient.Dispense(shared.PluginName)
     if err != nil {
         logger.Error("🔌❌ error dispensing plugin",
             "error", err,
@@ -178,8 +365,96 @@ logger.Debug("🔌✅ RPC client started successfully",
     }
     logger.Debug("✅ type assertion successful")
 
-    // Process commands
-    if err := handleCommand(logger, kv); err != nil {
+    conn := connectionInfo{
+        Network:  rpcAddr.Network(),
+        Address:  rpcAddr.String(),
+        Protocol: string(protocol),
+        Version:  version,
+        Secure:   !insecure,
+    }
+    crash := &crashDiagnoser{client: client, cmd: config.Cmd, stderr: launched.stderr}
+    return runCommands(kv, args, conn, crash.diagnose, logger)
+}
+
+// runCommands runs the command in args, or a repl, against kv, reached as
+// conn describes. diagnose, if not nil, explains errors caused by the
+// plugin process dying.
+func runCommands(kv shared.KV, args []string, conn connectionInfo, diagnose func(error) error, logger hclog.Logger) error {
+    // Bound each request so a stuck plugin can't hang the CLI
+    requestTimeout := shared.DefaultRequestTimeout
+    if envTimeout := os.Getenv("PLUGIN_KV_REQUEST_TIMEOUT"); envTimeout != "" {
+        parsed, err := time.ParseDuration(envTimeout)
+        if err != nil {
+            logger.Warn("⏱️⚠️ invalid PLUGIN_KV_REQUEST_TIMEOUT value, using default",
+                "value", envTimeout,
+                "default", requestTimeout,
+                "error", err)
+        } else {
+            requestTimeout = parsed
+        }
+    }
+    if grpcClient, ok := kv.(*shared.GRPCClient); ok {
+        grpcClient.RequestTimeout = requestTimeout
+        logger.Debug("⏱️ request timeout configured", "timeout", requestTimeout)
+        // RPCs the plugin's version doesn't serve are refused up front
+        grpcClient.ProtocolVersion = conn.Version
+    }
+
+    // --namespace applies to every command, including each one in a repl
+    args, namespace, err := extractFlagValue(args, "--namespace")
+    if err != nil {
+        return fmt.Errorf("usage: %s [--namespace name] command [args]: %w", os.Args[0], err)
+    }
+
+    // Process commands under one span so every RPC shares a trace
+    command := "kv"
+    if len(args) > 0 {
+        command = "kv " + args[0]
+    }
+    ctx, span := otel.Tracer("kv-go-client").Start(context.Background(), command)
+    defer span.End()
+    if namespace != "" {
+        logger.Debug("🗂️ using namespace", "namespace", namespace)
+        ctx = shared.WithNamespace(ctx, namespace)
+    }
+
+    eventSink, err := eventSinkFromEnv()
+    if err != nil {
+        return err
+    }
+    if grpcClient, ok := kv.(*shared.GRPCClient); ok && eventSink {
+        if err := grpcClient.SetEventSink(ctx, &loggingSink{logger: logger}); err != nil {
+            logger.Error("📣❌ registering event sink failed", "error", err)
+            return fmt.Errorf("error registering event sink: %w", err)
+        }
+        logger.Debug("📣 event sink registered")
+    }
+
+    // repl keeps the plugin running for every command read from stdin;
+    // it's killed by run's deferred cleanup once stdin reaches EOF
+    session := newKVSession(kv, conn.Version, logger)
+    session.conn = conn
+    session.diagnose = diagnose
+    if len(args) > 0 && args[0] == "repl" {
+        if len(args) != 1 {
+            return fmt.Errorf("usage: %s [--namespace name] repl < commands", os.Args[0])
+        }
+        logger.Debug("🐚 starting repl")
+        return session.REPL(ctx, os.Stdin)
+    }
+    if err := session.explain(session.Execute(ctx, args)); err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            // The command printed its help, which is all that was asked for
+            return nil
+        }
+        if errors.Is(err, errPluginTerminated) {
+            logger.Error("💥 plugin process terminated unexpectedly", "error", err)
+            return err
+        }
+        if isDeadlineExceeded(err) {
+            logger.Error("⏱️❌ request timed out", "timeout", requestTimeout, "error", err)
+            return fmt.Errorf("plugin did not respond within %s (raise PLUGIN_KV_REQUEST_TIMEOUT to wait longer)", requestTimeout)
+        }
         return err
     }
 
@@ -187,50 +462,722 @@ logger.Debug("🔌✅ RPC client started successfully",
     return nil
 }
 
-func handleCommand(logger hclog.Logger, kv shared.KV) error {
-    if len(os.Args) < 2 {
+// isDeadlineExceeded reports whether err came from a request running out of time.
+func isDeadlineExceeded(err error) bool {
+    return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// extractFlag removes every occurrence of flag from args and reports whether
+// it was present.
+func extractFlag(args []string, flag string) ([]string, bool) {
+    rest := make([]string, 0, len(args))
+    found := false
+    for _, arg := range args {
+        if arg == flag {
+            found = true
+            continue
+        }
+        rest = append(rest, arg)
+    }
+    return rest, found
+}
+
+// extractFlagValue removes flag and the value after it (or flag=value) from
+// args and returns that value, or "" if flag is absent.
+func extractFlagValue(args []string, flag string) ([]string, string, error) {
+    rest := make([]string, 0, len(args))
+    value := ""
+    for i := 0; i < len(args); i++ {
+        arg := args[i]
+        switch {
+        case arg == flag:
+            if i+1 == len(args) {
+                return nil, "", fmt.Errorf("%s needs a value", flag)
+            }
+            i++
+            value = args[i]
+        case strings.HasPrefix(arg, flag+"="):
+            value = strings.TrimPrefix(arg, flag+"=")
+        default:
+            rest = append(rest, arg)
+        }
+    }
+    return rest, value, nil
+}
+
+// readBatchItems parses key=value lines for batch-put. Blank lines are
+// skipped; the value is everything after the first '='.
+func readBatchItems(r io.Reader) (map[string][]byte, error) {
+    items := map[string][]byte{}
+    scanner := bufio.NewScanner(r)
+    line := 0
+    for scanner.Scan() {
+        line++
+        text := scanner.Text()
+        if strings.TrimSpace(text) == "" {
+            continue
+        }
+        key, value, ok := strings.Cut(text, "=")
+        if !ok || key == "" {
+            return nil, fmt.Errorf("line %d: expected key=value, got %q", line, text)
+        }
+        items[key] = []byte(value)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    return items, nil
+}
+
+// Execute runs one command, args[0] being the subcommand, against the
+// session's plugin.
+func (s *KVSession) Execute(ctx context.Context, args []string) error {
+    // Tag the command's log lines, and the plugin's for its RPCs, with one ID
+    ctx = shared.WithRequestID(ctx, shared.NewRequestID())
+    logger := shared.RequestLogger(ctx, s.logger)
+    if len(args) == 0 {
         logger.Error("❌ insufficient command line arguments")
-        return fmt.Errorf("usage: %s [get|put] key [value]", os.Args[0])
+        return fmt.Errorf("usage: %s [--insecure] [--namespace name] [get|put|mput|delete|rename|list|scan|exists|incr|batch-put|export|import|watch|health|stats|ping|info|repl] key [value]", os.Args[0])
     }
+    err := s.execute(ctx, logger, args)
+    var usage *usageError
+    if errors.As(err, &usage) {
+        logger.Error("❌ invalid arguments", "command", args[0], "error", usage.reason)
+    }
+    return err
+}
 
-    switch os.Args[1] {
+// execute runs the subcommand args[0], giving it the arguments after it.
+func (s *KVSession) execute(ctx context.Context, logger hclog.Logger, args []string) error {
+    kv := s.kv
+    switch args[0] {
     case "get":
-        if len(os.Args) != 3 {
-            logger.Error("❌ invalid number of arguments for get operation")
-            return fmt.Errorf("usage: %s get key", os.Args[0])
+        cmd := newCommand("get", "[--stream] [--output-file path] [--if-modified-after RFC3339-time] [--show-content-type] key",
+            "Print the value stored under key.")
+        stream := cmd.flags.Bool("stream", false, "stream the value in chunks, for values too large for one message")
+        outputFile := cmd.flags.String("output-file", "", "write the value to `path` instead of stdout")
+        since := cmd.flags.String("if-modified-after", "", "print nothing unless the value changed after `time`, given in RFC3339")
+        showContentType := cmd.flags.Bool("show-content-type", false, "print a \"content-type: type\" line before the value")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 1 {
+            return cmd.usageError("want one key, got %d arguments", len(args))
+        }
+        if *stream && *since != "" {
+            return cmd.usageError("--stream can't be combined with --if-modified-after")
+        }
+        if *showContentType && (*stream || *since != "") {
+            return cmd.usageError("--show-content-type can't be combined with --stream or --if-modified-after")
+        }
+        var ifModifiedAfter time.Time
+        if *since != "" {
+            if ifModifiedAfter, err = time.Parse(time.RFC3339Nano, *since); err != nil {
+                return cmd.usageError("invalid --if-modified-after: %v", err)
+            }
+        }
+        key := args[0]
+        if *stream {
+            grpcClient, ok := kv.(*shared.GRPCClient)
+            if !ok {
+                return fmt.Errorf("--stream is not supported by %T", kv)
+            }
+            logger.Debug("📥 executing streaming get operation", "key", key)
+            var out io.Writer = s.stdout
+            var file *os.File
+            if *outputFile != "" {
+                file, err = os.Create(*outputFile)
+                if err != nil {
+                    return fmt.Errorf("error creating output file: %w", err)
+                }
+                out = file
+            }
+            err := grpcClient.GetStream(ctx, key, out)
+            if file != nil {
+                if closeErr := file.Close(); err == nil && closeErr != nil {
+                    err = fmt.Errorf("error writing output file: %w", closeErr)
+                }
+                if err != nil {
+                    os.Remove(*outputFile)
+                }
+            }
+            if err != nil {
+                if errors.Is(err, shared.ErrKeyNotFound) {
+                    logger.Debug("📥🔍 key not found", "key", key)
+                    return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+                }
+                logger.Error("📥❌ streaming get operation failed",
+                    "key", key,
+                    "error", err)
+                return fmt.Errorf("error getting value: %w", err)
+            }
+            if file == nil {
+                fmt.Fprintln(s.stdout)
+            }
+            logger.Debug("📥✅ streaming get operation successful", "key", key)
+            break
+        }
+        logger.Debug("📥 executing get operation", "key", key, "if_modified_after", *since)
+        var result []byte
+        var contentType string
+        modified, modTime := true, time.Time{}
+        if *showContentType {
+            result, contentType, err = kv.GetWithContentType(ctx, key)
+        } else if ifModifiedAfter.IsZero() {
+            result, err = kv.Get(ctx, key)
+        } else {
+            result, modified, modTime, err = kv.GetConditional(ctx, key, ifModifiedAfter)
+        }
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            logger.Debug("📥🔍 key not found", "key", key)
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
         }
-        logger.Debug("📥 executing get operation", "key", os.Args[2])
-        result, err := kv.Get(os.Args[2])
         if err != nil {
             logger.Error("📥❌ get operation failed",
-                "key", os.Args[2],
+                "key", key,
                 "error", err)
             return fmt.Errorf("error getting value: %w", err)
         }
+        if !modified {
+            // Nothing is printed or written, so the caller keeps its copy
+            logger.Info("📥 value not modified", "key", key, "mod_time", modTime.Format(time.RFC3339Nano))
+            break
+        }
         logger.Debug("📥✅ get operation successful",
-            "key", os.Args[2],
+            "key", key,
             "value_length", len(result))
-        fmt.Println(string(result))
+        if *showContentType {
+            fmt.Fprintf(s.stdout, "content-type: %s\n", contentType)
+        }
+        if *outputFile != "" {
+            // Write the bytes exactly, without the newline printed to a terminal
+            if err := os.WriteFile(*outputFile, result, 0644); err != nil {
+                return fmt.Errorf("error writing output file: %w", err)
+            }
+            break
+        }
+        fmt.Fprintln(s.stdout, string(result))
 
     case "put":
-        if len(os.Args) != 4 {
-            logger.Error("❌ invalid number of arguments for put operation")
-            return fmt.Errorf("usage: %s put key value", os.Args[0])
+        cmd := newCommand("put", "key value | put key - | put --stdin key | put --value-file path key",
+            "Store value under key. A value of - reads it from stdin.")
+        fromStdin := cmd.flags.Bool("stdin", false, "read the value from stdin, byte for byte")
+        valueFile := cmd.flags.String("value-file", "", "read the value from `path`")
+        contentType := cmd.flags.String("content-type", "", "store the value as media `type`, such as application/json (default "+shared.DefaultContentType+")")
+        ifAbsent := cmd.flags.Bool("if-absent", false, "only store the value if key doesn't exist yet")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) == 2 && args[1] == "-" {
+            args, *fromStdin = args[:1], true
+        }
+        if *fromStdin && *valueFile != "" {
+            return cmd.usageError("--stdin can't be combined with --value-file")
+        }
+        if *ifAbsent && *contentType != "" {
+            return cmd.usageError("--if-absent can't be combined with --content-type")
+        }
+        if err := shared.CheckContentType(*contentType); err != nil {
+            return cmd.usageError("invalid --content-type: %v", err)
+        }
+        fromArg := !*fromStdin && *valueFile == ""
+        if fromArg && len(args) != 2 {
+            return cmd.usageError("want a key and a value, got %d arguments", len(args))
+        }
+        if !fromArg && len(args) != 1 {
+            return cmd.usageError("want only a key when the value is read elsewhere, got %d arguments", len(args))
+        }
+        key := args[0]
+        var value []byte
+        switch {
+        case *fromStdin:
+            // Read verbatim so binary and multi-line values survive
+            value, err = io.ReadAll(s.stdin)
+            if err != nil {
+                return fmt.Errorf("error reading value from stdin: %w", err)
+            }
+        case *valueFile != "":
+            value, err = os.ReadFile(*valueFile)
+            if err != nil {
+                return fmt.Errorf("error reading value file: %w", err)
+            }
+        default:
+            value = []byte(args[1])
         }
         logger.Debug("📤 executing put operation",
-            "key", os.Args[2],
-            "value_length", len(os.Args[3]))
-        if err := kv.Put(os.Args[2], []byte(os.Args[3])); err != nil {
+            "key", key,
+            "value_length", len(value),
+            "from_stdin", *fromStdin,
+            "value_file", *valueFile,
+            "content_type", *contentType,
+            "if_absent", *ifAbsent)
+        if *ifAbsent {
+            created, err := kv.PutIfAbsent(ctx, key, value)
+            if err != nil {
+                logger.Error("📤❌ put operation failed",
+                    "key", key,
+                    "error", err)
+                return fmt.Errorf("error putting value: %w", err)
+            }
+            if !created {
+                logger.Info("📤 key already exists, value left unchanged", "key", key)
+                break
+            }
+            logger.Info("📤✅ successfully created key", "key", key)
+            break
+        }
+        // The idempotency key lets the Put be retried without writing twice
+        putCtx := shared.WithIdempotencyKey(ctx, shared.NewIdempotencyKey())
+        if *contentType != "" {
+            err = kv.PutWithContentType(putCtx, key, value, *contentType, 0)
+        } else {
+            err = kv.Put(putCtx, key, value)
+        }
+        if err != nil {
             logger.Error("📤❌ put operation failed",
-                "key", os.Args[2],
+                "key", key,
                 "error", err)
             return fmt.Errorf("error putting value: %w", err)
         }
-        logger.Info("📤✅ successfully put value", "key", os.Args[2])
+        logger.Info("📤✅ successfully put value", "key", key)
+
+    case "delete":
+        cmd := newCommand("delete", "key", "Delete key.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 1 {
+            return cmd.usageError("want one key, got %d arguments", len(args))
+        }
+        logger.Debug("🗑️ executing delete operation", "key", args[0])
+        if err := kv.Delete(ctx, args[0]); err != nil {
+            logger.Error("🗑️❌ delete operation failed",
+                "key", args[0],
+                "error", err)
+            return fmt.Errorf("error deleting value: %w", err)
+        }
+        logger.Info("🗑️✅ successfully deleted value", "key", args[0])
+
+    case "rename":
+        cmd := newCommand("rename", "[--overwrite] old-key new-key", "Move the value of old-key to new-key.")
+        overwrite := cmd.flags.Bool("overwrite", false, "replace a value already stored under new-key")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 2 {
+            return cmd.usageError("want the old and new key, got %d arguments", len(args))
+        }
+        logger.Debug("🚚 executing rename operation", "old_key", args[0], "new_key", args[1], "overwrite", *overwrite)
+        if err := kv.Rename(ctx, args[0], args[1], *overwrite); err != nil {
+            logger.Error("🚚❌ rename operation failed",
+                "old_key", args[0],
+                "new_key", args[1],
+                "error", err)
+            return fmt.Errorf("error renaming key: %w", err)
+        }
+        logger.Info("🚚✅ successfully renamed key", "old_key", args[0], "new_key", args[1])
+
+    case "list":
+        cmd := newCommand("list", "[--page-size n] [prefix]", "Print every key starting with prefix.")
+        pageSize := cmd.flags.Int("page-size", 0, "fetch keys `n` at a time instead of all at once")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if cmd.isSet("page-size") && *pageSize < 1 {
+            return cmd.usageError("--page-size must be at least 1, got %d", *pageSize)
+        }
+        if len(args) > 1 {
+            return cmd.usageError("want at most one prefix, got %d arguments", len(args))
+        }
+        prefix := ""
+        if len(args) == 1 {
+            prefix = args[0]
+        }
+        if *pageSize == 0 {
+            logger.Debug("📋 executing list operation", "prefix", prefix)
+            keys, err := kv.List(ctx, prefix)
+            if err != nil {
+                logger.Error("📋❌ list operation failed",
+                    "prefix", prefix,
+                    "error", err)
+                return fmt.Errorf("error listing keys: %w", err)
+            }
+            logger.Debug("📋✅ list operation successful",
+                "prefix", prefix,
+                "key_count", len(keys))
+            for _, key := range keys {
+                fmt.Fprintln(s.stdout, key)
+            }
+            break
+        }
+
+        // Fetch a page at a time, printing each as it arrives
+        logger.Debug("📋 executing paged list operation", "prefix", prefix, "page_size", *pageSize)
+        token, pages, total := "", 0, 0
+        for {
+            keys, next, err := kv.ListPage(ctx, prefix, token, *pageSize)
+            if err != nil {
+                logger.Error("📋❌ list operation failed",
+                    "prefix", prefix,
+                    "page", pages+1,
+                    "error", err)
+                return fmt.Errorf("error listing keys: %w", err)
+            }
+            for _, key := range keys {
+                fmt.Fprintln(s.stdout, key)
+            }
+            pages++
+            total += len(keys)
+            if next == "" {
+                break
+            }
+            token = next
+        }
+        logger.Debug("📋✅ list operation successful",
+            "prefix", prefix,
+            "key_count", total,
+            "pages", pages)
+
+    case "scan":
+        cmd := newCommand("scan", "[prefix]", "Print key=value for every key starting with prefix.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) > 1 {
+            return cmd.usageError("want at most one prefix, got %d arguments", len(args))
+        }
+        prefix := ""
+        if len(args) == 1 {
+            prefix = args[0]
+        }
+        logger.Debug("🧺 executing scan operation", "prefix", prefix)
+        values, truncated, err := kv.Scan(ctx, prefix)
+        if err != nil {
+            logger.Error("🧺❌ scan operation failed",
+                "prefix", prefix,
+                "error", err)
+            return fmt.Errorf("error scanning keys: %w", err)
+        }
+        logger.Debug("🧺✅ scan operation successful",
+            "prefix", prefix,
+            "key_count", len(values),
+            "truncated", truncated)
+        keys := make([]string, 0, len(values))
+        for key := range values {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        for _, key := range keys {
+            fmt.Fprintf(s.stdout, "%s=%s\n", key, values[key])
+        }
+        if truncated {
+            logger.Warn("🧺⚠️ scan truncated by the plugin; raise PLUGIN_KV_SCAN_LIMIT or narrow the prefix",
+                "key_count", len(values))
+        }
+
+    case "exists":
+        cmd := newCommand("exists", "key", "Print whether key exists, exiting with status 1 if it doesn't.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 1 {
+            return cmd.usageError("want one key, got %d arguments", len(args))
+        }
+        logger.Debug("🔎 executing exists operation", "key", args[0])
+        exists, err := kv.Exists(ctx, args[0])
+        if err != nil {
+            logger.Error("🔎❌ exists operation failed",
+                "key", args[0],
+                "error", err)
+            return fmt.Errorf("error checking key: %w", err)
+        }
+        logger.Debug("🔎✅ exists operation successful",
+            "key", args[0],
+            "exists", exists)
+        fmt.Fprintln(s.stdout, exists)
+        if !exists {
+            return errKeyAbsent
+        }
+
+    case "incr":
+        cmd := newCommand("incr", "key delta", "Add delta, which may be negative, to the integer under key and print the result.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 2 {
+            return cmd.usageError("want a key and a delta, got %d arguments", len(args))
+        }
+        delta, err := strconv.ParseInt(args[1], 10, 64)
+        if err != nil {
+            return fmt.Errorf("invalid delta %q: must be an integer", args[1])
+        }
+        logger.Debug("➕ executing incr operation", "key", args[0], "delta", delta)
+        total, err := kv.Increment(ctx, args[0], delta)
+        if err != nil {
+            logger.Error("➕❌ incr operation failed",
+                "key", args[0],
+                "error", err)
+            return fmt.Errorf("error incrementing value: %w", err)
+        }
+        logger.Debug("➕✅ incr operation successful",
+            "key", args[0],
+            "value", total)
+        fmt.Fprintln(s.stdout, total)
+
+    case "batch-put":
+        cmd := newCommand("batch-put", "< key=value lines", "Store every key=value line read from stdin in one request.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 0 {
+            return cmd.usageError("want no arguments, got %d", len(args))
+        }
+        items, err := readBatchItems(s.stdin)
+        if err != nil {
+            logger.Error("📤❌ invalid batch-put input", "error", err)
+            return fmt.Errorf("error reading batch: %w", err)
+        }
+        logger.Debug("📤 executing batch-put operation", "item_count", len(items))
+        if err := kv.BatchPut(ctx, items); err != nil {
+            logger.Error("📤❌ batch-put operation failed",
+                "item_count", len(items),
+                "error", err)
+            return fmt.Errorf("error putting batch: %w", err)
+        }
+        logger.Info("📤✅ successfully put batch", "item_count", len(items))
+
+    case "mput":
+        cmd := newCommand("mput", "key=value [key=value ...]", "Store each pair separately, printing every key's result.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) == 0 {
+            return cmd.usageError("want at least one key=value pair")
+        }
+        // Parse everything first so a typo doesn't leave a partial load
+        keys := make([]string, 0, len(args))
+        values := make([][]byte, 0, len(args))
+        for _, arg := range args {
+            key, value, ok := strings.Cut(arg, "=")
+            if !ok || key == "" {
+                return fmt.Errorf("invalid mput pair %q: expected key=value", arg)
+            }
+            keys = append(keys, key)
+            values = append(values, []byte(value))
+        }
+
+        // Put each pair separately rather than through BatchPut, which
+        // stops at the first bad key, so every key gets its own result
+        logger.Debug("📤 executing mput operation", "item_count", len(keys))
+        failed := 0
+        for i, key := range keys {
+            if err := kv.Put(ctx, key, values[i]); err != nil {
+                logger.Error("📤❌ mput put failed",
+                    "key", key,
+                    "error", err)
+                fmt.Fprintf(s.stdout, "%s: error: %v\n", key, err)
+                failed++
+                continue
+            }
+            fmt.Fprintf(s.stdout, "%s: ok\n", key)
+        }
+        if failed > 0 {
+            return fmt.Errorf("mput failed for %d of %d keys", failed, len(keys))
+        }
+        logger.Info("📤✅ successfully put all pairs", "item_count", len(keys))
+
+    case "health":
+        cmd := newCommand("health", "", "Print the plugin's health status, SERVING or NOT_SERVING.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 0 {
+            return cmd.usageError("want no arguments, got %d", len(args))
+        }
+        grpcClient, ok := kv.(*shared.GRPCClient)
+        if !ok {
+            return fmt.Errorf("health checks are not supported by %T", kv)
+        }
+        logger.Debug("🩺 executing health operation")
+        if err := grpcClient.HealthCheck(ctx); err != nil {
+            logger.Error("🩺❌ health check failed", "error", err)
+            if errors.Is(err, shared.ErrNotServing) {
+                fmt.Fprintln(s.stdout, "NOT_SERVING")
+            }
+            return fmt.Errorf("health check failed: %w", err)
+        }
+        logger.Debug("🩺✅ health check successful")
+        fmt.Fprintln(s.stdout, "SERVING")
+
+    case "stats":
+        cmd := newCommand("stats", "", "Print how many keys are stored and their total size in bytes.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 0 {
+            return cmd.usageError("want no arguments, got %d", len(args))
+        }
+        logger.Debug("📊 executing stats operation")
+        keyCount, totalBytes, err := kv.Stats(ctx)
+        if err != nil {
+            logger.Error("📊❌ stats operation failed", "error", err)
+            return fmt.Errorf("error getting stats: %w", err)
+        }
+        logger.Debug("📊✅ stats operation successful",
+            "key_count", keyCount,
+            "total_bytes", totalBytes)
+        fmt.Fprintf(s.stdout, "keys: %d\nbytes: %d\n", keyCount, totalBytes)
+
+    case "ping":
+        cmd := newCommand("ping", "[--count n]", "Time round trips to the plugin and print their latency.")
+        count := cmd.flags.Int("count", defaultPingCount, "send `n` pings")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if *count < 1 {
+            return cmd.usageError("--count must be at least 1, got %d", *count)
+        }
+        if len(args) != 0 {
+            return cmd.usageError("want no arguments, got %d", len(args))
+        }
+        p, ok := kv.(pinger)
+        if !ok {
+            return fmt.Errorf("ping is not supported by %T", kv)
+        }
+        logger.Debug("🏓 executing ping operation", "count", *count)
+        if err := pingPlugin(ctx, p, *count, s.stdout); err != nil {
+            logger.Error("🏓❌ ping failed", "error", err)
+            return err
+        }
+
+    case "info":
+        cmd := newCommand("info", "[--json]", "Print the connection negotiated with the plugin.")
+        asJSON := cmd.flags.Bool("json", false, "print the connection as JSON")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 0 {
+            return cmd.usageError("want no arguments, got %d", len(args))
+        }
+        info := s.conn
+        info.Version = s.version
+        if p, ok := kv.(peerCertifier); ok && info.Secure {
+            logger.Debug("🔐 fetching the plugin's certificate")
+            if info, err = describeConnection(ctx, p, info); err != nil {
+                logger.Error("🔐❌ fetching the plugin's certificate failed", "error", err)
+                return fmt.Errorf("error describing connection: %w", err)
+            }
+        }
+        if err := printConnectionInfo(s.stdout, info, *asJSON); err != nil {
+            return err
+        }
+
+    case "export":
+        cmd := newCommand("export", "file", "Write every key and value to an archive file.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 1 {
+            return cmd.usageError("want one file, got %d arguments", len(args))
+        }
+        path := args[0]
+        file, err := os.Create(path)
+        if err != nil {
+            return fmt.Errorf("error creating export file: %w", err)
+        }
+        logger.Debug("📦 executing export operation", "path", path)
+        count, err := exportArchive(ctx, kv, "", file)
+        if closeErr := file.Close(); err == nil && closeErr != nil {
+            err = closeErr
+        }
+        if err != nil {
+            os.Remove(path)
+            logger.Error("📦❌ export operation failed",
+                "path", path,
+                "error", err)
+            return fmt.Errorf("error exporting keys: %w", err)
+        }
+        logger.Info("📦✅ successfully exported keys", "path", path, "key_count", count)
+        fmt.Fprintf(s.stdout, "exported %d keys\n", count)
+
+    case "import":
+        cmd := newCommand("import", "file", "Store every key and value in an archive file written by export.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) != 1 {
+            return cmd.usageError("want one file, got %d arguments", len(args))
+        }
+        path := args[0]
+        file, err := os.Open(path)
+        if err != nil {
+            return fmt.Errorf("error opening import file: %w", err)
+        }
+        defer file.Close()
+        logger.Debug("📦 executing import operation", "path", path)
+        count, err := importArchive(ctx, kv, file)
+        if err != nil {
+            logger.Error("📦❌ import operation failed",
+                "path", path,
+                "imported", count,
+                "error", err)
+            return fmt.Errorf("error importing keys (%d imported before the failure): %w", count, err)
+        }
+        logger.Info("📦✅ successfully imported keys", "path", path, "key_count", count)
+        fmt.Fprintf(s.stdout, "imported %d keys\n", count)
+
+    case "watch":
+        cmd := newCommand("watch", "[prefix]", "Print every change to keys starting with prefix until interrupted.")
+        args, err := cmd.parse(args[1:], s.stdout)
+        if err != nil {
+            return err
+        }
+        if len(args) > 1 {
+            return cmd.usageError("want at most one prefix, got %d arguments", len(args))
+        }
+        prefix := ""
+        if len(args) == 1 {
+            prefix = args[0]
+        }
+        watchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+        defer stop()
+
+        logger.Debug("👀 executing watch operation", "prefix", prefix)
+        events, err := kv.Watch(watchCtx, prefix)
+        if err != nil {
+            logger.Error("👀❌ watch operation failed",
+                "prefix", prefix,
+                "error", err)
+            return fmt.Errorf("error watching keys: %w", err)
+        }
+        for event := range events {
+            if event.Op == shared.EventDelete {
+                fmt.Fprintf(s.stdout, "%s %s\n", event.Op, event.Key)
+                continue
+            }
+            fmt.Fprintf(s.stdout, "%s %s=%s\n", event.Op, event.Key, event.Value)
+        }
+        logger.Debug("👀✅ watch operation ended", "prefix", prefix)
 
     default:
-        logger.Error("❓❌ unknown command", "command", os.Args[1])
-        return fmt.Errorf("unknown command: %q (use 'get' or 'put')", os.Args[1])
+        logger.Error("❓❌ unknown command", "command", args[0])
+        return fmt.Errorf("unknown command: %q (use 'get', 'put', 'mput', 'delete', 'rename', 'list', 'scan', 'exists', 'incr', 'batch-put', 'export', 'import', 'watch', 'health', 'stats', 'ping', 'info' or 'repl', with -h for a command's help)", args[0])
     }
 
     return nil
@@ -238,6 +1185,13 @@ func handleCommand(logger hclog.Logger, kv shared.KV) error {
 
 func main() {
     if err := run(); err != nil {
+        if errors.Is(err, errKeyAbsent) {
+            os.Exit(1)
+        }
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+            os.Exit(exitCodeKeyNotFound)
+        }
         fmt.Fprintf(os.Stderr, "❌ error: %v\n", err)
         os.Exit(1)
     }
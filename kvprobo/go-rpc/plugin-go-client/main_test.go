@@ -0,0 +1,544 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/main_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// testPluginEnv makes the test binary serve the KV plugin instead of
+// running tests, so the client can launch it as a real plugin process.
+// Setting it to wrong-cookie serves with a different magic cookie, to
+// manual-tls serves with the certificates from the environment, to crash
+// serves a KV whose Get kills the plugin process, to fail-start explains on
+// stderr why it can't start and exits, and to flaky-start exits before the
+// handshake unless the file named by flakyStartMarkerEnv exists, creating
+// it so the next launch succeeds. protocol-v1 serves only KV protocol
+// version 1, as a plugin built before version 2 would.
+const testPluginEnv = "KV_GO_CLIENT_TEST_PLUGIN"
+
+// flakyStartMarkerEnv names the file flaky-start uses to remember that it
+// has already failed once.
+const flakyStartMarkerEnv = "KV_GO_CLIENT_TEST_FLAKY_MARKER"
+
+func TestMain(m *testing.M) {
+    if mode := os.Getenv(testPluginEnv); mode != "" {
+        if mode == "fail-start" {
+            fmt.Fprintln(os.Stderr, "kv-go-server: opening data directory: permission denied")
+            os.Exit(1)
+        }
+        if mode == "flaky-start" {
+            marker := os.Getenv(flakyStartMarkerEnv)
+            if _, err := os.Stat(marker); err != nil {
+                os.WriteFile(marker, nil, 0600)
+                os.Exit(1)
+            }
+        }
+        handshake := shared.Handshake
+        if mode == "wrong-cookie" {
+            handshake.MagicCookieValue = "goodbye"
+        }
+        var tlsProvider func() (*tls.Config, error)
+        if mode == "manual-tls" {
+            tlsProvider = func() (*tls.Config, error) { return shared.ServerTLSConfigFromEnv(shared.TLSPolicy{}, nil) }
+        }
+        var impl shared.KV = &recordingKV{}
+        if mode == "crash" {
+            impl = crashingKV{}
+        }
+        versions := shared.VersionedPlugins(&shared.KVGRPCPlugin{Impl: impl})
+        if mode == "protocol-v1" {
+            versions = map[int]plugin.PluginSet{1: versions[1]}
+        }
+        plugin.Serve(&plugin.ServeConfig{
+            HandshakeConfig:  handshake,
+            VersionedPlugins: versions,
+            GRPCServer:       plugin.DefaultGRPCServer,
+            TLSProvider:      tlsProvider,
+        })
+        return
+    }
+    os.Exit(m.Run())
+}
+
+// recordingKV remembers the last Put and returns it from Get. Calling any
+// other KV method panics.
+type recordingKV struct {
+    shared.KV
+    key   string
+    value []byte
+}
+
+func (r *recordingKV) Put(ctx context.Context, key string, value []byte) error {
+    r.key, r.value = key, value
+    return nil
+}
+
+func (r *recordingKV) Get(ctx context.Context, key string) ([]byte, error) {
+    if key != r.key {
+        return nil, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return r.value, nil
+}
+
+func (r *recordingKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := r.Get(ctx, key)
+    return value, shared.DefaultContentType, err
+}
+
+// runCommand executes args in a session reading stdin in place of the
+// process's own.
+func runCommand(t *testing.T, kv shared.KV, stdin []byte, args ...string) error {
+    t.Helper()
+
+    session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+    session.stdin = bytes.NewReader(stdin)
+    session.stdout = io.Discard
+    return session.Execute(context.Background(), args)
+}
+
+func TestPutFromStdin(t *testing.T) {
+    // Binary, with a NUL and a trailing newline that must be kept
+    value := []byte("line one\nline two\x00\xff\n")
+
+    for _, args := range [][]string{
+        {"put", "blob", "-"},
+        {"put", "--stdin", "blob"},
+        {"put", "blob", "--stdin"},
+    } {
+        kv := &recordingKV{}
+        if err := runCommand(t, kv, value, args...); err != nil {
+            t.Fatalf("%v failed: %v", args, err)
+        }
+        if kv.key != "blob" || !bytes.Equal(kv.value, value) {
+            t.Fatalf("%v stored %q = %q, want blob = %q", args, kv.key, kv.value, value)
+        }
+    }
+
+    kv := &recordingKV{}
+    if err := runCommand(t, kv, []byte("ignored"), "put", "k", "inline"); err != nil {
+        t.Fatalf("put key value failed: %v", err)
+    }
+    if string(kv.value) != "inline" {
+        t.Fatalf("put key value stored %q, want %q", kv.value, "inline")
+    }
+
+    if err := runCommand(t, &recordingKV{}, nil, "put", "--stdin", "k", "extra"); err == nil {
+        t.Fatalf("put --stdin with a value argument succeeded, want a usage error")
+    }
+}
+
+func TestValueAndOutputFiles(t *testing.T) {
+    dir := t.TempDir()
+    in := filepath.Join(dir, "in.bin")
+    out := filepath.Join(dir, "out.bin")
+    value := []byte{0x00, 0x01, 0xfe, 0xff, '\n', 0x7f}
+    if err := os.WriteFile(in, value, 0644); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+
+    kv := &recordingKV{}
+    if err := runCommand(t, kv, nil, "put", "--value-file", in, "blob"); err != nil {
+        t.Fatalf("put --value-file failed: %v", err)
+    }
+    if err := runCommand(t, kv, nil, "get", "blob", "--output-file="+out); err != nil {
+        t.Fatalf("get --output-file failed: %v", err)
+    }
+    got, err := os.ReadFile(out)
+    if err != nil {
+        t.Fatalf("ReadFile failed: %v", err)
+    }
+    if !bytes.Equal(got, value) {
+        t.Fatalf("round trip wrote %q, want %q", got, value)
+    }
+
+    if err := runCommand(t, kv, nil, "put", "--value-file", filepath.Join(dir, "missing"), "k"); err == nil {
+        t.Fatalf("put --value-file with a missing file succeeded")
+    }
+    if err := runCommand(t, kv, nil, "get", "blob", "--output-file", filepath.Join(dir, "no", "such", "dir")); err == nil {
+        t.Fatalf("get --output-file into a missing directory succeeded")
+    }
+    if err := runCommand(t, kv, nil, "put", "--value-file", in, "k", "inline"); err == nil {
+        t.Fatalf("put --value-file with a value argument succeeded, want a usage error")
+    }
+}
+
+// mapKV is an in-memory KV that fails Put for keys in reject. It also
+// serves the List, Get and BatchPut calls export and import make,
+// ListPage, using the last key of a page as the next page's token, and
+// content types.
+type mapKV struct {
+    shared.KV
+    data         map[string][]byte
+    reject       map[string]bool
+    contentTypes map[string]string
+}
+
+func (m *mapKV) Put(ctx context.Context, key string, value []byte) error {
+    if m.reject[key] {
+        return fmt.Errorf("%w: %q", shared.ErrInvalidKey, key)
+    }
+    m.data[key] = value
+    delete(m.contentTypes, key)
+    return nil
+}
+
+func (m *mapKV) List(ctx context.Context, prefix string) ([]string, error) {
+    keys := []string{}
+    for key := range m.data {
+        if strings.HasPrefix(key, prefix) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+    return keys, nil
+}
+
+func (m *mapKV) ListPage(ctx context.Context, prefix, pageToken string, pageSize int) ([]string, string, error) {
+    keys, _ := m.List(ctx, prefix)
+    start := sort.SearchStrings(keys, pageToken)
+    if start < len(keys) && keys[start] == pageToken {
+        start++
+    }
+    end := min(start+pageSize, len(keys))
+    if end == len(keys) {
+        return keys[start:end], "", nil
+    }
+    return keys[start:end], keys[end-1], nil
+}
+
+func (m *mapKV) Get(ctx context.Context, key string) ([]byte, error) {
+    value, ok := m.data[key]
+    if !ok {
+        return nil, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return value, nil
+}
+
+func (m *mapKV) PutIfAbsent(ctx context.Context, key string, value []byte) (bool, error) {
+    if _, ok := m.data[key]; ok {
+        return false, nil
+    }
+    return true, m.Put(ctx, key, value)
+}
+
+func (m *mapKV) Rename(ctx context.Context, oldKey, newKey string, overwrite bool) error {
+    value, ok := m.data[oldKey]
+    if !ok {
+        return shared.ErrKeyNotFound
+    }
+    if _, ok := m.data[newKey]; ok && !overwrite {
+        return shared.ErrKeyExists
+    }
+    m.data[newKey] = value
+    delete(m.data, oldKey)
+    return nil
+}
+
+func (m *mapKV) PutWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error {
+    if m.contentTypes == nil {
+        m.contentTypes = map[string]string{}
+    }
+    if err := m.Put(ctx, key, value); err != nil {
+        return err
+    }
+    m.contentTypes[key] = contentType
+    return nil
+}
+
+func (m *mapKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := m.Get(ctx, key)
+    contentType := m.contentTypes[key]
+    if contentType == "" {
+        contentType = shared.DefaultContentType
+    }
+    return value, contentType, err
+}
+
+func (m *mapKV) BatchPut(ctx context.Context, items map[string][]byte) error {
+    for key, value := range items {
+        m.data[key] = value
+    }
+    return nil
+}
+
+func TestListFollowsPages(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    var want strings.Builder
+    for i := 0; i < 7; i++ {
+        key := fmt.Sprintf("k%d", i)
+        kv.data[key] = nil
+        fmt.Fprintln(&want, key)
+    }
+    kv.data["other"] = nil
+
+    for _, args := range [][]string{
+        {"list", "--page-size", "3", "k"},
+        {"list", "k", "--page-size=1"},
+        {"list", "--page-size", "100", "k"},
+    } {
+        var stdout bytes.Buffer
+        session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+        session.stdout = &stdout
+        if err := session.Execute(context.Background(), args); err != nil {
+            t.Fatalf("%v failed: %v", args, err)
+        }
+        if stdout.String() != want.String() {
+            t.Fatalf("%v printed %q, want %q", args, stdout.String(), want.String())
+        }
+    }
+
+    if err := runCommand(t, kv, nil, "list", "--page-size", "0"); err == nil {
+        t.Fatalf("list --page-size 0 succeeded, want a usage error")
+    }
+}
+
+// datedKV holds one value, last written at modTime.
+type datedKV struct {
+    shared.KV
+    value   []byte
+    modTime time.Time
+}
+
+func (d *datedKV) GetConditional(ctx context.Context, key string, ifModifiedAfter time.Time) ([]byte, bool, time.Time, error) {
+    if !d.modTime.After(ifModifiedAfter) {
+        return nil, false, d.modTime, nil
+    }
+    return d.value, true, d.modTime, nil
+}
+
+func TestGetIfModifiedAfter(t *testing.T) {
+    written := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    kv := &datedKV{value: []byte("fresh"), modTime: written}
+
+    for _, tt := range []struct {
+        since string
+        want  string
+    }{
+        {"2024-05-01T11:59:59Z", "fresh\n"},
+        {"2024-05-01T12:00:00Z", ""},
+        {"2024-05-01T14:00:00+02:00", ""},
+    } {
+        var stdout bytes.Buffer
+        session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+        session.stdout = &stdout
+        if err := session.Execute(context.Background(), []string{"get", "--if-modified-after", tt.since, "k"}); err != nil {
+            t.Fatalf("get --if-modified-after %s failed: %v", tt.since, err)
+        }
+        if stdout.String() != tt.want {
+            t.Fatalf("get --if-modified-after %s printed %q, want %q", tt.since, stdout.String(), tt.want)
+        }
+    }
+
+    if err := runCommand(t, kv, nil, "get", "--if-modified-after", "yesterday", "k"); err == nil {
+        t.Fatalf("get --if-modified-after with a malformed time succeeded, want a usage error")
+    }
+    if err := runCommand(t, kv, nil, "get", "--stream", "--if-modified-after", "2024-05-01T12:00:00Z", "k"); err == nil {
+        t.Fatalf("get --stream --if-modified-after succeeded, want a usage error")
+    }
+}
+
+func TestPutAndGetContentType(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    if err := runCommand(t, kv, nil, "put", "--content-type", "application/json", "doc", `{"a":1}`); err != nil {
+        t.Fatalf("put --content-type failed: %v", err)
+    }
+    if err := runCommand(t, kv, nil, "put", "plain", "v"); err != nil {
+        t.Fatalf("put failed: %v", err)
+    }
+
+    for _, tt := range []struct {
+        args []string
+        want string
+    }{
+        {[]string{"get", "--show-content-type", "doc"}, "content-type: application/json\n{\"a\":1}\n"},
+        {[]string{"get", "plain", "--show-content-type"}, "content-type: application/octet-stream\nv\n"},
+        {[]string{"get", "doc"}, "{\"a\":1}\n"},
+    } {
+        var stdout bytes.Buffer
+        session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+        session.stdout = &stdout
+        if err := session.Execute(context.Background(), tt.args); err != nil {
+            t.Fatalf("%v failed: %v", tt.args, err)
+        }
+        if stdout.String() != tt.want {
+            t.Fatalf("%v printed %q, want %q", tt.args, stdout.String(), tt.want)
+        }
+    }
+
+    for _, args := range [][]string{
+        {"put", "--content-type", "not a type", "k", "v"},
+        {"get", "--show-content-type", "--stream", "doc"},
+    } {
+        var usage *usageError
+        if err := runCommand(t, kv, nil, args...); !errors.As(err, &usage) {
+            t.Fatalf("%v = %v, want a usage error", args, err)
+        }
+    }
+}
+
+func TestPutIfAbsent(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    for _, value := range []string{"original", "clobber"} {
+        if err := runCommand(t, kv, nil, "put", "--if-absent", "k", value); err != nil {
+            t.Fatalf("put --if-absent k %s failed: %v", value, err)
+        }
+    }
+    if got := string(kv.data["k"]); got != "original" {
+        t.Fatalf("k = %q after a second put --if-absent, want original", got)
+    }
+
+    var usage *usageError
+    if err := runCommand(t, kv, nil, "put", "--if-absent", "--content-type", "text/plain", "k2", "v"); !errors.As(err, &usage) {
+        t.Fatalf("put --if-absent --content-type = %v, want a usage error", err)
+    }
+}
+
+func TestRename(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{"a": []byte("1"), "b": []byte("2")}}
+    if err := runCommand(t, kv, nil, "rename", "a", "b"); !errors.Is(err, shared.ErrKeyExists) {
+        t.Fatalf("rename onto an existing key = %v, want ErrKeyExists", err)
+    }
+    if err := runCommand(t, kv, nil, "rename", "missing", "c"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("rename of a missing key = %v, want ErrKeyNotFound", err)
+    }
+    if err := runCommand(t, kv, nil, "rename", "a", "b", "--overwrite"); err != nil {
+        t.Fatalf("rename --overwrite failed: %v", err)
+    }
+    if _, ok := kv.data["a"]; ok || string(kv.data["b"]) != "1" {
+        t.Fatalf("after rename data = %q, want only b=1", kv.data)
+    }
+    var usage *usageError
+    if err := runCommand(t, kv, nil, "rename", "b"); !errors.As(err, &usage) {
+        t.Fatalf("rename with one key = %v, want a usage error", err)
+    }
+}
+
+func TestMultiPut(t *testing.T) {
+    kv := &mapKV{data: map[string][]byte{}}
+    if err := runCommand(t, kv, nil, "mput", "a=1", "b=two", "c=x=y", "empty="); err != nil {
+        t.Fatalf("mput failed: %v", err)
+    }
+    want := map[string]string{"a": "1", "b": "two", "c": "x=y", "empty": ""}
+    if len(kv.data) != len(want) {
+        t.Fatalf("mput stored %d keys, want %d", len(kv.data), len(want))
+    }
+    for key, value := range want {
+        if got, ok := kv.data[key]; !ok || string(got) != value {
+            t.Fatalf("mput stored %q = %q, want %q", key, got, value)
+        }
+    }
+
+    // One bad key fails the command without stopping the others
+    kv = &mapKV{data: map[string][]byte{}, reject: map[string]bool{"bad": true}}
+    if err := runCommand(t, kv, nil, "mput", "a=1", "bad=2", "c=3"); err == nil {
+        t.Fatalf("mput with a rejected key succeeded")
+    }
+    if string(kv.data["a"]) != "1" || string(kv.data["c"]) != "3" {
+        t.Fatalf("mput after a rejected key stored %q, want a and c", kv.data)
+    }
+
+    kv = &mapKV{data: map[string][]byte{}}
+    if err := runCommand(t, kv, nil, "mput", "a=1", "novalue"); err == nil || len(kv.data) != 0 {
+        t.Fatalf("mput with a malformed pair = %v and stored %q, want a usage error and nothing stored", err, kv.data)
+    }
+}
+
+func TestStartTimeoutFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value string
+        want  time.Duration
+    }{
+        {"", defaultStartTimeout},
+        {"30s", 30 * time.Second},
+        {"1m30s", 90 * time.Second},
+        {"soon", defaultStartTimeout},
+        {"0s", defaultStartTimeout},
+        {"-5s", defaultStartTimeout},
+    } {
+        t.Setenv("PLUGIN_START_TIMEOUT", tt.value)
+        config := newClientConfig("/bin/true", hclog.NewNullLogger(), true, nil)
+        if config.StartTimeout != tt.want {
+            t.Fatalf("PLUGIN_START_TIMEOUT=%q gave StartTimeout %s, want %s", tt.value, config.StartTimeout, tt.want)
+        }
+    }
+}
+
+func TestCheckClientTLSEnv(t *testing.T) {
+    for _, tt := range []struct {
+        autoMTLS   bool
+        clientCert string
+        serverCert string
+        caCert     string
+        wantErr    bool
+    }{
+        {true, "", "", "", false},
+        {true, "client-pem", "", "", true},
+        {true, "", "server-pem", "", true},
+        {true, "client-pem", "server-pem", "", true},
+        {true, "", "", "ca-pem", true},
+        {false, "", "", "", true},
+        {false, "client-pem", "", "", true},
+        {false, "", "server-pem", "", true},
+        {false, "", "", "ca-pem", true},
+        {false, "client-pem", "server-pem", "", false},
+        {false, "client-pem", "", "ca-pem", false},
+    } {
+        t.Setenv("PLUGIN_CLIENT_CERT", tt.clientCert)
+        t.Setenv("PLUGIN_SERVER_CERT", tt.serverCert)
+        t.Setenv("PLUGIN_CA_CERT", tt.caCert)
+        err := checkClientTLSEnv(tt.autoMTLS, false)
+        if (err != nil) != tt.wantErr {
+            t.Fatalf("checkClientTLSEnv(%t) with client cert %q, server cert %q and CA %q = %v, want error %t",
+                tt.autoMTLS, tt.clientCert, tt.serverCert, tt.caCert, err, tt.wantErr)
+        }
+    }
+    t.Setenv("PLUGIN_CLIENT_CERT", "")
+    t.Setenv("PLUGIN_SERVER_CERT", "")
+    t.Setenv("PLUGIN_CA_CERT", "")
+    t.Setenv("PLUGIN_SERVER_CERT_PIN", "ab:cd")
+    if err := checkClientTLSEnv(true, false); err == nil {
+        t.Fatalf("checkClientTLSEnv(true) accepted a certificate pin")
+    }
+}
+
+func TestCheckClientTLSEnvInsecure(t *testing.T) {
+    t.Setenv("PLUGIN_CLIENT_CERT", "")
+    t.Setenv("PLUGIN_SERVER_CERT", "")
+    t.Setenv("PLUGIN_CA_CERT", "")
+    t.Setenv("PLUGIN_SERVER_CERT_PIN", "")
+
+    // Without the opt-in, no certificates means no connection
+    err := checkClientTLSEnv(false, false)
+    if err == nil || !strings.Contains(err.Error(), "--insecure") {
+        t.Fatalf("checkClientTLSEnv without TLS or --insecure = %v, want an error suggesting --insecure", err)
+    }
+    if err := checkClientTLSEnv(false, true); err != nil {
+        t.Fatalf("checkClientTLSEnv with --insecure = %v", err)
+    }
+
+    // --insecure can't be combined with any form of TLS
+    if err := checkClientTLSEnv(true, true); err == nil {
+        t.Fatalf("checkClientTLSEnv accepted --insecure with AutoMTLS")
+    }
+    t.Setenv("PLUGIN_CA_CERT", "ca-pem")
+    if err := checkClientTLSEnv(false, true); err == nil {
+        t.Fatalf("checkClientTLSEnv accepted --insecure with a CA certificate")
+    }
+}
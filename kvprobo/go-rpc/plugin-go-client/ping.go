@@ -0,0 +1,51 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/ping.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "time"
+)
+
+// defaultPingCount is how many pings the ping command sends without --count.
+const defaultPingCount = 5
+
+// pinger is the part of shared.GRPCClient the ping command needs.
+type pinger interface {
+    Ping(ctx context.Context, payload []byte) ([]byte, int64, error)
+}
+
+// pingPlugin pings p count times, printing the round-trip time of each ping
+// and how far the plugin's clock is from ours, then a summary. The skew
+// assumes the request and the reply took equally long.
+func pingPlugin(ctx context.Context, p pinger, count int, out io.Writer) error {
+    var total, fastest, slowest time.Duration
+    for seq := 1; seq <= count; seq++ {
+        payload := binary.BigEndian.AppendUint64(nil, uint64(seq))
+
+        sent := time.Now()
+        echo, serverTime, err := p.Ping(ctx, payload)
+        rtt := time.Since(sent)
+        if err != nil {
+            return fmt.Errorf("ping %d: %w", seq, err)
+        }
+        if !bytes.Equal(echo, payload) {
+            return fmt.Errorf("ping %d: plugin echoed %x, want %x", seq, echo, payload)
+        }
+
+        skew := time.Unix(0, serverTime).Sub(sent.Add(rtt / 2))
+        fmt.Fprintf(out, "seq=%d rtt=%s skew=%s\n", seq, rtt, skew)
+
+        total += rtt
+        if seq == 1 || rtt < fastest {
+            fastest = rtt
+        }
+        slowest = max(slowest, rtt)
+    }
+    fmt.Fprintf(out, "%d pings: min=%s avg=%s max=%s\n", count, fastest, total/time.Duration(count), slowest)
+    return nil
+}
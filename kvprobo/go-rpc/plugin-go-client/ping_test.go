@@ -0,0 +1,44 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/ping_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "strings"
+    "testing"
+    "time"
+)
+
+// fakePinger echoes every payload, optionally corrupting it.
+type fakePinger struct {
+    calls   int
+    corrupt bool
+}
+
+func (f *fakePinger) Ping(ctx context.Context, payload []byte) ([]byte, int64, error) {
+    f.calls++
+    if f.corrupt {
+        return []byte("junk"), time.Now().UnixNano(), nil
+    }
+    return payload, time.Now().UnixNano(), nil
+}
+
+func TestPingPlugin(t *testing.T) {
+    p := &fakePinger{}
+    var out bytes.Buffer
+    if err := pingPlugin(context.Background(), p, 3, &out); err != nil {
+        t.Fatalf("pingPlugin failed: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if p.calls != 3 || len(lines) != 4 {
+        t.Fatalf("pingPlugin made %d calls and printed %q, want 3 pings and a summary", p.calls, out.String())
+    }
+    if !strings.HasPrefix(lines[0], "seq=1 rtt=") || !strings.HasPrefix(lines[3], "3 pings: min=") {
+        t.Fatalf("pingPlugin printed %q", out.String())
+    }
+
+    if err := pingPlugin(context.Background(), &fakePinger{corrupt: true}, 1, &out); err == nil {
+        t.Fatalf("pingPlugin accepted a wrong echo")
+    }
+}
@@ -0,0 +1,162 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/session.go
+
+package main
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// replPrompt is written to stderr before each command the REPL reads, so
+// stdout only carries command output.
+const replPrompt = "kv> "
+
+// errStdinInREPL is returned by commands that read their input from stdin
+// when run from the REPL, where stdin carries the commands themselves.
+var errStdinInREPL = errors.New("stdin holds the repl's commands; use --value-file instead")
+
+// KVSession holds a dispensed plugin so several commands can run over one
+// connection instead of launching the plugin for each.
+type KVSession struct {
+    kv      shared.KV
+    version int
+    logger  hclog.Logger
+    stdin   io.Reader
+    stdout  io.Writer
+    stderr  io.Writer
+
+    // diagnose, when set, explains errors caused by the plugin process
+    // dying, such as crashDiagnoser.diagnose.
+    diagnose func(error) error
+
+    // conn describes the connection to the plugin for the info command.
+    conn connectionInfo
+}
+
+// newKVSession returns a session for kv, which speaks protocol version
+// version, using the process's standard streams.
+func newKVSession(kv shared.KV, version int, logger hclog.Logger) *KVSession {
+    return &KVSession{
+        kv:      kv,
+        version: version,
+        logger:  logger,
+        stdin:   os.Stdin,
+        stdout:  os.Stdout,
+        stderr:  os.Stderr,
+    }
+}
+
+// REPL reads commands from in, one per line, and executes each in turn
+// until EOF or "exit". A failing command is reported and the next one
+// still runs; the error returned says how many failed.
+func (s *KVSession) REPL(ctx context.Context, in io.Reader) error {
+    command := *s
+    command.stdin = stdinInREPL{}
+
+    scanner := bufio.NewScanner(in)
+    ran, failed := 0, 0
+    for {
+        fmt.Fprint(s.stderr, replPrompt)
+        if !scanner.Scan() {
+            break
+        }
+        args, err := splitCommandLine(scanner.Text())
+        if err == nil && len(args) == 0 {
+            continue
+        }
+        if err == nil && (args[0] == "exit" || args[0] == "quit") {
+            break
+        }
+        if err == nil && args[0] == "repl" {
+            err = errors.New("already in the repl")
+        }
+        if err == nil {
+            err = command.explain(command.Execute(ctx, args))
+        }
+        if errors.Is(err, flag.ErrHelp) {
+            err = nil
+        }
+
+        ran++
+        if err != nil {
+            failed++
+            fmt.Fprintf(s.stderr, "❌ error: %v\n", err)
+        }
+        // Nothing is left to run the remaining commands against
+        if ctx.Err() != nil || errors.Is(err, errPluginTerminated) {
+            break
+        }
+    }
+    fmt.Fprintln(s.stderr)
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("error reading commands: %w", err)
+    }
+
+    s.logger.Debug("🏁 repl finished", "commands", ran, "failed", failed)
+    if failed > 0 {
+        return fmt.Errorf("%d of %d commands failed", failed, ran)
+    }
+    return nil
+}
+
+// explain passes err through the session's diagnose function, if any.
+func (s *KVSession) explain(err error) error {
+    if err == nil || s.diagnose == nil {
+        return err
+    }
+    return s.diagnose(err)
+}
+
+// stdinInREPL stands in for stdin while the REPL is reading it.
+type stdinInREPL struct{}
+
+func (stdinInREPL) Read([]byte) (int, error) { return 0, errStdinInREPL }
+
+// splitCommandLine splits line into words at spaces. Single or double
+// quotes group a word that contains spaces, and # starts a comment.
+func splitCommandLine(line string) ([]string, error) {
+    var words []string
+    var word strings.Builder
+    inWord := false
+    var quote rune
+    for _, r := range line {
+        switch {
+        case quote != 0:
+            if r == quote {
+                quote = 0
+            } else {
+                word.WriteRune(r)
+            }
+        case r == '\'' || r == '"':
+            quote, inWord = r, true
+        case r == ' ' || r == '\t':
+            if inWord {
+                words = append(words, word.String())
+                word.Reset()
+                inWord = false
+            }
+        case r == '#' && !inWord:
+            return words, nil
+        default:
+            word.WriteRune(r)
+            inWord = true
+        }
+    }
+    if quote != 0 {
+        return nil, fmt.Errorf("unterminated %c quote", quote)
+    }
+    if inWord {
+        words = append(words, word.String())
+    }
+    return words, nil
+}
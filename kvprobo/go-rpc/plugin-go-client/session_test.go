@@ -0,0 +1,101 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/session_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestREPLRunsCommandsOverOneSession(t *testing.T) {
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=1")
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        t.Fatalf("Client() failed: %v", err)
+    }
+    raw, err := rpcClient.Dispense(shared.PluginName)
+    if err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+
+    var stdout, stderr bytes.Buffer
+    session := newKVSession(raw.(shared.KV), client.NegotiatedVersion(), hclog.NewNullLogger())
+    session.stdout, session.stderr = &stdout, &stderr
+
+    commands := strings.Join([]string{
+        "put a 1",
+        "get a",
+        "",
+        "# the test plugin only remembers the last put",
+        `put b "two words"`,
+        "get b",
+        "get a",
+        "put c --stdin",
+    }, "\n")
+    err = session.REPL(context.Background(), strings.NewReader(commands))
+    if err == nil || err.Error() != "2 of 6 commands failed" {
+        t.Fatalf("REPL() = %v, want 2 of 6 commands failed", err)
+    }
+    if got, want := stdout.String(), "1\ntwo words\n"; got != want {
+        t.Fatalf("REPL printed %q, want %q", got, want)
+    }
+    if !strings.Contains(stderr.String(), "key not found") || !strings.Contains(stderr.String(), errStdinInREPL.Error()) {
+        t.Fatalf("REPL errors = %q, want the missing key and the stdin refusal", stderr.String())
+    }
+    if client.Exited() {
+        t.Fatalf("plugin exited during the repl")
+    }
+}
+
+func TestREPLStopsAtExit(t *testing.T) {
+    kv := &recordingKV{}
+    var stdout bytes.Buffer
+    session := newKVSession(kv, shared.ProtocolVersion, hclog.NewNullLogger())
+    session.stdout, session.stderr = &stdout, &bytes.Buffer{}
+
+    if err := session.REPL(context.Background(), strings.NewReader("put k v\nexit\nput k ignored\n")); err != nil {
+        t.Fatalf("REPL() = %v", err)
+    }
+    if string(kv.value) != "v" {
+        t.Fatalf("command after exit ran: stored %q", kv.value)
+    }
+    if err := session.REPL(context.Background(), strings.NewReader("repl\n")); err == nil {
+        t.Fatalf("nested repl succeeded")
+    }
+}
+
+func TestSplitCommandLine(t *testing.T) {
+    for _, tt := range []struct {
+        line string
+        want []string
+    }{
+        {"get key", []string{"get", "key"}},
+        {"  put  k\tv  ", []string{"put", "k", "v"}},
+        {`put k "a b" 'c "d"'`, []string{"put", "k", "a b", `c "d"`}},
+        {`put k ""`, []string{"put", "k", ""}},
+        {"list # all of them", []string{"list"}},
+        {"put k a#b", []string{"put", "k", "a#b"}},
+        {"# comment", nil},
+    } {
+        got, err := splitCommandLine(tt.line)
+        if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+            t.Fatalf("splitCommandLine(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+        }
+    }
+    if _, err := splitCommandLine(`put k "open`); err == nil {
+        t.Fatalf("splitCommandLine accepted an unterminated quote")
+    }
+}
+
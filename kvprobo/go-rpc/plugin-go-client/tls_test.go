@@ -0,0 +1,159 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/tls_test.go
+
+package main
+
+import (
+    "context"
+    "crypto"
+    "crypto/x509"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// testCA issues localhost certificates for manual mTLS tests.
+type testCA struct {
+    certPEM []byte
+    cert    *x509.Certificate
+    key     crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+    t.Helper()
+    certPEM, keyPEM, err := shared.GenerateCA(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCA failed: %v", err)
+    }
+    cert, err := shared.ParseCertificate(certPEM, nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    key, err := shared.ParsePrivateKey(keyPEM, nil)
+    if err != nil {
+        t.Fatalf("ParsePrivateKey failed: %v", err)
+    }
+    return &testCA{certPEM: certPEM, cert: cert, key: key}
+}
+
+// issue returns the PEM certificate and key of a new localhost leaf.
+func (ca *testCA) issue(t *testing.T) (string, string) {
+    t.Helper()
+    certPEM, keyPEM, err := shared.GenerateSignedCert(ca.cert, ca.key, nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateSignedCert failed: %v", err)
+    }
+    return string(certPEM), string(keyPEM)
+}
+
+// connectManualTLS launches the test plugin with the manual mTLS
+// certificates in the environment and dispenses its KV.
+func connectManualTLS(t *testing.T) (shared.KV, error) {
+    t.Helper()
+    tlsConfig, err := shared.ClientTLSConfigFromEnv(shared.TLSPolicy{}, nil)
+    if err != nil {
+        t.Fatalf("ClientTLSConfigFromEnv failed: %v", err)
+    }
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=manual-tls")
+    config.TLSConfig = tlsConfig
+    client := plugin.NewClient(config)
+    t.Cleanup(client.Kill)
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        return nil, err
+    }
+    raw, err := rpcClient.Dispense(shared.PluginName)
+    if err != nil {
+        return nil, err
+    }
+    return raw.(shared.KV), nil
+}
+
+func TestManualMTLS(t *testing.T) {
+    ca := newTestCA(t)
+    clientCert, clientKey := ca.issue(t)
+    serverCert, serverKey := ca.issue(t)
+    t.Setenv("PLUGIN_CA_CERT", string(ca.certPEM))
+    t.Setenv("PLUGIN_CLIENT_CERT", clientCert)
+    t.Setenv("PLUGIN_CLIENT_KEY", clientKey)
+    t.Setenv("PLUGIN_SERVER_CERT", serverCert)
+    t.Setenv("PLUGIN_SERVER_KEY", serverKey)
+
+    kv, err := connectManualTLS(t)
+    if err != nil {
+        t.Fatalf("connecting with manual mTLS failed: %v", err)
+    }
+    ctx := context.Background()
+    if err := kv.Put(ctx, "greeting", []byte("hello")); err != nil {
+        t.Fatalf("Put over manual mTLS failed: %v", err)
+    }
+    if value, err := kv.Get(ctx, "greeting"); err != nil || string(value) != "hello" {
+        t.Fatalf("Get over manual mTLS = %q, %v; want hello", value, err)
+    }
+}
+
+func TestManualMTLSRejectsUnknownClient(t *testing.T) {
+    ca := newTestCA(t)
+    serverCert, serverKey := ca.issue(t)
+    // The client's certificate comes from a CA the plugin doesn't trust
+    clientCert, clientKey := newTestCA(t).issue(t)
+    t.Setenv("PLUGIN_CA_CERT", string(ca.certPEM))
+    t.Setenv("PLUGIN_CLIENT_CERT", clientCert)
+    t.Setenv("PLUGIN_CLIENT_KEY", clientKey)
+    t.Setenv("PLUGIN_SERVER_CERT", serverCert)
+    t.Setenv("PLUGIN_SERVER_KEY", serverKey)
+
+    kv, err := connectManualTLS(t)
+    if err == nil {
+        err = kv.Put(context.Background(), "greeting", []byte("hello"))
+    }
+    if err == nil {
+        t.Fatalf("a client with an untrusted certificate completed a Put")
+    }
+}
+
+func TestManualMTLSCertPin(t *testing.T) {
+    ca := newTestCA(t)
+    clientCert, clientKey := ca.issue(t)
+    serverCert, serverKey := ca.issue(t)
+    t.Setenv("PLUGIN_CA_CERT", string(ca.certPEM))
+    t.Setenv("PLUGIN_CLIENT_CERT", clientCert)
+    t.Setenv("PLUGIN_CLIENT_KEY", clientKey)
+    t.Setenv("PLUGIN_SERVER_CERT", serverCert)
+    t.Setenv("PLUGIN_SERVER_KEY", serverKey)
+
+    leaf, err := shared.ParseCertificate([]byte(serverCert), nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    t.Setenv("PLUGIN_SERVER_CERT_PIN", strings.ToUpper(shared.CertificateFingerprint(leaf)))
+    kv, err := connectManualTLS(t)
+    if err == nil {
+        err = kv.Put(context.Background(), "greeting", []byte("hello"))
+    }
+    if err != nil {
+        t.Fatalf("Put with a matching pin failed: %v", err)
+    }
+
+    // Another certificate from the same CA passes chain verification but
+    // not the pin
+    other, _ := ca.issue(t)
+    otherLeaf, err := shared.ParseCertificate([]byte(other), nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    t.Setenv("PLUGIN_SERVER_CERT_PIN", shared.CertificateFingerprint(otherLeaf))
+    kv, err = connectManualTLS(t)
+    if err == nil {
+        err = kv.Put(context.Background(), "greeting", []byte("hello"))
+    }
+    if err == nil || !strings.Contains(err.Error(), "certificate pin mismatch") {
+        t.Fatalf("Put with a mismatched pin = %v, want a certificate pin mismatch", err)
+    }
+}
@@ -0,0 +1,131 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-client/version_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "io"
+    "os"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestProtocolVersionMismatch(t *testing.T) {
+    // A client built for a version the plugin doesn't speak yet
+    future := shared.ProtocolVersion + 1
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=1")
+    config.VersionedPlugins = map[int]plugin.PluginSet{
+        future: {shared.PluginName: &shared.KVGRPCPlugin{}},
+    }
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    _, err := client.Client()
+    err = shared.HandshakeError(err)
+    var mismatch *shared.VersionMismatchError
+    if !errors.As(err, &mismatch) || !errors.Is(err, shared.ErrVersionMismatch) {
+        t.Fatalf("Client() error = %v, want a VersionMismatchError", err)
+    }
+    // Without a match the plugin reports the oldest version it speaks
+    if mismatch.PluginVersion != 1 || len(mismatch.Supported) != 1 || mismatch.Supported[0] != future {
+        t.Fatalf("mismatch = %+v, want plugin version 1 and supported [%d]", mismatch, future)
+    }
+    t.Logf("mismatch reported as: %v", err)
+}
+
+func TestProtocolVersionMatch(t *testing.T) {
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=1")
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        t.Fatalf("Client() failed: %v", err)
+    }
+    if got := client.NegotiatedVersion(); got != shared.ProtocolVersion {
+        t.Fatalf("NegotiatedVersion() = %d, want %d", got, shared.ProtocolVersion)
+    }
+    if _, err := rpcClient.Dispense(shared.PluginName); err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+}
+
+func TestOlderPluginRefusesNewerRPCs(t *testing.T) {
+    ctx := context.Background()
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=protocol-v1")
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    rpcClient, err := client.Client()
+    if err != nil {
+        t.Fatalf("Client() failed: %v", err)
+    }
+    negotiated := client.NegotiatedVersion()
+    if negotiated != 1 {
+        t.Fatalf("NegotiatedVersion() = %d, want 1", negotiated)
+    }
+    raw, err := rpcClient.Dispense(shared.PluginName)
+    if err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+    kv := raw.(*shared.GRPCClient)
+    kv.ProtocolVersion = negotiated
+
+    session := newKVSession(kv, negotiated, hclog.NewNullLogger())
+    session.stdout = io.Discard
+    if err := session.Execute(ctx, []string{"put", "a", "v"}); err != nil {
+        t.Fatalf("put against a version 1 plugin failed: %v", err)
+    }
+    since := time.Now().Add(-time.Hour).Format(time.RFC3339)
+    for _, args := range [][]string{
+        {"rename", "a", "b"},
+        {"scan"},
+        {"stats"},
+        {"ping"},
+        // Flags that switch a version 1 command onto a newer RPC
+        {"list", "--page-size", "10"},
+        {"get", "--if-modified-after", since, "a"},
+        {"put", "--if-absent", "c", "v"},
+    } {
+        if err := session.Execute(ctx, args); !errors.Is(err, shared.ErrVersionMismatch) {
+            t.Fatalf("%v against a version 1 plugin = %v, want ErrVersionMismatch", args, err)
+        }
+    }
+    if _, _, err := kv.GetBatchStream(ctx, []string{"a"}); !errors.Is(err, shared.ErrVersionMismatch) {
+        t.Fatalf("GetBatchStream against a version 1 plugin = %v, want ErrVersionMismatch", err)
+    }
+    if err := kv.SetEventSink(ctx, &loggingSink{logger: hclog.NewNullLogger()}); !errors.Is(err, shared.ErrVersionMismatch) {
+        t.Fatalf("SetEventSink against a version 1 plugin = %v, want ErrVersionMismatch", err)
+    }
+
+    // The refused rename never reached the plugin
+    if err := session.Execute(ctx, []string{"get", "a"}); err != nil {
+        t.Fatalf("get against a version 1 plugin failed: %v", err)
+    }
+}
+
+func TestWrongMagicCookie(t *testing.T) {
+    config := newClientConfig(os.Args[0], hclog.NewNullLogger(), false, nil)
+    config.Cmd.Env = append(os.Environ(), testPluginEnv+"=wrong-cookie")
+    client := plugin.NewClient(config)
+    defer client.Kill()
+
+    _, err := client.Client()
+    err = shared.HandshakeError(err)
+    if !errors.Is(err, shared.ErrCookieMismatch) {
+        t.Fatalf("Client() error = %v, want ErrCookieMismatch", err)
+    }
+    if !strings.Contains(err.Error(), "is this the right plugin binary?") {
+        t.Fatalf("Client() error = %q, want the wrong-binary hint", err)
+    }
+}
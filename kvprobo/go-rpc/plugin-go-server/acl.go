@@ -0,0 +1,106 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/acl.go
+
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// accessLevel is what an access list lets a client do.
+type accessLevel string
+
+const (
+    accessReadOnly  accessLevel = "read-only"
+    accessReadWrite accessLevel = "read-write"
+)
+
+// accessList maps the common names of client certificates to what those
+// clients may do, as read from PLUGIN_KV_ACL_FILE:
+//
+//   {"reporting-job": "read-only", "ingest": "read-write"}
+//
+// A client whose name isn't listed may not use the KV service at all. An
+// empty list allows everything.
+type accessList map[string]accessLevel
+
+// loadAccessList reads and checks the access list at path.
+func loadAccessList(path string) (accessList, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading access list: %w", err)
+    }
+    var acl accessList
+    if err := json.Unmarshal(data, &acl); err != nil {
+        return nil, fmt.Errorf("invalid access list %s: %w", path, err)
+    }
+    for name, level := range acl {
+        if level != accessReadOnly && level != accessReadWrite {
+            return nil, fmt.Errorf("invalid access list %s: %q has access %q, want %q or %q",
+                path, name, level, accessReadOnly, accessReadWrite)
+        }
+    }
+    return acl, nil
+}
+
+// accessListFromEnv loads the access list named by PLUGIN_KV_ACL_FILE. It
+// returns nil, allowing everything, when unset.
+func accessListFromEnv() (accessList, error) {
+    path := os.Getenv("PLUGIN_KV_ACL_FILE")
+    if path == "" {
+        return nil, nil
+    }
+    return loadAccessList(path)
+}
+
+// check returns a PermissionDenied status unless the client of the RPC
+// under ctx may call method. Only the KV service is guarded, leaving
+// health checks and go-plugin's own services alone.
+func (a accessList) check(ctx context.Context, method string) error {
+    if len(a) == 0 || !strings.HasPrefix(method, "/"+proto.KV_ServiceDesc.ServiceName+"/") {
+        return nil
+    }
+    name := ""
+    if cert := shared.PeerCertificateFromContext(ctx); cert != nil {
+        name = cert.Subject.CommonName
+    }
+    level, ok := a[name]
+    switch {
+    case !ok:
+        return status.Errorf(codes.PermissionDenied, "%s: client %q is not in the access list", shared.ErrPermissionDenied, name)
+    case level == accessReadOnly && mutatingMethods[method]:
+        return status.Errorf(codes.PermissionDenied, "%s: client %q is read-only and may not call %s", shared.ErrPermissionDenied, name, method)
+    }
+    return nil
+}
+
+// unaryInterceptor refuses unary RPCs the access list doesn't allow.
+func (a accessList) unaryInterceptor() grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        if err := a.check(ctx, info.FullMethod); err != nil {
+            return nil, err
+        }
+        return handler(ctx, req)
+    }
+}
+
+// streamInterceptor refuses streaming RPCs, such as Watch and GetStream,
+// the access list doesn't allow.
+func (a accessList) streamInterceptor() grpc.StreamServerInterceptor {
+    return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+        if err := a.check(ss.Context(), info.FullMethod); err != nil {
+            return err
+        }
+        return handler(srv, ss)
+    }
+}
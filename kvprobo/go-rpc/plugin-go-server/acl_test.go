@@ -0,0 +1,107 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/acl_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// serveKVWithACL serves kv under acl over mTLS to a client whose
+// certificate is for clientCN.
+func serveKVWithACL(t *testing.T, kv *KV, acl accessList, clientCN string) *shared.GRPCClient {
+    t.Helper()
+    serverCreds, clientCreds := mtlsTestCredentials(t, clientCN)
+    server := newGRPCServer([]grpc.ServerOption{grpc.Creds(serverCreds)}, serverOptions{acl: acl}, hclog.NewNullLogger())
+    return serveKVWithCreds(t, server, kv, clientCreds)
+}
+
+func TestAccessList(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newMemStore(), nil)
+    if err := kv.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    acl := accessList{"reporting-job": accessReadOnly, "ingest": accessReadWrite}
+
+    reader := serveKVWithACL(t, kv, acl, "reporting-job")
+    err := reader.Put(ctx, "k", []byte("changed"))
+    if status.Code(err) != codes.PermissionDenied || !errors.Is(err, shared.ErrPermissionDenied) {
+        t.Fatalf("read-only Put() = %v, want PermissionDenied and ErrPermissionDenied", err)
+    }
+    if value, err := reader.Get(ctx, "k"); err != nil || string(value) != "v" {
+        t.Fatalf("read-only Get() = %q, %v, want the unchanged value", value, err)
+    }
+    var streamed bytes.Buffer
+    if err := reader.GetStream(ctx, "k", &streamed); err != nil || streamed.String() != "v" {
+        t.Fatalf("read-only GetStream() = %q, %v, want the value", streamed.String(), err)
+    }
+
+    writer := serveKVWithACL(t, kv, acl, "ingest")
+    if err := writer.Put(ctx, "k", []byte("changed")); err != nil {
+        t.Fatalf("read-write Put() failed: %v", err)
+    }
+
+    // Unlisted clients can't read either, streamed or not
+    stranger := serveKVWithACL(t, kv, acl, "stranger")
+    if _, err := stranger.Get(ctx, "k"); !errors.Is(err, shared.ErrPermissionDenied) {
+        t.Fatalf("unlisted Get() = %v, want ErrPermissionDenied", err)
+    }
+    if err := stranger.GetStream(ctx, "k", io.Discard); status.Code(err) != codes.PermissionDenied {
+        t.Fatalf("unlisted GetStream() = %v, want PermissionDenied", err)
+    }
+
+    // No list allows everything, as before access lists existed
+    open := serveKVWithACL(t, kv, nil, "stranger")
+    if err := open.Put(ctx, "k", []byte("open")); err != nil {
+        t.Fatalf("Put() without an access list failed: %v", err)
+    }
+}
+
+func TestAccessListFromEnv(t *testing.T) {
+    dir := t.TempDir()
+    write := func(name, content string) string {
+        path := filepath.Join(dir, name)
+        if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+            t.Fatalf("WriteFile failed: %v", err)
+        }
+        return path
+    }
+
+    t.Setenv("PLUGIN_KV_ACL_FILE", "")
+    if acl, err := accessListFromEnv(); acl != nil || err != nil {
+        t.Fatalf("accessListFromEnv() unset = %v, %v, want nothing", acl, err)
+    }
+
+    t.Setenv("PLUGIN_KV_ACL_FILE", write("good.json", `{"reporting-job": "read-only", "ingest": "read-write"}`))
+    acl, err := accessListFromEnv()
+    if err != nil || len(acl) != 2 || acl["ingest"] != accessReadWrite {
+        t.Fatalf("accessListFromEnv() = %v, %v, want both clients", acl, err)
+    }
+
+    for name, content := range map[string]string{
+        "level.json":  `{"ingest": "admin"}`,
+        "syntax.json": `{"ingest": `,
+    } {
+        t.Setenv("PLUGIN_KV_ACL_FILE", write(name, content))
+        if _, err := accessListFromEnv(); err == nil {
+            t.Fatalf("accessListFromEnv() accepted %s", content)
+        }
+    }
+    t.Setenv("PLUGIN_KV_ACL_FILE", filepath.Join(dir, "missing.json"))
+    if _, err := accessListFromEnv(); err == nil {
+        t.Fatalf("accessListFromEnv() accepted a missing file")
+    }
+}
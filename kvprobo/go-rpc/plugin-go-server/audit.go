@@ -0,0 +1,165 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/audit.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// auditRecord is one line of the audit log, describing a write to one key.
+// Values themselves are never logged, only their size.
+type auditRecord struct {
+    Time       time.Time `json:"time"`
+    Op         string    `json:"op"`
+    Namespace  string    `json:"namespace"`
+    Key        string    `json:"key"`
+    ValueBytes int       `json:"value_bytes"`
+    RequestID  string    `json:"request_id,omitempty"`
+    Peer       string    `json:"peer,omitempty"`
+    Code       string    `json:"code"`
+}
+
+// auditLog appends a JSON line per key written to a file opened in append
+// mode, syncing after every request so a record survives a crash.
+type auditLog struct {
+    mu   sync.Mutex
+    file *os.File
+}
+
+// openAuditLog opens path for appending, creating it readable by the owner only.
+func openAuditLog(path string) (*auditLog, error) {
+    file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+    if err != nil {
+        return nil, fmt.Errorf("opening audit log: %w", err)
+    }
+    return &auditLog{file: file}, nil
+}
+
+// auditLogFromEnv opens the audit log at PLUGIN_KV_AUDIT_LOG. It returns
+// nil when unset.
+func auditLogFromEnv() (*auditLog, error) {
+    path := os.Getenv("PLUGIN_KV_AUDIT_LOG")
+    if path == "" {
+        return nil, nil
+    }
+    return openAuditLog(path)
+}
+
+// append writes records with a single write, then syncs the file.
+func (a *auditLog) append(records []auditRecord) error {
+    var buf bytes.Buffer
+    encoder := json.NewEncoder(&buf)
+    for _, record := range records {
+        if err := encoder.Encode(record); err != nil {
+            return err
+        }
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if _, err := a.file.Write(buf.Bytes()); err != nil {
+        return err
+    }
+    return a.file.Sync()
+}
+
+// Close closes the audit log file.
+func (a *auditLog) Close() error {
+    return a.file.Close()
+}
+
+// unaryInterceptor records every mutating KV request once it has been
+// handled, with its outcome, so refused and failed writes are logged too.
+// A record that can't be written is reported but doesn't change the
+// response, as the write has already been applied.
+func (a *auditLog) unaryInterceptor(logger hclog.Logger) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        if !mutatingMethods[info.FullMethod] {
+            return handler(ctx, req)
+        }
+        resp, err := handler(ctx, req)
+
+        records := auditRecords(path.Base(info.FullMethod), req)
+        now := time.Now().UTC()
+        requestID := shared.RequestIDFromContext(ctx)
+        namespace := shared.NamespaceFromContext(ctx)
+        subject := shared.PeerSubject(ctx)
+        code := status.Code(err).String()
+        for i := range records {
+            records[i].Time = now
+            records[i].Namespace = namespace
+            records[i].RequestID = requestID
+            records[i].Peer = subject
+            records[i].Code = code
+        }
+        if auditErr := a.append(records); auditErr != nil {
+            shared.RequestLogger(ctx, logger).Error("🧾❌ failed to write audit record",
+                "method", info.FullMethod,
+                "error", auditErr)
+        }
+        return resp, err
+    }
+}
+
+// auditRecords returns a record, without the request-wide fields, for
+// each key req writes.
+func auditRecords(op string, req interface{}) []auditRecord {
+    switch req := req.(type) {
+    case *proto.PutRequest:
+        return []auditRecord{{Op: op, Key: req.Key, ValueBytes: len(req.Value)}}
+    case *proto.DeleteRequest:
+        return []auditRecord{{Op: op, Key: req.Key}}
+    case *proto.CasRequest:
+        return []auditRecord{{Op: op, Key: req.Key, ValueBytes: len(req.NewValue)}}
+    case *proto.PutIfVersionRequest:
+        return []auditRecord{{Op: op, Key: req.Key, ValueBytes: len(req.Value)}}
+    case *proto.PutIfAbsentRequest:
+        return []auditRecord{{Op: op, Key: req.Key, ValueBytes: len(req.Value)}}
+    case *proto.RenameRequest:
+        return []auditRecord{{Op: op + ".From", Key: req.OldKey}, {Op: op + ".To", Key: req.NewKey}}
+    case *proto.IncrementRequest:
+        return []auditRecord{{Op: op, Key: req.Key}}
+    case *proto.BatchPutRequest:
+        keys := make([]string, 0, len(req.Items))
+        for key := range req.Items {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        records := make([]auditRecord, 0, len(keys))
+        for _, key := range keys {
+            records = append(records, auditRecord{Op: op, Key: key, ValueBytes: len(req.Items[key])})
+        }
+        return records
+    case *proto.TransactionRequest:
+        records := make([]auditRecord, 0, len(req.Ops))
+        for _, txOp := range req.Ops {
+            switch {
+            case txOp.GetPut() != nil:
+                records = append(records, auditRecord{Op: op + ".Put", Key: txOp.GetPut().Key, ValueBytes: len(txOp.GetPut().Value)})
+            case txOp.GetDelete() != nil:
+                records = append(records, auditRecord{Op: op + ".Delete", Key: txOp.GetDelete().Key})
+            case txOp.GetCompareAndSwap() != nil:
+                cas := txOp.GetCompareAndSwap()
+                records = append(records, auditRecord{Op: op + ".CompareAndSwap", Key: cas.Key, ValueBytes: len(cas.NewValue)})
+            }
+        }
+        return records
+    }
+    // A mutating RPC this switch doesn't know yet is still recorded
+    return []auditRecord{{Op: op}}
+}
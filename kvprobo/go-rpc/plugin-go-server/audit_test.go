@@ -0,0 +1,119 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/audit_test.go
+
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// readAuditLog parses every record in the audit log at path.
+func readAuditLog(t *testing.T, path string) []auditRecord {
+    t.Helper()
+    file, err := os.Open(path)
+    if err != nil {
+        t.Fatalf("opening audit log failed: %v", err)
+    }
+    defer file.Close()
+
+    var records []auditRecord
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        var record auditRecord
+        if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+            t.Fatalf("audit line %q isn't a JSON record: %v", scanner.Text(), err)
+        }
+        records = append(records, record)
+    }
+    return records
+}
+
+func TestAuditLogRecordsPutWithPeerSubject(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.log")
+    t.Setenv("PLUGIN_KV_AUDIT_LOG", path)
+    audit, err := auditLogFromEnv()
+    if err != nil {
+        t.Fatalf("auditLogFromEnv() failed: %v", err)
+    }
+    defer audit.Close()
+
+    serverCreds, clientCreds := mtlsTestCredentials(t, "audited-client")
+    server := newGRPCServer([]grpc.ServerOption{grpc.Creds(serverCreds)}, serverOptions{audit: audit}, hclog.NewNullLogger())
+    client := serveKVWithCreds(t, server, NewKV(newMemStore(), nil), clientCreds)
+
+    before := time.Now().UTC()
+    id := shared.NewRequestID()
+    ctx := shared.WithNamespace(shared.WithRequestID(context.Background(), id), "team")
+    if err := client.Put(ctx, "greeting", []byte("hello")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    // Reads aren't audited
+    if _, err := client.Get(ctx, "greeting"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+
+    records := readAuditLog(t, path)
+    if len(records) != 1 {
+        t.Fatalf("audit log has %d records, want 1 for the Put: %+v", len(records), records)
+    }
+    got := records[0]
+    if got.Op != "Put" || got.Key != "greeting" || got.Namespace != "team" || got.ValueBytes != 5 || got.Code != "OK" {
+        t.Fatalf("audit record = %+v, want an OK Put of 5 bytes to team/greeting", got)
+    }
+    if got.RequestID != id {
+        t.Fatalf("audit record request ID = %q, want %q", got.RequestID, id)
+    }
+    if got.Peer != "CN=audited-client,O=HashiCorp" {
+        t.Fatalf("audit record peer = %q, want the client certificate's subject", got.Peer)
+    }
+    if got.Time.Before(before.Add(-time.Second)) || got.Time.After(time.Now().Add(time.Second)) {
+        t.Fatalf("audit record time %s isn't the time of the Put", got.Time)
+    }
+
+    // Reopening appends rather than truncating
+    reopened, err := openAuditLog(path)
+    if err != nil {
+        t.Fatalf("openAuditLog() failed: %v", err)
+    }
+    defer reopened.Close()
+    if err := reopened.append([]auditRecord{{Op: "Delete", Key: "greeting"}}); err != nil {
+        t.Fatalf("append failed: %v", err)
+    }
+    if records := readAuditLog(t, path); len(records) != 2 || records[0].Op != "Put" {
+        t.Fatalf("after reopening, audit log = %+v, want the Put kept and a Delete appended", records)
+    }
+}
+
+func TestAuditRecordsForBatchesAndTransactions(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.log")
+    audit, err := openAuditLog(path)
+    if err != nil {
+        t.Fatalf("openAuditLog() failed: %v", err)
+    }
+    defer audit.Close()
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{readOnly: true, audit: audit}, hclog.NewNullLogger()), NewKV(newMemStore(), nil))
+
+    // Read-only mode refuses the batch, which is still recorded per key
+    if err := client.BatchPut(context.Background(), map[string][]byte{"b": []byte("22"), "a": []byte("1")}); err == nil {
+        t.Fatalf("BatchPut in read-only mode succeeded")
+    }
+    records := readAuditLog(t, path)
+    if len(records) != 2 || records[0].Key != "a" || records[1].Key != "b" || records[1].ValueBytes != 2 {
+        t.Fatalf("audit records = %+v, want a and b from the batch", records)
+    }
+    for _, record := range records {
+        if record.Op != "BatchPut" || record.Code != "FailedPrecondition" || record.Peer != "" {
+            t.Fatalf("audit record = %+v, want a refused BatchPut with no peer certificate", record)
+        }
+    }
+}
@@ -0,0 +1,39 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/conditional.go
+
+package main
+
+import (
+    "context"
+    "time"
+)
+
+// GetConditional returns the value of key only if the store last wrote it
+// after ifModifiedAfter. The value is still loaded, so an expired key reads
+// as missing; what is saved is sending it back. A store that doesn't know
+// when key was written always returns the value.
+func (k *KV) GetConditional(ctx context.Context, key string, ifModifiedAfter time.Time) ([]byte, bool, time.Time, error) {
+    defer k.locks.rlock(key)()
+
+    if err := validateKey(key); err != nil {
+        return nil, false, time.Time{}, err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, false, time.Time{}, err
+    }
+
+    k.log(ctx).Debug("🗄️📥 getting value if modified", "key", key, "if_modified_after", ifModifiedAfter)
+
+    value, err := k.load(ctx, key)
+    if err != nil {
+        return nil, false, time.Time{}, err
+    }
+    modTime, err := k.store.ModTime(ctx, key)
+    if err != nil {
+        return nil, false, time.Time{}, err
+    }
+    if !ifModifiedAfter.IsZero() && !modTime.IsZero() && !modTime.After(ifModifiedAfter) {
+        k.log(ctx).Debug("🗄️📥 value not modified", "key", key, "mod_time", modTime)
+        return nil, false, modTime, nil
+    }
+    return value, true, modTime, nil
+}
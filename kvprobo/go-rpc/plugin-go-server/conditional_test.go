@@ -0,0 +1,73 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/conditional_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestGetConditional(t *testing.T) {
+    bolt, err := newBoltStore(filepath.Join(t.TempDir(), "kv.db"))
+    if err != nil {
+        t.Fatalf("newBoltStore failed: %v", err)
+    }
+    t.Cleanup(func() { bolt.Close() })
+
+    for name, store := range map[string]Store{
+        "file":   newFileStore(t.TempDir()),
+        "memory": newMemStore(),
+        "bolt":   bolt,
+    } {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            client := serveKV(t, NewKV(store, nil))
+
+            if _, _, _, err := client.GetConditional(ctx, "k", time.Now()); !errors.Is(err, shared.ErrKeyNotFound) {
+                t.Fatalf("GetConditional(missing) = %v, want ErrKeyNotFound", err)
+            }
+
+            before := time.Now().Add(-time.Second)
+            if err := client.Put(ctx, "k", []byte("v1")); err != nil {
+                t.Fatalf("Put failed: %v", err)
+            }
+
+            value, modified, modTime, err := client.GetConditional(ctx, "k", before)
+            if err != nil || !modified || string(value) != "v1" {
+                t.Fatalf("GetConditional(before the Put) = %q, %t, %v, want v1 modified", value, modified, err)
+            }
+            if !modTime.After(before) {
+                t.Fatalf("GetConditional reported mod time %s, want after %s", modTime, before)
+            }
+
+            value, modified, got, err := client.GetConditional(ctx, "k", modTime)
+            if err != nil || modified || len(value) != 0 {
+                t.Fatalf("GetConditional(at the mod time) = %q, %t, %v, want not modified and no value", value, modified, err)
+            }
+            if !got.Equal(modTime) {
+                t.Fatalf("GetConditional reported mod time %s when not modified, want %s", got, modTime)
+            }
+
+            value, modified, _, err = client.GetConditional(ctx, "k", time.Time{})
+            if err != nil || !modified || string(value) != "v1" {
+                t.Fatalf("GetConditional(zero time) = %q, %t, %v, want v1 modified", value, modified, err)
+            }
+        })
+    }
+}
+
+func TestGetConditionalWithUnknownModTime(t *testing.T) {
+    store := newFakeStore()
+    store.data["k"] = []byte("v")
+    kv := NewKV(store, nil)
+
+    value, modified, modTime, err := kv.GetConditional(context.Background(), "k", time.Now())
+    if err != nil || !modified || string(value) != "v" || !modTime.IsZero() {
+        t.Fatalf("GetConditional = %q, %t, %s, %v, want v modified with no mod time", value, modified, modTime, err)
+    }
+}
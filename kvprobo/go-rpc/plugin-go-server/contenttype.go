@@ -0,0 +1,96 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/contenttype.go
+
+package main
+
+import (
+    "context"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// PutWithContentType stores value with contentType in its record header.
+// The default content type isn't stored, so such a value is written just
+// as Put or PutWithTTL would write it. Like Put, it is applied once per
+// kv-idempotency-key.
+func (k *KV) PutWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error {
+    id := shared.IdempotencyKeyFromContext(ctx)
+    if id == "" || k.idempotency == nil {
+        return k.putWithContentType(ctx, key, value, contentType, ttl)
+    }
+    repeated, err := k.idempotency.do(ctx, idempotencyScope(ctx, id), k.now(), func() error {
+        return k.putWithContentType(ctx, key, value, contentType, ttl)
+    })
+    if repeated {
+        k.log(ctx).Debug("🗄️📤 repeated put not applied again", "key", key, "idempotency_key", id)
+    }
+    return err
+}
+
+func (k *KV) putWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error {
+    defer k.locks.lock(key)()
+
+    if key == "" {
+        return nil
+    }
+
+    if err := validateKey(key); err != nil {
+        return err
+    }
+    if err := shared.CheckValueSize(key, value, k.valueLimit()); err != nil {
+        return err
+    }
+    if err := shared.CheckContentType(contentType); err != nil {
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    if contentType == shared.DefaultContentType {
+        contentType = ""
+    }
+
+    var expiresAt time.Time
+    if ttl > 0 {
+        expiresAt = k.now().Add(ttl)
+    }
+
+    k.log(ctx).Debug("🗄️📤 putting value with content type",
+        "key", key,
+        "value_length", len(value),
+        "content_type", contentType,
+        "ttl", ttl)
+
+    if _, err := k.saveTyped(ctx, key, value, expiresAt, contentType); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: value})
+    return nil
+}
+
+// GetWithContentType returns the value of key and its content type, which
+// is shared.DefaultContentType for a value stored without one.
+func (k *KV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    defer k.locks.rlock(key)()
+
+    if key == "" {
+        return nil, shared.DefaultContentType, nil
+    }
+
+    if err := validateKey(key); err != nil {
+        return nil, "", err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, "", err
+    }
+
+    k.log(ctx).Debug("🗄️📥 getting value with content type", "key", key)
+    value, _, contentType, err := k.loadTyped(ctx, key)
+    if err != nil {
+        return nil, "", err
+    }
+    if contentType == "" {
+        contentType = shared.DefaultContentType
+    }
+    return value, contentType, nil
+}
@@ -0,0 +1,77 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/contenttype_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestContentTypeRoundTrip(t *testing.T) {
+    dir := t.TempDir()
+    client := serveKV(t, NewKV(newFileStore(dir), nil))
+    ctx := context.Background()
+
+    doc := []byte(`{"answer": 42}`)
+    if err := client.PutWithContentType(ctx, "doc", doc, "application/json; charset=utf-8", 0); err != nil {
+        t.Fatalf("PutWithContentType failed: %v", err)
+    }
+    if err := client.Put(ctx, "blob", []byte{0, 1, 2}); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    // The content type is kept on disk, so a restarted server still has it
+    restarted := serveKV(t, NewKV(newFileStore(dir), nil))
+    for _, tt := range []struct {
+        key, want string
+    }{
+        {"doc", "application/json; charset=utf-8"},
+        {"blob", shared.DefaultContentType},
+    } {
+        if _, contentType, err := restarted.GetWithContentType(ctx, tt.key); err != nil || contentType != tt.want {
+            t.Fatalf("GetWithContentType(%s) = %q, %v, want %q", tt.key, contentType, err, tt.want)
+        }
+    }
+    if value, err := restarted.Get(ctx, "doc"); err != nil || !bytes.Equal(value, doc) {
+        t.Fatalf("Get(doc) = %q, %v, want %q", value, err, doc)
+    }
+
+    // Rewriting without a content type resets it
+    if err := client.Put(ctx, "doc", []byte("plain")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, contentType, err := client.GetWithContentType(ctx, "doc"); err != nil || contentType != shared.DefaultContentType {
+        t.Fatalf("GetWithContentType after Put = %q, %v, want %s", contentType, err, shared.DefaultContentType)
+    }
+
+    if err := client.PutWithContentType(ctx, "doc", doc, "not a type", 0); !errors.Is(err, shared.ErrInvalidContentType) {
+        t.Fatalf("PutWithContentType with a bad type = %v, want ErrInvalidContentType", err)
+    }
+}
+
+func TestPutWithContentTypeKeepsVersionAndTTL(t *testing.T) {
+    now := time.Unix(1700000000, 0)
+    kv := NewKV(newMemStore(), nil)
+    kv.now = func() time.Time { return now }
+    ctx := context.Background()
+
+    if err := kv.Put(ctx, "k", []byte("v1")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.PutWithContentType(ctx, "k", []byte("v2"), "text/plain", time.Minute); err != nil {
+        t.Fatalf("PutWithContentType failed: %v", err)
+    }
+    if _, version, err := kv.GetVersioned(ctx, "k"); err != nil || version != 2 {
+        t.Fatalf("GetVersioned() = %d, %v, want version 2", version, err)
+    }
+
+    now = now.Add(2 * time.Minute)
+    if _, _, err := kv.GetWithContentType(ctx, "k"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("GetWithContentType() after the ttl = %v, want ErrKeyNotFound", err)
+    }
+}
@@ -0,0 +1,64 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/counter.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "strconv"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// Increment holds key's write lock across the read and the write, so
+// concurrent increments of the same key never lose an update. The new total
+// keeps key's expiry and content type.
+func (k *KV) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+    defer k.locks.lock(key)()
+
+    if err := validateKey(key); err != nil {
+        return 0, err
+    }
+    if err := ctx.Err(); err != nil {
+        return 0, err
+    }
+
+    k.log(ctx).Debug("🗄️➕ incrementing value", "key", key, "delta", delta)
+
+    var current int64
+    value, expiresAt, version, contentType, err := k.loadRecord(ctx, key)
+    switch {
+    case errors.Is(err, shared.ErrKeyNotFound):
+    case err != nil:
+        return 0, err
+    default:
+        current, err = strconv.ParseInt(string(value), 10, 64)
+        if err != nil {
+            return 0, fmt.Errorf("%w: %q holds %q", shared.ErrNotANumber, key, truncateForError(value))
+        }
+    }
+
+    if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+        return 0, fmt.Errorf("%w: %q is %d, adding %d overflows int64", shared.ErrOutOfRange, key, current, delta)
+    }
+    total := current + delta
+
+    encoded := []byte(strconv.FormatInt(total, 10))
+    if err := k.store.Put(ctx, key, encodeTypedValue(encoded, expiresAt, version+1, contentType)); err != nil {
+        return 0, err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: encoded})
+    return total, nil
+}
+
+// truncateForError shortens value so a large non-numeric value doesn't end
+// up in full in an error message.
+func truncateForError(value []byte) string {
+    const limit = 32
+    if len(value) <= limit {
+        return string(value)
+    }
+    return string(value[:limit]) + "..."
+}
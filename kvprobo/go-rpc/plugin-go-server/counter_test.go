@@ -0,0 +1,87 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/counter_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "math"
+    "sync"
+    "testing"
+
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestIncrement(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newMemStore(), nil)
+
+    if total, err := kv.Increment(ctx, "hits", 5); err != nil || total != 5 {
+        t.Fatalf("Increment of a missing key = %d, %v; want 5, nil", total, err)
+    }
+    if total, err := kv.Increment(ctx, "hits", -7); err != nil || total != -2 {
+        t.Fatalf("Increment by -7 = %d, %v; want -2, nil", total, err)
+    }
+    if value, err := kv.Get(ctx, "hits"); err != nil || string(value) != "-2" {
+        t.Fatalf("stored value = %q, %v; want \"-2\"", value, err)
+    }
+
+    if err := kv.Put(ctx, "name", []byte("alice")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, err := kv.Increment(ctx, "name", 1); !errors.Is(err, shared.ErrNotANumber) {
+        t.Fatalf("Increment of a non-numeric value = %v, want ErrNotANumber", err)
+    }
+
+    if err := kv.Put(ctx, "big", []byte("9223372036854775807")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, err := kv.Increment(ctx, "big", 1); !errors.Is(err, shared.ErrOutOfRange) || errors.Is(err, shared.ErrNotANumber) {
+        t.Fatalf("Increment past MaxInt64 = %v, want ErrOutOfRange", err)
+    }
+    if total, err := kv.Increment(ctx, "big", math.MinInt64); err != nil || total != -1 {
+        t.Fatalf("Increment by MinInt64 = %d, %v; want -1, nil", total, err)
+    }
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+    ctx := context.Background()
+    client := serveKV(t, NewKV(newMemStore(), nil))
+
+    const workers, perWorker = 8, 25
+    var wg sync.WaitGroup
+    errs := make(chan error, workers)
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < perWorker; j++ {
+                if _, err := client.Increment(ctx, "counter", 1); err != nil {
+                    errs <- err
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    close(errs)
+    for err := range errs {
+        t.Fatalf("Increment failed: %v", err)
+    }
+
+    total, err := client.Increment(ctx, "counter", 0)
+    if err != nil || total != workers*perWorker {
+        t.Fatalf("counter after %d increments = %d, %v; want %d", workers*perWorker, total, err, workers*perWorker)
+    }
+
+    if err := client.Put(ctx, "text", []byte("abc")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    _, err = client.Increment(ctx, "text", 1)
+    if !errors.Is(err, shared.ErrNotANumber) || status.Code(err) != codes.FailedPrecondition {
+        t.Fatalf("Increment of text over gRPC = %v, want ErrNotANumber with code FailedPrecondition", err)
+    }
+}
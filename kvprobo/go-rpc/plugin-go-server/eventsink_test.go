@@ -0,0 +1,57 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/eventsink_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/hashicorp/go-plugin"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// chanSink hands every event it is notified of to a channel.
+type chanSink chan shared.Event
+
+func (s chanSink) Notify(ctx context.Context, event shared.Event) error {
+    s <- event
+    return nil
+}
+
+func TestEventSinkReceivesPut(t *testing.T) {
+    logger := hclog.NewNullLogger()
+    client, server := plugin.TestPluginGRPCConn(t, false, map[string]plugin.Plugin{
+        shared.PluginName: &shared.KVGRPCPlugin{Impl: NewKV(newMemStore(), logger), Logger: logger},
+    })
+    t.Cleanup(func() {
+        client.Close()
+        server.Stop()
+    })
+
+    raw, err := client.Dispense(shared.PluginName)
+    if err != nil {
+        t.Fatalf("Dispense failed: %v", err)
+    }
+    kv := raw.(*shared.GRPCClient)
+
+    ctx := context.Background()
+    sink := make(chanSink, 1)
+    if err := kv.SetEventSink(ctx, sink); err != nil {
+        t.Fatalf("SetEventSink failed: %v", err)
+    }
+    if err := kv.Put(ctx, "greeting", []byte("hello")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    select {
+    case event := <-sink:
+        if event.Op != shared.EventPut || event.Key != "greeting" || string(event.Value) != "hello" {
+            t.Fatalf("event = %v %q %q, want put greeting hello", event.Op, event.Key, event.Value)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("the event sink was never called")
+    }
+}
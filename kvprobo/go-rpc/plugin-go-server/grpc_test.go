@@ -0,0 +1,324 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/grpc_test.go
+
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "net"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+    "google.golang.org/grpc/status"
+    "google.golang.org/grpc/test/bufconn"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// serveKV exposes kv over an in-memory gRPC connection and returns the client side.
+func serveKV(t *testing.T, kv *KV) shared.KV {
+    t.Helper()
+    return shared.NewInProcessKV(t, kv)
+}
+
+// serveKVOn registers kv on server, serves it in memory and returns the
+// client side.
+func serveKVOn(t *testing.T, server *grpc.Server, kv *KV) *shared.GRPCClient {
+    t.Helper()
+    return serveKVWithCreds(t, server, kv, insecure.NewCredentials())
+}
+
+// serveKVWithCreds is serveKVOn with the client dialling with creds.
+func serveKVWithCreds(t *testing.T, server *grpc.Server, kv *KV, creds credentials.TransportCredentials) *shared.GRPCClient {
+    t.Helper()
+
+    plugin := &shared.KVGRPCPlugin{Impl: kv}
+    if err := plugin.GRPCServer(nil, server); err != nil {
+        t.Fatalf("registering KV server failed: %v", err)
+    }
+    raw, err := plugin.GRPCClient(context.Background(), nil, dialTestServerWithCreds(t, server, creds))
+    if err != nil {
+        t.Fatalf("creating KV client failed: %v", err)
+    }
+    return raw.(*shared.GRPCClient)
+}
+
+// mtlsTestCredentials returns transport credentials for a server named
+// localhost and a client presenting a certificate for clientCN, each
+// trusting only the other's certificate.
+func mtlsTestCredentials(t *testing.T, clientCN string) (server, client credentials.TransportCredentials) {
+    t.Helper()
+
+    serverCert, serverPool := testKeyPair(t, shared.DefaultCertificateConfig())
+    clientConfig := shared.DefaultCertificateConfig()
+    clientConfig.CommonName = clientCN
+    clientCert, clientPool := testKeyPair(t, clientConfig)
+
+    server = credentials.NewTLS(&tls.Config{
+        Certificates: []tls.Certificate{serverCert},
+        ClientAuth:   tls.RequireAndVerifyClientCert,
+        ClientCAs:    clientPool,
+    })
+    client = credentials.NewTLS(&tls.Config{
+        Certificates: []tls.Certificate{clientCert},
+        RootCAs:      serverPool,
+        ServerName:   "localhost",
+    })
+    return server, client
+}
+
+// testKeyPair generates a self-signed certificate from cfg and returns it
+// with a pool trusting it.
+func testKeyPair(t *testing.T, cfg *shared.CertificateConfig) (tls.Certificate, *x509.CertPool) {
+    t.Helper()
+
+    certPEM, keyPEM, err := shared.GenerateCert(cfg, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    cert, err := tls.X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        t.Fatalf("X509KeyPair failed: %v", err)
+    }
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(certPEM) {
+        t.Fatalf("AppendCertsFromPEM found no certificate")
+    }
+    return cert, pool
+}
+
+// dialTestServer serves server on an in-memory listener and returns a
+// connection to it. Both are shut down when the test ends.
+func dialTestServer(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+    t.Helper()
+    return dialTestServerWithCreds(t, server, insecure.NewCredentials())
+}
+
+// dialTestServerWithCreds is dialTestServer with the connection dialled
+// with creds.
+func dialTestServerWithCreds(t *testing.T, server *grpc.Server, creds credentials.TransportCredentials) *grpc.ClientConn {
+    t.Helper()
+
+    listener := bufconn.Listen(1 << 20)
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufconn",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(creds))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return conn
+}
+
+func TestGetStatusCodes(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    client := serveKV(t, NewKV(newFileStore(dir), nil))
+
+    if err := client.Put(ctx, "present", []byte("value")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    // A directory where a value file should be makes the read itself fail
+    if err := os.Mkdir(filepath.Join(dir, "unreadable"), 0700); err != nil {
+        t.Fatalf("Mkdir failed: %v", err)
+    }
+
+    tests := []struct {
+        key      string
+        code     codes.Code
+        sentinel error
+    }{
+        {"present", codes.OK, nil},
+        {"missing", codes.NotFound, shared.ErrKeyNotFound},
+        {"../escape", codes.InvalidArgument, shared.ErrInvalidKey},
+        {"unreadable", codes.Internal, shared.ErrStorageFailure},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.key, func(t *testing.T) {
+            _, err := client.Get(ctx, tt.key)
+            if status.Code(err) != tt.code {
+                t.Fatalf("Get(%q) code = %v (%v), want %v", tt.key, status.Code(err), err, tt.code)
+            }
+            if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+                t.Fatalf("Get(%q) = %v, want errors.Is %v", tt.key, err, tt.sentinel)
+            }
+        })
+    }
+}
+
+func TestReflectionListsKVService(t *testing.T) {
+    for _, tt := range []struct {
+        env  string
+        want bool
+    }{
+        {"true", true},
+        {"", false},
+    } {
+        t.Run("PLUGIN_KV_REFLECTION="+tt.env, func(t *testing.T) {
+            t.Setenv("PLUGIN_KV_REFLECTION", tt.env)
+
+            server := newGRPCServer(nil, serverOptions{}, hclog.NewNullLogger())
+            if err := (&shared.KVGRPCPlugin{Impl: NewKV(newMemStore(), nil)}).GRPCServer(nil, server); err != nil {
+                t.Fatalf("registering KV server failed: %v", err)
+            }
+            conn := dialTestServer(t, server)
+
+            stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+            if err == nil {
+                err = stream.Send(&reflectionpb.ServerReflectionRequest{
+                    MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+                })
+            }
+            var resp *reflectionpb.ServerReflectionResponse
+            if err == nil {
+                resp, err = stream.Recv()
+            }
+
+            if !tt.want {
+                if status.Code(err) != codes.Unimplemented {
+                    t.Fatalf("reflection without PLUGIN_KV_REFLECTION = %v, want code Unimplemented", err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("listing services failed: %v", err)
+            }
+            found := false
+            for _, service := range resp.GetListServicesResponse().GetService() {
+                if service.Name == "proto.KV" {
+                    found = true
+                }
+            }
+            if !found {
+                t.Fatalf("reflection services = %v, want proto.KV", resp.GetListServicesResponse().GetService())
+            }
+        })
+    }
+}
+
+func TestHealthCheck(t *testing.T) {
+    ctx := context.Background()
+    h := newKVHealth()
+    server := newGRPCServer(nil, serverOptions{health: h}, hclog.NewNullLogger())
+
+    // Stand in for the Health service go-plugin registers on the same server
+    pluginHealth := health.NewServer()
+    pluginHealth.SetServingStatus("plugin", healthpb.HealthCheckResponse_SERVING)
+    healthpb.RegisterHealthServer(server, pluginHealth)
+
+    client := serveKVOn(t, server, NewKV(newMemStore(), nil))
+
+    if err := client.HealthCheck(ctx); !errors.Is(err, shared.ErrNotServing) {
+        t.Fatalf("HealthCheck before the store is ready = %v, want ErrNotServing", err)
+    }
+
+    h.setServing(true)
+    if err := client.HealthCheck(ctx); err != nil {
+        t.Fatalf("HealthCheck after startup = %v, want SERVING", err)
+    }
+
+    h.shutdown()
+    if err := client.HealthCheck(ctx); !errors.Is(err, shared.ErrNotServing) {
+        t.Fatalf("HealthCheck during shutdown = %v, want ErrNotServing", err)
+    }
+
+    // go-plugin's own check is passed through untouched
+    resp, err := healthpb.NewHealthClient(dialTestServer(t, server)).Check(ctx, &healthpb.HealthCheckRequest{Service: "plugin"})
+    if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+        t.Fatalf("plugin health = %v, %v; want SERVING", resp, err)
+    }
+}
+
+func TestPutCancelledMidWrite(t *testing.T) {
+    dir := t.TempDir()
+    store := newFileStore(dir)
+    writing := make(chan struct{})
+    release := make(chan struct{})
+    store.writeFile = func(name string, data []byte, perm os.FileMode) error {
+        close(writing)
+        <-release
+        return os.WriteFile(name, data, perm)
+    }
+    // Report what the server handler itself returned
+    handled := make(chan error, 1)
+    server := grpc.NewServer(grpc.UnaryInterceptor(
+        func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+            resp, err := handler(ctx, req)
+            handled <- err
+            return resp, err
+        }))
+    client := serveKVOn(t, server, NewKV(store, nil))
+
+    ctx, cancel := context.WithCancel(context.Background())
+    result := make(chan error, 1)
+    go func() {
+        result <- client.Put(ctx, "slow", []byte("value"))
+    }()
+
+    <-writing
+    cancel()
+    if err := <-result; status.Code(err) != codes.Canceled {
+        t.Fatalf("Put = %v, want code Canceled", err)
+    }
+    if err := <-handled; status.Code(err) != codes.Canceled {
+        t.Fatalf("server Put handler returned %v, want code Canceled", err)
+    }
+
+    // Let the stalled write finish; it must not land
+    close(release)
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        entries, err := os.ReadDir(filepath.Join(dir, fileStoreTempDir))
+        if err != nil {
+            t.Fatalf("reading temp dir failed: %v", err)
+        }
+        if len(entries) == 0 {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("abandoned temp file was not cleaned up: %v", entries)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    if exists, err := store.Exists(context.Background(), "slow"); err != nil || exists {
+        t.Fatalf("Exists(slow) after a cancelled Put = %v, %v; want false, nil", exists, err)
+    }
+}
+
+func TestGRPCServerValueSizeLimit(t *testing.T) {
+    ctx := context.Background()
+    limit := shared.DefaultMaxValueBytes
+    // Over gRPC's 4MB default, so only a sized receive limit lets the
+    // value reach the size check
+    if limit <= 4<<20 {
+        t.Fatalf("DefaultMaxValueBytes = %d, want it over 4MB for this test", limit)
+    }
+    server := newGRPCServer(nil, serverOptions{maxRecvMsgSize: shared.MaxRecvMsgSize(limit)}, hclog.NewNullLogger())
+    client := serveKVOn(t, server, NewKV(newMemStore(), nil))
+
+    if err := client.Put(ctx, "k", make([]byte, limit)); err != nil {
+        t.Fatalf("Put of a value at the %d byte limit = %v, want nil", limit, err)
+    }
+    err := client.Put(ctx, "k", make([]byte, limit+1))
+    if !errors.Is(err, shared.ErrValueTooLarge) || status.Code(err) != codes.InvalidArgument {
+        t.Fatalf("Put of a value one byte over the limit = %v, want ErrValueTooLarge", err)
+    }
+}
@@ -0,0 +1,105 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/health.go
+
+package main
+
+import (
+    "context"
+    "sync"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// kvHealth reports whether the KV service can take requests, using the
+// standard gRPC health protocol. go-plugin registers its own Health service
+// on the server for its "plugin" check, and a second registration would
+// panic, so kvHealth answers Check calls for the KV service (and the overall
+// "" service) from an interceptor and passes every other check through.
+//
+// While the store can't be written, the service reports NOT_SERVING and
+// writes fail with Unavailable, but reads are still served.
+type kvHealth struct {
+    server *health.Server
+
+    mu         sync.Mutex
+    serving    bool
+    unwritable error // why the store can't be written, or nil
+}
+
+// newKVHealth starts out NOT_SERVING until setServing is called.
+func newKVHealth() *kvHealth {
+    h := &kvHealth{server: health.NewServer()}
+    h.setServing(false)
+    return h
+}
+
+func (h *kvHealth) setServing(serving bool) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.serving = serving
+    h.update()
+}
+
+// setUnwritable records err as the reason the store can't be written, or
+// that it can again when err is nil.
+func (h *kvHealth) setUnwritable(err error) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.unwritable = err
+    h.update()
+}
+
+// writeError returns the error writes fail with while the store can't be
+// written, or nil.
+func (h *kvHealth) writeError() error {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if h.unwritable == nil {
+        return nil
+    }
+    return status.Errorf(codes.Unavailable, "storage is not writable: %v", h.unwritable)
+}
+
+// update publishes the status; h.mu must be held.
+func (h *kvHealth) update() {
+    status := healthpb.HealthCheckResponse_NOT_SERVING
+    if h.serving && h.unwritable == nil {
+        status = healthpb.HealthCheckResponse_SERVING
+    }
+    h.server.SetServingStatus("", status)
+    h.server.SetServingStatus(proto.KV_ServiceDesc.ServiceName, status)
+}
+
+// shutdown reports NOT_SERVING from now on, even if setServing is called again.
+func (h *kvHealth) shutdown() {
+    h.server.Shutdown()
+}
+
+// unaryInterceptor answers Health/Check for the services kvHealth owns, and
+// refuses KV writes while the store can't be written.
+func (h *kvHealth) unaryInterceptor() grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        if mutatingMethods[info.FullMethod] {
+            if err := h.writeError(); err != nil {
+                return nil, err
+            }
+        }
+        if info.FullMethod != healthpb.Health_Check_FullMethodName {
+            return handler(ctx, req)
+        }
+        check, ok := req.(*healthpb.HealthCheckRequest)
+        if !ok || !h.owns(check.Service) {
+            return handler(ctx, req)
+        }
+        return h.server.Check(ctx, check)
+    }
+}
+
+func (h *kvHealth) owns(service string) bool {
+    return service == "" || service == proto.KV_ServiceDesc.ServiceName
+}
@@ -0,0 +1,103 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/idempotency.go
+
+package main
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// idempotencyWindow is how long a Put's idempotency key is remembered. It
+// only has to outlast the client's retries, so it is kept short to bound
+// how many keys are held.
+const idempotencyWindow = 5 * time.Minute
+
+// idempotentPut is one Put seen with an idempotency key. done is closed
+// once err holds its result.
+type idempotentPut struct {
+    done    chan struct{}
+    err     error
+    expires time.Time
+}
+
+// idempotencyCache remembers which idempotency keys have been used, so a
+// retried Put returns the first attempt's result instead of writing again.
+type idempotencyCache struct {
+    mu   sync.Mutex
+    puts map[string]*idempotentPut
+    // order holds the keys in puts oldest first. Every key is kept for the
+    // same window, so this is also the order they expire in.
+    order []string
+}
+
+func newIdempotencyCache() *idempotencyCache {
+    return &idempotencyCache{puts: map[string]*idempotentPut{}}
+}
+
+// do runs put unless key was used within idempotencyWindow, in which case
+// it returns that Put's result, waiting for it if it is still running, and
+// reports it as repeated. A Put that fails is forgotten so a retry can try
+// it again.
+func (c *idempotencyCache) do(ctx context.Context, key string, now time.Time, put func() error) (repeated bool, err error) {
+    c.mu.Lock()
+    c.expire(now)
+    if seen, ok := c.puts[key]; ok {
+        c.mu.Unlock()
+        select {
+        case <-seen.done:
+            return true, seen.err
+        case <-ctx.Done():
+            return true, ctx.Err()
+        }
+    }
+    p := &idempotentPut{done: make(chan struct{}), expires: now.Add(idempotencyWindow)}
+    c.puts[key] = p
+    c.order = append(c.order, key)
+    c.mu.Unlock()
+
+    p.err = put()
+    if p.err != nil {
+        c.mu.Lock()
+        delete(c.puts, key)
+        c.forget(key)
+        c.mu.Unlock()
+    }
+    close(p.done)
+    return false, p.err
+}
+
+// expire drops the keys whose window has passed. c.mu must be held.
+func (c *idempotencyCache) expire(now time.Time) {
+    n := 0
+    for ; n < len(c.order); n++ {
+        p, ok := c.puts[c.order[n]]
+        if ok && now.Before(p.expires) {
+            break
+        }
+        if ok {
+            delete(c.puts, c.order[n])
+        }
+    }
+    c.order = c.order[n:]
+}
+
+// forget removes key from c.order. A stale entry would otherwise hold up
+// expire once a retry reused key with a later window. c.mu must be held.
+func (c *idempotencyCache) forget(key string) {
+    // Usually the newest key, so search from the end
+    for i := len(c.order) - 1; i >= 0; i-- {
+        if c.order[i] == key {
+            c.order = append(c.order[:i], c.order[i+1:]...)
+            return
+        }
+    }
+}
+
+// idempotencyScope keeps the same idempotency key used in two namespaces
+// from colliding.
+func idempotencyScope(ctx context.Context, key string) string {
+    return shared.NamespaceFromContext(ctx) + "\x00" + key
+}
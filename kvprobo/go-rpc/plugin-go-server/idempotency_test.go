@@ -0,0 +1,121 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/idempotency_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestPutWithIdempotencyKeyWritesOnce(t *testing.T) {
+    kv := NewKV(newMemStore(), nil)
+    client := serveKV(t, kv)
+
+    version := func() uint64 {
+        t.Helper()
+        _, version, err := kv.GetVersioned(context.Background(), "k")
+        if err != nil {
+            t.Fatalf("GetVersioned failed: %v", err)
+        }
+        return version
+    }
+
+    ctx := shared.WithIdempotencyKey(context.Background(), "put-1")
+    for i := 0; i < 2; i++ {
+        if err := client.Put(ctx, "k", []byte("v")); err != nil {
+            t.Fatalf("Put %d failed: %v", i+1, err)
+        }
+    }
+    if got := version(); got != 1 {
+        t.Fatalf("two Puts with one idempotency key left version %d, want 1 write", got)
+    }
+
+    // A new key, or none, writes again
+    if err := client.Put(shared.WithIdempotencyKey(context.Background(), "put-2"), "k", []byte("v")); err != nil {
+        t.Fatalf("Put with a new idempotency key failed: %v", err)
+    }
+    if err := client.Put(context.Background(), "k", []byte("v")); err != nil {
+        t.Fatalf("Put without an idempotency key failed: %v", err)
+    }
+    if got := version(); got != 3 {
+        t.Fatalf("version = %d after two more Puts, want 3", got)
+    }
+
+    // The same key in another namespace is a different Put
+    other := shared.WithNamespace(ctx, "other")
+    if err := client.Put(other, "k", []byte("v")); err != nil {
+        t.Fatalf("Put in another namespace failed: %v", err)
+    }
+    if _, err := client.Get(shared.WithNamespace(context.Background(), "other"), "k"); err != nil {
+        t.Fatalf("Put in another namespace wasn't applied: %v", err)
+    }
+}
+
+func TestIdempotencyCacheForgetsFailuresAndExpiredKeys(t *testing.T) {
+    ctx := context.Background()
+    cache := newIdempotencyCache()
+    now := time.Now()
+    runs := 0
+    put := func(err error) func() error {
+        return func() error {
+            runs++
+            return err
+        }
+    }
+
+    failed := errors.New("disk full")
+    if _, err := cache.do(ctx, "k", now, put(failed)); !errors.Is(err, failed) {
+        t.Fatalf("do() = %v, want %v", err, failed)
+    }
+    if repeated, err := cache.do(ctx, "k", now, put(nil)); repeated || err != nil {
+        t.Fatalf("do() after a failure = %t, %v, want a fresh run", repeated, err)
+    }
+    if repeated, err := cache.do(ctx, "k", now.Add(idempotencyWindow-time.Second), put(nil)); !repeated || err != nil {
+        t.Fatalf("do() within the window = %t, %v, want a repeat", repeated, err)
+    }
+    if repeated, _ := cache.do(ctx, "k", now.Add(idempotencyWindow), put(nil)); repeated {
+        t.Fatalf("do() once the window passed was treated as a repeat")
+    }
+    if runs != 3 {
+        t.Fatalf("put ran %d times, want 3", runs)
+    }
+    if len(cache.puts) != 1 {
+        t.Fatalf("cache holds %d keys, want 1", len(cache.puts))
+    }
+}
+
+func TestIdempotencyCacheFailureLeavesNoStaleOrder(t *testing.T) {
+    ctx := context.Background()
+    cache := newIdempotencyCache()
+    now := time.Now()
+    failed := errors.New("disk full")
+
+    // k fails and is retried after other keys, so a stale entry for k
+    // would sit ahead of them and keep them from expiring
+    if _, err := cache.do(ctx, "k", now, func() error { return failed }); !errors.Is(err, failed) {
+        t.Fatalf("do() = %v, want %v", err, failed)
+    }
+    if _, err := cache.do(ctx, "other", now.Add(time.Second), func() error { return nil }); err != nil {
+        t.Fatalf("do(other) failed: %v", err)
+    }
+    if _, err := cache.do(ctx, "k", now.Add(time.Minute), func() error { return nil }); err != nil {
+        t.Fatalf("do() retrying k failed: %v", err)
+    }
+    if len(cache.order) != 2 {
+        t.Fatalf("order = %q, want each remembered key once", cache.order)
+    }
+
+    cache.mu.Lock()
+    cache.expire(now.Add(idempotencyWindow + 2*time.Second))
+    cache.mu.Unlock()
+    if _, ok := cache.puts["other"]; ok {
+        t.Fatalf("other outlived its window")
+    }
+    if _, ok := cache.puts["k"]; !ok || len(cache.order) != 1 {
+        t.Fatalf("the retried k was dropped early: order = %q", cache.order)
+    }
+}
@@ -0,0 +1,84 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/limiter.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "sync/atomic"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// concurrencyLimiter caps how many KV requests run at once, so a burst of
+// clients can't exhaust the store's file descriptors or memory. Requests
+// over the cap are refused rather than queued.
+type concurrencyLimiter struct {
+    // slots has room for the number of requests allowed at once, or is nil
+    // for no limit. setMax swaps in a new channel; requests already running
+    // free their slot in the one they took it from.
+    slots atomic.Pointer[chan struct{}]
+}
+
+// newConcurrencyLimiter allows up to max requests at once. Zero or less
+// allows any number.
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+    l := &concurrencyLimiter{}
+    l.setMax(max)
+    return l
+}
+
+// setMax changes the limit for requests that arrive from now on.
+func (l *concurrencyLimiter) setMax(max int) {
+    if max <= 0 {
+        l.slots.Store(nil)
+        return
+    }
+    slots := make(chan struct{}, max)
+    l.slots.Store(&slots)
+}
+
+// maxConcurrencyFromEnv reads PLUGIN_KV_MAX_CONCURRENCY, the most KV
+// requests served at once. It returns 0, meaning no limit, when unset.
+func maxConcurrencyFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_MAX_CONCURRENCY")
+    if value == "" {
+        return 0, nil
+    }
+    max, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_MAX_CONCURRENCY %q: %w", value, err)
+    }
+    if max < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_MAX_CONCURRENCY must not be negative, got %d", max)
+    }
+    return max, nil
+}
+
+// unaryInterceptor fails KV requests with ResourceExhausted while every
+// slot is taken. Health checks and go-plugin's own services aren't
+// limited, and neither are streams such as Watch, which stay open
+// without touching the store.
+func (l *concurrencyLimiter) unaryInterceptor() grpc.UnaryServerInterceptor {
+    prefix := "/" + proto.KV_ServiceDesc.ServiceName + "/"
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        slots := l.slots.Load()
+        if slots == nil || !strings.HasPrefix(info.FullMethod, prefix) {
+            return handler(ctx, req)
+        }
+        select {
+        case *slots <- struct{}{}:
+        default:
+            return nil, status.Errorf(codes.ResourceExhausted, "server is handling its limit of %d concurrent requests", cap(*slots))
+        }
+        defer func() { <-*slots }()
+        return handler(ctx, req)
+    }
+}
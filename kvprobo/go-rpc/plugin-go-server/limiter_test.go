@@ -0,0 +1,84 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/limiter_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+)
+
+// blockingStore holds every Get until release is closed, announcing each
+// one on started.
+type blockingStore struct {
+    Store
+    started chan struct{}
+    release chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, key string) ([]byte, error) {
+    s.started <- struct{}{}
+    <-s.release
+    return s.Store.Get(ctx, key)
+}
+
+func TestConcurrencyLimitRefusesExcessRequests(t *testing.T) {
+    const limit, requests = 2, 5
+    store := &blockingStore{Store: newMemStore(), started: make(chan struct{}, requests), release: make(chan struct{})}
+    if err := store.Put(context.Background(), "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{limiter: newConcurrencyLimiter(limit)}, hclog.NewNullLogger()), NewKV(store, nil))
+
+    errs := make(chan error, requests)
+    for i := 0; i < requests; i++ {
+        go func() {
+            _, err := client.Get(context.Background(), "k")
+            errs <- err
+        }()
+    }
+
+    // The requests over the limit are refused while the others hold the store
+    for i := 0; i < requests-limit; i++ {
+        if err := <-errs; status.Code(err) != codes.ResourceExhausted {
+            t.Fatalf("request over the limit = %v, want ResourceExhausted", err)
+        }
+    }
+    for i := 0; i < limit; i++ {
+        <-store.started
+    }
+    close(store.release)
+    for i := 0; i < limit; i++ {
+        if err := <-errs; err != nil {
+            t.Fatalf("request within the limit failed: %v", err)
+        }
+    }
+
+    // Freed slots are reusable
+    if _, err := client.Get(context.Background(), "k"); err != nil {
+        t.Fatalf("Get after the burst failed: %v", err)
+    }
+}
+
+func TestMaxConcurrencyFromEnv(t *testing.T) {
+    for _, tc := range []struct {
+        value   string
+        want    int
+        wantErr bool
+    }{
+        {"", 0, false},
+        {"16", 16, false},
+        {"0", 0, false},
+        {"-1", 0, true},
+        {"lots", 0, true},
+    } {
+        t.Setenv("PLUGIN_KV_MAX_CONCURRENCY", tc.value)
+        got, err := maxConcurrencyFromEnv()
+        if (err != nil) != tc.wantErr || got != tc.want {
+            t.Errorf("maxConcurrencyFromEnv(%q) = %d, %v; want %d, error %v", tc.value, got, err, tc.want, tc.wantErr)
+        }
+    }
+}
@@ -0,0 +1,91 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/locks.go
+
+package main
+
+import (
+    "hash/fnv"
+    "sort"
+    "sync"
+)
+
+// lockStripes is how many locks keys are spread over. Keys that hash to
+// the same stripe share a lock, which only costs them some parallelism.
+const lockStripes = 64
+
+// keyLocks serialises operations on the same key while letting operations
+// on different keys run in parallel. Operations on several keys lock their
+// stripes in ascending order, so two of them can't deadlock, and ones that
+// need the whole store to hold still lock every stripe.
+type keyLocks struct {
+    stripes [lockStripes]sync.RWMutex
+}
+
+func stripeOf(key string) int {
+    h := fnv.New32a()
+    h.Write([]byte(key))
+    return int(h.Sum32() % lockStripes)
+}
+
+// stripesOf returns the distinct stripes keys hash to, in ascending order.
+func stripesOf(keys []string) []int {
+    seen := map[int]bool{}
+    var stripes []int
+    for _, key := range keys {
+        if i := stripeOf(key); !seen[i] {
+            seen[i] = true
+            stripes = append(stripes, i)
+        }
+    }
+    sort.Ints(stripes)
+    return stripes
+}
+
+// lock takes the write locks for keys and returns a function releasing them.
+func (l *keyLocks) lock(keys ...string) func() {
+    stripes := stripesOf(keys)
+    for _, i := range stripes {
+        l.stripes[i].Lock()
+    }
+    return func() {
+        for _, i := range stripes {
+            l.stripes[i].Unlock()
+        }
+    }
+}
+
+// rlock takes the read locks for keys and returns a function releasing them.
+func (l *keyLocks) rlock(keys ...string) func() {
+    stripes := stripesOf(keys)
+    for _, i := range stripes {
+        l.stripes[i].RLock()
+    }
+    return func() {
+        for _, i := range stripes {
+            l.stripes[i].RUnlock()
+        }
+    }
+}
+
+// lockAll takes every write lock, excluding all other operations.
+func (l *keyLocks) lockAll() func() {
+    for i := range l.stripes {
+        l.stripes[i].Lock()
+    }
+    return func() {
+        for i := range l.stripes {
+            l.stripes[i].Unlock()
+        }
+    }
+}
+
+// rlockAll takes every read lock, excluding all writers.
+func (l *keyLocks) rlockAll() func() {
+    for i := range l.stripes {
+        l.stripes[i].RLock()
+    }
+    return func() {
+        for i := range l.stripes {
+            l.stripes[i].RUnlock()
+        }
+    }
+}
@@ -4,18 +4,27 @@
 package main
 
 import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
     "os"
     "os/signal"
+    "sort"
     "sync"
+    "sync/atomic"
     "syscall"
     "time"
 
     "strconv"
     "strings"
 
+    "crypto/tls"
     "crypto/x509"
 
     "google.golang.org/grpc"
+    "google.golang.org/grpc/reflection"
     // "google.golang.org/grpc/credentials"
 
     "github.com/hashicorp/go-hclog"
@@ -23,45 +32,435 @@ import (
     "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
 )
 
+// maxKeyLength keeps the backing filename within the 255-byte limit of common filesystems.
+const maxKeyLength = 255
+
+// validateKey rejects keys that could escape the data directory or produce
+// an unusable filename. The empty key names the data directory itself, so
+// it is rejected too; Put, Get and Delete treat it as a no-op before
+// getting here.
+func validateKey(key string) error {
+    switch {
+    case key == "":
+        return fmt.Errorf("%w: empty key", shared.ErrInvalidKey)
+    case len(key) > maxKeyLength:
+        return fmt.Errorf("%w: key exceeds %d bytes", shared.ErrInvalidKey, maxKeyLength)
+    case strings.ContainsAny(key, "/\\"):
+        return fmt.Errorf("%w: %q contains a path separator", shared.ErrInvalidKey, key)
+    case key == "." || strings.Contains(key, ".."):
+        return fmt.Errorf("%w: %q contains \"..\"", shared.ErrInvalidKey, key)
+    case strings.ContainsRune(key, 0):
+        return fmt.Errorf("%w: key contains a NUL byte", shared.ErrInvalidKey)
+    }
+    return nil
+}
+
+// KV validates keys and serialises access to each key of the configured
+// Store.
 type KV struct {
     logger hclog.Logger
-    mu     sync.RWMutex
+    locks  keyLocks
+    store  Store
+    now    func() time.Time
+
+    // maxValueBytes rejects larger values before anything is written. Zero
+    // allows any size. It is atomic so a SIGHUP can change it while
+    // requests are running.
+    maxValueBytes atomic.Int64
+
+    // scanLimit caps how many entries Scan returns. Zero returns them all.
+    scanLimit int
+
+    watchMu  sync.Mutex
+    watchers map[*watcher]struct{}
+
+    // idempotency remembers the kv-idempotency-key of recent Puts.
+    idempotency *idempotencyCache
+}
+
+// NewKV returns a KV backed by store, keeping each namespace's keys apart.
+func NewKV(store Store, logger hclog.Logger) *KV {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+    k := &KV{
+        logger:      logger,
+        store:       namespacedStore{store},
+        now:         time.Now,
+        scanLimit:   defaultScanLimit,
+        idempotency: newIdempotencyCache(),
+    }
+    k.maxValueBytes.Store(shared.DefaultMaxValueBytes)
+    return k
+}
+
+// valueLimit returns the largest value k currently accepts, zero for no
+// limit.
+func (k *KV) valueLimit() int {
+    return int(k.maxValueBytes.Load())
+}
+
+// log returns k's logger tagged with the request ID of the RPC ctx belongs to.
+func (k *KV) log(ctx context.Context) hclog.Logger {
+    return shared.RequestLogger(ctx, k.logger)
 }
 
-func (k *KV) Put(key string, value []byte) error {
-    k.mu.Lock()
-    defer k.mu.Unlock()
+// Put stores value under key. A Put carrying a kv-idempotency-key seen
+// within idempotencyWindow returns the first one's result without writing.
+func (k *KV) Put(ctx context.Context, key string, value []byte) error {
+    id := shared.IdempotencyKeyFromContext(ctx)
+    if id == "" || k.idempotency == nil {
+        return k.put(ctx, key, value)
+    }
+    repeated, err := k.idempotency.do(ctx, idempotencyScope(ctx, id), k.now(), func() error {
+        return k.put(ctx, key, value)
+    })
+    if repeated {
+        k.log(ctx).Debug("🗄️📤 repeated put not applied again", "key", key, "idempotency_key", id)
+    }
+    return err
+}
+
+func (k *KV) put(ctx context.Context, key string, value []byte) error {
+    defer k.locks.lock(key)()
 
     if key == "" {
         return nil
     }
 
-    k.logger.Debug("🗄️📤 putting value",
+    if err := validateKey(key); err != nil {
+        return err
+    }
+    if err := shared.CheckValueSize(key, value, k.valueLimit()); err != nil {
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    k.log(ctx).Debug("🗄️📤 putting value",
         "key", key,
         "value_length", len(value))
 
-    return os.WriteFile("/tmp/kv-data-"+key, value, 0644)
+    if _, err := k.save(ctx, key, value, time.Time{}); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: value})
+    return nil
 }
 
-func (k *KV) Get(key string) ([]byte, error) {
-    k.mu.RLock()
-    defer k.mu.RUnlock()
+func (k *KV) Get(ctx context.Context, key string) ([]byte, error) {
+    defer k.locks.rlock(key)()
 
     if key == "" {
         return nil, nil
     }
 
-    k.logger.Debug("🗄️📥 getting value", "key", key)
-    return os.ReadFile("/tmp/kv-data-" + key)
+    if err := validateKey(key); err != nil {
+        return nil, err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    k.log(ctx).Debug("🗄️📥 getting value", "key", key)
+    return k.load(ctx, key)
+}
+
+func (k *KV) Delete(ctx context.Context, key string) error {
+    defer k.locks.lock(key)()
+
+    if key == "" {
+        return nil
+    }
+
+    if err := validateKey(key); err != nil {
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    k.log(ctx).Debug("🗄️🗑️ deleting value", "key", key)
+    if err := k.store.Delete(ctx, key); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventDelete, Key: key})
+    return nil
+}
+
+func (k *KV) List(ctx context.Context, prefix string) ([]string, error) {
+    defer k.locks.rlockAll()()
+
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    k.log(ctx).Debug("🗄️📋 listing keys", "prefix", prefix)
+    keys, err := k.store.List(ctx, prefix)
+    if err != nil {
+        return nil, err
+    }
+
+    // Expired keys read as missing, so they aren't listed either
+    listed := make([]string, 0, len(keys))
+    for _, key := range keys {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        live, err := k.live(ctx, key)
+        if err != nil {
+            return nil, err
+        }
+        if live {
+            listed = append(listed, key)
+        }
+    }
+    return listed, nil
+}
+
+func (k *KV) Exists(ctx context.Context, key string) (bool, error) {
+    defer k.locks.rlock(key)()
+
+    if err := validateKey(key); err != nil {
+        return false, err
+    }
+    if err := ctx.Err(); err != nil {
+        return false, err
+    }
+
+    k.log(ctx).Debug("🗄️🔎 checking existence", "key", key)
+    exists, err := k.store.Exists(ctx, key)
+    if err != nil || !exists {
+        return false, err
+    }
+
+    // The entry may be present but expired
+    if _, err := k.load(ctx, key); err != nil {
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            return false, nil
+        }
+        return false, err
+    }
+    return true, nil
+}
+
+// BatchPut writes items in key order. Every key is validated before anything
+// is written; a store failure part-way through leaves the earlier keys
+// written and reports the key that failed.
+func (k *KV) BatchPut(ctx context.Context, items map[string][]byte) error {
+    keys := make([]string, 0, len(items))
+    for key := range items {
+        if key == "" {
+            continue
+        }
+        if err := validateKey(key); err != nil {
+            return err
+        }
+        if err := shared.CheckValueSize(key, items[key], k.valueLimit()); err != nil {
+            return err
+        }
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    defer k.locks.lock(keys...)()
+
+    k.log(ctx).Debug("🗄️📤 putting batch", "item_count", len(keys))
+
+    for i, key := range keys {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if _, err := k.save(ctx, key, items[key], time.Time{}); err != nil {
+            return fmt.Errorf("batch put stopped at %q after %d of %d keys: %w", key, i, len(keys), err)
+        }
+        k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: items[key]})
+    }
+    return nil
+}
+
+// BatchGet returns the values of the keys that exist. Missing keys are
+// omitted from the result; any other failure aborts the whole batch.
+func (k *KV) BatchGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+    defer k.locks.rlock(keys...)()
+
+    for _, key := range keys {
+        // Empty keys are skipped, as Get ignores them
+        if key == "" {
+            continue
+        }
+        if err := validateKey(key); err != nil {
+            return nil, err
+        }
+    }
+
+    k.log(ctx).Debug("🗄️📥 getting batch", "key_count", len(keys))
+
+    values := make(map[string][]byte, len(keys))
+    for _, key := range keys {
+        if key == "" {
+            continue
+        }
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        value, err := k.load(ctx, key)
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("batch get of %q: %w", key, err)
+        }
+        values[key] = value
+    }
+    return values, nil
+}
+
+// CompareAndSwap holds key's write lock across the read and the write so no
+// other caller can change key in between. The swapped value keeps key's
+// expiry and content type.
+func (k *KV) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+    defer k.locks.lock(key)()
+
+    if err := validateKey(key); err != nil {
+        return false, err
+    }
+    if err := shared.CheckValueSize(key, new, k.valueLimit()); err != nil {
+        return false, err
+    }
+    if err := ctx.Err(); err != nil {
+        return false, err
+    }
+
+    k.log(ctx).Debug("🗄️🔁 compare-and-swap", "key", key)
+
+    current, expiresAt, version, contentType, err := k.loadRecord(ctx, key)
+    if errors.Is(err, shared.ErrKeyNotFound) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    if !bytes.Equal(current, old) {
+        return false, nil
+    }
+    if err := k.store.Put(ctx, key, encodeTypedValue(new, expiresAt, version+1, contentType)); err != nil {
+        return false, err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: new})
+    return true, nil
+}
+
+// PutIfAbsent holds key's write lock across the check and the write, and
+// creates the key with Store.Create so a writer outside this process can't
+// slip in between either. An expired value counts as absent.
+func (k *KV) PutIfAbsent(ctx context.Context, key string, value []byte) (bool, error) {
+    defer k.locks.lock(key)()
+
+    if err := validateKey(key); err != nil {
+        return false, err
+    }
+    if err := shared.CheckValueSize(key, value, k.valueLimit()); err != nil {
+        return false, err
+    }
+    if err := ctx.Err(); err != nil {
+        return false, err
+    }
+
+    k.log(ctx).Debug("🗄️📤 putting value if absent", "key", key)
+
+    // Loading drops an expired value, so it doesn't block the create
+    _, err := k.load(ctx, key)
+    if err == nil {
+        return false, nil
+    }
+    if !errors.Is(err, shared.ErrKeyNotFound) {
+        return false, err
+    }
+    err = k.store.Create(ctx, key, encodeValue(value, time.Time{}, 1))
+    if errors.Is(err, shared.ErrKeyExists) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: value})
+    return true, nil
+}
+
+// Rename holds the write locks of both keys, so no reader sees the value
+// under both or neither. newKey takes over oldKey's value, TTL and content
+// type as a new write: its version goes past both keys' versions, and its
+// modification time is the rename's, so no earlier PutIfVersion or
+// GetConditional can mistake it for a value it saw. An expired value counts
+// as absent on either side.
+func (k *KV) Rename(ctx context.Context, oldKey, newKey string, overwrite bool) error {
+    defer k.locks.lock(oldKey, newKey)()
+
+    if err := validateKey(oldKey); err != nil {
+        return err
+    }
+    if err := validateKey(newKey); err != nil {
+        return err
+    }
+    if oldKey == newKey {
+        return fmt.Errorf("%w: can't rename %q to itself", shared.ErrInvalidKey, oldKey)
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    k.log(ctx).Debug("🗄️🚚 renaming key", "old_key", oldKey, "new_key", newKey, "overwrite", overwrite)
+
+    value, expiresAt, oldVersion, contentType, err := k.loadRecord(ctx, oldKey)
+    if err != nil {
+        return err
+    }
+    // Loading drops an expired value, so it doesn't block the rename
+    _, _, newVersion, _, err := k.loadRecord(ctx, newKey)
+    switch {
+    case err == nil && !overwrite:
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, newKey)
+    case err != nil && !errors.Is(err, shared.ErrKeyNotFound):
+        return err
+    }
+    record := encodeTypedValue(value, expiresAt, max(oldVersion, newVersion)+1, contentType)
+    if err := k.store.Rename(ctx, oldKey, newKey, record, overwrite); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventDelete, Key: oldKey})
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: newKey, Value: value})
+    return nil
 }
 
 func main() {
-    logger := hclog.New(&hclog.LoggerOptions{
-        Name:       "📡 kv-go-server",
-        Level:      hclog.Trace,
-        Output:     os.Stderr,
-        JSONFormat: false,
-    })
+    // Settings in the reload file apply from the start, not just after a SIGHUP
+    reloadErr := applyReloadFile()
+
+    logger, err := shared.NewLoggerFromEnv("📡 kv-go-server", os.Stderr, shared.DefaultLogLevel)
+    if err != nil {
+        logger.Error("📝❌ Invalid logging setting", "error", err)
+        exitWithError()
+    }
+    if reloadErr != nil {
+        logger.Error("🔃❌ Invalid reload file", "error", reloadErr)
+        exitWithError()
+    }
+
+    // Validation runs by hand, outside a host, so there's no cookie to check
+    validateOnly, err := validateOnlyFromEnv()
+    if err != nil {
+        logger.Error("🧪❌ Invalid validation setting", "error", err)
+        exitWithError()
+    }
+
+    // Catch being launched directly or by a host expecting another plugin
+    if !validateOnly {
+        if err := shared.CheckMagicCookie(); err != nil {
+            logger.Error("🤝❌ refusing to start", "error", err)
+            exitWithError()
+        }
+    }
 
     // show some environment variables if `PLUGIN_SHOW_ENV` is `true`
     shared.DisplayFilteredEnv(logger, []string{
@@ -71,25 +470,39 @@ func main() {
     })
 
     // Determine if AutoMTLS is enabled
-    autoMTLS := true // Default to true
-    autoMTLSValue := os.Getenv("PLUGIN_AUTO_MTLS")
-    if autoMTLSValue != "" {
-        autoMTLS, _ = strconv.ParseBool(strings.ToLower(autoMTLSValue))
+    autoMTLS := shared.AutoMTLSFromEnv(logger)
+    allowInsecure, err := allowInsecureFromEnv()
+    if err != nil {
+        logger.Error("📡❌ invalid TLS configuration", "error", err)
+        exitWithError()
+    }
+    if err := checkServerTLSEnv(autoMTLS, allowInsecure); err != nil {
+        logger.Error("📡❌ invalid TLS configuration", "error", err)
+        exitWithError()
     }
 
-    if autoMTLS {
+    var clientCAs *x509.CertPool
+    if allowInsecure {
+        logger.Warn("📡🔓⚠️ TLS is DISABLED by PLUGIN_KV_ALLOW_INSECURE: clients are not authenticated and keys and values travel in plaintext")
+    } else if autoMTLS {
         logger.Info("📡🔐 AutoMTLS is enabled. Proceeding with TLS setup...")
 
         // Load and parse certificate from the environment variable
         certPEM := os.Getenv("PLUGIN_CLIENT_CERT")
-        if certPEM == "" {
-            logger.Error("📡❌ Certificate not found in PLUGIN_CLIENT_CERT")
+
+        // Display certificate details
+        logger.Info("🔌🔐 Client Certificate Details:")
+        clientCert, err := shared.DecodeAndLogCertificate(certPEM, logger)
+        if err != nil {
+            logger.Error("📡❌ Invalid client certificate in PLUGIN_CLIENT_CERT", "error", err)
             exitWithError()
         }
+        logger.Debug("📡🔐 client certificate decoded",
+            "subject", clientCert.Subject.CommonName,
+            "not_after", clientCert.NotAfter)
 
-        // Display certificate details if
-        logger.Info("🔌🔐 Client Certificate Details:")
-        if shared.DecodeAndLogCertificate(certPEM, logger) != nil {
+        if err := shared.CheckCertValidity(clientCert, time.Now()); err != nil {
+            logger.Error("📡❌ Client certificate in PLUGIN_CLIENT_CERT cannot be used", "error", err)
             exitWithError()
         }
 
@@ -99,37 +512,281 @@ func main() {
             logger.Error("📡❌ Failed to append certificate to trust pool")
             exitWithError()
         }
+        clientCAs = certPool
 
     } else {
-        logger.Info("📡🚫 AutoMTLS is disabled. Skipping TLS setup.")
+        logger.Info("📡🔐 AutoMTLS is disabled. Using the provided certificates.")
+        certPool, err := shared.PeerCertPool("PLUGIN_CLIENT_CERT")
+        if err != nil {
+            logger.Error("📡❌ Cannot verify client certificates", "error", err)
+            exitWithError()
+        }
+        clientCAs = certPool
+    }
+
+    // Serve a provided certificate if one is configured. Under AutoMTLS
+    // go-plugin builds the TLS config, so the host enforces the policy
+    tlsPolicy, err := shared.TLSPolicyFromEnv()
+    if err != nil {
+        logger.Error("📡❌ Invalid TLS policy", "error", err)
+        exitWithError()
+    }
+    var tlsProvider func() (*tls.Config, error)
+    if !allowInsecure {
+        tlsProvider, err = serverTLSProvider(clientCAs, tlsPolicy, logger)
+        if err != nil {
+            logger.Error("📡❌ Failed to load server certificate", "error", err)
+            exitWithError()
+        }
     }
 
     // Create shutdown channel
     shutdown := make(chan os.Signal, 1)
     signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
+    // Set up the storage backend
+    store, err := newStoreFromEnv(logger)
+    if err != nil {
+        logger.Error("🗄️❌ Failed to initialize storage backend", "error", err)
+        exitWithError()
+    }
+
+    // gzip is always registered so compressed and plain clients both work;
+    // the setting is only checked here so a typo fails loudly
+    compressor, err := shared.CompressionFromEnv()
+    if err != nil {
+        logger.Error("🗜️❌ Invalid compression setting", "error", err)
+        exitWithError()
+    }
+    if compressor != "" {
+        logger.Info("🗜️ compression enabled", "compressor", compressor)
+    }
+
+    // Ping idle clients so a crashed host doesn't leave a half-open connection
+    keepaliveInterval, err := shared.KeepaliveIntervalFromEnv()
+    if err != nil {
+        logger.Error("💓❌ Invalid keepalive setting", "error", err)
+        exitWithError()
+    }
+
+    // Refuse values large enough to fill the disk
+    maxValueBytes, err := shared.MaxValueBytesFromEnv()
+    if err != nil {
+        logger.Error("🗄️❌ Invalid value size limit", "error", err)
+        exitWithError()
+    }
+
+    // Keep scans from building unbounded responses
+    scanLimit, err := scanLimitFromEnv()
+    if err != nil {
+        logger.Error("🗄️❌ Invalid scan limit", "error", err)
+        exitWithError()
+    }
+
+    // Refuse requests beyond the limit instead of overloading the store
+    maxConcurrency, err := maxConcurrencyFromEnv()
+    if err != nil {
+        logger.Error("🚦❌ Invalid concurrency limit", "error", err)
+        exitWithError()
+    }
+    // Always installed, so a SIGHUP can turn the limit on later
+    limiter := newConcurrencyLimiter(maxConcurrency)
+    if maxConcurrency > 0 {
+        logger.Info("🚦 concurrency limit enabled", "max_concurrency", maxConcurrency)
+    }
+
+    // Serve reference data without letting clients change it
+    readOnly, err := readOnlyFromEnv()
+    if err != nil {
+        logger.Error("📖❌ Invalid read-only setting", "error", err)
+        exitWithError()
+    }
+    if readOnly {
+        logger.Info("📖 read-only mode enabled, writes will be refused")
+    }
+
+    // Record every write for compliance when a log path is configured
+    audit, err := auditLogFromEnv()
+    if err != nil {
+        logger.Error("🧾❌ Failed to open audit log", "error", err)
+        exitWithError()
+    }
+    if audit != nil {
+        logger.Info("🧾 audit log enabled", "path", os.Getenv("PLUGIN_KV_AUDIT_LOG"))
+    }
+
+    // Limit what each client certificate may do when an access list is given
+    acl, err := accessListFromEnv()
+    if err != nil {
+        logger.Error("🛂❌ Invalid access list", "error", err)
+        exitWithError()
+    }
+    if len(acl) > 0 {
+        logger.Info("🛂 access list enabled", "path", os.Getenv("PLUGIN_KV_ACL_FILE"), "clients", len(acl))
+    }
+
+    // Warn about RPCs slow enough to point at a stalled disk
+    slowThreshold, err := slowThresholdFromEnv()
+    if err != nil {
+        logger.Error("🐢❌ Invalid slow request threshold", "error", err)
+        exitWithError()
+    }
+
+    // Keep other local users off the plugin socket when a mode is given
+    socketMode, err := socketModeFromEnv()
+    if err != nil {
+        logger.Error("🔌❌ Invalid socket mode", "error", err)
+        exitWithError()
+    }
+
     // Create KV implementation
-    kv := &KV{
-        logger: logger.Named("kv"),
-        mu:     sync.RWMutex{},
+    kv := NewKV(store, logger.Named("kv"))
+    kv.maxValueBytes.Store(int64(maxValueBytes))
+    kv.scanLimit = scanLimit
+
+    // The store is ready, so report the KV service as healthy
+    kvHealth := newKVHealth()
+    kvHealth.setServing(true)
+
+    // Trace RPCs when a collector is configured
+    tracing, err := shared.TracingFromEnv()
+    if err != nil {
+        logger.Error("🔭❌ Invalid tracing setting", "error", err)
+        exitWithError()
+    }
+    stopTracing, err := tracing.Start(context.Background(), "kv-go-server")
+    if err != nil {
+        logger.Error("🔭❌ Failed to start tracing", "error", err)
+        exitWithError()
+    }
+    if tracing.Enabled() {
+        logger.Info("🔭 tracing enabled", "endpoint", tracing.Endpoint, "redact_keys", tracing.RedactKeys)
+    }
+
+    // Export request metrics when an address is configured
+    metrics := newKVMetrics()
+    var metricsServer *http.Server
+    if addr := os.Getenv("PLUGIN_KV_METRICS_ADDR"); addr != "" {
+        metricsServer, err = metrics.serve(addr, logger)
+        if err != nil {
+            logger.Error("📈❌ Failed to start metrics server", "error", err)
+            exitWithError()
+        }
+    }
+
+    // Purge expired keys in the background
+    sweepInterval, err := sweepIntervalFromEnv()
+    if err != nil {
+        logger.Error("🗄️❌ Invalid sweep interval", "error", err)
+        exitWithError()
+    }
+
+    // Everything is set up; in validation mode build the TLS config too,
+    // so a bad certificate is caught, then release it all and stop here
+    if validateOnly {
+        if tlsProvider != nil {
+            if _, err := tlsProvider(); err != nil {
+                logger.Error("📡❌ Failed to build TLS config", "error", err)
+                exitWithError()
+            }
+        }
+        if metricsServer != nil {
+            metricsServer.Close()
+        }
+        if audit != nil {
+            audit.Close()
+        }
+        flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        stopTracing(flushCtx)
+        cancel()
+        if err := closeStore(store); err != nil {
+            logger.Error("🗄️❌ Failed to close storage backend", "error", err)
+            exitWithError()
+        }
+        logger.Info("🧪✅ configuration is valid",
+            "auto_mtls", autoMTLS,
+            "insecure", allowInsecure,
+            "store", fmt.Sprintf("%T", store),
+            "compression", compressor,
+            "keepalive", keepaliveInterval,
+            "max_value_bytes", maxValueBytes,
+            "scan_limit", scanLimit,
+            "max_concurrency", maxConcurrency,
+            "read_only", readOnly,
+            "audit_log", audit != nil,
+            "acl_clients", len(acl),
+            "slow_threshold", slowThreshold,
+            "socket_mode", fmt.Sprintf("%#o", socketMode),
+            "sweep_interval", sweepInterval,
+            "tracing", tracing.Enabled(),
+            "metrics", metricsServer != nil)
+        os.Exit(0)
     }
 
+    sweepCtx, stopSweeper := context.WithCancel(context.Background())
+    go kv.RunSweeper(sweepCtx, sweepInterval)
+
+    // Stop taking writes, rather than failing each one, while the data
+    // directory can't be written
+    if dataDir := storeDataDir(store); dataDir != "" {
+        go watchWritable(sweepCtx, dataDir, writableCheckInterval, kvHealth, logger)
+    }
+
+    // Apply log level and limit changes on SIGHUP without restarting
+    sighup := make(chan os.Signal, 1)
+    signal.Notify(sighup, syscall.SIGHUP)
+    reloadCtx, stopReloads := context.WithCancel(context.Background())
+    go (&reloader{logger: logger, kv: kv, limiter: limiter}).run(reloadCtx, sighup)
+
+    // Remember the server go-plugin builds so shutdown can drain it
+    grpcServer := &serverRef{}
+
     config := &plugin.ServeConfig{
         HandshakeConfig: shared.Handshake,
-        Plugins: map[string]plugin.Plugin{
-            "kv_grpc": &shared.KVGRPCPlugin{
-                Impl: kv,
-            },
-        },
+        // KV enforces the value size limit itself, where a reload can change it
+        VersionedPlugins: shared.VersionedPlugins(&shared.KVGRPCPlugin{
+            Impl:   kv,
+            Logger: logger,
+        }),
         Logger: logger,
-        //TLSProvider: tlsConfig,
+        TLSProvider: tlsProvider,
         GRPCServer: func(opts []grpc.ServerOption) *grpc.Server {
             // Extract and log the certificate
             if autoMTLS {
                 logger.Info("🔐⛓️‍💥✅ AutoMTLS support is enabled.")
             }
 
-            return grpc.NewServer(opts...)
+            opts = append(opts, shared.KeepaliveServerOptions(keepaliveInterval)...)
+            opts = append(opts, tracing.ServerOptions()...)
+            server := newGRPCServer(opts, serverOptions{
+                health:        kvHealth,
+                metrics:       metrics,
+                limiter:       limiter,
+                readOnly:      readOnly,
+                audit:         audit,
+                acl:           acl,
+                slowThreshold: slowThreshold,
+                // Sized at startup; values a reload allows beyond this still
+                // fail in the transport
+                maxRecvMsgSize: shared.MaxRecvMsgSize(maxValueBytes),
+            }, logger)
+            grpcServer.set(server)
+
+            // go-plugin builds the server once it is listening, and doesn't
+            // accept connections until this returns
+            if socketMode != 0 {
+                paths, err := restrictSockets(socketMode)
+                if err != nil {
+                    logger.Error("🔌❌ Failed to restrict the plugin socket", "error", err)
+                    exitWithError()
+                }
+                if len(paths) == 0 {
+                    logger.Warn("🔌⚠️ PLUGIN_KV_SOCKET_MODE is set, but the plugin isn't listening on a Unix socket")
+                } else {
+                    logger.Info("🔌 restricted the plugin socket", "paths", paths, "mode", fmt.Sprintf("%#o", socketMode))
+                }
+            }
+            return server
         },
     }
 
@@ -155,6 +812,8 @@ func main() {
         case <-serverDone:
             logger.Info("🗄️🛑 plugin server exited before receiving a signal")
         }
+        kvHealth.shutdown()
+        drainGRPCServer(grpcServer.get(), drainTimeout, logger)
 
         cleanup := make(chan struct{})
         go func() {
@@ -169,12 +828,213 @@ func main() {
             logger.Warn("🗄️⏳ cleanup timeout reached")
         }
 
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        if metricsServer != nil {
+            if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+                logger.Warn("📈⏳ metrics server did not stop cleanly", "error", err)
+            }
+        }
+        if err := stopTracing(shutdownCtx); err != nil {
+            logger.Warn("🔭⏳ failed to flush traces", "error", err)
+        }
+        cancel()
+        stopSweeper()
+        stopReloads()
+        if err := closeStore(store); err != nil {
+            logger.Error("🗄️❌ failed to close storage backend", "error", err)
+        }
+        if audit != nil {
+            if err := audit.Close(); err != nil {
+                logger.Error("🧾❌ failed to close audit log", "error", err)
+            }
+        }
+
         os.Exit(0)
     }()
 
     <-serverDone
 }
 
+// serverOptions picks what newGRPCServer adds around the KV service. The
+// zero value adds request logging alone, so a new interceptor only needs a
+// field here, not a change to every caller.
+type serverOptions struct {
+    // health, metrics and limiter, when non-nil, report health, record
+    // metrics and bound concurrency for the KV service.
+    health  *kvHealth
+    metrics *kvMetrics
+    limiter *concurrencyLimiter
+
+    // readOnly refuses every KV write.
+    readOnly bool
+
+    // audit, when non-nil, records every write.
+    audit *auditLog
+
+    // acl refuses the RPCs it doesn't allow the client to make.
+    acl accessList
+
+    // slowThreshold, when positive, logs slower RPCs as warnings.
+    slowThreshold time.Duration
+
+    // maxRecvMsgSize, when positive, replaces gRPC's 4MB receive limit.
+    maxRecvMsgSize int
+}
+
+// newGRPCServer builds the server go-plugin serves on from opts, adding
+// request logging and whatever config asks for. Setting
+// PLUGIN_KV_REFLECTION=true also registers gRPC server reflection so tools
+// like grpcurl can list and call the KV service; it is off by default
+// because it advertises the full API to anyone who connects.
+func newGRPCServer(opts []grpc.ServerOption, config serverOptions, logger hclog.Logger) *grpc.Server {
+    interceptors := []grpc.UnaryServerInterceptor{shared.LoggingUnaryInterceptor(logger.Named("rpc"))}
+    if config.metrics != nil {
+        interceptors = append(interceptors, config.metrics.unaryInterceptor())
+    }
+    // Before read-only mode and the limiter, so refused writes are recorded
+    if config.audit != nil {
+        interceptors = append(interceptors, config.audit.unaryInterceptor(logger.Named("audit")))
+    }
+    // After the audit log, so denied requests are recorded
+    if len(config.acl) > 0 {
+        interceptors = append(interceptors, config.acl.unaryInterceptor())
+        opts = append(opts, grpc.ChainStreamInterceptor(config.acl.streamInterceptor()))
+    }
+    // Before the limiter, so refused writes don't take a slot
+    if config.readOnly {
+        interceptors = append(interceptors, readOnlyUnaryInterceptor())
+    }
+    // After metrics, so refused requests are still counted
+    if config.limiter != nil {
+        interceptors = append(interceptors, config.limiter.unaryInterceptor())
+    }
+    if config.health != nil {
+        interceptors = append(interceptors, config.health.unaryInterceptor())
+    }
+    // Innermost, so time spent waiting on the other interceptors isn't
+    // blamed on the store
+    if config.slowThreshold > 0 {
+        interceptors = append(interceptors, slowRequestUnaryInterceptor(config.slowThreshold, logger.Named("slow")))
+    }
+    opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+    if config.maxRecvMsgSize > 0 {
+        opts = append(opts, grpc.MaxRecvMsgSize(config.maxRecvMsgSize))
+    }
+    server := grpc.NewServer(opts...)
+
+    if enabled, _ := strconv.ParseBool(os.Getenv("PLUGIN_KV_REFLECTION")); enabled {
+        logger.Warn("🔍 gRPC server reflection is enabled")
+        reflection.Register(server)
+    }
+    return server
+}
+
+// checkServerTLSEnv requires the client certificate go-plugin passes in
+// PLUGIN_CLIENT_CERT under AutoMTLS, and a provided server certificate and
+// a way to verify the client when AutoMTLS is off, so the server never
+// falls back to plaintext or accepts unverified clients by accident.
+// allowInsecure, from PLUGIN_KV_ALLOW_INSECURE, is the deliberate way to,
+// and needs AutoMTLS off and no server certificate.
+func checkServerTLSEnv(autoMTLS, allowInsecure bool) error {
+    if allowInsecure {
+        if autoMTLS {
+            return errors.New("PLUGIN_KV_ALLOW_INSECURE disables TLS, but AutoMTLS is enabled; set PLUGIN_AUTO_MTLS=false as well")
+        }
+        if os.Getenv("PLUGIN_SERVER_CERT") != "" || os.Getenv("PLUGIN_SERVER_CERT_FILE") != "" {
+            return errors.New("PLUGIN_KV_ALLOW_INSECURE disables TLS, but a server certificate is configured; unset one or the other")
+        }
+        return nil
+    }
+    if autoMTLS {
+        if os.Getenv("PLUGIN_CLIENT_CERT") == "" {
+            return errors.New("AutoMTLS is enabled, but no client certificate was provided in PLUGIN_CLIENT_CERT")
+        }
+        if os.Getenv("PLUGIN_SERVER_CERT") != "" {
+            return errors.New("AutoMTLS is enabled, but PLUGIN_SERVER_CERT is set; it is only used with PLUGIN_AUTO_MTLS=false")
+        }
+        return nil
+    }
+    if os.Getenv("PLUGIN_SERVER_CERT") == "" && os.Getenv("PLUGIN_SERVER_CERT_FILE") == "" {
+        return errors.New("PLUGIN_AUTO_MTLS=false requires PLUGIN_SERVER_CERT or PLUGIN_SERVER_CERT_FILE; refusing to serve without TLS (set PLUGIN_KV_ALLOW_INSECURE=true to do so deliberately)")
+    }
+    if os.Getenv("PLUGIN_CLIENT_CERT") == "" && os.Getenv("PLUGIN_CA_CERT") == "" {
+        return errors.New("PLUGIN_AUTO_MTLS=false requires PLUGIN_CLIENT_CERT or PLUGIN_CA_CERT to verify the client")
+    }
+    return nil
+}
+
+// allowInsecureFromEnv reads PLUGIN_KV_ALLOW_INSECURE, which lets the
+// server run without TLS. It is false when unset.
+func allowInsecureFromEnv() (bool, error) {
+    value := os.Getenv("PLUGIN_KV_ALLOW_INSECURE")
+    if value == "" {
+        return false, nil
+    }
+    allow, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid PLUGIN_KV_ALLOW_INSECURE %q: %w", value, err)
+    }
+    return allow, nil
+}
+
+// serverTLSProvider returns a TLSProvider serving the PEM certificate in
+// PLUGIN_SERVER_CERT and PLUGIN_SERVER_KEY, or the one named by
+// PLUGIN_SERVER_CERT_FILE and PLUGIN_SERVER_KEY_FILE. When none are set it
+// returns nil so go-plugin falls back to generating a certificate via AutoMTLS.
+// Provided certificates are served under policy.
+func serverTLSProvider(clientCAs *x509.CertPool, policy shared.TLSPolicy, logger hclog.Logger) (func() (*tls.Config, error), error) {
+    certFile := os.Getenv("PLUGIN_SERVER_CERT_FILE")
+    keyFile := os.Getenv("PLUGIN_SERVER_KEY_FILE")
+    if os.Getenv("PLUGIN_SERVER_CERT") != "" {
+        if certFile != "" || keyFile != "" {
+            return nil, fmt.Errorf("set PLUGIN_SERVER_CERT or PLUGIN_SERVER_CERT_FILE, not both")
+        }
+        config, err := shared.ServerTLSConfigFromEnv(policy, logger)
+        if err != nil {
+            return nil, err
+        }
+        logger.Info("📡🔐 using server certificate from PLUGIN_SERVER_CERT",
+            "subject", config.Certificates[0].Leaf.Subject.CommonName)
+        return func() (*tls.Config, error) { return config.Clone(), nil }, nil
+    }
+    if certFile == "" && keyFile == "" {
+        logger.Debug("📡🔐 no server certificate files configured, using a generated certificate")
+        return nil, nil
+    }
+    if certFile == "" || keyFile == "" {
+        return nil, fmt.Errorf("PLUGIN_SERVER_CERT_FILE and PLUGIN_SERVER_KEY_FILE must be set together")
+    }
+
+    // Re-read the files over time so a renewed certificate is picked up
+    // without a restart
+    interval, err := certRotateIntervalFromEnv()
+    if err != nil {
+        return nil, err
+    }
+    rotator, err := newCertRotator(func() (tls.Certificate, error) {
+        return shared.LoadCertificate(certFile, keyFile, logger)
+    }, logger)
+    if err != nil {
+        return nil, err
+    }
+    go rotator.run(context.Background(), interval)
+    logger.Info("📡📂 using server certificate from disk",
+        "cert_file", certFile,
+        "rotate_interval", interval)
+
+    return func() (*tls.Config, error) {
+        config := &tls.Config{
+            GetCertificate: rotator.getCertificate,
+        }
+        policy.Apply(config)
+        if clientCAs != nil {
+            config.ClientAuth = tls.RequireAndVerifyClientCert
+            config.ClientCAs = clientCAs
+        }
+        return config, nil
+    }, nil
+}
+
 func exitWithError() {
     os.Exit(1)
 }
@@ -0,0 +1,674 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/main_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// runMainEnv makes the test binary run the server's main instead of the
+// tests, so tests can check how the server exits.
+const runMainEnv = "KV_GO_SERVER_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+    if os.Getenv(runMainEnv) != "" {
+        main()
+        return
+    }
+    os.Exit(m.Run())
+}
+
+// fakeStore is a minimal in-memory Store that records how often it is used.
+// Keys in failOn make Get, Put and Commit return the mapped error.
+type fakeStore struct {
+    data   map[string][]byte
+    calls  int
+    failOn map[string]error
+}
+
+func newFakeStore() *fakeStore {
+    return &fakeStore{data: map[string][]byte{}}
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string) ([]byte, error) {
+    s.calls++
+    if err := s.failOn[key]; err != nil {
+        return nil, err
+    }
+    v, ok := s.data[key]
+    if !ok {
+        return nil, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return v, nil
+}
+
+func (s *fakeStore) Put(ctx context.Context, key string, value []byte) error {
+    s.calls++
+    if err := s.failOn[key]; err != nil {
+        return err
+    }
+    s.data[key] = value
+    return nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, key string, value []byte) error {
+    s.calls++
+    if err := s.failOn[key]; err != nil {
+        return err
+    }
+    if _, ok := s.data[key]; ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, key)
+    }
+    s.data[key] = value
+    return nil
+}
+
+func (s *fakeStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    s.calls++
+    if _, ok := s.data[oldKey]; !ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, oldKey)
+    }
+    if _, ok := s.data[newKey]; ok && !overwrite {
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, newKey)
+    }
+    s.data[newKey] = value
+    delete(s.data, oldKey)
+    return nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, key string) error {
+    s.calls++
+    if _, ok := s.data[key]; !ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    delete(s.data, key)
+    return nil
+}
+
+func (s *fakeStore) List(ctx context.Context, prefix string) ([]string, error) {
+    s.calls++
+    keys := []string{}
+    for key := range s.data {
+        if strings.HasPrefix(key, prefix) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+    return keys, nil
+}
+
+func (s *fakeStore) Exists(ctx context.Context, key string) (bool, error) {
+    s.calls++
+    _, ok := s.data[key]
+    return ok, nil
+}
+
+// ModTime reports every key's write time as unknown.
+func (s *fakeStore) ModTime(ctx context.Context, key string) (time.Time, error) {
+    s.calls++
+    if _, ok := s.data[key]; !ok {
+        return time.Time{}, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return time.Time{}, nil
+}
+
+func (s *fakeStore) Commit(ctx context.Context, writes []storeWrite) error {
+    s.calls++
+    for _, w := range writes {
+        if err := s.failOn[w.key]; err != nil {
+            return err
+        }
+    }
+    for _, w := range writes {
+        if w.delete {
+            delete(s.data, w.key)
+        } else {
+            s.data[w.key] = w.value
+        }
+    }
+    return nil
+}
+
+func TestValidateKey(t *testing.T) {
+    tests := []struct {
+        name  string
+        key   string
+        valid bool
+    }{
+        {"simple", "hello", true},
+        {"dotted", "app.config.v1", true},
+        {"dashes and underscores", "user-42_name", true},
+        {"max length", strings.Repeat("k", maxKeyLength), true},
+        {"parent traversal", "../../etc/passwd", false},
+        {"bare parent", "..", false},
+        {"current dir", ".", false},
+        {"embedded parent", "a..b", false},
+        {"slash", "nested/key", false},
+        {"absolute", "/etc/passwd", false},
+        {"backslash", `..\windows`, false},
+        {"nul byte", "key\x00.txt", false},
+        {"empty", "", false},
+        {"too long", strings.Repeat("k", maxKeyLength+1), false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := validateKey(tt.key)
+            if tt.valid && err != nil {
+                t.Fatalf("validateKey(%q) returned unexpected error: %v", tt.key, err)
+            }
+            if !tt.valid && !errors.Is(err, shared.ErrInvalidKey) {
+                t.Fatalf("validateKey(%q) = %v, want ErrInvalidKey", tt.key, err)
+            }
+        })
+    }
+}
+
+func TestKVRejectsTraversal(t *testing.T) {
+    ctx := context.Background()
+    store := newFakeStore()
+    kv := NewKV(store, nil)
+    key := "../kv-traversal-test"
+
+    if err := kv.Put(ctx, key, []byte("pwned")); !errors.Is(err, shared.ErrInvalidKey) {
+        t.Fatalf("Put(%q) = %v, want ErrInvalidKey", key, err)
+    }
+    if _, err := kv.Get(ctx, key); !errors.Is(err, shared.ErrInvalidKey) {
+        t.Fatalf("Get(%q) = %v, want ErrInvalidKey", key, err)
+    }
+    if err := kv.Delete(ctx, key); !errors.Is(err, shared.ErrInvalidKey) {
+        t.Fatalf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+    }
+    if store.calls != 0 {
+        t.Fatalf("store was called %d times for an invalid key", store.calls)
+    }
+}
+
+func TestKVRejectsEmptyKey(t *testing.T) {
+    ctx := context.Background()
+    store := newFakeStore()
+    kv := NewKV(store, nil)
+
+    calls := map[string]func() error{
+        "CompareAndSwap": func() error {
+            _, err := kv.CompareAndSwap(ctx, "", nil, []byte("v"))
+            return err
+        },
+        "PutIfAbsent": func() error {
+            _, err := kv.PutIfAbsent(ctx, "", []byte("v"))
+            return err
+        },
+        "Exists": func() error {
+            _, err := kv.Exists(ctx, "")
+            return err
+        },
+        "GetVersioned": func() error {
+            _, _, err := kv.GetVersioned(ctx, "")
+            return err
+        },
+        "PutIfVersion": func() error {
+            return kv.PutIfVersion(ctx, "", []byte("v"), 0)
+        },
+        "Increment": func() error {
+            _, err := kv.Increment(ctx, "", 1)
+            return err
+        },
+        "GetConditional": func() error {
+            _, _, _, err := kv.GetConditional(ctx, "", time.Time{})
+            return err
+        },
+        "Rename from": func() error {
+            return kv.Rename(ctx, "", "k", false)
+        },
+        "Rename to": func() error {
+            return kv.Rename(ctx, "k", "", true)
+        },
+        "Transaction": func() error {
+            return kv.Transaction(ctx, []shared.TxOp{{Kind: shared.TxPut, Key: "", Value: []byte("v")}})
+        },
+    }
+    for name, call := range calls {
+        if err := call(); !errors.Is(err, shared.ErrInvalidKey) {
+            t.Errorf("%s with an empty key = %v, want ErrInvalidKey", name, err)
+        }
+    }
+
+    // These ignore the empty key rather than failing
+    if err := kv.Put(ctx, "", []byte("v")); err != nil {
+        t.Errorf("Put with an empty key = %v, want nil", err)
+    }
+    if value, err := kv.Get(ctx, ""); err != nil || value != nil {
+        t.Errorf("Get with an empty key = %q, %v, want nil", value, err)
+    }
+    if err := kv.Delete(ctx, ""); err != nil {
+        t.Errorf("Delete with an empty key = %v, want nil", err)
+    }
+    if values, err := kv.BatchGet(ctx, []string{""}); err != nil || len(values) != 0 {
+        t.Errorf("BatchGet of an empty key = %v, %v, want no values", values, err)
+    }
+    if store.calls != 0 {
+        t.Fatalf("store was called %d times for an empty key", store.calls)
+    }
+}
+
+func TestKVMaxValueBytes(t *testing.T) {
+    ctx := context.Background()
+    const limit = 8
+
+    tests := []struct {
+        name    string
+        size    int
+        wantErr bool
+    }{
+        {"just under", limit - 1, false},
+        {"at the limit", limit, false},
+        {"just over", limit + 1, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            store := newFakeStore()
+            kv := NewKV(store, nil)
+            kv.maxValueBytes.Store(limit)
+            value := bytes.Repeat([]byte("x"), tt.size)
+
+            puts := map[string]func() error{
+                "Put":        func() error { return kv.Put(ctx, "k", value) },
+                "PutWithTTL": func() error { return kv.PutWithTTL(ctx, "k", value, time.Hour) },
+                "BatchPut":   func() error { return kv.BatchPut(ctx, map[string][]byte{"k": value}) },
+            }
+            for name, put := range puts {
+                store.calls = 0
+                err := put()
+                if !tt.wantErr {
+                    if err != nil {
+                        t.Fatalf("%s of %d bytes = %v, want nil", name, tt.size, err)
+                    }
+                    continue
+                }
+                if !errors.Is(err, shared.ErrValueTooLarge) {
+                    t.Fatalf("%s of %d bytes = %v, want ErrValueTooLarge", name, tt.size, err)
+                }
+                if want := fmt.Sprintf("is %d bytes, limit is %d", tt.size, limit); !strings.Contains(err.Error(), want) {
+                    t.Fatalf("%s error %q does not mention %q", name, err, want)
+                }
+                if store.calls != 0 {
+                    t.Fatalf("store was called %d times for an oversized %s", store.calls, name)
+                }
+            }
+        })
+    }
+}
+
+func TestKVAgainstFakeStore(t *testing.T) {
+    ctx := context.Background()
+    store := newFakeStore()
+    kv := NewKV(store, nil)
+
+    if err := kv.Put(ctx, "app.name", []byte("kv")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.Put(ctx, "app.version", []byte("1")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if string(store.data["app.name"]) != "kv" {
+        t.Fatalf("Put did not reach the store: %q", store.data["app.name"])
+    }
+
+    got, err := kv.Get(ctx, "app.name")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if string(got) != "kv" {
+        t.Fatalf("Get = %q, want %q", got, "kv")
+    }
+
+    keys, err := kv.List(ctx, "app.")
+    if err != nil {
+        t.Fatalf("List failed: %v", err)
+    }
+    if strings.Join(keys, ",") != "app.name,app.version" {
+        t.Fatalf("List = %v, want [app.name app.version]", keys)
+    }
+
+    if err := kv.Delete(ctx, "app.name"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if err := kv.Delete(ctx, "app.name"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("second Delete = %v, want ErrKeyNotFound", err)
+    }
+}
+
+func TestKVBatchPutPartialFailure(t *testing.T) {
+    ctx := context.Background()
+    errDisk := errors.New("disk full")
+    store := newFakeStore()
+    store.failOn = map[string]error{"b": errDisk}
+    kv := NewKV(store, nil)
+
+    err := kv.BatchPut(ctx, map[string][]byte{
+        "a": []byte("1"),
+        "b": []byte("2"),
+        "c": []byte("3"),
+    })
+    if !errors.Is(err, errDisk) {
+        t.Fatalf("BatchPut = %v, want %v", err, errDisk)
+    }
+    if !strings.Contains(err.Error(), `"b"`) {
+        t.Fatalf("BatchPut error %q does not name the failing key", err)
+    }
+    if string(store.data["a"]) != "1" {
+        t.Fatalf("key before the failure was not written: %q", store.data["a"])
+    }
+    if _, ok := store.data["c"]; ok {
+        t.Fatalf("key after the failure was written")
+    }
+}
+
+func TestKVBatchPutRejectsInvalidKeyUpFront(t *testing.T) {
+    store := newFakeStore()
+    kv := NewKV(store, nil)
+
+    err := kv.BatchPut(context.Background(), map[string][]byte{
+        "good":    []byte("1"),
+        "../evil": []byte("2"),
+    })
+    if !errors.Is(err, shared.ErrInvalidKey) {
+        t.Fatalf("BatchPut = %v, want ErrInvalidKey", err)
+    }
+    if store.calls != 0 {
+        t.Fatalf("store was called %d times for a batch with an invalid key", store.calls)
+    }
+}
+
+func TestKVBatchGet(t *testing.T) {
+    ctx := context.Background()
+    errDisk := errors.New("read error")
+    store := newFakeStore()
+    store.data["a"] = []byte("1")
+    store.data["empty"] = []byte{}
+    kv := NewKV(store, nil)
+
+    values, err := kv.BatchGet(ctx, []string{"a", "missing", "empty"})
+    if err != nil {
+        t.Fatalf("BatchGet failed: %v", err)
+    }
+    if len(values) != 2 || string(values["a"]) != "1" {
+        t.Fatalf("BatchGet = %q, want a and empty", values)
+    }
+    if _, ok := values["empty"]; !ok {
+        t.Fatalf("BatchGet dropped a key holding an empty value")
+    }
+    if _, ok := values["missing"]; ok {
+        t.Fatalf("BatchGet reported a value for a missing key")
+    }
+
+    store.failOn = map[string]error{"a": errDisk}
+    if _, err := kv.BatchGet(ctx, []string{"missing", "a"}); !errors.Is(err, errDisk) {
+        t.Fatalf("BatchGet with a failing key = %v, want %v", err, errDisk)
+    }
+}
+
+func TestKVCompareAndSwap(t *testing.T) {
+    ctx := context.Background()
+    store := newFakeStore()
+    kv := NewKV(store, nil)
+
+    if swapped, err := kv.CompareAndSwap(ctx, "k", nil, []byte("v1")); err != nil || swapped {
+        t.Fatalf("CompareAndSwap on a missing key = %v, %v; want false, nil", swapped, err)
+    }
+    store.data["k"] = []byte("v1")
+    if swapped, err := kv.CompareAndSwap(ctx, "k", []byte("stale"), []byte("v2")); err != nil || swapped {
+        t.Fatalf("CompareAndSwap with a stale value = %v, %v; want false, nil", swapped, err)
+    }
+    if swapped, err := kv.CompareAndSwap(ctx, "k", []byte("v1"), []byte("v2")); err != nil || !swapped {
+        t.Fatalf("CompareAndSwap with the current value = %v, %v; want true, nil", swapped, err)
+    }
+    // The swap is the key's second version, so it is stored with a header
+    if value, err := kv.Get(ctx, "k"); err != nil || string(value) != "v2" {
+        t.Fatalf("value after swap = %q, %v; want %q", value, err, "v2")
+    }
+}
+
+func TestKVCompareAndSwapRace(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newMemStore(), nil)
+
+    for i := 0; i < 100; i++ {
+        if err := kv.Put(ctx, "race", []byte("start")); err != nil {
+            t.Fatalf("Put failed: %v", err)
+        }
+
+        var wg sync.WaitGroup
+        start := make(chan struct{})
+        results := make([]bool, 2)
+        for g := range results {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                <-start
+                swapped, err := kv.CompareAndSwap(ctx, "race", []byte("start"), []byte(fmt.Sprint("writer-", g)))
+                if err != nil {
+                    t.Errorf("CompareAndSwap failed: %v", err)
+                }
+                results[g] = swapped
+            }()
+        }
+        close(start)
+        wg.Wait()
+
+        if results[0] == results[1] {
+            t.Fatalf("iteration %d: swaps = %v, want exactly one to succeed", i, results)
+        }
+    }
+}
+
+func TestKVPutIfAbsent(t *testing.T) {
+    ctx := context.Background()
+    now := time.Unix(1700000000, 0)
+    kv := NewKV(newMemStore(), nil)
+    kv.now = func() time.Time { return now }
+    client := serveKV(t, kv)
+
+    if created, err := client.PutIfAbsent(ctx, "k", []byte("original")); err != nil || !created {
+        t.Fatalf("first PutIfAbsent = %v, %v; want true, nil", created, err)
+    }
+    if created, err := client.PutIfAbsent(ctx, "k", []byte("clobber")); err != nil || created {
+        t.Fatalf("second PutIfAbsent = %v, %v; want false, nil", created, err)
+    }
+    if value, version, err := client.GetVersioned(ctx, "k"); err != nil || string(value) != "original" || version != 1 {
+        t.Fatalf("after a refused create GetVersioned() = %q, %d, %v; want original at version 1", value, version, err)
+    }
+
+    // An expired value no longer blocks the create
+    if err := kv.PutWithTTL(ctx, "ttl", []byte("old"), time.Minute); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    now = now.Add(2 * time.Minute)
+    if created, err := client.PutIfAbsent(ctx, "ttl", []byte("new")); err != nil || !created {
+        t.Fatalf("PutIfAbsent over an expired value = %v, %v; want true, nil", created, err)
+    }
+}
+
+func TestKVRename(t *testing.T) {
+    ctx := context.Background()
+    client := serveKV(t, NewKV(newFileStore(t.TempDir()), nil))
+
+    if err := client.PutWithContentType(ctx, "old", []byte("value"), "text/plain", 0); err != nil {
+        t.Fatalf("PutWithContentType failed: %v", err)
+    }
+    if err := client.Put(ctx, "taken", []byte("other")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    if err := client.Rename(ctx, "missing", "new", false); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Rename of a missing key = %v, want ErrKeyNotFound", err)
+    }
+    if err := client.Rename(ctx, "old", "taken", false); !errors.Is(err, shared.ErrKeyExists) {
+        t.Fatalf("Rename onto an existing key = %v, want ErrKeyExists", err)
+    }
+    if value, err := client.Get(ctx, "taken"); err != nil || string(value) != "other" {
+        t.Fatalf("after a refused rename Get(taken) = %q, %v; want it unchanged", value, err)
+    }
+    if err := client.Rename(ctx, "old", "old", true); !errors.Is(err, shared.ErrInvalidKey) {
+        t.Fatalf("Rename of a key to itself = %v, want ErrInvalidKey", err)
+    }
+
+    if err := client.Rename(ctx, "old", "new", false); err != nil {
+        t.Fatalf("Rename failed: %v", err)
+    }
+    if _, err := client.Get(ctx, "old"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get of the renamed key = %v, want ErrKeyNotFound", err)
+    }
+    value, contentType, err := client.GetWithContentType(ctx, "new")
+    if err != nil || string(value) != "value" || contentType != "text/plain" {
+        t.Fatalf("GetWithContentType(new) = %q, %q, %v; want the value with its content type", value, contentType, err)
+    }
+
+    if err := client.Rename(ctx, "new", "taken", true); err != nil {
+        t.Fatalf("Rename with overwrite failed: %v", err)
+    }
+    if value, err := client.Get(ctx, "taken"); err != nil || string(value) != "value" {
+        t.Fatalf("after an overwriting rename Get(taken) = %q, %v; want value", value, err)
+    }
+}
+
+func TestKVRenameIsANewWrite(t *testing.T) {
+    for name, open := range storeBackends {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            kv := NewKV(open(t), nil)
+            // Both keys at version 3, with oldKey written first
+            for _, key := range []string{"old", "taken"} {
+                for i := 0; i < 3; i++ {
+                    if err := kv.Put(ctx, key, []byte(fmt.Sprint(key, "-", i))); err != nil {
+                        t.Fatalf("Put(%q) failed: %v", key, err)
+                    }
+                }
+            }
+            _, _, seen, err := kv.GetConditional(ctx, "taken", time.Time{})
+            if err != nil {
+                t.Fatalf("GetConditional failed: %v", err)
+            }
+            // Past the granularity of file modification times
+            time.Sleep(20 * time.Millisecond)
+
+            if err := kv.Rename(ctx, "old", "taken", true); err != nil {
+                t.Fatalf("Rename failed: %v", err)
+            }
+            if _, version, err := kv.GetVersioned(ctx, "taken"); err != nil || version != 4 {
+                t.Fatalf("GetVersioned(taken) = %d, %v; want version 4", version, err)
+            }
+            if err := kv.PutIfVersion(ctx, "taken", []byte("stale"), 3); !errors.Is(err, shared.ErrVersionConflict) {
+                t.Fatalf("PutIfVersion at the version before the rename = %v, want ErrVersionConflict", err)
+            }
+            value, modified, _, err := kv.GetConditional(ctx, "taken", seen)
+            if err != nil || !modified || string(value) != "old-2" {
+                t.Fatalf("GetConditional(taken) after the rename = %q, %t, %v; want old-2 modified", value, modified, err)
+            }
+        })
+    }
+}
+
+func TestKVPutIfAbsentRace(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newFileStore(t.TempDir()), nil)
+
+    var wg sync.WaitGroup
+    start := make(chan struct{})
+    results := make([]bool, 8)
+    for g := range results {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            created, err := kv.PutIfAbsent(ctx, "race", []byte(fmt.Sprint("writer-", g)))
+            if err != nil {
+                t.Errorf("PutIfAbsent failed: %v", err)
+            }
+            results[g] = created
+        }()
+    }
+    close(start)
+    wg.Wait()
+
+    winners := 0
+    for g, created := range results {
+        if created {
+            winners++
+            if value, err := kv.Get(ctx, "race"); err != nil || string(value) != fmt.Sprint("writer-", g) {
+                t.Fatalf("Get() = %q, %v, want the winner's value", value, err)
+            }
+        }
+    }
+    if winners != 1 {
+        t.Fatalf("%d PutIfAbsent calls created the key, want exactly one", winners)
+    }
+}
+
+func TestCheckServerTLSEnv(t *testing.T) {
+    for _, tt := range []struct {
+        autoMTLS       bool
+        clientCert     string
+        serverCert     string
+        serverCertFile string
+        caCert         string
+        wantErr        bool
+    }{
+        {true, "client-pem", "", "", "", false},
+        {true, "", "", "", "", true},
+        {true, "client-pem", "", "server.pem", "", false},
+        {true, "client-pem", "server-pem", "", "", true},
+        {false, "", "", "", "", true},
+        {false, "client-pem", "", "", "", true},
+        {false, "", "server-pem", "", "", true},
+        {false, "client-pem", "server-pem", "", "", false},
+        {false, "client-pem", "", "server.pem", "", false},
+        {false, "", "server-pem", "", "ca-pem", false},
+    } {
+        t.Setenv("PLUGIN_CLIENT_CERT", tt.clientCert)
+        t.Setenv("PLUGIN_SERVER_CERT", tt.serverCert)
+        t.Setenv("PLUGIN_SERVER_CERT_FILE", tt.serverCertFile)
+        t.Setenv("PLUGIN_CA_CERT", tt.caCert)
+        err := checkServerTLSEnv(tt.autoMTLS, false)
+        if (err != nil) != tt.wantErr {
+            t.Fatalf("checkServerTLSEnv(%t) with client cert %q, server cert %q, server cert file %q and CA %q = %v, want error %t",
+                tt.autoMTLS, tt.clientCert, tt.serverCert, tt.serverCertFile, tt.caCert, err, tt.wantErr)
+        }
+    }
+}
+
+func TestAllowInsecureOptIn(t *testing.T) {
+    plaintext := []string{"PLUGIN_AUTO_MTLS=false", "PLUGIN_KV_DATA_DIR=" + t.TempDir()}
+
+    code, log := runValidation(t, plaintext...)
+    if code == 0 || !strings.Contains(log, "PLUGIN_KV_ALLOW_INSECURE=true") {
+        t.Fatalf("serving without TLS or the opt-in exited %d, want a failure naming the opt-in:\n%s", code, log)
+    }
+
+    code, log = runValidation(t, append(plaintext, "PLUGIN_KV_ALLOW_INSECURE=true")...)
+    if code != 0 || !strings.Contains(log, "TLS is DISABLED") {
+        t.Fatalf("serving without TLS after opting in exited %d, want success and a warning:\n%s", code, log)
+    }
+
+    for _, env := range [][]string{
+        {"PLUGIN_KV_ALLOW_INSECURE=true", "PLUGIN_CLIENT_CERT=client-pem"},
+        {"PLUGIN_KV_ALLOW_INSECURE=true", "PLUGIN_AUTO_MTLS=false", "PLUGIN_SERVER_CERT=server-pem"},
+        {"PLUGIN_KV_ALLOW_INSECURE=sometimes", "PLUGIN_AUTO_MTLS=false"},
+    } {
+        if code, log := runValidation(t, env...); code == 0 {
+            t.Fatalf("%v exited 0, want a failure:\n%s", env, log)
+        }
+    }
+}
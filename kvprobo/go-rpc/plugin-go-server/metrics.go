@@ -0,0 +1,94 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/metrics.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "net"
+    "net/http"
+    "path"
+    "strings"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// kvMetrics counts and times KV requests for Prometheus. It uses its own
+// registry so only KV series are exported.
+type kvMetrics struct {
+    registry *prometheus.Registry
+    requests *prometheus.CounterVec
+    duration *prometheus.HistogramVec
+}
+
+func newKVMetrics() *kvMetrics {
+    m := &kvMetrics{
+        registry: prometheus.NewRegistry(),
+        requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "kv_requests_total",
+            Help: "KV requests handled, by method and gRPC status code.",
+        }, []string{"method", "code"}),
+        duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name:    "kv_request_duration_seconds",
+            Help:    "Time taken to handle KV requests, by method.",
+            Buckets: prometheus.DefBuckets,
+        }, []string{"method"}),
+    }
+    m.registry.MustRegister(m.requests, m.duration)
+    return m
+}
+
+// unaryInterceptor records every unary KV call. Other services on the
+// server, such as go-plugin's own, are not counted.
+func (m *kvMetrics) unaryInterceptor() grpc.UnaryServerInterceptor {
+    prefix := "/" + proto.KV_ServiceDesc.ServiceName + "/"
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        if !strings.HasPrefix(info.FullMethod, prefix) {
+            return handler(ctx, req)
+        }
+        start := time.Now()
+        resp, err := handler(ctx, req)
+
+        method := path.Base(info.FullMethod)
+        m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+        m.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+        return resp, err
+    }
+}
+
+// handler serves the metrics in the Prometheus text format.
+func (m *kvMetrics) handler() http.Handler {
+    return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// serve starts an HTTP server exposing /metrics on addr. The listener
+// is opened before returning so a bad address fails at startup.
+func (m *kvMetrics) serve(addr string, logger hclog.Logger) (*http.Server, error) {
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", m.handler())
+    server := &http.Server{
+        Addr:              listener.Addr().String(),
+        Handler:           mux,
+        ReadHeaderTimeout: 10 * time.Second,
+    }
+
+    logger.Info("📈 serving metrics", "addr", server.Addr)
+    go func() {
+        if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            logger.Error("📈❌ metrics server failed", "error", err)
+        }
+    }()
+    return server, nil
+}
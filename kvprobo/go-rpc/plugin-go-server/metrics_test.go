@@ -0,0 +1,56 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/metrics_test.go
+
+package main
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+func TestMetricsScrape(t *testing.T) {
+    ctx := context.Background()
+    metrics := newKVMetrics()
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{metrics: metrics}, hclog.NewNullLogger()), NewKV(newMemStore(), nil))
+
+    server, err := metrics.serve("127.0.0.1:0", hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("starting metrics server failed: %v", err)
+    }
+    t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+    for i := 0; i < 2; i++ {
+        if err := client.Put(ctx, "k", []byte("v")); err != nil {
+            t.Fatalf("Put failed: %v", err)
+        }
+    }
+    // A Get miss is a successful RPC, so a Delete stands in for a failure
+    if err := client.Delete(ctx, "missing"); err == nil {
+        t.Fatalf("Delete(missing) succeeded, want NotFound")
+    }
+
+    resp, err := http.Get("http://" + server.Addr + "/metrics")
+    if err != nil {
+        t.Fatalf("scraping metrics failed: %v", err)
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        t.Fatalf("reading metrics failed: %v", err)
+    }
+
+    for _, want := range []string{
+        `kv_requests_total{code="OK",method="Put"} 2`,
+        `kv_requests_total{code="NotFound",method="Delete"} 1`,
+        `kv_request_duration_seconds_count{method="Put"} 2`,
+        `kv_request_duration_seconds_count{method="Delete"} 1`,
+    } {
+        if !strings.Contains(string(body), want) {
+            t.Errorf("metrics missing %q:\n%s", want, body)
+        }
+    }
+}
@@ -0,0 +1,184 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/namespace.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// namespaceSeparator joins a namespace to the keys stored in it. Valid keys
+// can never contain it, so a namespaced key can't collide with a key in the
+// default namespace, which is stored without a prefix so that data written
+// before namespaces existed stays where it was.
+const namespaceSeparator = ".."
+
+// maxNamespaceLength leaves most of maxKeyLength to the key itself.
+const maxNamespaceLength = 64
+
+// validateNamespace applies the key rules to a namespace name.
+func validateNamespace(namespace string) error {
+    if len(namespace) > maxNamespaceLength {
+        return fmt.Errorf("%w: namespace exceeds %d bytes", shared.ErrInvalidNamespace, maxNamespaceLength)
+    }
+    if err := validateKey(namespace); err != nil {
+        return fmt.Errorf("%w: %q is not a valid name", shared.ErrInvalidNamespace, namespace)
+    }
+    return nil
+}
+
+type allNamespacesKey struct{}
+
+// withAllNamespaces returns a context under which a namespacedStore passes
+// keys through untouched, for maintenance such as the expiry sweep that has
+// to see every namespace at once.
+func withAllNamespaces(ctx context.Context) context.Context {
+    return context.WithValue(ctx, allNamespacesKey{}, true)
+}
+
+func allNamespaces(ctx context.Context) bool {
+    all, _ := ctx.Value(allNamespacesKey{}).(bool)
+    return all
+}
+
+// keyPrefix returns what keys in ctx's namespace are stored under.
+func keyPrefix(ctx context.Context) (string, error) {
+    if allNamespaces(ctx) {
+        return "", nil
+    }
+    namespace := shared.NamespaceFromContext(ctx)
+    if namespace == shared.DefaultNamespace {
+        return "", nil
+    }
+    if err := validateNamespace(namespace); err != nil {
+        return "", err
+    }
+    return namespace + namespaceSeparator, nil
+}
+
+// splitNamespace returns the namespace and key a stored key belongs to.
+func splitNamespace(stored string) (string, string) {
+    if namespace, key, ok := strings.Cut(stored, namespaceSeparator); ok {
+        return namespace, key
+    }
+    return shared.DefaultNamespace, stored
+}
+
+// namespacedStore isolates tenants sharing a Store by prefixing every key
+// with the namespace named in the request's kv-namespace header.
+type namespacedStore struct {
+    Store
+}
+
+// key returns the stored form of key in ctx's namespace.
+func (s namespacedStore) key(ctx context.Context, key string) (string, error) {
+    prefix, err := keyPrefix(ctx)
+    if err != nil {
+        return "", err
+    }
+    if len(prefix)+len(key) > maxKeyLength {
+        return "", fmt.Errorf("%w: key exceeds %d bytes in namespace %q",
+            shared.ErrInvalidKey, maxKeyLength-len(prefix), strings.TrimSuffix(prefix, namespaceSeparator))
+    }
+    return prefix + key, nil
+}
+
+func (s namespacedStore) Get(ctx context.Context, key string) ([]byte, error) {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+    return s.Store.Get(ctx, stored)
+}
+
+func (s namespacedStore) Put(ctx context.Context, key string, value []byte) error {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return err
+    }
+    return s.Store.Put(ctx, stored, value)
+}
+
+func (s namespacedStore) Create(ctx context.Context, key string, value []byte) error {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return err
+    }
+    return s.Store.Create(ctx, stored, value)
+}
+
+func (s namespacedStore) Delete(ctx context.Context, key string) error {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return err
+    }
+    return s.Store.Delete(ctx, stored)
+}
+
+func (s namespacedStore) Exists(ctx context.Context, key string) (bool, error) {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return false, err
+    }
+    return s.Store.Exists(ctx, stored)
+}
+
+func (s namespacedStore) ModTime(ctx context.Context, key string) (time.Time, error) {
+    stored, err := s.key(ctx, key)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return s.Store.ModTime(ctx, stored)
+}
+
+// List returns the keys in ctx's namespace without their prefix. The
+// default namespace leaves out every namespaced key.
+func (s namespacedStore) List(ctx context.Context, prefix string) ([]string, error) {
+    nsPrefix, err := keyPrefix(ctx)
+    if err != nil {
+        return nil, err
+    }
+    stored, err := s.Store.List(ctx, nsPrefix+prefix)
+    if err != nil || allNamespaces(ctx) {
+        return stored, err
+    }
+
+    keys := make([]string, 0, len(stored))
+    for _, key := range stored {
+        key = strings.TrimPrefix(key, nsPrefix)
+        if strings.Contains(key, namespaceSeparator) {
+            continue
+        }
+        keys = append(keys, key)
+    }
+    return keys, nil
+}
+
+func (s namespacedStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    storedOld, err := s.key(ctx, oldKey)
+    if err != nil {
+        return err
+    }
+    storedNew, err := s.key(ctx, newKey)
+    if err != nil {
+        return err
+    }
+    return s.Store.Rename(ctx, storedOld, storedNew, value, overwrite)
+}
+
+func (s namespacedStore) Commit(ctx context.Context, writes []storeWrite) error {
+    stored := make([]storeWrite, len(writes))
+    for i, w := range writes {
+        key, err := s.key(ctx, w.key)
+        if err != nil {
+            return err
+        }
+        w.key = key
+        stored[i] = w
+    }
+    return s.Store.Commit(ctx, stored)
+}
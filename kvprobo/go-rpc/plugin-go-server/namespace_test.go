@@ -0,0 +1,66 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/namespace_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestNamespacesIsolateKeys(t *testing.T) {
+    client := serveKV(t, NewKV(newFileStore(t.TempDir()), nil))
+    ctx := context.Background()
+    tenantA := shared.WithNamespace(ctx, "tenant-a")
+    tenantB := shared.WithNamespace(ctx, "tenant-b")
+
+    for ctx, value := range map[context.Context]string{ctx: "default", tenantA: "a", tenantB: "b"} {
+        if err := client.Put(ctx, "k", []byte(value)); err != nil {
+            t.Fatalf("Put %q failed: %v", value, err)
+        }
+    }
+    for ctx, want := range map[context.Context]string{ctx: "default", tenantA: "a", tenantB: "b"} {
+        if got, err := client.Get(ctx, "k"); err != nil || string(got) != want {
+            t.Fatalf("Get = %q, %v; want %q", got, err, want)
+        }
+    }
+
+    if err := client.Delete(tenantA, "k"); err != nil {
+        t.Fatalf("Delete in tenant-a failed: %v", err)
+    }
+    if _, err := client.Get(tenantA, "k"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get after Delete in tenant-a = %v, want ErrKeyNotFound", err)
+    }
+    if got, err := client.Get(tenantB, "k"); err != nil || string(got) != "b" {
+        t.Fatalf("Get in tenant-b after deleting from tenant-a = %q, %v; want b", got, err)
+    }
+
+    // Neither namespace sees the other's keys
+    for ctx, want := range map[context.Context][]string{ctx: {"k"}, tenantA: nil, tenantB: {"k"}} {
+        if keys, err := client.List(ctx, ""); err != nil || !reflect.DeepEqual(keys, want) {
+            t.Fatalf("List = %v, %v; want %v", keys, err, want)
+        }
+    }
+
+    if err := client.Put(shared.WithNamespace(ctx, "../etc"), "k", nil); !errors.Is(err, shared.ErrInvalidNamespace) {
+        t.Fatalf("Put in namespace ../etc = %v, want ErrInvalidNamespace", err)
+    }
+}
+
+func TestSweepExpiredCoversEveryNamespace(t *testing.T) {
+    kv, clock := newTTLTestKV(newMemStore())
+    client := serveKV(t, kv)
+    tenant := shared.WithNamespace(context.Background(), "tenant")
+
+    if err := client.PutWithTTL(tenant, "k", []byte("v"), time.Minute); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    clock.advance(2 * time.Minute)
+    if removed, err := kv.SweepExpired(context.Background()); err != nil || removed != 1 {
+        t.Fatalf("SweepExpired = %d, %v; want 1", removed, err)
+    }
+}
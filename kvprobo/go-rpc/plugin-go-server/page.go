@@ -0,0 +1,95 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/page.go
+
+package main
+
+import (
+    "context"
+    "encoding/base64"
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+const (
+    // defaultPageSize is how many keys ListPage returns when the caller
+    // doesn't say.
+    defaultPageSize = 100
+    // maxPageSize caps the keys in one ListPage response.
+    maxPageSize = 1000
+)
+
+// ListPage sorts the keys under prefix and returns the pageSize after the
+// key pageToken names. Because the token holds a key rather than a
+// position, keys written or deleted between pages don't make a caller
+// skip or repeat the ones it hasn't seen yet. Expired keys are left out, as
+// List leaves them out.
+func (k *KV) ListPage(ctx context.Context, prefix, pageToken string, pageSize int) ([]string, string, error) {
+    defer k.locks.rlockAll()()
+
+    if err := ctx.Err(); err != nil {
+        return nil, "", err
+    }
+    after, err := decodePageToken(prefix, pageToken)
+    if err != nil {
+        return nil, "", err
+    }
+    if pageSize <= 0 {
+        pageSize = defaultPageSize
+    }
+    pageSize = min(pageSize, maxPageSize)
+
+    k.log(ctx).Debug("🗄️📋 listing a page of keys", "prefix", prefix, "after", after, "page_size", pageSize)
+
+    keys, err := k.store.List(ctx, prefix)
+    if err != nil {
+        return nil, "", err
+    }
+    sort.Strings(keys)
+
+    start := 0
+    if after != "" {
+        start = sort.Search(len(keys), func(i int) bool { return keys[i] > after })
+    }
+    // One live key past the page shows there is another page
+    var page []string
+    for _, key := range keys[start:] {
+        if err := ctx.Err(); err != nil {
+            return nil, "", err
+        }
+        live, err := k.live(ctx, key)
+        if err != nil {
+            return nil, "", err
+        }
+        if !live {
+            continue
+        }
+        if len(page) == pageSize {
+            return page, encodePageToken(page[len(page)-1]), nil
+        }
+        page = append(page, key)
+    }
+    if page == nil {
+        page = []string{}
+    }
+    return page, "", nil
+}
+
+// encodePageToken makes a token resuming a listing after key.
+func encodePageToken(key string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodePageToken returns the key token resumes after, or "" for the
+// first page. A token for a key outside prefix came from another listing.
+func decodePageToken(prefix, token string) (string, error) {
+    if token == "" {
+        return "", nil
+    }
+    key, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil || len(key) == 0 || !strings.HasPrefix(string(key), prefix) {
+        return "", fmt.Errorf("%w: %q", shared.ErrInvalidPageToken, token)
+    }
+    return string(key), nil
+}
@@ -0,0 +1,100 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/page_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// listAllPages follows ListPage tokens to the end, returning every key and
+// how many pages it took.
+func listAllPages(t *testing.T, kv shared.KV, prefix string, pageSize int) ([]string, int) {
+    t.Helper()
+    var all []string
+    token, pages := "", 0
+    for {
+        keys, next, err := kv.ListPage(context.Background(), prefix, token, pageSize)
+        if err != nil {
+            t.Fatalf("ListPage(%q, page %d) failed: %v", prefix, pages+1, err)
+        }
+        all = append(all, keys...)
+        pages++
+        if next == "" {
+            return all, pages
+        }
+        token = next
+    }
+}
+
+func TestListPageTraversesEveryKey(t *testing.T) {
+    ctx := context.Background()
+    client := serveKV(t, NewKV(newMemStore(), nil))
+
+    // Written out of order; pages come back sorted
+    var want []string
+    for _, i := range []int{4, 9, 0, 7, 2, 5, 8, 1, 6, 3} {
+        if err := client.Put(ctx, fmt.Sprintf("item-%d", i), []byte("v")); err != nil {
+            t.Fatalf("Put failed: %v", err)
+        }
+    }
+    for i := 0; i < 10; i++ {
+        want = append(want, fmt.Sprintf("item-%d", i))
+    }
+    if err := client.Put(ctx, "other", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    for _, tc := range []struct{ pageSize, pages int }{{3, 4}, {5, 2}, {10, 1}, {0, 1}} {
+        keys, pages := listAllPages(t, client, "item-", tc.pageSize)
+        if !reflect.DeepEqual(keys, want) || pages != tc.pages {
+            t.Errorf("page size %d listed %v in %d pages, want %v in %d", tc.pageSize, keys, pages, want, tc.pages)
+        }
+    }
+}
+
+func TestListPageResumesAfterChanges(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newMemStore(), nil)
+    for _, key := range []string{"a", "b", "c", "d", "e"} {
+        if err := kv.Put(ctx, key, []byte("v")); err != nil {
+            t.Fatalf("Put failed: %v", err)
+        }
+    }
+
+    first, token, err := kv.ListPage(ctx, "", "", 2)
+    if err != nil || !reflect.DeepEqual(first, []string{"a", "b"}) {
+        t.Fatalf("first page = %v, %v; want [a b]", first, err)
+    }
+
+    // Deleting the last key seen and adding one before it moves nothing
+    // the rest of the listing returns
+    if err := kv.Delete(ctx, "b"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if err := kv.Put(ctx, "aa", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    rest, next, err := kv.ListPage(ctx, "", token, 10)
+    if err != nil || next != "" || !reflect.DeepEqual(rest, []string{"c", "d", "e"}) {
+        t.Fatalf("second page = %v, %q, %v; want [c d e] and no more", rest, next, err)
+    }
+}
+
+func TestListPageRejectsForeignToken(t *testing.T) {
+    client := serveKV(t, NewKV(newMemStore(), nil))
+    for _, token := range []string{"not base64!", encodePageToken("elsewhere")} {
+        _, _, err := client.ListPage(context.Background(), "item-", token, 10)
+        if !errors.Is(err, shared.ErrInvalidPageToken) || status.Code(err) != codes.InvalidArgument {
+            t.Fatalf("ListPage with token %q = %v, want ErrInvalidPageToken with code InvalidArgument", token, err)
+        }
+    }
+}
@@ -0,0 +1,56 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/readonly.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// mutatingMethods are the KV RPCs that change stored data. Add new write
+// RPCs here so read-only mode keeps refusing them.
+var mutatingMethods = map[string]bool{
+    proto.KV_Put_FullMethodName:            true,
+    proto.KV_Delete_FullMethodName:         true,
+    proto.KV_BatchPut_FullMethodName:       true,
+    proto.KV_CompareAndSwap_FullMethodName: true,
+    proto.KV_PutIfAbsent_FullMethodName:    true,
+    proto.KV_Rename_FullMethodName:         true,
+    proto.KV_PutIfVersion_FullMethodName:   true,
+    proto.KV_Increment_FullMethodName:      true,
+    proto.KV_Transaction_FullMethodName:    true,
+}
+
+// readOnlyFromEnv reads PLUGIN_KV_READ_ONLY. It is false when unset.
+func readOnlyFromEnv() (bool, error) {
+    value := os.Getenv("PLUGIN_KV_READ_ONLY")
+    if value == "" {
+        return false, nil
+    }
+    readOnly, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid PLUGIN_KV_READ_ONLY %q: %w", value, err)
+    }
+    return readOnly, nil
+}
+
+// readOnlyUnaryInterceptor fails every mutating KV request with
+// FailedPrecondition before it reaches the store. Reads, health checks and
+// watches are served as usual.
+func readOnlyUnaryInterceptor() grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        if mutatingMethods[info.FullMethod] {
+            return nil, status.Errorf(codes.FailedPrecondition, "%s: %s is not allowed", shared.ErrReadOnly, info.FullMethod)
+        }
+        return handler(ctx, req)
+    }
+}
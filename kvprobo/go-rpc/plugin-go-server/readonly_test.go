@@ -0,0 +1,65 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/readonly_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestReadOnlyRefusesWrites(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newMemStore(), nil)
+    if err := kv.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{readOnly: true}, hclog.NewNullLogger()), kv)
+
+    err := client.Put(ctx, "k", []byte("changed"))
+    if status.Code(err) != codes.FailedPrecondition || !errors.Is(err, shared.ErrReadOnly) {
+        t.Fatalf("Put() = %v, want FailedPrecondition and ErrReadOnly", err)
+    }
+    if err := client.Delete(ctx, "k"); !errors.Is(err, shared.ErrReadOnly) {
+        t.Fatalf("Delete() = %v, want ErrReadOnly", err)
+    }
+    if _, err := client.Increment(ctx, "n", 1); !errors.Is(err, shared.ErrReadOnly) {
+        t.Fatalf("Increment() = %v, want ErrReadOnly", err)
+    }
+
+    value, err := client.Get(ctx, "k")
+    if err != nil || string(value) != "v" {
+        t.Fatalf("Get() = %q, %v, want the unchanged value", value, err)
+    }
+    if keys, err := client.List(ctx, ""); err != nil || len(keys) != 1 {
+        t.Fatalf("List() = %v, %v, want [k]", keys, err)
+    }
+    if exists, err := client.Exists(ctx, "k"); err != nil || !exists {
+        t.Fatalf("Exists() = %t, %v, want true", exists, err)
+    }
+}
+
+func TestReadOnlyFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value   string
+        want    bool
+        wantErr bool
+    }{
+        {"", false, false},
+        {"true", true, false},
+        {"0", false, false},
+        {"sometimes", false, true},
+    } {
+        t.Setenv("PLUGIN_KV_READ_ONLY", tt.value)
+        got, err := readOnlyFromEnv()
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Fatalf("readOnlyFromEnv() with %q = %t, %v, want %t and error %t", tt.value, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
@@ -0,0 +1,141 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/reload.go
+
+package main
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "os"
+    "slices"
+    "strings"
+    "sync"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// reloadableSettings are the variables a SIGHUP reads again. Certificates
+// and the storage backend are fixed at startup, since changing them under
+// running requests isn't safe.
+var reloadableSettings = []string{
+    "PLUGIN_LOG_LEVEL",
+    "PLUGIN_KV_MAX_VALUE_BYTES",
+    "PLUGIN_KV_MAX_CONCURRENCY",
+}
+
+// startupSettings holds the reloadable settings as the server was started
+// with, so one removed from the reload file goes back to its startup value.
+var (
+    startupSettingsOnce sync.Once
+    startupSettings     map[string]string
+)
+
+// applyReloadFile reads PLUGIN_KV_RELOAD_FILE, one NAME=value per line, and
+// sets the environment from it over the startup values. Blank lines and
+// lines starting with # are skipped; a name outside reloadableSettings is
+// an error. Without PLUGIN_KV_RELOAD_FILE it does nothing.
+func applyReloadFile() error {
+    path := os.Getenv("PLUGIN_KV_RELOAD_FILE")
+    if path == "" {
+        return nil
+    }
+
+    startupSettingsOnce.Do(func() {
+        startupSettings = map[string]string{}
+        for _, name := range reloadableSettings {
+            if value, ok := os.LookupEnv(name); ok {
+                startupSettings[name] = value
+            }
+        }
+    })
+
+    f, err := os.Open(path)
+    if err != nil {
+        return fmt.Errorf("reading PLUGIN_KV_RELOAD_FILE: %w", err)
+    }
+    defer f.Close()
+
+    settings := map[string]string{}
+    scanner := bufio.NewScanner(f)
+    for line := 1; scanner.Scan(); line++ {
+        text := strings.TrimSpace(scanner.Text())
+        if text == "" || strings.HasPrefix(text, "#") {
+            continue
+        }
+        name, value, ok := strings.Cut(text, "=")
+        name = strings.TrimSpace(name)
+        if !ok || !slices.Contains(reloadableSettings, name) {
+            return fmt.Errorf("%s:%d: want NAME=value with NAME one of %s", path, line, strings.Join(reloadableSettings, ", "))
+        }
+        settings[name] = strings.TrimSpace(value)
+    }
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("reading PLUGIN_KV_RELOAD_FILE: %w", err)
+    }
+
+    for _, name := range reloadableSettings {
+        value, ok := settings[name]
+        if !ok {
+            value, ok = startupSettings[name]
+        }
+        if ok {
+            os.Setenv(name, value)
+        } else {
+            os.Unsetenv(name)
+        }
+    }
+    return nil
+}
+
+// reloader applies the reloadable settings to a running server.
+type reloader struct {
+    logger  hclog.Logger
+    kv      *KV
+    limiter *concurrencyLimiter
+}
+
+// reload reads the settings again and applies them only if all of them are
+// valid, so a typo leaves the running configuration alone.
+func (r *reloader) reload() error {
+    if err := applyReloadFile(); err != nil {
+        return err
+    }
+    level, _, err := shared.LogOptionsFromEnv(shared.DefaultLogLevel)
+    if err != nil {
+        return err
+    }
+    maxValueBytes, err := shared.MaxValueBytesFromEnv()
+    if err != nil {
+        return err
+    }
+    maxConcurrency, err := maxConcurrencyFromEnv()
+    if err != nil {
+        return err
+    }
+
+    // Sub-loggers share the level, so this reaches every part of the server
+    r.logger.SetLevel(level)
+    r.kv.maxValueBytes.Store(int64(maxValueBytes))
+    r.limiter.setMax(maxConcurrency)
+    r.logger.Info("🔃 configuration reloaded",
+        "log_level", level,
+        "max_value_bytes", maxValueBytes,
+        "max_concurrency", maxConcurrency)
+    return nil
+}
+
+// run reloads on every signal from sighup until ctx is done.
+func (r *reloader) run(ctx context.Context, sighup <-chan os.Signal) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-sighup:
+            if err := r.reload(); err != nil {
+                r.logger.Error("🔃❌ reload failed, keeping the current configuration", "error", err)
+            }
+        }
+    }
+}
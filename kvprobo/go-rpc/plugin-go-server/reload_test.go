@@ -0,0 +1,110 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/reload_test.go
+
+package main
+
+import (
+    "context"
+    "io"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "sync"
+    "syscall"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// writeReloadFile points PLUGIN_KV_RELOAD_FILE at a file holding content.
+func writeReloadFile(t *testing.T, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "reload.env")
+    if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    t.Setenv("PLUGIN_KV_RELOAD_FILE", path)
+    return path
+}
+
+func TestSIGHUPReloadsSettings(t *testing.T) {
+    t.Setenv("PLUGIN_LOG_LEVEL", "info")
+    t.Setenv("PLUGIN_KV_MAX_VALUE_BYTES", "")
+    t.Setenv("PLUGIN_KV_MAX_CONCURRENCY", "")
+    startupSettingsOnce = sync.Once{}
+    path := writeReloadFile(t, "")
+
+    logger := hclog.New(&hclog.LoggerOptions{Level: hclog.Info, Output: io.Discard})
+    named := logger.Named("kv")
+    kv := NewKV(newMemStore(), named)
+    limiter := newConcurrencyLimiter(0)
+
+    sighup := make(chan os.Signal, 1)
+    signal.Notify(sighup, syscall.SIGHUP)
+    defer signal.Stop(sighup)
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go (&reloader{logger: logger, kv: kv, limiter: limiter}).run(ctx, sighup)
+
+    content := "# raised while debugging\nPLUGIN_LOG_LEVEL=debug\nPLUGIN_KV_MAX_VALUE_BYTES = 64\nPLUGIN_KV_MAX_CONCURRENCY=2\n"
+    if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    self, err := os.FindProcess(os.Getpid())
+    if err != nil {
+        t.Fatalf("FindProcess failed: %v", err)
+    }
+    if err := self.Signal(syscall.SIGHUP); err != nil {
+        t.Fatalf("sending SIGHUP failed: %v", err)
+    }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for named.GetLevel() != hclog.Debug {
+        if time.Now().After(deadline) {
+            t.Fatalf("log level is %s after SIGHUP, want debug", named.GetLevel())
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    if got := kv.valueLimit(); got != 64 {
+        t.Fatalf("value limit is %d after SIGHUP, want 64", got)
+    }
+    if slots := limiter.slots.Load(); slots == nil || cap(*slots) != 2 {
+        t.Fatalf("concurrency limit wasn't set to 2 by SIGHUP")
+    }
+
+    // Removing a setting from the file restores its startup value
+    if err := os.WriteFile(path, []byte("PLUGIN_KV_MAX_CONCURRENCY=2\n"), 0600); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    if err := (&reloader{logger: logger, kv: kv, limiter: limiter}).reload(); err != nil {
+        t.Fatalf("reload failed: %v", err)
+    }
+    if named.GetLevel() != hclog.Info {
+        t.Fatalf("log level is %s once removed from the file, want info", named.GetLevel())
+    }
+}
+
+func TestReloadKeepsConfigurationOnError(t *testing.T) {
+    t.Setenv("PLUGIN_LOG_LEVEL", "info")
+    t.Setenv("PLUGIN_KV_MAX_VALUE_BYTES", "")
+    t.Setenv("PLUGIN_KV_MAX_CONCURRENCY", "")
+    startupSettingsOnce = sync.Once{}
+
+    logger := hclog.New(&hclog.LoggerOptions{Level: hclog.Info, Output: io.Discard})
+    kv := NewKV(newMemStore(), nil)
+    r := &reloader{logger: logger, kv: kv, limiter: newConcurrencyLimiter(0)}
+
+    for _, content := range []string{
+        "PLUGIN_LOG_LEVEL=debug\nPLUGIN_KV_MAX_VALUE_BYTES=lots\n",
+        "PLUGIN_LOG_LEVEL=debug\nPLUGIN_CLIENT_CERT=new-cert\n",
+        "PLUGIN_LOG_LEVEL\n",
+    } {
+        writeReloadFile(t, content)
+        if err := r.reload(); err == nil {
+            t.Fatalf("reload of %q succeeded, want an error", content)
+        }
+        if logger.GetLevel() != hclog.Info {
+            t.Fatalf("failed reload of %q changed the log level to %s", content, logger.GetLevel())
+        }
+    }
+}
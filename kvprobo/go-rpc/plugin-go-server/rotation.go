@@ -0,0 +1,116 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/rotation.go
+
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "fmt"
+    "os"
+    "sync/atomic"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// minRotateDelay stops a certificate that is already past its renewal
+// point, or that the reload didn't replace, from being reloaded in a
+// tight loop.
+const minRotateDelay = time.Minute
+
+// certRotator serves its current certificate to each new TLS handshake and
+// replaces it from load over time. Connections that are already open keep
+// the certificate they were established with.
+type certRotator struct {
+    load   func() (tls.Certificate, error)
+    cert   atomic.Pointer[tls.Certificate]
+    logger hclog.Logger
+    now    func() time.Time
+}
+
+// newCertRotator loads the first certificate, failing if it can't.
+func newCertRotator(load func() (tls.Certificate, error), logger hclog.Logger) (*certRotator, error) {
+    r := &certRotator{load: load, logger: logger, now: time.Now}
+    if err := r.rotate(); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+// getCertificate is the tls.Config.GetCertificate callback.
+func (r *certRotator) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+    return r.cert.Load(), nil
+}
+
+// rotate loads a certificate and serves it to new connections.
+func (r *certRotator) rotate() error {
+    cert, err := r.load()
+    if err != nil {
+        return err
+    }
+    if cert.Leaf == nil {
+        leaf, err := x509.ParseCertificate(cert.Certificate[0])
+        if err != nil {
+            return fmt.Errorf("parsing rotated certificate: %w", err)
+        }
+        cert.Leaf = leaf
+    }
+
+    old := r.cert.Swap(&cert)
+    if old != nil && old.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) == 0 {
+        r.logger.Debug("📡🔐 server certificate unchanged", "not_after", cert.Leaf.NotAfter)
+        return nil
+    }
+    r.logger.Info("📡🔐 serving new server certificate",
+        "subject", cert.Leaf.Subject.CommonName,
+        "serial", cert.Leaf.SerialNumber.Text(16),
+        "not_after", cert.Leaf.NotAfter)
+    return nil
+}
+
+// nextRotation is interval when set. Otherwise it is the time until two
+// thirds of the current certificate's validity has passed, leaving the last
+// third to pick up a renewed one.
+func (r *certRotator) nextRotation(interval time.Duration) time.Duration {
+    if interval > 0 {
+        return interval
+    }
+    leaf := r.cert.Load().Leaf
+    renewAt := leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3)
+    return max(renewAt.Sub(r.now()), minRotateDelay)
+}
+
+// run rotates on the nextRotation schedule until ctx is cancelled. A failed
+// reload keeps the current certificate.
+func (r *certRotator) run(ctx context.Context, interval time.Duration) {
+    for {
+        timer := time.NewTimer(r.nextRotation(interval))
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-timer.C:
+            if err := r.rotate(); err != nil {
+                r.logger.Warn("📡⚠️ failed to rotate server certificate, keeping the current one", "error", err)
+            }
+        }
+    }
+}
+
+// certRotateIntervalFromEnv reads PLUGIN_KV_CERT_ROTATE_INTERVAL as a Go
+// duration. Zero, the default, rotates based on the certificate's validity.
+func certRotateIntervalFromEnv() (time.Duration, error) {
+    value := os.Getenv("PLUGIN_KV_CERT_ROTATE_INTERVAL")
+    if value == "" {
+        return 0, nil
+    }
+    interval, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_CERT_ROTATE_INTERVAL %q: %w", value, err)
+    }
+    if interval < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_CERT_ROTATE_INTERVAL must not be negative, got %s", interval)
+    }
+    return interval, nil
+}
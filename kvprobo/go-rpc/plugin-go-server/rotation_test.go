@@ -0,0 +1,176 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/rotation_test.go
+
+package main
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "io"
+    "math/big"
+    "net"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// issuingLoader returns a load function that signs a fresh localhost
+// certificate with a test CA on every call, and a pool trusting that CA.
+func issuingLoader(t *testing.T) (func() (tls.Certificate, error), *x509.CertPool) {
+    t.Helper()
+    caPEM, caKeyPEM, err := shared.GenerateCA(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCA failed: %v", err)
+    }
+    ca, err := shared.ParseCertificate(caPEM, nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    caKey, err := shared.ParsePrivateKey(caKeyPEM, nil)
+    if err != nil {
+        t.Fatalf("ParsePrivateKey failed: %v", err)
+    }
+    pool := x509.NewCertPool()
+    pool.AddCert(ca)
+
+    return func() (tls.Certificate, error) {
+        certPEM, keyPEM, err := shared.GenerateSignedCert(ca, caKey, nil, nil)
+        if err != nil {
+            return tls.Certificate{}, err
+        }
+        return tls.X509KeyPair(certPEM, keyPEM)
+    }, pool
+}
+
+// echo serves conn until the peer closes it.
+func echo(conn net.Conn) {
+    defer conn.Close()
+    io.Copy(conn, conn)
+}
+
+// servedSerial returns the serial of the certificate conn's server
+// presented, after checking the connection still carries data.
+func servedSerial(t *testing.T, conn *tls.Conn) *big.Int {
+    t.Helper()
+    if _, err := conn.Write([]byte("ping")); err != nil {
+        t.Fatalf("write failed: %v", err)
+    }
+    buf := make([]byte, 4)
+    if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+        t.Fatalf("echo = %q, %v; want ping", buf, err)
+    }
+    return conn.ConnectionState().PeerCertificates[0].SerialNumber
+}
+
+func TestCertRotatorServesNewCertificateToNewConnections(t *testing.T) {
+    load, pool := issuingLoader(t)
+    rotator, err := newCertRotator(load, hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newCertRotator failed: %v", err)
+    }
+
+    listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: rotator.getCertificate})
+    if err != nil {
+        t.Fatalf("Listen failed: %v", err)
+    }
+    defer listener.Close()
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go echo(conn)
+        }
+    }()
+
+    dial := func() *tls.Conn {
+        conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+        if err != nil {
+            t.Fatalf("Dial failed: %v", err)
+        }
+        t.Cleanup(func() { conn.Close() })
+        return conn
+    }
+
+    before := dial()
+    oldSerial := servedSerial(t, before)
+
+    if err := rotator.rotate(); err != nil {
+        t.Fatalf("rotate failed: %v", err)
+    }
+
+    after := dial()
+    if newSerial := servedSerial(t, after); newSerial.Cmp(oldSerial) == 0 {
+        t.Fatalf("new connection got serial %s again, want the rotated certificate", newSerial.Text(16))
+    }
+    if serial := servedSerial(t, before); serial.Cmp(oldSerial) != 0 {
+        t.Fatalf("existing connection now reports serial %s, want %s", serial.Text(16), oldSerial.Text(16))
+    }
+}
+
+func TestCertRotatorKeepsCertificateWhenReloadFails(t *testing.T) {
+    load, _ := issuingLoader(t)
+    fail := false
+    rotator, err := newCertRotator(func() (tls.Certificate, error) {
+        if fail {
+            return tls.Certificate{}, io.ErrUnexpectedEOF
+        }
+        return load()
+    }, hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newCertRotator failed: %v", err)
+    }
+    current, _ := rotator.getCertificate(nil)
+
+    fail = true
+    if err := rotator.rotate(); err == nil {
+        t.Fatalf("rotate with a failing loader succeeded")
+    }
+    if cert, _ := rotator.getCertificate(nil); cert != current {
+        t.Fatalf("failed rotation replaced the served certificate")
+    }
+}
+
+func TestCertRotatorNextRotation(t *testing.T) {
+    load, _ := issuingLoader(t)
+    rotator, err := newCertRotator(load, hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newCertRotator failed: %v", err)
+    }
+    leaf := rotator.cert.Load().Leaf
+    lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+
+    if got := rotator.nextRotation(time.Hour); got != time.Hour {
+        t.Fatalf("nextRotation(1h) = %s, want the configured interval", got)
+    }
+
+    rotator.now = func() time.Time { return leaf.NotBefore }
+    if got, want := rotator.nextRotation(0), lifetime*2/3; got != want {
+        t.Fatalf("nextRotation(0) at issue = %s, want two thirds of the lifetime, %s", got, want)
+    }
+
+    rotator.now = func() time.Time { return leaf.NotAfter }
+    if got := rotator.nextRotation(0); got != minRotateDelay {
+        t.Fatalf("nextRotation(0) at expiry = %s, want %s", got, minRotateDelay)
+    }
+}
+
+func TestCertRotateIntervalFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_CERT_ROTATE_INTERVAL", "")
+    if interval, err := certRotateIntervalFromEnv(); err != nil || interval != 0 {
+        t.Fatalf("certRotateIntervalFromEnv() unset = %s, %v; want 0", interval, err)
+    }
+    t.Setenv("PLUGIN_KV_CERT_ROTATE_INTERVAL", "6h")
+    if interval, err := certRotateIntervalFromEnv(); err != nil || interval != 6*time.Hour {
+        t.Fatalf("certRotateIntervalFromEnv() = %s, %v; want 6h", interval, err)
+    }
+    for _, value := range []string{"soon", "-1m"} {
+        t.Setenv("PLUGIN_KV_CERT_ROTATE_INTERVAL", value)
+        if _, err := certRotateIntervalFromEnv(); err == nil {
+            t.Fatalf("certRotateIntervalFromEnv() accepted %q", value)
+        }
+    }
+}
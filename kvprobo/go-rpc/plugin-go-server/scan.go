@@ -0,0 +1,73 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/scan.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// defaultScanLimit is the most entries Scan returns when
+// PLUGIN_KV_SCAN_LIMIT is unset.
+const defaultScanLimit = 1000
+
+// Scan reads every key under prefix in sorted order, skipping expired
+// ones, and stops once k.scanLimit entries have been read.
+func (k *KV) Scan(ctx context.Context, prefix string) (map[string][]byte, bool, error) {
+    defer k.locks.rlockAll()()
+
+    if err := ctx.Err(); err != nil {
+        return nil, false, err
+    }
+
+    k.log(ctx).Debug("🗄️🧺 scanning keys", "prefix", prefix, "limit", k.scanLimit)
+
+    keys, err := k.store.List(ctx, prefix)
+    if err != nil {
+        return nil, false, err
+    }
+    sort.Strings(keys)
+
+    values := map[string][]byte{}
+    for _, key := range keys {
+        if err := ctx.Err(); err != nil {
+            return nil, false, err
+        }
+        value, err := k.load(ctx, key)
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            continue
+        }
+        if err != nil {
+            return nil, false, fmt.Errorf("scan of %q: %w", key, err)
+        }
+        if k.scanLimit > 0 && len(values) == k.scanLimit {
+            k.log(ctx).Debug("🗄️🧺 scan truncated", "prefix", prefix, "limit", k.scanLimit)
+            return values, true, nil
+        }
+        values[key] = value
+    }
+    return values, false, nil
+}
+
+// scanLimitFromEnv reads PLUGIN_KV_SCAN_LIMIT. Zero lets Scan return every
+// matching key.
+func scanLimitFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_SCAN_LIMIT")
+    if value == "" {
+        return defaultScanLimit, nil
+    }
+    limit, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_SCAN_LIMIT %q: %w", value, err)
+    }
+    if limit < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_SCAN_LIMIT must not be negative, got %d", limit)
+    }
+    return limit, nil
+}
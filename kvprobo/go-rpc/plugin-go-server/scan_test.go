@@ -0,0 +1,87 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/scan_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestScan(t *testing.T) {
+    ctx := context.Background()
+    kv, clock := newTTLTestKV(newFileStore(t.TempDir()))
+    for key, value := range map[string]string{"app-a": "1", "app-b": "2", "app-c": "3", "other": "x"} {
+        if err := kv.Put(ctx, key, []byte(value)); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    if err := kv.PutWithTTL(ctx, "app-0", []byte("gone"), time.Second); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    clock.advance(time.Minute)
+
+    values, truncated, err := kv.Scan(ctx, "app-")
+    if err != nil || truncated {
+        t.Fatalf("Scan(app-) = %v, truncated %t; want every key", err, truncated)
+    }
+    if len(values) != 3 || string(values["app-a"]) != "1" || string(values["app-c"]) != "3" {
+        t.Fatalf("Scan(app-) = %q, want app-a, app-b and app-c", values)
+    }
+
+    values, truncated, err = kv.Scan(ctx, "missing-")
+    if err != nil || truncated || values == nil || len(values) != 0 {
+        t.Fatalf("Scan(missing-) = %q, %t, %v; want an empty map", values, truncated, err)
+    }
+}
+
+func TestScanLimit(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newFileStore(t.TempDir()), nil)
+    for _, key := range []string{"k3", "k1", "k2"} {
+        if err := kv.Put(ctx, key, []byte(key)); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    client := serveKV(t, kv)
+
+    kv.scanLimit = 2
+    values, truncated, err := client.Scan(ctx, "k")
+    if err != nil || !truncated {
+        t.Fatalf("Scan over the limit = %v, truncated %t; want truncated", err, truncated)
+    }
+    if len(values) != 2 || values["k1"] == nil || values["k2"] == nil {
+        t.Fatalf("Scan over the limit = %q, want the first two keys", values)
+    }
+
+    // Exactly at the limit is not truncated
+    kv.scanLimit = 3
+    if values, truncated, err := client.Scan(ctx, "k"); err != nil || truncated || len(values) != 3 {
+        t.Fatalf("Scan at the limit = %q, %t, %v; want all three", values, truncated, err)
+    }
+
+    kv.scanLimit = 0
+    if values, truncated, err := client.Scan(ctx, ""); err != nil || truncated || len(values) != 3 {
+        t.Fatalf("Scan without a limit = %q, %t, %v; want all three", values, truncated, err)
+    }
+    if values, truncated, err := client.Scan(ctx, "nope"); err != nil || truncated || len(values) != 0 {
+        t.Fatalf("Scan of an empty prefix over gRPC = %q, %t, %v; want nothing", values, truncated, err)
+    }
+}
+
+func TestScanLimitFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_SCAN_LIMIT", "")
+    if limit, err := scanLimitFromEnv(); err != nil || limit != defaultScanLimit {
+        t.Fatalf("scanLimitFromEnv() unset = %d, %v", limit, err)
+    }
+    t.Setenv("PLUGIN_KV_SCAN_LIMIT", "0")
+    if limit, err := scanLimitFromEnv(); err != nil || limit != 0 {
+        t.Fatalf("scanLimitFromEnv() = %d, %v; want 0", limit, err)
+    }
+    for _, value := range []string{"lots", "-1"} {
+        t.Setenv("PLUGIN_KV_SCAN_LIMIT", value)
+        if _, err := scanLimitFromEnv(); err == nil {
+            t.Fatalf("scanLimitFromEnv() accepted %q", value)
+        }
+    }
+}
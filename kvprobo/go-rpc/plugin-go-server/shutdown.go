@@ -0,0 +1,65 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/shutdown.go
+
+package main
+
+import (
+    "sync"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+)
+
+// drainTimeout bounds how long shutdown waits for in-flight RPCs. Watch
+// streams never finish on their own, so without a bound they would hold
+// the process open forever.
+const drainTimeout = 5 * time.Second
+
+// serverRef remembers the gRPC server go-plugin builds through the
+// GRPCServer callback so the shutdown handler can drain it.
+type serverRef struct {
+    mu     sync.Mutex
+    server *grpc.Server
+}
+
+func (r *serverRef) set(server *grpc.Server) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.server = server
+}
+
+func (r *serverRef) get() *grpc.Server {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.server
+}
+
+// drainGRPCServer stops server from accepting new RPCs and waits for the
+// ones in flight to finish, so a Put that has started still reaches the
+// store. After timeout the remaining RPCs are cancelled. It reports whether
+// the drain completed without being forced.
+func drainGRPCServer(server *grpc.Server, timeout time.Duration, logger hclog.Logger) bool {
+    if server == nil {
+        return true
+    }
+
+    logger.Info("🗄️🛑 draining in-flight requests", "timeout", timeout)
+    drained := make(chan struct{})
+    go func() {
+        server.GracefulStop()
+        close(drained)
+    }()
+
+    select {
+    case <-drained:
+        logger.Info("🗄️✅ in-flight requests finished")
+        return true
+    case <-time.After(timeout):
+        logger.Warn("🗄️⏳ drain timeout reached, cancelling remaining requests")
+        // Stop cancels the remaining RPCs, but both it and GracefulStop
+        // block until handlers that ignore cancellation return, so don't
+        // wait for either
+        go server.Stop()
+        return false
+    }
+}
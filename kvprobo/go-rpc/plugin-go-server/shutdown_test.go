@@ -0,0 +1,71 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/shutdown_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+)
+
+// slowStore holds every Put until release is closed, signalling started
+// once one is waiting.
+type slowStore struct {
+    Store
+    started chan struct{}
+    release chan struct{}
+}
+
+func (s *slowStore) Put(ctx context.Context, key string, value []byte) error {
+    close(s.started)
+    <-s.release
+    return s.Store.Put(ctx, key, value)
+}
+
+func TestDrainFinishesInFlightPut(t *testing.T) {
+    store := &slowStore{Store: newFakeStore(), started: make(chan struct{}), release: make(chan struct{})}
+    server := grpc.NewServer()
+    client := serveKVOn(t, server, NewKV(store, nil))
+
+    putErr := make(chan error, 1)
+    go func() { putErr <- client.Put(context.Background(), "slow", []byte("value")) }()
+    <-store.started
+
+    drained := make(chan bool, 1)
+    go func() { drained <- drainGRPCServer(server, 5*time.Second, hclog.NewNullLogger()) }()
+
+    // The drain must wait for the Put rather than cut it off
+    select {
+    case <-drained:
+        t.Fatalf("drain finished while a Put was still in flight")
+    case <-time.After(50 * time.Millisecond):
+    }
+    close(store.release)
+
+    if err := <-putErr; err != nil {
+        t.Fatalf("in-flight Put failed during drain: %v", err)
+    }
+    if !<-drained {
+        t.Fatalf("drain was forced, want a graceful stop")
+    }
+    if value, err := store.Store.Get(context.Background(), "slow"); err != nil || string(value) != "value" {
+        t.Fatalf("stored value = %q, %v; want the in-flight write", value, err)
+    }
+}
+
+func TestDrainForcesStopAfterTimeout(t *testing.T) {
+    store := &slowStore{Store: newFakeStore(), started: make(chan struct{}), release: make(chan struct{})}
+    defer close(store.release)
+    server := grpc.NewServer()
+    client := serveKVOn(t, server, NewKV(store, nil))
+
+    go client.Put(context.Background(), "stuck", []byte("value"))
+    <-store.started
+
+    if drainGRPCServer(server, 50*time.Millisecond, hclog.NewNullLogger()) {
+        t.Fatalf("drain of a stuck Put reported a graceful stop")
+    }
+}
@@ -0,0 +1,60 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/slowlog.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// defaultSlowThreshold is how long an RPC may take before it is logged as
+// slow when PLUGIN_KV_SLOW_THRESHOLD is unset.
+const defaultSlowThreshold = time.Second
+
+// slowThresholdFromEnv reads PLUGIN_KV_SLOW_THRESHOLD, a duration such as
+// "250ms". Zero turns slow-request logging off.
+func slowThresholdFromEnv() (time.Duration, error) {
+    value := os.Getenv("PLUGIN_KV_SLOW_THRESHOLD")
+    if value == "" {
+        return defaultSlowThreshold, nil
+    }
+    threshold, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_SLOW_THRESHOLD %q: %w", value, err)
+    }
+    if threshold < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_SLOW_THRESHOLD must not be negative, got %s", threshold)
+    }
+    return threshold, nil
+}
+
+// slowRequestUnaryInterceptor warns about every RPC that takes longer than
+// threshold to handle, with the key it was for, so storage stalls show up
+// in the logs without a metrics backend.
+func slowRequestUnaryInterceptor(threshold time.Duration, logger hclog.Logger) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        start := time.Now()
+        resp, err := handler(ctx, req)
+        if elapsed := time.Since(start); elapsed > threshold {
+            args := []interface{}{
+                "method", info.FullMethod,
+                "elapsed", elapsed,
+                "threshold", threshold,
+                "code", status.Code(err),
+            }
+            if keyed, ok := req.(interface{ GetKey() string }); ok {
+                args = append(args, "key", keyed.GetKey())
+            }
+            shared.ServerRequestLogger(ctx, logger).Warn("📡🐢 slow RPC", args...)
+        }
+        return resp, err
+    }
+}
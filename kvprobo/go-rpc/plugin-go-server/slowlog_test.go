@@ -0,0 +1,81 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/slowlog_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// stallingStore takes delay over every Get once stalled is set, like a
+// stalled disk.
+type stallingStore struct {
+    Store
+    delay   time.Duration
+    stalled atomic.Bool
+}
+
+func (s *stallingStore) Get(ctx context.Context, key string) ([]byte, error) {
+    if s.stalled.Load() {
+        time.Sleep(s.delay)
+    }
+    return s.Store.Get(ctx, key)
+}
+
+func TestSlowRequestsAreLogged(t *testing.T) {
+    store := &stallingStore{Store: newMemStore(), delay: 50 * time.Millisecond}
+    var logs bytes.Buffer
+    logger := hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Warn})
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{slowThreshold: 20 * time.Millisecond}, logger), NewKV(store, nil))
+
+    ctx := context.Background()
+    if err := client.Put(ctx, "fast", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if logs.Len() != 0 {
+        t.Fatalf("a fast Put was logged as slow:\n%s", logs.String())
+    }
+    store.stalled.Store(true)
+    if _, err := client.Get(ctx, "fast"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+
+    line := logs.String()
+    for _, want := range []string{"[WARN]  slow: 📡🐢 slow RPC", "method=/proto.KV/Get", "key=fast", "threshold=20ms"} {
+        if !strings.Contains(line, want) {
+            t.Fatalf("slow Get log %q doesn't contain %q", line, want)
+        }
+    }
+    _, elapsedText, _ := strings.Cut(line, "elapsed=")
+    elapsedText, _, _ = strings.Cut(elapsedText, " ")
+    elapsed, err := time.ParseDuration(elapsedText)
+    if err != nil || elapsed < store.delay {
+        t.Fatalf("slow Get logged elapsed=%q, want at least %s", elapsedText, store.delay)
+    }
+}
+
+func TestSlowThresholdFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value   string
+        want    time.Duration
+        wantErr bool
+    }{
+        {"", time.Second, false},
+        {"250ms", 250 * time.Millisecond, false},
+        {"0", 0, false},
+        {"-1s", 0, true},
+        {"soon", 0, true},
+    } {
+        t.Setenv("PLUGIN_KV_SLOW_THRESHOLD", tt.value)
+        got, err := slowThresholdFromEnv()
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Fatalf("slowThresholdFromEnv() with %q = %s, %v, want %s and error %t", tt.value, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
@@ -0,0 +1,75 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/socket.go
+
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "syscall"
+)
+
+// socketModeFromEnv reads PLUGIN_KV_SOCKET_MODE, the permissions to give
+// the Unix socket the plugin listens on, in octal such as "0600". It
+// returns 0, which leaves the socket as go-plugin created it, when unset.
+func socketModeFromEnv() (os.FileMode, error) {
+    value := os.Getenv("PLUGIN_KV_SOCKET_MODE")
+    if value == "" {
+        return 0, nil
+    }
+    mode, err := strconv.ParseUint(value, 8, 32)
+    if err != nil || mode > 0o777 {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_SOCKET_MODE %q: want permission bits in octal, such as 0600", value)
+    }
+    // The host connects as the owner, so it needs read and write access
+    if mode&0o600 != 0o600 {
+        return 0, fmt.Errorf("PLUGIN_KV_SOCKET_MODE %#o must give the owner read and write access", mode)
+    }
+    return os.FileMode(mode), nil
+}
+
+// listeningUnixSockets returns the paths of the Unix sockets this process
+// listens on. go-plugin doesn't hand its listener to the plugin, so they
+// are found among the process's open file descriptors.
+func listeningUnixSockets() ([]string, error) {
+    entries, err := os.ReadDir("/dev/fd")
+    if err != nil {
+        return nil, err
+    }
+    var paths []string
+    for _, entry := range entries {
+        fd, err := strconv.Atoi(entry.Name())
+        if err != nil {
+            continue
+        }
+        // Descriptors that aren't sockets fail both calls
+        if listening, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_ACCEPTCONN); err != nil || listening == 0 {
+            continue
+        }
+        addr, err := syscall.Getsockname(fd)
+        if err != nil {
+            continue
+        }
+        // Abstract sockets have no file to change
+        if unix, ok := addr.(*syscall.SockaddrUnix); ok && unix.Name != "" && !strings.HasPrefix(unix.Name, "@") {
+            paths = append(paths, unix.Name)
+        }
+    }
+    return paths, nil
+}
+
+// restrictSockets gives every Unix socket this process listens on mode,
+// and returns their paths.
+func restrictSockets(mode os.FileMode) ([]string, error) {
+    paths, err := listeningUnixSockets()
+    if err != nil {
+        return nil, fmt.Errorf("finding the plugin socket: %w", err)
+    }
+    for _, path := range paths {
+        if err := os.Chmod(path, mode); err != nil {
+            return nil, fmt.Errorf("setting the plugin socket's mode: %w", err)
+        }
+    }
+    return paths, nil
+}
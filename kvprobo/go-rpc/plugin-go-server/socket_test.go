@@ -0,0 +1,83 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/socket_test.go
+
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "os"
+    "os/exec"
+    "strings"
+    "syscall"
+    "testing"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestSocketModeFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value   string
+        want    os.FileMode
+        wantErr bool
+    }{
+        {"", 0, false},
+        {"0600", 0o600, false},
+        {"660", 0o660, false},
+        {"0400", 0, true},
+        {"01600", 0, true},
+        {"rw", 0, true},
+    } {
+        t.Setenv("PLUGIN_KV_SOCKET_MODE", tt.value)
+        got, err := socketModeFromEnv()
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Fatalf("socketModeFromEnv() with %q = %#o, %v, want %#o and error %t", tt.value, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
+
+func TestSocketModeIsApplied(t *testing.T) {
+    cmd := exec.Command(os.Args[0])
+    cmd.Env = []string{
+        runMainEnv + "=1",
+        shared.Handshake.MagicCookieKey + "=" + shared.Handshake.MagicCookieValue,
+        "PLUGIN_AUTO_MTLS=false",
+        "PLUGIN_KV_ALLOW_INSECURE=true",
+        "PLUGIN_KV_DATA_DIR=" + t.TempDir(),
+        "PLUGIN_UNIX_SOCKET_DIR=" + t.TempDir(),
+        "PLUGIN_KV_SOCKET_MODE=0600",
+    }
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        t.Fatalf("StdoutPipe failed: %v", err)
+    }
+    if err := cmd.Start(); err != nil {
+        t.Fatalf("starting the server failed: %v", err)
+    }
+    t.Cleanup(func() {
+        cmd.Process.Signal(syscall.SIGTERM)
+        cmd.Wait()
+    })
+
+    // The handshake line is printed once the socket is ready:
+    // core-version|app-version|network|address|protocol|cert
+    line, err := bufio.NewReader(stdout).ReadString('\n')
+    if err != nil {
+        cmd.Process.Kill()
+        cmd.Wait()
+        t.Fatalf("reading the handshake line failed: %v\n%s", err, stderr.String())
+    }
+    fields := strings.Split(strings.TrimSpace(line), "|")
+    if len(fields) < 4 || fields[2] != "unix" {
+        t.Fatalf("handshake line %q doesn't name a Unix socket", line)
+    }
+
+    info, err := os.Stat(fields[3])
+    if err != nil {
+        t.Fatalf("Stat of the socket failed: %v", err)
+    }
+    if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+        t.Fatalf("socket mode = %s, want a socket with 0600", info.Mode())
+    }
+}
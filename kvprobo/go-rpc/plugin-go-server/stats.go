@@ -0,0 +1,46 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/stats.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// Stats walks every key with every read lock held, so the totals describe one
+// consistent state of the store. Sizes are of the values as written, not
+// as encoded on disk.
+func (k *KV) Stats(ctx context.Context) (int64, int64, error) {
+    defer k.locks.rlockAll()()
+
+    if err := ctx.Err(); err != nil {
+        return 0, 0, err
+    }
+
+    k.log(ctx).Debug("🗄️📊 computing stats")
+
+    keys, err := k.store.List(ctx, "")
+    if err != nil {
+        return 0, 0, err
+    }
+
+    var keyCount, totalBytes int64
+    for _, key := range keys {
+        if err := ctx.Err(); err != nil {
+            return 0, 0, err
+        }
+        value, err := k.load(ctx, key)
+        if errors.Is(err, shared.ErrKeyNotFound) {
+            continue
+        }
+        if err != nil {
+            return 0, 0, fmt.Errorf("stats of %q: %w", key, err)
+        }
+        keyCount++
+        totalBytes += int64(len(value))
+    }
+    return keyCount, totalBytes, nil
+}
@@ -0,0 +1,41 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/stats_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestStats(t *testing.T) {
+    ctx := context.Background()
+    kv, clock := newTTLTestKV(newFileStore(t.TempDir()))
+    client := serveKV(t, kv)
+
+    if keys, size, err := client.Stats(ctx); err != nil || keys != 0 || size != 0 {
+        t.Fatalf("Stats() of an empty store = %d, %d, %v; want 0, 0", keys, size, err)
+    }
+
+    for key, value := range map[string]string{"a": "1", "b": "twenty", "c": ""} {
+        if err := client.Put(ctx, key, []byte(value)); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    // Versioned values carry a header on disk that isn't counted
+    if err := client.Put(ctx, "a", []byte("one")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.PutWithTTL(ctx, "temp", []byte("expired"), time.Second); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    clock.advance(time.Minute)
+
+    keys, size, err := client.Stats(ctx)
+    if err != nil {
+        t.Fatalf("Stats() failed: %v", err)
+    }
+    if keys != 3 || size != int64(len("one")+len("twenty")) {
+        t.Fatalf("Stats() = %d keys, %d bytes; want 3 keys, 9 bytes", keys, size)
+    }
+}
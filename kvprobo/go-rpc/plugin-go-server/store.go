@@ -0,0 +1,581 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// Store is the persistence backend behind KV. Keys handed to a Store have
+// already been validated by KV; ctx carries the caller's deadline for
+// backends whose operations can block.
+type Store interface {
+    Get(ctx context.Context, key string) ([]byte, error)
+    Put(ctx context.Context, key string, value []byte) error
+    Delete(ctx context.Context, key string) error
+    List(ctx context.Context, prefix string) ([]string, error)
+    Exists(ctx context.Context, key string) (bool, error)
+    // ModTime returns when key was last written, or the zero Time if the
+    // store can't tell.
+    ModTime(ctx context.Context, key string) (time.Time, error)
+
+    // Create stores value only if key doesn't exist yet, failing with
+    // shared.ErrKeyExists otherwise, so the check and the write can't be
+    // split by another writer sharing the store.
+    Create(ctx context.Context, key string, value []byte) error
+
+    // Rename stores value under newKey as a fresh write and removes oldKey
+    // in one step. value is what KV made of oldKey's entry; it is passed in
+    // rather than moved as it is because the entry's version has to go up.
+    // It fails with shared.ErrKeyNotFound if oldKey doesn't exist, and with
+    // shared.ErrKeyExists if newKey does unless overwrite is set. KV never
+    // passes the same key twice.
+    Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error
+
+    // Commit applies writes as a unit: either all of them land or none do.
+    Commit(ctx context.Context, writes []storeWrite) error
+}
+
+// storeWrite is one change applied by Store.Commit. A delete removes key and
+// ignores value. KV never passes the same key twice in one commit.
+type storeWrite struct {
+    key    string
+    value  []byte
+    delete bool
+}
+
+// fileStoreTempDir holds values while they are being written. Its name
+// contains "..", so it can never collide with a valid key, and List skips
+// it because it is a directory.
+const fileStoreTempDir = "..kv-tmp"
+
+// fileStore keeps each value in its own file under dir, or, when sharded,
+// under one of up to 256 subdirectories of it chosen by the key's hash.
+type fileStore struct {
+    dir     string
+    sharded bool
+
+    // writeFile is writeFileSync; tests replace it to simulate a slow disk.
+    writeFile func(name string, data []byte, perm os.FileMode) error
+}
+
+func newFileStore(dir string) *fileStore {
+    return &fileStore{dir: dir, writeFile: writeFileSync}
+}
+
+// newShardedFileStore is newFileStore with the sharded layout. Values left
+// directly in dir by a flat store aren't seen until migrateToSharded moves
+// them.
+func newShardedFileStore(dir string) *fileStore {
+    store := newFileStore(dir)
+    store.sharded = true
+    return store
+}
+
+// writeFileSync is os.WriteFile followed by an fsync, so the data is on disk
+// before the file is renamed into place. Without it a crash soon after the
+// rename could leave the key pointing at an empty or partial file.
+func writeFileSync(name string, data []byte, perm os.FileMode) error {
+    f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        f.Close()
+        return err
+    }
+    if err := f.Sync(); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// syncDir fsyncs dir so renames into it survive a crash. Some platforms
+// can't sync a directory; there the rename is as durable as it gets.
+func syncDir(dir string) error {
+    d, err := os.Open(dir)
+    if err != nil {
+        return err
+    }
+    defer d.Close()
+    if err := d.Sync(); err != nil && !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, fs.ErrInvalid) {
+        return err
+    }
+    return nil
+}
+
+// path returns the backing file for a validated key.
+func (s *fileStore) path(key string) string {
+    if s.sharded {
+        return filepath.Join(s.dir, shardName(key), key)
+    }
+    return filepath.Join(s.dir, key)
+}
+
+// keyDir returns the directory holding key's backing file, which is synced
+// after the file is renamed.
+func (s *fileStore) keyDir(key string) string {
+    return filepath.Dir(s.path(key))
+}
+
+// prepare creates key's shard directory the first time a key lands in it.
+// Flat stores have nothing to create.
+func (s *fileStore) prepare(key string) error {
+    if !s.sharded {
+        return nil
+    }
+    return makeShard(s.dir, shardName(key))
+}
+
+// Get reads in the background so a stalled filesystem can't hold the caller
+// past ctx's deadline.
+func (s *fileStore) Get(ctx context.Context, key string) ([]byte, error) {
+    type result struct {
+        value []byte
+        err   error
+    }
+    done := make(chan result, 1)
+    go func() {
+        value, err := os.ReadFile(s.path(key))
+        done <- result{value, err}
+    }()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    case r := <-done:
+        if errors.Is(r.err, fs.ErrNotExist) {
+            return nil, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+        }
+        return r.value, r.err
+    }
+}
+
+// Put writes and fsyncs value to a temporary file in the background and
+// only renames it over the key if ctx is still live, so a cancelled request
+// never changes the stored value and a crash leaves either the old value or
+// the new one, never a torn write.
+func (s *fileStore) Put(ctx context.Context, key string, value []byte) error {
+    tmp, err := s.createTemp("put-*")
+    if err != nil {
+        return err
+    }
+
+    done := make(chan error, 1)
+    go func() {
+        done <- s.writeFile(tmp, value, 0644)
+    }()
+
+    select {
+    case <-ctx.Done():
+        // Clean up once the abandoned write finishes
+        go func() {
+            <-done
+            os.Remove(tmp)
+        }()
+        return ctx.Err()
+    case err := <-done:
+        if err == nil {
+            err = ctx.Err()
+        }
+        if err == nil {
+            err = s.prepare(key)
+        }
+        if err == nil {
+            err = os.Rename(tmp, s.path(key))
+        }
+        if err != nil {
+            os.Remove(tmp)
+            return err
+        }
+        return syncDir(s.keyDir(key))
+    }
+}
+
+// Create claims the key's file with O_EXCL, so it fails if the file exists
+// even when another process shares the directory, then writes and fsyncs
+// value. A failed write removes the file again.
+func (s *fileStore) Create(ctx context.Context, key string, value []byte) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    if err := s.prepare(key); err != nil {
+        return err
+    }
+    f, err := os.OpenFile(s.path(key), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+    if errors.Is(err, fs.ErrExist) {
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, key)
+    }
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(value)
+    if err == nil {
+        err = f.Sync()
+    }
+    if closeErr := f.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        os.Remove(s.path(key))
+        return err
+    }
+    return syncDir(s.keyDir(key))
+}
+
+// Rename writes and fsyncs value to a temporary file, moves it under
+// newKey, then removes oldKey's file. Without overwrite the temporary file
+// is linked under its new name, which fails if that name is taken even when
+// another process shares the directory. A crash between the two steps
+// leaves both keys, never neither.
+func (s *fileStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    if _, err := os.Stat(s.path(oldKey)); errors.Is(err, fs.ErrNotExist) {
+        return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, oldKey)
+    } else if err != nil {
+        return err
+    }
+
+    tmp, err := s.createTemp("rename-*")
+    if err != nil {
+        return err
+    }
+    // Once moved or linked into place, removing the temporary name is
+    // either a no-op or drops the extra link
+    defer os.Remove(tmp)
+    if err := s.writeFile(tmp, value, 0644); err != nil {
+        return err
+    }
+    if err := s.prepare(newKey); err != nil {
+        return err
+    }
+    if overwrite {
+        err = os.Rename(tmp, s.path(newKey))
+    } else {
+        err = os.Link(tmp, s.path(newKey))
+    }
+    switch {
+    case errors.Is(err, fs.ErrExist):
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, newKey)
+    case err != nil:
+        return err
+    }
+    if err := os.Remove(s.path(oldKey)); err != nil {
+        if !overwrite {
+            os.Remove(s.path(newKey))
+        }
+        return err
+    }
+    if err := syncDir(s.keyDir(oldKey)); err != nil {
+        return err
+    }
+    return syncDir(s.keyDir(newKey))
+}
+
+// createTemp makes an empty file in the temp directory and returns its path.
+func (s *fileStore) createTemp(pattern string) (string, error) {
+    tmpDir := filepath.Join(s.dir, fileStoreTempDir)
+    if err := os.MkdirAll(tmpDir, 0700); err != nil {
+        return "", err
+    }
+    tmp, err := os.CreateTemp(tmpDir, pattern)
+    if err != nil {
+        return "", err
+    }
+    // Keep the permissions os.WriteFile would give a new value file
+    err = tmp.Chmod(0644)
+    tmp.Close()
+    if err != nil {
+        os.Remove(tmp.Name())
+        return "", err
+    }
+    return tmp.Name(), nil
+}
+
+// Commit stages every new value in a temp file, then swaps them in one key
+// at a time. Each existing file is first moved aside, so a failed rename
+// can put back everything swapped so far. The KV lock keeps readers from
+// seeing the swap half done.
+func (s *fileStore) Commit(ctx context.Context, writes []storeWrite) error {
+    staged := make([]string, len(writes))
+    defer func() {
+        for _, tmp := range staged {
+            if tmp != "" {
+                os.Remove(tmp)
+            }
+        }
+    }()
+    for i, w := range writes {
+        if w.delete {
+            continue
+        }
+        tmp, err := s.createTemp("tx-*")
+        if err != nil {
+            return err
+        }
+        staged[i] = tmp
+        if err := s.writeFile(tmp, w.value, 0644); err != nil {
+            return err
+        }
+    }
+    // Last chance to back out before anything visible changes
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    type swapped struct {
+        key    string
+        backup string
+        placed bool
+    }
+    var done []swapped
+    rollback := func() {
+        for i := len(done) - 1; i >= 0; i-- {
+            if done[i].placed {
+                os.Remove(s.path(done[i].key))
+            }
+            if done[i].backup != "" {
+                os.Rename(done[i].backup, s.path(done[i].key))
+            }
+        }
+    }
+
+    for i, w := range writes {
+        step := swapped{key: w.key}
+        if _, err := os.Lstat(s.path(w.key)); err == nil {
+            backup, err := s.createTemp("tx-old-*")
+            if err == nil {
+                err = os.Rename(s.path(w.key), backup)
+            }
+            if err != nil {
+                os.Remove(backup)
+                rollback()
+                return err
+            }
+            step.backup = backup
+        } else if w.delete {
+            rollback()
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, w.key)
+        }
+        if !w.delete {
+            err := s.prepare(w.key)
+            if err == nil {
+                err = os.Rename(staged[i], s.path(w.key))
+            }
+            if err != nil {
+                done = append(done, step)
+                rollback()
+                return err
+            }
+            staged[i] = ""
+            step.placed = true
+        }
+        done = append(done, step)
+    }
+
+    synced := map[string]bool{}
+    for _, step := range done {
+        if step.backup != "" {
+            os.Remove(step.backup)
+        }
+        if dir := s.keyDir(step.key); !synced[dir] {
+            if err := syncDir(dir); err != nil {
+                return err
+            }
+            synced[dir] = true
+        }
+    }
+    return nil
+}
+
+func (s *fileStore) Delete(ctx context.Context, key string) error {
+    if err := os.Remove(s.path(key)); err != nil {
+        if errors.Is(err, fs.ErrNotExist) {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+        }
+        return err
+    }
+    return nil
+}
+
+func (s *fileStore) List(ctx context.Context, prefix string) ([]string, error) {
+    dirs := []string{s.dir}
+    if s.sharded {
+        shards, err := listShards(s.dir)
+        if err != nil {
+            return nil, err
+        }
+        dirs = shards
+    }
+
+    keys := []string{}
+    for _, dir := range dirs {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+            return nil, err
+        }
+        for _, entry := range entries {
+            if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), prefix) {
+                keys = append(keys, entry.Name())
+            }
+        }
+    }
+    sort.Strings(keys)
+    return keys, nil
+}
+
+func (s *fileStore) Exists(ctx context.Context, key string) (bool, error) {
+    info, err := os.Stat(s.path(key))
+    if errors.Is(err, fs.ErrNotExist) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    return info.Mode().IsRegular(), nil
+}
+
+// ModTime is the backing file's modification time, which every Put and
+// Commit sets by renaming a new file into place.
+func (s *fileStore) ModTime(ctx context.Context, key string) (time.Time, error) {
+    info, err := os.Stat(s.path(key))
+    if errors.Is(err, fs.ErrNotExist) {
+        return time.Time{}, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    if err != nil {
+        return time.Time{}, err
+    }
+    return info.ModTime(), nil
+}
+
+// resolveDataDir returns the directory backing the file store, taken from
+// PLUGIN_KV_DATA_DIR or, when unset, a fresh per-process temporary directory.
+func resolveDataDir() (string, error) {
+    dataDir := os.Getenv("PLUGIN_KV_DATA_DIR")
+    if dataDir == "" {
+        return os.MkdirTemp("", "kv-data-")
+    }
+
+    if err := os.MkdirAll(dataDir, 0700); err != nil {
+        return "", fmt.Errorf("creating data directory %q: %w", dataDir, err)
+    }
+    return dataDir, nil
+}
+
+// newStoreFromEnv builds the Store selected by PLUGIN_KV_BACKEND, encrypting
+// values when PLUGIN_KV_ENCRYPTION_KEY is set, compressing them when
+// PLUGIN_KV_COMPRESS_AT_REST is and caching them in memory when
+// PLUGIN_KV_CACHE_ENTRIES is. A bad setting fails before any storage is
+// touched.
+func newStoreFromEnv(logger hclog.Logger) (Store, error) {
+    key, err := encryptionKeyFromEnv()
+    if err != nil {
+        return nil, err
+    }
+    compress, err := compressAtRestFromEnv()
+    if err != nil {
+        return nil, err
+    }
+    cacheEntries, err := cacheEntriesFromEnv()
+    if err != nil {
+        return nil, err
+    }
+    store, err := openStoreBackend(logger)
+    if err != nil {
+        return nil, err
+    }
+    _, inMemory := store.(*memStore)
+
+    if key != nil {
+        logger.Info("🗄️🔒 encrypting values at rest with AES-256-GCM")
+        encrypted, err := newEncryptedStore(store, key)
+        if err != nil {
+            closeStore(store)
+            return nil, err
+        }
+        store = encrypted
+    }
+    // Outside encryption, as ciphertext doesn't compress. Persistent
+    // backends always decode, so values compressed by an earlier run still
+    // read once it's turned off
+    if compress {
+        logger.Info("🗄️🗜️ compressing values at rest with gzip")
+    }
+    if compress || !inMemory {
+        store = newCompressedStore(store, compress)
+    }
+    // Outermost, so cache hits skip decryption too
+    if cacheEntries > 0 {
+        logger.Info("🗄️⚡ caching recently used values", "entries", cacheEntries)
+        store = newCachedStore(store, cacheEntries)
+    }
+    return store, nil
+}
+
+// openStoreBackend opens the Store selected by PLUGIN_KV_BACKEND
+// ("file", the default, "memory" or "bolt"). The file backend uses the
+// sharded layout when PLUGIN_KV_SHARDED is set, first moving any values a
+// flat store left behind into it.
+func openStoreBackend(logger hclog.Logger) (Store, error) {
+    backend := strings.ToLower(os.Getenv("PLUGIN_KV_BACKEND"))
+    switch backend {
+    case "", "file":
+        sharded, err := shardedFromEnv()
+        if err != nil {
+            return nil, err
+        }
+        dataDir, err := resolveDataDir()
+        if err != nil {
+            return nil, err
+        }
+        if !sharded {
+            logger.Info("🗄️📁 using file backend", "path", dataDir)
+            return newFileStore(dataDir), nil
+        }
+        migrated, err := migrateToSharded(dataDir)
+        if err != nil {
+            return nil, fmt.Errorf("migrating %q to the sharded layout: %w", dataDir, err)
+        }
+        if migrated > 0 {
+            logger.Info("🗄️🚚 moved values into the sharded layout", "values", migrated)
+        }
+        logger.Info("🗄️📁 using sharded file backend", "path", dataDir)
+        return newShardedFileStore(dataDir), nil
+    case "memory":
+        logger.Info("🗄️🧠 using in-memory backend; data will not survive a restart")
+        return newMemStore(), nil
+    case "bolt":
+        path := os.Getenv("PLUGIN_KV_BOLT_PATH")
+        if path == "" {
+            return nil, fmt.Errorf("PLUGIN_KV_BOLT_PATH must be set when PLUGIN_KV_BACKEND=bolt")
+        }
+        logger.Info("🗄️🔩 using bolt backend", "path", path)
+        return newBoltStore(path)
+    default:
+        return nil, fmt.Errorf("unknown PLUGIN_KV_BACKEND %q (use 'file', 'memory' or 'bolt')", backend)
+    }
+}
+
+// closeStore releases any resources held by store, such as a database handle.
+func closeStore(store Store) error {
+    if closer, ok := store.(io.Closer); ok {
+        return closer.Close()
+    }
+    return nil
+}
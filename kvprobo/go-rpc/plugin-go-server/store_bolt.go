@@ -0,0 +1,197 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_bolt.go
+
+package main
+
+import (
+    "context"
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// boltBucket holds every key written by boltStore.
+var boltBucket = []byte("kv")
+
+// boltModTimeBucket holds when each key was last written, as a big-endian
+// UnixNano. Keys written before it existed have no entry.
+var boltModTimeBucket = []byte("kv-mtime")
+
+// boltStore keeps all values in a single bucket of a BoltDB file, giving
+// durable storage without one file per key.
+type boltStore struct {
+    db *bolt.DB
+}
+
+func newBoltStore(path string) (*boltStore, error) {
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("opening bolt database %q: %w", path, err)
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        if _, err := tx.CreateBucketIfNotExists(boltBucket); err != nil {
+            return err
+        }
+        _, err := tx.CreateBucketIfNotExists(boltModTimeBucket)
+        return err
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("creating bolt bucket: %w", err)
+    }
+    return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Get(ctx context.Context, key string) ([]byte, error) {
+    var value []byte
+    err := s.db.View(func(tx *bolt.Tx) error {
+        v := tx.Bucket(boltBucket).Get([]byte(key))
+        if v == nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+        }
+        // Bolt values are only valid for the life of the transaction.
+        value = append([]byte{}, v...)
+        return nil
+    })
+    return value, err
+}
+
+func (s *boltStore) Put(ctx context.Context, key string, value []byte) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        if err := tx.Bucket(boltBucket).Put([]byte(key), value); err != nil {
+            return err
+        }
+        return touchBoltKey(tx, key, time.Now())
+    })
+}
+
+func (s *boltStore) Create(ctx context.Context, key string, value []byte) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(boltBucket)
+        if bucket.Get([]byte(key)) != nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyExists, key)
+        }
+        if err := bucket.Put([]byte(key), value); err != nil {
+            return err
+        }
+        return touchBoltKey(tx, key, time.Now())
+    })
+}
+
+// Rename writes newKey and deletes oldKey in one transaction.
+func (s *boltStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(boltBucket)
+        if bucket.Get([]byte(oldKey)) == nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, oldKey)
+        }
+        if !overwrite && bucket.Get([]byte(newKey)) != nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyExists, newKey)
+        }
+        if err := bucket.Put([]byte(newKey), value); err != nil {
+            return err
+        }
+        if err := bucket.Delete([]byte(oldKey)); err != nil {
+            return err
+        }
+        if err := touchBoltKey(tx, newKey, time.Now()); err != nil {
+            return err
+        }
+        return tx.Bucket(boltModTimeBucket).Delete([]byte(oldKey))
+    })
+}
+
+// touchBoltKey records that key was written at now.
+func touchBoltKey(tx *bolt.Tx, key string, now time.Time) error {
+    return tx.Bucket(boltModTimeBucket).Put([]byte(key), binary.BigEndian.AppendUint64(nil, uint64(now.UnixNano())))
+}
+
+func (s *boltStore) Delete(ctx context.Context, key string) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(boltBucket)
+        if bucket.Get([]byte(key)) == nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+        }
+        if err := bucket.Delete([]byte(key)); err != nil {
+            return err
+        }
+        return tx.Bucket(boltModTimeBucket).Delete([]byte(key))
+    })
+}
+
+func (s *boltStore) Exists(ctx context.Context, key string) (bool, error) {
+    exists := false
+    err := s.db.View(func(tx *bolt.Tx) error {
+        exists = tx.Bucket(boltBucket).Get([]byte(key)) != nil
+        return nil
+    })
+    return exists, err
+}
+
+func (s *boltStore) ModTime(ctx context.Context, key string) (time.Time, error) {
+    var modTime time.Time
+    err := s.db.View(func(tx *bolt.Tx) error {
+        if tx.Bucket(boltBucket).Get([]byte(key)) == nil {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+        }
+        if v := tx.Bucket(boltModTimeBucket).Get([]byte(key)); len(v) == 8 {
+            modTime = time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+        }
+        return nil
+    })
+    return modTime, err
+}
+
+func (s *boltStore) List(ctx context.Context, prefix string) ([]string, error) {
+    keys := []string{}
+    err := s.db.View(func(tx *bolt.Tx) error {
+        // Bolt iterates in byte order, so the result is already sorted.
+        c := tx.Bucket(boltBucket).Cursor()
+        p := []byte(prefix)
+        for k, _ := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = c.Next() {
+            keys = append(keys, string(k))
+        }
+        return nil
+    })
+    return keys, err
+}
+
+// Commit applies writes in a single bolt transaction, which bolt rolls
+// back if any of them fails.
+func (s *boltStore) Commit(ctx context.Context, writes []storeWrite) error {
+    now := time.Now()
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(boltBucket)
+        for _, w := range writes {
+            if !w.delete {
+                if err := bucket.Put([]byte(w.key), w.value); err != nil {
+                    return err
+                }
+                if err := touchBoltKey(tx, w.key, now); err != nil {
+                    return err
+                }
+                continue
+            }
+            if bucket.Get([]byte(w.key)) == nil {
+                return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, w.key)
+            }
+            if err := bucket.Delete([]byte(w.key)); err != nil {
+                return err
+            }
+            if err := tx.Bucket(boltModTimeBucket).Delete([]byte(w.key)); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// Close releases the database file lock.
+func (s *boltStore) Close() error {
+    return s.db.Close()
+}
@@ -0,0 +1,175 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_cached.go
+
+package main
+
+import (
+    "container/list"
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "sync"
+)
+
+// cachedStore keeps the most recently used values of the wrapped Store in
+// memory, so reads of hot keys don't go to disk. Writes go through to the
+// wrapped Store first and only then update the cache, so the cache never
+// holds a value the Store doesn't. Missing keys aren't cached.
+//
+// A read that misses fills the cache after reading the Store; KV's per-key
+// locks keep a write to the same key from landing in between.
+type cachedStore struct {
+    Store
+
+    mu       sync.Mutex
+    capacity int
+    order    *list.List // of *cacheEntry, most recently used first
+    entries  map[string]*list.Element
+}
+
+type cacheEntry struct {
+    key   string
+    value []byte
+}
+
+// newCachedStore caches up to capacity values of store.
+func newCachedStore(store Store, capacity int) *cachedStore {
+    return &cachedStore{
+        Store:    store,
+        capacity: capacity,
+        order:    list.New(),
+        entries:  make(map[string]*list.Element),
+    }
+}
+
+// cacheEntriesFromEnv reads PLUGIN_KV_CACHE_ENTRIES, how many values to
+// keep in memory. It returns 0, which disables the cache, when unset.
+func cacheEntriesFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_CACHE_ENTRIES")
+    if value == "" {
+        return 0, nil
+    }
+    entries, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_CACHE_ENTRIES %q: %w", value, err)
+    }
+    if entries < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_CACHE_ENTRIES must not be negative, got %d", entries)
+    }
+    return entries, nil
+}
+
+// lookup returns a copy of key's cached value and marks it recently used.
+func (s *cachedStore) lookup(key string) ([]byte, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    elem, ok := s.entries[key]
+    if !ok {
+        return nil, false
+    }
+    s.order.MoveToFront(elem)
+    return append([]byte(nil), elem.Value.(*cacheEntry).value...), true
+}
+
+// remember caches a copy of value for key, evicting the least recently
+// used value if the cache is full.
+func (s *cachedStore) remember(key string, value []byte) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    value = append([]byte(nil), value...)
+    if elem, ok := s.entries[key]; ok {
+        elem.Value.(*cacheEntry).value = value
+        s.order.MoveToFront(elem)
+        return
+    }
+    s.entries[key] = s.order.PushFront(&cacheEntry{key: key, value: value})
+    if s.order.Len() > s.capacity {
+        oldest := s.order.Back()
+        s.order.Remove(oldest)
+        delete(s.entries, oldest.Value.(*cacheEntry).key)
+    }
+}
+
+// forget drops key from the cache.
+func (s *cachedStore) forget(key string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if elem, ok := s.entries[key]; ok {
+        s.order.Remove(elem)
+        delete(s.entries, key)
+    }
+}
+
+func (s *cachedStore) Get(ctx context.Context, key string) ([]byte, error) {
+    if value, ok := s.lookup(key); ok {
+        return value, nil
+    }
+    value, err := s.Store.Get(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+    s.remember(key, value)
+    return value, nil
+}
+
+func (s *cachedStore) Put(ctx context.Context, key string, value []byte) error {
+    if err := s.Store.Put(ctx, key, value); err != nil {
+        // The write may have landed anyway, so don't trust the old value
+        s.forget(key)
+        return err
+    }
+    s.remember(key, value)
+    return nil
+}
+
+func (s *cachedStore) Create(ctx context.Context, key string, value []byte) error {
+    if err := s.Store.Create(ctx, key, value); err != nil {
+        s.forget(key)
+        return err
+    }
+    s.remember(key, value)
+    return nil
+}
+
+func (s *cachedStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    defer s.forget(newKey)
+    defer s.forget(oldKey)
+    return s.Store.Rename(ctx, oldKey, newKey, value, overwrite)
+}
+
+func (s *cachedStore) Delete(ctx context.Context, key string) error {
+    defer s.forget(key)
+    return s.Store.Delete(ctx, key)
+}
+
+func (s *cachedStore) Exists(ctx context.Context, key string) (bool, error) {
+    if _, ok := s.lookup(key); ok {
+        return true, nil
+    }
+    return s.Store.Exists(ctx, key)
+}
+
+func (s *cachedStore) Commit(ctx context.Context, writes []storeWrite) error {
+    if err := s.Store.Commit(ctx, writes); err != nil {
+        for _, w := range writes {
+            s.forget(w.key)
+        }
+        return err
+    }
+    for _, w := range writes {
+        if w.delete {
+            s.forget(w.key)
+        } else {
+            s.remember(w.key, w.value)
+        }
+    }
+    return nil
+}
+
+// Close closes the wrapped Store, if it needs closing.
+func (s *cachedStore) Close() error {
+    return closeStore(s.Store)
+}
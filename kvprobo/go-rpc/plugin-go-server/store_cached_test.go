@@ -0,0 +1,117 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_cached_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestCachedStoreServesRepeatReadsFromMemory(t *testing.T) {
+    ctx := context.Background()
+    backing := newFakeStore()
+    backing.data["hot"] = []byte("value")
+    store := newCachedStore(backing, 4)
+
+    for i := 0; i < 2; i++ {
+        if value, err := store.Get(ctx, "hot"); err != nil || string(value) != "value" {
+            t.Fatalf("Get(hot) = %q, %v; want value", value, err)
+        }
+    }
+    if backing.calls != 1 {
+        t.Fatalf("two Gets made %d store calls, want 1", backing.calls)
+    }
+
+    // Callers get their own copy
+    value, _ := store.Get(ctx, "hot")
+    value[0] = 'X'
+    if value, _ := store.Get(ctx, "hot"); string(value) != "value" {
+        t.Fatalf("changing a returned value changed the cache to %q", value)
+    }
+}
+
+func TestCachedStoreWritesThrough(t *testing.T) {
+    ctx := context.Background()
+    backing := newFakeStore()
+    store := newCachedStore(backing, 4)
+
+    value := []byte("one")
+    if err := store.Put(ctx, "k", value); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, ok := backing.data["k"]; !ok {
+        t.Fatalf("Put didn't reach the store")
+    }
+    value[0] = 'X'
+    calls := backing.calls
+    if got, err := store.Get(ctx, "k"); err != nil || string(got) != "one" {
+        t.Fatalf("Get after Put = %q, %v; want one, unaffected by the caller's buffer", got, err)
+    }
+    if backing.calls != calls {
+        t.Fatalf("Get after Put went to the store")
+    }
+
+    if err := store.Delete(ctx, "k"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if _, err := store.Get(ctx, "k"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get after Delete = %v, want ErrKeyNotFound", err)
+    }
+
+    if err := store.Commit(ctx, []storeWrite{{key: "a", value: []byte("1")}, {key: "b", value: []byte("2")}}); err != nil {
+        t.Fatalf("Commit failed: %v", err)
+    }
+    calls = backing.calls
+    if got, err := store.Get(ctx, "b"); err != nil || string(got) != "2" || backing.calls != calls {
+        t.Fatalf("Get after Commit = %q, %v with %d store calls; want 2 from the cache", got, err, backing.calls-calls)
+    }
+
+    // A failed write leaves nothing stale behind
+    backing.failOn = map[string]error{"a": errors.New("disk full")}
+    if err := store.Put(ctx, "a", []byte("3")); err == nil {
+        t.Fatalf("Put to a failing store succeeded")
+    }
+    if _, ok := store.lookup("a"); ok {
+        t.Fatalf("failed Put left a cached value")
+    }
+}
+
+func TestCachedStoreEvictsLeastRecentlyUsed(t *testing.T) {
+    ctx := context.Background()
+    store := newCachedStore(newFakeStore(), 2)
+    for _, key := range []string{"a", "b"} {
+        if err := store.Put(ctx, key, []byte(key)); err != nil {
+            t.Fatalf("Put(%s) failed: %v", key, err)
+        }
+    }
+    store.Get(ctx, "a")
+    store.Put(ctx, "c", []byte("c"))
+
+    for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+        if _, ok := store.lookup(key); ok != want {
+            t.Errorf("%s cached = %v, want %v", key, ok, want)
+        }
+    }
+}
+
+func TestNewStoreFromEnvCache(t *testing.T) {
+    t.Setenv("PLUGIN_KV_BACKEND", "memory")
+    t.Setenv("PLUGIN_KV_CACHE_ENTRIES", "16")
+    store, err := newStoreFromEnv(hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newStoreFromEnv failed: %v", err)
+    }
+    if cached, ok := store.(*cachedStore); !ok || cached.capacity != 16 {
+        t.Fatalf("newStoreFromEnv returned %T, want a *cachedStore of 16 entries", store)
+    }
+
+    t.Setenv("PLUGIN_KV_CACHE_ENTRIES", "-1")
+    if _, err := newStoreFromEnv(hclog.NewNullLogger()); err == nil {
+        t.Fatal("newStoreFromEnv accepted a negative cache size")
+    }
+}
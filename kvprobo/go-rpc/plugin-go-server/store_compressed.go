@@ -0,0 +1,153 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_compressed.go
+
+package main
+
+import (
+    "bytes"
+    "compress/gzip"
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+// atRestMagic starts every value compressedStore encodes, followed by one
+// byte naming the codec. Values written before compression was enabled
+// don't start with it, so they are returned as they are.
+const atRestMagic = "\x00kvz"
+
+// Codec bytes following atRestMagic.
+const (
+    atRestCodecNone byte = 0
+    atRestCodecGzip byte = 1
+)
+
+// compressedStore gzips values before they reach the wrapped Store and
+// expands them again on the way out. A value that doesn't shrink is stored
+// as it is, so incompressible data costs nothing extra. It decodes values
+// even when compress is false, so data written while compression was on
+// stays readable after it is turned off.
+type compressedStore struct {
+    Store
+    compress bool
+}
+
+func newCompressedStore(store Store, compress bool) *compressedStore {
+    return &compressedStore{Store: store, compress: compress}
+}
+
+// compressAtRestFromEnv reads PLUGIN_KV_COMPRESS_AT_REST, "gzip" or
+// "none". It reports false when unset.
+func compressAtRestFromEnv() (bool, error) {
+    switch value := strings.ToLower(os.Getenv("PLUGIN_KV_COMPRESS_AT_REST")); value {
+    case "", "none":
+        return false, nil
+    case "gzip":
+        return true, nil
+    default:
+        return false, fmt.Errorf("unsupported PLUGIN_KV_COMPRESS_AT_REST %q (use \"gzip\" or \"none\")", value)
+    }
+}
+
+// encode returns value as it should be stored.
+func (s *compressedStore) encode(value []byte) ([]byte, error) {
+    if s.compress {
+        var buf bytes.Buffer
+        buf.WriteString(atRestMagic)
+        buf.WriteByte(atRestCodecGzip)
+        zw := gzip.NewWriter(&buf)
+        if _, err := zw.Write(value); err != nil {
+            return nil, err
+        }
+        if err := zw.Close(); err != nil {
+            return nil, err
+        }
+        if buf.Len() < len(value) {
+            return buf.Bytes(), nil
+        }
+    }
+    // A raw value that happens to start with the magic needs a header too,
+    // or it would be mistaken for an encoded one
+    if bytes.HasPrefix(value, []byte(atRestMagic)) {
+        return append([]byte(atRestMagic+string(atRestCodecNone)), value...), nil
+    }
+    return value, nil
+}
+
+// decode reverses encode for key's stored value.
+func (s *compressedStore) decode(key string, stored []byte) ([]byte, error) {
+    if !bytes.HasPrefix(stored, []byte(atRestMagic)) || len(stored) == len(atRestMagic) {
+        return stored, nil
+    }
+    body := stored[len(atRestMagic)+1:]
+    switch codec := stored[len(atRestMagic)]; codec {
+    case atRestCodecNone:
+        return body, nil
+    case atRestCodecGzip:
+        zr, err := gzip.NewReader(bytes.NewReader(body))
+        if err != nil {
+            return nil, fmt.Errorf("decompressing %q: %w", key, err)
+        }
+        value, err := io.ReadAll(zr)
+        if err != nil {
+            return nil, fmt.Errorf("decompressing %q: %w", key, err)
+        }
+        return value, nil
+    default:
+        return nil, fmt.Errorf("%q is stored with unknown codec %d", key, codec)
+    }
+}
+
+func (s *compressedStore) Get(ctx context.Context, key string) ([]byte, error) {
+    stored, err := s.Store.Get(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+    return s.decode(key, stored)
+}
+
+func (s *compressedStore) Put(ctx context.Context, key string, value []byte) error {
+    encoded, err := s.encode(value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Put(ctx, key, encoded)
+}
+
+func (s *compressedStore) Create(ctx context.Context, key string, value []byte) error {
+    encoded, err := s.encode(value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Create(ctx, key, encoded)
+}
+
+func (s *compressedStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    encoded, err := s.encode(value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Rename(ctx, oldKey, newKey, encoded, overwrite)
+}
+
+func (s *compressedStore) Commit(ctx context.Context, writes []storeWrite) error {
+    encoded := make([]storeWrite, len(writes))
+    for i, w := range writes {
+        encoded[i] = w
+        if w.delete {
+            continue
+        }
+        value, err := s.encode(w.value)
+        if err != nil {
+            return err
+        }
+        encoded[i].value = value
+    }
+    return s.Store.Commit(ctx, encoded)
+}
+
+// Close closes the wrapped Store, if it needs closing.
+func (s *compressedStore) Close() error {
+    return closeStore(s.Store)
+}
@@ -0,0 +1,103 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_compressed_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestCompressedStoreRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    kv := NewKV(newCompressedStore(newFileStore(dir), true), nil)
+
+    text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 200))
+    random := make([]byte, 4096)
+    if _, err := rand.Read(random); err != nil {
+        t.Fatalf("rand.Read failed: %v", err)
+    }
+    // A raw value that looks like an encoded one must survive as well
+    lookalike := []byte(atRestMagic + "\x01not gzip")
+
+    if err := kv.Put(ctx, "text", text); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.Transaction(ctx, []shared.TxOp{
+        {Kind: shared.TxPut, Key: "random", Value: random},
+        {Kind: shared.TxPut, Key: "lookalike", Value: lookalike},
+    }); err != nil {
+        t.Fatalf("Transaction failed: %v", err)
+    }
+
+    for key, want := range map[string][]byte{"text": text, "random": random, "lookalike": lookalike} {
+        value, err := kv.Get(ctx, key)
+        if err != nil || !bytes.Equal(value, want) {
+            t.Fatalf("Get(%q) = %d bytes, %v; want the %d bytes written", key, len(value), err, len(want))
+        }
+    }
+
+    onDisk := func(key string) []byte {
+        data, err := os.ReadFile(filepath.Join(dir, key))
+        if err != nil {
+            t.Fatalf("reading %q from disk failed: %v", key, err)
+        }
+        return data
+    }
+    if stored := onDisk("text"); !bytes.HasPrefix(stored, []byte(atRestMagic+"\x01")) || len(stored) > len(text)/10 {
+        t.Fatalf("compressible value of %d bytes stored as %d bytes, want it gzipped behind the header", len(text), len(stored))
+    }
+    if stored := onDisk("random"); !bytes.Equal(stored, random) {
+        t.Fatalf("incompressible value wasn't stored as it is (%d bytes on disk)", len(stored))
+    }
+}
+
+func TestCompressedStoreReadsUncompressedData(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    // Written before compression was turned on
+    if err := os.WriteFile(filepath.Join(dir, "old"), []byte("plain value"), 0600); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    if err := NewKV(newCompressedStore(newFileStore(dir), true), nil).Put(ctx, "new", bytes.Repeat([]byte("z"), 1000)); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    // Reads work with compression on, and after it's turned off again
+    for _, compress := range []bool{true, false} {
+        kv := NewKV(newCompressedStore(newFileStore(dir), compress), nil)
+        if value, err := kv.Get(ctx, "old"); err != nil || string(value) != "plain value" {
+            t.Fatalf("Get(old) with compress=%t = %q, %v", compress, value, err)
+        }
+        if value, err := kv.Get(ctx, "new"); err != nil || !bytes.Equal(value, bytes.Repeat([]byte("z"), 1000)) {
+            t.Fatalf("Get(new) with compress=%t = %d bytes, %v", compress, len(value), err)
+        }
+    }
+}
+
+func TestCompressAtRestFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_BACKEND", "file")
+    t.Setenv("PLUGIN_KV_DATA_DIR", t.TempDir())
+    t.Setenv("PLUGIN_KV_COMPRESS_AT_REST", "gzip")
+    store, err := newStoreFromEnv(hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newStoreFromEnv failed: %v", err)
+    }
+    if compressed, ok := store.(*compressedStore); !ok || !compressed.compress {
+        t.Fatalf("newStoreFromEnv returned %T, want a compressing *compressedStore", store)
+    }
+
+    t.Setenv("PLUGIN_KV_COMPRESS_AT_REST", "zstd")
+    if _, err := newStoreFromEnv(hclog.NewNullLogger()); err == nil {
+        t.Fatal("newStoreFromEnv accepted an unknown codec")
+    }
+}
@@ -0,0 +1,132 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_encrypted.go
+
+package main
+
+import (
+    "context"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "os"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// encryptionKeySize is the AES-256 key length PLUGIN_KV_ENCRYPTION_KEY must decode to.
+const encryptionKeySize = 32
+
+// encryptedStore seals every value with AES-256-GCM before it reaches the
+// wrapped Store. Each value gets a random nonce, stored in front of the
+// ciphertext, and is bound to its key so values can't be swapped between
+// keys on disk.
+type encryptedStore struct {
+    Store
+    aead cipher.AEAD
+}
+
+func newEncryptedStore(store Store, key []byte) (*encryptedStore, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    aead, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+    return &encryptedStore{Store: store, aead: aead}, nil
+}
+
+// encryptionKeyFromEnv decodes PLUGIN_KV_ENCRYPTION_KEY, 64 hex digits. It
+// returns nil when the variable is unset, which leaves values unencrypted.
+func encryptionKeyFromEnv() ([]byte, error) {
+    value, ok := os.LookupEnv("PLUGIN_KV_ENCRYPTION_KEY")
+    if !ok {
+        return nil, nil
+    }
+    key, err := hex.DecodeString(value)
+    if err != nil {
+        return nil, fmt.Errorf("PLUGIN_KV_ENCRYPTION_KEY is not valid hex: %w", err)
+    }
+    if len(key) != encryptionKeySize {
+        return nil, fmt.Errorf("PLUGIN_KV_ENCRYPTION_KEY must be %d bytes (%d hex digits), got %d bytes",
+            encryptionKeySize, 2*encryptionKeySize, len(key))
+    }
+    return key, nil
+}
+
+func (s *encryptedStore) seal(key string, value []byte) ([]byte, error) {
+    nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(value)+s.aead.Overhead())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+    return s.aead.Seal(nonce, nonce, value, []byte(key)), nil
+}
+
+func (s *encryptedStore) open(key string, sealed []byte) ([]byte, error) {
+    if len(sealed) < s.aead.NonceSize() {
+        return nil, fmt.Errorf("%w: %q is too short to be an encrypted value", shared.ErrDecryptionFailed, key)
+    }
+    nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+    value, err := s.aead.Open(nil, nonce, ciphertext, []byte(key))
+    if err != nil {
+        return nil, fmt.Errorf("%w: %q was written with a different key or is corrupt", shared.ErrDecryptionFailed, key)
+    }
+    return value, nil
+}
+
+func (s *encryptedStore) Get(ctx context.Context, key string) ([]byte, error) {
+    sealed, err := s.Store.Get(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+    return s.open(key, sealed)
+}
+
+func (s *encryptedStore) Put(ctx context.Context, key string, value []byte) error {
+    sealed, err := s.seal(key, value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Put(ctx, key, sealed)
+}
+
+func (s *encryptedStore) Create(ctx context.Context, key string, value []byte) error {
+    sealed, err := s.seal(key, value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Create(ctx, key, sealed)
+}
+
+// Rename seals value for newKey, since a sealed value is bound to the key
+// it was sealed for.
+func (s *encryptedStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    sealed, err := s.seal(newKey, value)
+    if err != nil {
+        return err
+    }
+    return s.Store.Rename(ctx, oldKey, newKey, sealed, overwrite)
+}
+
+func (s *encryptedStore) Commit(ctx context.Context, writes []storeWrite) error {
+    sealed := make([]storeWrite, len(writes))
+    for i, w := range writes {
+        sealed[i] = w
+        if w.delete {
+            continue
+        }
+        value, err := s.seal(w.key, w.value)
+        if err != nil {
+            return err
+        }
+        sealed[i].value = value
+    }
+    return s.Store.Commit(ctx, sealed)
+}
+
+// Close closes the wrapped Store, if it needs closing.
+func (s *encryptedStore) Close() error {
+    return closeStore(s.Store)
+}
@@ -0,0 +1,135 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_encrypted_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func newTestEncryptedStore(t *testing.T, dir string, fill byte) *encryptedStore {
+    t.Helper()
+    store, err := newEncryptedStore(newFileStore(dir), bytes.Repeat([]byte{fill}, encryptionKeySize))
+    if err != nil {
+        t.Fatalf("newEncryptedStore failed: %v", err)
+    }
+    return store
+}
+
+func TestEncryptedStoreRename(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newTestEncryptedStore(t, t.TempDir(), 0x01), nil)
+    if err := kv.Put(ctx, "old", []byte("secret")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.Rename(ctx, "old", "new", false); err != nil {
+        t.Fatalf("Rename failed: %v", err)
+    }
+    // The value was sealed again for its new key
+    if value, err := kv.Get(ctx, "new"); err != nil || string(value) != "secret" {
+        t.Fatalf("Get(new) = %q, %v; want secret", value, err)
+    }
+    if exists, err := kv.Exists(ctx, "old"); err != nil || exists {
+        t.Fatalf("Exists(old) = %v, %v; want false", exists, err)
+    }
+}
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    kv := NewKV(newTestEncryptedStore(t, dir, 0x01), nil)
+    secret := []byte("correct horse battery staple")
+
+    if err := kv.Put(ctx, "password", secret); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := kv.Transaction(ctx, []shared.TxOp{{Kind: shared.TxPut, Key: "token", Value: secret}}); err != nil {
+        t.Fatalf("Transaction failed: %v", err)
+    }
+
+    for _, key := range []string{"password", "token"} {
+        value, err := kv.Get(ctx, key)
+        if err != nil || !bytes.Equal(value, secret) {
+            t.Fatalf("Get(%q) = %q, %v; want %q", key, value, err, secret)
+        }
+        onDisk, err := os.ReadFile(filepath.Join(dir, key))
+        if err != nil {
+            t.Fatalf("reading %q from disk failed: %v", key, err)
+        }
+        if bytes.Contains(onDisk, secret) {
+            t.Fatalf("%q is stored as plaintext: %q", key, onDisk)
+        }
+    }
+
+    // The same value encrypts differently each time
+    first, _ := os.ReadFile(filepath.Join(dir, "password"))
+    if err := kv.Put(ctx, "password", secret); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    second, _ := os.ReadFile(filepath.Join(dir, "password"))
+    if bytes.Equal(first, second) {
+        t.Fatalf("rewriting a value produced identical ciphertext; the nonce is not random")
+    }
+}
+
+func TestEncryptedStoreWrongKey(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    if err := newTestEncryptedStore(t, dir, 0x01).Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    client := serveKV(t, NewKV(newTestEncryptedStore(t, dir, 0x02), nil))
+    _, err := client.Get(ctx, "k")
+    if !errors.Is(err, shared.ErrDecryptionFailed) || !strings.Contains(err.Error(), "different key") {
+        t.Fatalf("Get with the wrong key = %v, want ErrDecryptionFailed naming a different key", err)
+    }
+
+    // A value moved to another key's file is rejected too
+    if err := os.Rename(filepath.Join(dir, "k"), filepath.Join(dir, "other")); err != nil {
+        t.Fatalf("Rename failed: %v", err)
+    }
+    if _, err := newTestEncryptedStore(t, dir, 0x01).Get(ctx, "other"); !errors.Is(err, shared.ErrDecryptionFailed) {
+        t.Fatalf("Get of a value moved between keys = %v, want ErrDecryptionFailed", err)
+    }
+}
+
+func TestEncryptionKeyFromEnv(t *testing.T) {
+    tests := []struct {
+        name    string
+        set     bool
+        value   string
+        wantKey bool
+        wantErr bool
+    }{
+        {"unset", false, "", false, false},
+        {"valid", true, strings.Repeat("ab", encryptionKeySize), true, false},
+        {"empty", true, "", false, true},
+        {"too short", true, strings.Repeat("ab", 16), false, true},
+        {"not hex", true, strings.Repeat("zz", encryptionKeySize), false, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // Setenv first so the original value is restored afterwards
+            t.Setenv("PLUGIN_KV_ENCRYPTION_KEY", tt.value)
+            if !tt.set {
+                os.Unsetenv("PLUGIN_KV_ENCRYPTION_KEY")
+            }
+            key, err := encryptionKeyFromEnv()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("encryptionKeyFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if (key != nil) != tt.wantKey {
+                t.Fatalf("encryptionKeyFromEnv() key = %x, want key %v", key, tt.wantKey)
+            }
+        })
+    }
+}
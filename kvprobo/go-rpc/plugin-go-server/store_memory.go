@@ -0,0 +1,143 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_memory.go
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// memStore keeps values in process memory. Nothing survives a restart, which
+// makes it handy for tests and quick experiments.
+type memStore struct {
+    mu       sync.RWMutex
+    data     map[string][]byte
+    modTimes map[string]time.Time
+}
+
+func newMemStore() *memStore {
+    return &memStore{data: make(map[string][]byte), modTimes: make(map[string]time.Time)}
+}
+
+func (s *memStore) Get(ctx context.Context, key string) ([]byte, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    value, ok := s.data[key]
+    if !ok {
+        return nil, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return append([]byte(nil), value...), nil
+}
+
+func (s *memStore) Put(ctx context.Context, key string, value []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    // Copy so later writes to the caller's buffer don't change the stored value.
+    s.data[key] = append([]byte(nil), value...)
+    s.modTimes[key] = time.Now()
+    return nil
+}
+
+func (s *memStore) Create(ctx context.Context, key string, value []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.data[key]; ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, key)
+    }
+    s.data[key] = append([]byte(nil), value...)
+    s.modTimes[key] = time.Now()
+    return nil
+}
+
+func (s *memStore) Rename(ctx context.Context, oldKey, newKey string, value []byte, overwrite bool) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.data[oldKey]; !ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, oldKey)
+    }
+    if _, ok := s.data[newKey]; ok && !overwrite {
+        return fmt.Errorf("%w: %q", shared.ErrKeyExists, newKey)
+    }
+    s.data[newKey] = append([]byte(nil), value...)
+    s.modTimes[newKey] = time.Now()
+    delete(s.data, oldKey)
+    delete(s.modTimes, oldKey)
+    return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.data[key]; !ok {
+        return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    delete(s.data, key)
+    delete(s.modTimes, key)
+    return nil
+}
+
+func (s *memStore) List(ctx context.Context, prefix string) ([]string, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    keys := []string{}
+    for key := range s.data {
+        if strings.HasPrefix(key, prefix) {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+    return keys, nil
+}
+
+func (s *memStore) Exists(ctx context.Context, key string) (bool, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    _, ok := s.data[key]
+    return ok, nil
+}
+
+func (s *memStore) ModTime(ctx context.Context, key string) (time.Time, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    modTime, ok := s.modTimes[key]
+    if !ok {
+        return time.Time{}, fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return modTime, nil
+}
+
+func (s *memStore) Commit(ctx context.Context, writes []storeWrite) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for _, w := range writes {
+        if _, ok := s.data[w.key]; w.delete && !ok {
+            return fmt.Errorf("%w: %q", shared.ErrKeyNotFound, w.key)
+        }
+    }
+    now := time.Now()
+    for _, w := range writes {
+        if w.delete {
+            delete(s.data, w.key)
+            delete(s.modTimes, w.key)
+        } else {
+            s.data[w.key] = append([]byte(nil), w.value...)
+            s.modTimes[w.key] = now
+        }
+    }
+    return nil
+}
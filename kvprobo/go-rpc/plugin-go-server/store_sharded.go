@@ -0,0 +1,168 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_sharded.go
+
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strconv"
+)
+
+// fileStoreMigrateDir holds flat values named like a shard directory while
+// migrateToSharded makes room for that directory. Like fileStoreTempDir it
+// contains "..", so it can never collide with a key or a shard.
+const fileStoreMigrateDir = "..kv-migrate"
+
+// shardedFromEnv reads PLUGIN_KV_SHARDED, which spreads the file backend's
+// values over subdirectories so no single directory grows huge. It reports
+// false when unset.
+func shardedFromEnv() (bool, error) {
+    value := os.Getenv("PLUGIN_KV_SHARDED")
+    if value == "" {
+        return false, nil
+    }
+    sharded, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid PLUGIN_KV_SHARDED %q: %w", value, err)
+    }
+    return sharded, nil
+}
+
+// shardName returns the subdirectory holding key in the sharded layout: the
+// first two hex digits of the key's SHA-256.
+func shardName(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:1])
+}
+
+// isShardName reports whether name could be a directory made by shardName.
+func isShardName(name string) bool {
+    if len(name) != 2 {
+        return false
+    }
+    for _, c := range name {
+        if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+            return false
+        }
+    }
+    return true
+}
+
+// makeShard creates the shard directory name in dir unless it exists, and
+// syncs dir so the new directory survives a crash.
+func makeShard(dir, name string) error {
+    err := os.Mkdir(filepath.Join(dir, name), 0700)
+    if errors.Is(err, fs.ErrExist) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    return syncDir(dir)
+}
+
+// listShards returns the paths of the shard directories in dir.
+func listShards(dir string) ([]string, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+    var shards []string
+    for _, entry := range entries {
+        if entry.IsDir() && isShardName(entry.Name()) {
+            shards = append(shards, filepath.Join(dir, entry.Name()))
+        }
+    }
+    return shards, nil
+}
+
+// migrateToSharded moves the values a flat file store left directly in dir
+// into their shards, and returns how many it moved. Files that can't be keys
+// are left where they are. It is safe to run again after being interrupted,
+// and does nothing once every value has moved.
+func migrateToSharded(dir string) (int, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return 0, err
+    }
+    staging := filepath.Join(dir, fileStoreMigrateDir)
+
+    // A value named like a shard would stop that shard's directory from
+    // being made, so set those aside first
+    var flat []string
+    for _, entry := range entries {
+        if !entry.Type().IsRegular() || validateKey(entry.Name()) != nil {
+            continue
+        }
+        if !isShardName(entry.Name()) {
+            flat = append(flat, entry.Name())
+            continue
+        }
+        if err := os.MkdirAll(staging, 0700); err != nil {
+            return 0, err
+        }
+        if err := os.Rename(filepath.Join(dir, entry.Name()), filepath.Join(staging, entry.Name())); err != nil {
+            return 0, err
+        }
+    }
+
+    // Also picks up values set aside by an interrupted run
+    staged, err := os.ReadDir(staging)
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return 0, err
+    }
+
+    moved := 0
+    for _, key := range flat {
+        if err := moveToShard(dir, dir, key); err != nil {
+            return moved, err
+        }
+        moved++
+    }
+    for _, entry := range staged {
+        if !entry.Type().IsRegular() {
+            continue
+        }
+        if err := moveToShard(dir, staging, entry.Name()); err != nil {
+            return moved, err
+        }
+        moved++
+    }
+    if err := os.Remove(staging); err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return moved, err
+    }
+    if moved > 0 {
+        return moved, syncDir(dir)
+    }
+    return 0, nil
+}
+
+// moveToShard moves key's file from the directory from into its shard of
+// dir. It links before removing, so a value already in the shard is never
+// overwritten; one that is the same file was linked by an interrupted run.
+func moveToShard(dir, from, key string) error {
+    shard := shardName(key)
+    if err := makeShard(dir, shard); err != nil {
+        return err
+    }
+    src := filepath.Join(from, key)
+    dst := filepath.Join(dir, shard, key)
+    if err := os.Link(src, dst); errors.Is(err, fs.ErrExist) {
+        srcInfo, srcErr := os.Stat(src)
+        dstInfo, dstErr := os.Stat(dst)
+        if srcErr != nil || dstErr != nil || !os.SameFile(srcInfo, dstInfo) {
+            return fmt.Errorf("%q is stored in both layouts; remove one copy and restart", key)
+        }
+    } else if err != nil {
+        return err
+    }
+    if err := os.Remove(src); err != nil {
+        return err
+    }
+    return syncDir(filepath.Join(dir, shard))
+}
@@ -0,0 +1,175 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_sharded_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestShardedFromEnv(t *testing.T) {
+    for _, tt := range []struct {
+        value   string
+        want    bool
+        wantErr bool
+    }{
+        {"", false, false},
+        {"true", true, false},
+        {"0", false, false},
+        {"sometimes", false, true},
+    } {
+        t.Setenv("PLUGIN_KV_SHARDED", tt.value)
+        got, err := shardedFromEnv()
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Fatalf("shardedFromEnv() with %q = %v, %v, want %v and error %t", tt.value, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
+
+func TestShardedFileStoreLayout(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    kv := NewKV(newShardedFileStore(dir), nil)
+
+    keys := []string{"alpha", "beta", "gamma"}
+    for _, key := range keys {
+        if err := kv.Put(ctx, key, []byte("value of "+key)); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    for _, key := range keys {
+        got, err := kv.Get(ctx, key)
+        if err != nil || string(got) != "value of "+key {
+            t.Fatalf("Get(%q) = %q, %v; want %q", key, got, err, "value of "+key)
+        }
+        data, err := os.ReadFile(filepath.Join(dir, shardName(key), key))
+        if err != nil || string(data) != "value of "+key {
+            t.Fatalf("%q isn't stored in its shard: %q, %v", key, data, err)
+        }
+        if _, err := os.Stat(filepath.Join(dir, key)); !os.IsNotExist(err) {
+            t.Fatalf("%q is also stored directly in the data directory (%v)", key, err)
+        }
+    }
+
+    listed, err := kv.List(ctx, "")
+    if err != nil || !reflect.DeepEqual(listed, keys) {
+        t.Fatalf("List = %v, %v; want %v", listed, err, keys)
+    }
+    if err := kv.Delete(ctx, "beta"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if _, err := kv.Get(ctx, "beta"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get after Delete = %v, want ErrKeyNotFound", err)
+    }
+    if listed, err := kv.List(ctx, "g"); err != nil || !reflect.DeepEqual(listed, []string{"gamma"}) {
+        t.Fatalf("List(g) = %v, %v; want [gamma]", listed, err)
+    }
+}
+
+func TestMigrateToSharded(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+
+    // A flat value named like a shard, and another value that belongs in
+    // that shard, whose directory the first is in the way of
+    crowded := ""
+    for i := 0; crowded == ""; i++ {
+        if key := fmt.Sprintf("key-%d", i); shardName(key) == "ab" {
+            crowded = key
+        }
+    }
+    flat := newFileStore(dir)
+    values := map[string]string{"ab": "shard-like", crowded: "crowded", "plain": "plain"}
+    for key, value := range values {
+        if err := flat.Put(ctx, key, []byte(value)); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    // Not a key, so it stays put
+    if err := os.WriteFile(filepath.Join(dir, "not..a-key"), []byte("x"), 0644); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+
+    moved, err := migrateToSharded(dir)
+    if err != nil || moved != len(values) {
+        t.Fatalf("migrateToSharded = %d, %v; want %d", moved, err, len(values))
+    }
+    sharded := newShardedFileStore(dir)
+    for key, value := range values {
+        got, err := sharded.Get(ctx, key)
+        if err != nil || string(got) != value {
+            t.Fatalf("Get(%q) after migrating = %q, %v; want %q", key, got, err, value)
+        }
+    }
+    if _, err := os.Stat(filepath.Join(dir, "not..a-key")); err != nil {
+        t.Fatalf("a file that isn't a key was moved: %v", err)
+    }
+    if _, err := os.Stat(filepath.Join(dir, fileStoreMigrateDir)); !os.IsNotExist(err) {
+        t.Fatalf("the staging directory was left behind (%v)", err)
+    }
+
+    // Nothing is left to move the second time
+    if moved, err := migrateToSharded(dir); err != nil || moved != 0 {
+        t.Fatalf("second migrateToSharded = %d, %v; want 0", moved, err)
+    }
+}
+
+func TestMigrateToShardedResumes(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    if err := newFileStore(dir).Put(ctx, "k", []byte("value")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    // Interrupted after linking the value into its shard
+    if err := makeShard(dir, shardName("k")); err != nil {
+        t.Fatalf("makeShard failed: %v", err)
+    }
+    if err := os.Link(filepath.Join(dir, "k"), filepath.Join(dir, shardName("k"), "k")); err != nil {
+        t.Fatalf("Link failed: %v", err)
+    }
+    if moved, err := migrateToSharded(dir); err != nil || moved != 1 {
+        t.Fatalf("migrateToSharded after an interruption = %d, %v; want 1", moved, err)
+    }
+    if got, err := newShardedFileStore(dir).Get(ctx, "k"); err != nil || string(got) != "value" {
+        t.Fatalf("Get after migrating = %q, %v; want value", got, err)
+    }
+
+    // A key written under both layouts is left for the operator to resolve
+    if err := newFileStore(dir).Put(ctx, "k", []byte("other")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, err := migrateToSharded(dir); err == nil {
+        t.Fatalf("migrateToSharded with a key in both layouts succeeded")
+    }
+}
+
+func TestOpenShardedBackendMigrates(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    if err := newFileStore(dir).Put(ctx, "old", []byte("flat")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    t.Setenv("PLUGIN_KV_DATA_DIR", dir)
+    t.Setenv("PLUGIN_KV_SHARDED", "true")
+
+    store, err := newStoreFromEnv(hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newStoreFromEnv failed: %v", err)
+    }
+    if got, err := store.Get(ctx, "old"); err != nil || string(got) != "flat" {
+        t.Fatalf("Get(old) = %q, %v; want flat", got, err)
+    }
+    if _, err := os.Stat(filepath.Join(dir, shardName("old"), "old")); err != nil {
+        t.Fatalf("old wasn't moved into its shard: %v", err)
+    }
+}
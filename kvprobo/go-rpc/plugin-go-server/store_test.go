@@ -0,0 +1,360 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/store_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+    ctx := context.Background()
+    store := newFileStore(t.TempDir())
+
+    if err := store.Put(ctx, "kv-server-test.key", []byte("value")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    got, err := store.Get(ctx, "kv-server-test.key")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if string(got) != "value" {
+        t.Fatalf("Get = %q, want %q", got, "value")
+    }
+
+    if err := store.Delete(ctx, "kv-server-test.key"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if err := store.Delete(ctx, "kv-server-test.key"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("second Delete = %v, want ErrKeyNotFound", err)
+    }
+}
+
+func TestFileStoreInterruptedWriteKeepsOldValue(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    store := newFileStore(dir)
+    if err := store.Put(ctx, "k", []byte("old value")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    // A crash after writing the temp file but before the rename
+    tmp, err := store.createTemp("put-*")
+    if err != nil {
+        t.Fatalf("createTemp failed: %v", err)
+    }
+    if err := os.WriteFile(tmp, []byte("new va"), 0644); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    if got, err := store.Get(ctx, "k"); err != nil || string(got) != "old value" {
+        t.Fatalf("Get after an interrupted write = %q, %v; want old value", got, err)
+    }
+    if keys, err := store.List(ctx, ""); err != nil || len(keys) != 1 || keys[0] != "k" {
+        t.Fatalf("List after an interrupted write = %v, %v; want [k]", keys, err)
+    }
+
+    // A write that fails partway through
+    store.writeFile = func(name string, data []byte, perm os.FileMode) error {
+        if err := os.WriteFile(name, data[:len(data)/2], perm); err != nil {
+            return err
+        }
+        return errors.New("disk went away")
+    }
+    if err := store.Put(ctx, "k", []byte("new value")); err == nil {
+        t.Fatalf("Put with a failing write succeeded")
+    }
+    if got, err := store.Get(ctx, "k"); err != nil || string(got) != "old value" {
+        t.Fatalf("Get after a failed write = %q, %v; want old value", got, err)
+    }
+    // Only the temp file from the simulated crash is left behind
+    entries, err := os.ReadDir(filepath.Join(dir, fileStoreTempDir))
+    if err != nil || len(entries) != 1 {
+        t.Fatalf("temp dir holds %d entries (%v), want 1", len(entries), err)
+    }
+}
+
+func TestResolveDataDirFromEnv(t *testing.T) {
+    ctx := context.Background()
+    dataDir := filepath.Join(t.TempDir(), "nested", "data")
+    t.Setenv("PLUGIN_KV_DATA_DIR", dataDir)
+
+    resolved, err := resolveDataDir()
+    if err != nil {
+        t.Fatalf("resolveDataDir failed: %v", err)
+    }
+    if resolved != dataDir {
+        t.Fatalf("resolveDataDir() = %q, want %q", resolved, dataDir)
+    }
+
+    kv := NewKV(newFileStore(resolved), nil)
+    if err := kv.Put(ctx, "hello", []byte("world")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    data, err := os.ReadFile(filepath.Join(dataDir, "hello"))
+    if err != nil {
+        t.Fatalf("value did not land in PLUGIN_KV_DATA_DIR: %v", err)
+    }
+    if string(data) != "world" {
+        t.Fatalf("stored value = %q, want %q", data, "world")
+    }
+}
+
+func TestMemStoreMissingKey(t *testing.T) {
+    ctx := context.Background()
+    store := newMemStore()
+
+    if _, err := store.Get(ctx, "missing"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get(missing) = %v, want ErrKeyNotFound", err)
+    }
+    if err := store.Delete(ctx, "missing"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Delete(missing) = %v, want ErrKeyNotFound", err)
+    }
+}
+
+func TestMemStoreCopiesValues(t *testing.T) {
+    ctx := context.Background()
+    store := newMemStore()
+    buf := []byte("original")
+
+    if err := store.Put(ctx, "key", buf); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    copy(buf, "mutated!")
+
+    got, err := store.Get(ctx, "key")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if string(got) != "original" {
+        t.Fatalf("stored value aliased the caller's buffer: %q", got)
+    }
+
+    got[0] = 'X'
+    again, _ := store.Get(ctx, "key")
+    if string(again) != "original" {
+        t.Fatalf("stored value aliased a returned buffer: %q", again)
+    }
+}
+
+func TestMemStoreConcurrentAccess(t *testing.T) {
+    ctx := context.Background()
+    store := newMemStore()
+    const workers = 16
+    const perWorker = 200
+
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            for i := 0; i < perWorker; i++ {
+                key := fmt.Sprintf("worker-%d-%d", w, i)
+                if err := store.Put(ctx, key, []byte(key)); err != nil {
+                    t.Errorf("Put(%q) failed: %v", key, err)
+                    return
+                }
+                if got, err := store.Get(ctx, key); err != nil || string(got) != key {
+                    t.Errorf("Get(%q) = %q, %v", key, got, err)
+                    return
+                }
+                if _, err := store.List(ctx, "worker-"); err != nil {
+                    t.Errorf("List failed: %v", err)
+                    return
+                }
+                if i%2 == 0 {
+                    if err := store.Delete(ctx, key); err != nil {
+                        t.Errorf("Delete(%q) failed: %v", key, err)
+                        return
+                    }
+                }
+            }
+        }(w)
+    }
+    wg.Wait()
+
+    keys, err := store.List(ctx, "worker-")
+    if err != nil {
+        t.Fatalf("List failed: %v", err)
+    }
+    if want := workers * perWorker / 2; len(keys) != want {
+        t.Fatalf("List returned %d keys, want %d", len(keys), want)
+    }
+}
+
+func TestNewStoreFromEnvMemory(t *testing.T) {
+    t.Setenv("PLUGIN_KV_BACKEND", "memory")
+
+    store, err := newStoreFromEnv(hclog.NewNullLogger())
+    if err != nil {
+        t.Fatalf("newStoreFromEnv failed: %v", err)
+    }
+    if _, ok := store.(*memStore); !ok {
+        t.Fatalf("newStoreFromEnv returned %T, want *memStore", store)
+    }
+}
+
+func TestNewStoreFromEnvUnknown(t *testing.T) {
+    t.Setenv("PLUGIN_KV_BACKEND", "carrier-pigeon")
+
+    if _, err := newStoreFromEnv(hclog.NewNullLogger()); err == nil {
+        t.Fatal("newStoreFromEnv accepted an unknown backend")
+    }
+}
+
+func TestBoltStorePersistsAcrossReopen(t *testing.T) {
+    ctx := context.Background()
+    path := filepath.Join(t.TempDir(), "kv.db")
+
+    store, err := newBoltStore(path)
+    if err != nil {
+        t.Fatalf("newBoltStore failed: %v", err)
+    }
+    if err := store.Put(ctx, "durable", []byte("value")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := store.Close(); err != nil {
+        t.Fatalf("Close failed: %v", err)
+    }
+
+    reopened, err := newBoltStore(path)
+    if err != nil {
+        t.Fatalf("reopening bolt store failed: %v", err)
+    }
+    defer reopened.Close()
+
+    got, err := reopened.Get(ctx, "durable")
+    if err != nil {
+        t.Fatalf("Get after reopen failed: %v", err)
+    }
+    if string(got) != "value" {
+        t.Fatalf("Get after reopen = %q, want %q", got, "value")
+    }
+
+    keys, err := reopened.List(ctx, "")
+    if err != nil {
+        t.Fatalf("List failed: %v", err)
+    }
+    if len(keys) != 1 || keys[0] != "durable" {
+        t.Fatalf("List = %v, want [durable]", keys)
+    }
+}
+
+func TestBoltStoreMissingKey(t *testing.T) {
+    ctx := context.Background()
+    store, err := newBoltStore(filepath.Join(t.TempDir(), "kv.db"))
+    if err != nil {
+        t.Fatalf("newBoltStore failed: %v", err)
+    }
+    defer store.Close()
+
+    if _, err := store.Get(ctx, "missing"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get(missing) = %v, want ErrKeyNotFound", err)
+    }
+    if err := store.Delete(ctx, "missing"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Delete(missing) = %v, want ErrKeyNotFound", err)
+    }
+}
+
+// storeBackends opens a fresh, empty instance of each Store implementation.
+var storeBackends = map[string]func(t *testing.T) Store{
+    "file":    func(t *testing.T) Store { return newFileStore(t.TempDir()) },
+    "sharded": func(t *testing.T) Store { return newShardedFileStore(t.TempDir()) },
+    "memory":  func(t *testing.T) Store { return newMemStore() },
+    "bolt": func(t *testing.T) Store {
+        store, err := newBoltStore(filepath.Join(t.TempDir(), "kv.db"))
+        if err != nil {
+            t.Fatalf("newBoltStore failed: %v", err)
+        }
+        t.Cleanup(func() { store.Close() })
+        return store
+    },
+}
+
+func TestStoreExists(t *testing.T) {
+    for name, open := range storeBackends {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            store := open(t)
+            if err := store.Put(ctx, "present", []byte("value")); err != nil {
+                t.Fatalf("Put failed: %v", err)
+            }
+            if err := store.Put(ctx, "empty", []byte{}); err != nil {
+                t.Fatalf("Put of an empty value failed: %v", err)
+            }
+
+            for key, want := range map[string]bool{"present": true, "empty": true, "absent": false} {
+                got, err := store.Exists(ctx, key)
+                if err != nil {
+                    t.Fatalf("Exists(%q) failed: %v", key, err)
+                }
+                if got != want {
+                    t.Fatalf("Exists(%q) = %v, want %v", key, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestStoreRename(t *testing.T) {
+    for name, open := range storeBackends {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            store := open(t)
+            for key, value := range map[string]string{"a": "moved", "b": "taken"} {
+                if err := store.Put(ctx, key, []byte(value)); err != nil {
+                    t.Fatalf("Put(%q) failed: %v", key, err)
+                }
+            }
+
+            if err := store.Rename(ctx, "a", "b", []byte("moved"), false); !errors.Is(err, shared.ErrKeyExists) {
+                t.Fatalf("Rename onto an existing key = %v, want shared.ErrKeyExists", err)
+            }
+            if err := store.Rename(ctx, "missing", "c", []byte("moved"), false); !errors.Is(err, shared.ErrKeyNotFound) {
+                t.Fatalf("Rename of a missing key = %v, want shared.ErrKeyNotFound", err)
+            }
+            if err := store.Rename(ctx, "a", "c", []byte("moved"), false); err != nil {
+                t.Fatalf("Rename to a new key failed: %v", err)
+            }
+            if err := store.Rename(ctx, "c", "b", []byte("moved"), true); err != nil {
+                t.Fatalf("Rename with overwrite failed: %v", err)
+            }
+
+            keys, err := store.List(ctx, "")
+            if err != nil || strings.Join(keys, ",") != "b" {
+                t.Fatalf("List() = %q, %v; want only b", keys, err)
+            }
+            if value, err := store.Get(ctx, "b"); err != nil || string(value) != "moved" {
+                t.Fatalf("Get(b) = %q, %v; want the moved value", value, err)
+            }
+        })
+    }
+}
+
+func TestStoreCreate(t *testing.T) {
+    for name, open := range storeBackends {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            store := open(t)
+            if err := store.Create(ctx, "k", []byte("first")); err != nil {
+                t.Fatalf("Create of a new key failed: %v", err)
+            }
+            if err := store.Create(ctx, "k", []byte("second")); !errors.Is(err, shared.ErrKeyExists) {
+                t.Fatalf("Create of an existing key = %v, want shared.ErrKeyExists", err)
+            }
+            if value, err := store.Get(ctx, "k"); err != nil || string(value) != "first" {
+                t.Fatalf("Get() = %q, %v, want the first value", value, err)
+            }
+        })
+    }
+}
@@ -0,0 +1,115 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/transaction.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// txEntry is the state of a key as seen part-way through a transaction.
+type txEntry struct {
+    value   []byte
+    version uint64
+    exists  bool
+}
+
+// Transaction plays ops against a private view of the keys they touch, then
+// hands the final state of each key to Store.Commit in one go. Nothing is
+// written unless every op succeeds, and the keys' write locks keep other
+// callers from seeing or changing them in between.
+func (k *KV) Transaction(ctx context.Context, ops []shared.TxOp) error {
+    keys := make([]string, len(ops))
+    for i, op := range ops {
+        keys[i] = op.Key
+    }
+    defer k.locks.lock(keys...)()
+
+    for i, op := range ops {
+        err := validateKey(op.Key)
+        if err == nil {
+            err = shared.CheckValueSize(op.Key, op.Value, k.valueLimit())
+        }
+        if err != nil {
+            return &shared.TxError{Index: i, Err: err}
+        }
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    k.log(ctx).Debug("🗄️🧾 running transaction", "op_count", len(ops))
+
+    view := map[string]*txEntry{}
+    existed := map[string]bool{}
+    var order []string
+    writes := map[string]storeWrite{}
+    for i, op := range ops {
+        entry, ok := view[op.Key]
+        if !ok {
+            value, version, err := k.loadVersioned(ctx, op.Key)
+            if err != nil && !errors.Is(err, shared.ErrKeyNotFound) {
+                return &shared.TxError{Index: i, Err: err}
+            }
+            entry = &txEntry{value: value, version: version, exists: err == nil}
+            view[op.Key] = entry
+            existed[op.Key] = entry.exists
+            order = append(order, op.Key)
+        }
+
+        var expiresAt time.Time
+        switch op.Kind {
+        case shared.TxPut:
+            if op.TTL > 0 {
+                expiresAt = k.now().Add(op.TTL)
+            }
+        case shared.TxDelete:
+            if !entry.exists {
+                return &shared.TxError{Index: i, Err: fmt.Errorf("%w: %q", shared.ErrKeyNotFound, op.Key)}
+            }
+            *entry = txEntry{}
+            writes[op.Key] = storeWrite{key: op.Key, delete: true}
+            continue
+        case shared.TxCompareAndSwap:
+            if !entry.exists || !bytes.Equal(entry.value, op.Old) {
+                return &shared.TxError{Index: i, Err: fmt.Errorf("%w: %q", shared.ErrCompareFailed, op.Key)}
+            }
+        default:
+            return &shared.TxError{Index: i, Err: status.Errorf(codes.InvalidArgument, "unknown transaction op %d", op.Kind)}
+        }
+
+        *entry = txEntry{value: op.Value, version: entry.version + 1, exists: true}
+        writes[op.Key] = storeWrite{key: op.Key, value: encodeValue(op.Value, expiresAt, entry.version)}
+    }
+
+    commit := make([]storeWrite, 0, len(order))
+    for _, key := range order {
+        // A key created and deleted again needs no write at all
+        if w, ok := writes[key]; ok && (existed[key] || !w.delete) {
+            commit = append(commit, w)
+        }
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    if err := k.store.Commit(ctx, commit); err != nil {
+        return err
+    }
+
+    for _, op := range ops {
+        if op.Kind == shared.TxDelete {
+            k.publish(ctx, shared.Event{Op: shared.EventDelete, Key: op.Key})
+        } else {
+            k.publish(ctx, shared.Event{Op: shared.EventPut, Key: op.Key, Value: op.Value})
+        }
+    }
+    return nil
+}
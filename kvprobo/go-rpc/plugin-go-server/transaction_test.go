@@ -0,0 +1,131 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/transaction_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestTransactionRollsBackOnFailedCompare(t *testing.T) {
+    for name, open := range storeBackends {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            kv := NewKV(open(t), nil)
+            if err := kv.Put(ctx, "balance", []byte("100")); err != nil {
+                t.Fatalf("Put failed: %v", err)
+            }
+            if err := kv.Put(ctx, "stale", []byte("old")); err != nil {
+                t.Fatalf("Put failed: %v", err)
+            }
+
+            err := kv.Transaction(ctx, []shared.TxOp{
+                {Kind: shared.TxPut, Key: "a", Value: []byte("1")},
+                {Kind: shared.TxPut, Key: "balance", Value: []byte("50")},
+                {Kind: shared.TxDelete, Key: "stale"},
+                {Kind: shared.TxCompareAndSwap, Key: "balance", Old: []byte("100"), Value: []byte("0")},
+            })
+            var txErr *shared.TxError
+            if !errors.As(err, &txErr) || txErr.Index != 3 || !errors.Is(err, shared.ErrCompareFailed) {
+                t.Fatalf("Transaction = %v, want a TxError at index 3 wrapping ErrCompareFailed", err)
+            }
+
+            // None of the earlier ops may be visible
+            if exists, _ := kv.Exists(ctx, "a"); exists {
+                t.Fatalf("key a exists after a rolled-back transaction")
+            }
+            if value, err := kv.Get(ctx, "balance"); err != nil || string(value) != "100" {
+                t.Fatalf("balance = %q, %v; want \"100\"", value, err)
+            }
+            if value, err := kv.Get(ctx, "stale"); err != nil || string(value) != "old" {
+                t.Fatalf("stale = %q, %v; want \"old\"", value, err)
+            }
+
+            // The same ops commit once the compare matches what the earlier put wrote
+            err = kv.Transaction(ctx, []shared.TxOp{
+                {Kind: shared.TxPut, Key: "a", Value: []byte("1")},
+                {Kind: shared.TxPut, Key: "balance", Value: []byte("50")},
+                {Kind: shared.TxDelete, Key: "stale"},
+                {Kind: shared.TxCompareAndSwap, Key: "balance", Old: []byte("50"), Value: []byte("0")},
+                {Kind: shared.TxPut, Key: "scratch", Value: []byte("x")},
+                {Kind: shared.TxDelete, Key: "scratch"},
+            })
+            if err != nil {
+                t.Fatalf("Transaction failed: %v", err)
+            }
+            if value, version, err := kv.GetVersioned(ctx, "balance"); err != nil || string(value) != "0" || version != 3 {
+                t.Fatalf("balance = %q at version %d, %v; want \"0\" at version 3", value, version, err)
+            }
+            for key, want := range map[string]bool{"a": true, "stale": false, "scratch": false} {
+                if exists, err := kv.Exists(ctx, key); err != nil || exists != want {
+                    t.Fatalf("Exists(%q) = %v, %v; want %v", key, exists, err, want)
+                }
+            }
+        })
+    }
+}
+
+func TestFileStoreCommitRestoresOnFailure(t *testing.T) {
+    ctx := context.Background()
+    dir := t.TempDir()
+    store := newFileStore(dir)
+    if err := store.Put(ctx, "first", []byte("old")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    // A non-empty directory can't be moved aside onto a file, so the second
+    // swap fails after the first has already happened
+    if err := os.MkdirAll(filepath.Join(dir, "blocked", "inner"), 0700); err != nil {
+        t.Fatalf("MkdirAll failed: %v", err)
+    }
+
+    err := store.Commit(ctx, []storeWrite{
+        {key: "first", value: []byte("new")},
+        {key: "blocked", value: []byte("value")},
+    })
+    if err == nil {
+        t.Fatalf("Commit over a directory succeeded, want an error")
+    }
+    if value, err := store.Get(ctx, "first"); err != nil || string(value) != "old" {
+        t.Fatalf("first after a failed commit = %q, %v; want \"old\"", value, err)
+    }
+    entries, err := os.ReadDir(filepath.Join(dir, fileStoreTempDir))
+    if err != nil {
+        t.Fatalf("reading temp dir failed: %v", err)
+    }
+    if len(entries) != 0 {
+        t.Fatalf("failed commit left temp files behind: %v", entries)
+    }
+}
+
+func TestTransactionOverGRPC(t *testing.T) {
+    ctx := context.Background()
+    client := serveKV(t, NewKV(newMemStore(), nil))
+
+    err := client.Transaction(ctx, []shared.TxOp{
+        {Kind: shared.TxPut, Key: "a", Value: []byte("1")},
+        {Kind: shared.TxDelete, Key: "missing"},
+    })
+    var txErr *shared.TxError
+    if !errors.As(err, &txErr) || txErr.Index != 1 || !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Transaction = %v, want a TxError at index 1 wrapping ErrKeyNotFound", err)
+    }
+    if _, err := client.Get(ctx, "a"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get(a) after a failed transaction = %v, want ErrKeyNotFound", err)
+    }
+
+    err = client.Transaction(ctx, []shared.TxOp{
+        {Kind: shared.TxPut, Key: "a", Value: []byte("1")},
+        {Kind: shared.TxCompareAndSwap, Key: "a", Old: []byte("1"), Value: []byte("2")},
+    })
+    if err != nil {
+        t.Fatalf("Transaction failed: %v", err)
+    }
+    if value, err := client.Get(ctx, "a"); err != nil || string(value) != "2" {
+        t.Fatalf("Get(a) = %q, %v; want \"2\"", value, err)
+    }
+}
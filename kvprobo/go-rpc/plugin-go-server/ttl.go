@@ -0,0 +1,282 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/ttl.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// recordHeader marks a stored value that carries metadata: a big-endian
+// UnixNano expiry, zero for none, then a big-endian version. Values without
+// it are stored verbatim and read as version 1, so data written before
+// expiry and versioning existed still reads back.
+var recordHeader = []byte("\x00kv-rec\x00")
+
+// recordHeaderLen is the header plus the expiry and version that follow it.
+var recordHeaderLen = len(recordHeader) + 16
+
+// typedRecordHeader marks a value stored with a content type: the
+// recordHeader fields, then a big-endian uint16 length and the content type.
+var typedRecordHeader = []byte("\x00kv-typ\x00")
+
+// typedRecordHeaderLen is the header plus the fixed-size fields that follow it.
+var typedRecordHeaderLen = len(typedRecordHeader) + 18
+
+// ttlHeader marks a value written before versioning that carries only an
+// expiry. It is still read but no longer written.
+var ttlHeader = []byte("\x00kv-ttl\x00")
+
+// ttlHeaderLen is the header plus the big-endian UnixNano expiry that follows it.
+var ttlHeaderLen = len(ttlHeader) + 8
+
+// defaultSweepInterval is how often expired keys are purged when
+// PLUGIN_KV_SWEEP_INTERVAL is unset.
+const defaultSweepInterval = time.Minute
+
+// encodeValue prepares value for the Store. A zero expiresAt means the value
+// never expires. The first version of a value that never expires is stored
+// as is, unless the raw value happens to start with a header, so
+// decodeValue never misreads user data.
+func encodeValue(value []byte, expiresAt time.Time, version uint64) []byte {
+    return encodeTypedValue(value, expiresAt, version, "")
+}
+
+// encodeTypedValue is encodeValue for a value with a content type. An
+// empty contentType, meaning shared.DefaultContentType, isn't stored.
+func encodeTypedValue(value []byte, expiresAt time.Time, version uint64, contentType string) []byte {
+    if expiresAt.IsZero() && version <= 1 && contentType == "" &&
+        !bytes.HasPrefix(value, recordHeader) && !bytes.HasPrefix(value, typedRecordHeader) &&
+        !bytes.HasPrefix(value, ttlHeader) {
+        return value
+    }
+
+    var expiry int64
+    if !expiresAt.IsZero() {
+        expiry = expiresAt.UnixNano()
+    }
+    header := recordHeader
+    if contentType != "" {
+        header = typedRecordHeader
+    }
+    encoded := make([]byte, 0, typedRecordHeaderLen+len(contentType)+len(value))
+    encoded = append(encoded, header...)
+    encoded = binary.BigEndian.AppendUint64(encoded, uint64(expiry))
+    encoded = binary.BigEndian.AppendUint64(encoded, max(version, 1))
+    if contentType != "" {
+        encoded = binary.BigEndian.AppendUint16(encoded, uint16(len(contentType)))
+        encoded = append(encoded, contentType...)
+    }
+    return append(encoded, value...)
+}
+
+// decodeValue strips the header written by encodeValue, if any.
+func decodeValue(raw []byte) ([]byte, time.Time, uint64, error) {
+    value, expiresAt, version, _, err := decodeTypedValue(raw)
+    return value, expiresAt, version, err
+}
+
+// decodeTypedValue is decodeValue that also returns the content type, or
+// "" for a value stored without one.
+func decodeTypedValue(raw []byte) ([]byte, time.Time, uint64, string, error) {
+    var expiry int64
+    var version uint64 = 1
+    var contentType string
+    switch {
+    case bytes.HasPrefix(raw, typedRecordHeader):
+        if len(raw) < typedRecordHeaderLen {
+            return nil, time.Time{}, 0, "", errors.New("stored value has a truncated header")
+        }
+        expiry = int64(binary.BigEndian.Uint64(raw[len(typedRecordHeader):]))
+        version = binary.BigEndian.Uint64(raw[len(typedRecordHeader)+8:])
+        typeLen := int(binary.BigEndian.Uint16(raw[len(typedRecordHeader)+16:]))
+        raw = raw[typedRecordHeaderLen:]
+        if len(raw) < typeLen {
+            return nil, time.Time{}, 0, "", errors.New("stored value has a truncated content type")
+        }
+        contentType, raw = string(raw[:typeLen]), raw[typeLen:]
+    case bytes.HasPrefix(raw, recordHeader):
+        if len(raw) < recordHeaderLen {
+            return nil, time.Time{}, 0, "", errors.New("stored value has a truncated header")
+        }
+        expiry = int64(binary.BigEndian.Uint64(raw[len(recordHeader):]))
+        version = binary.BigEndian.Uint64(raw[len(recordHeader)+8:])
+        raw = raw[recordHeaderLen:]
+    case bytes.HasPrefix(raw, ttlHeader):
+        if len(raw) < ttlHeaderLen {
+            return nil, time.Time{}, 0, "", errors.New("stored value has a truncated expiry header")
+        }
+        expiry = int64(binary.BigEndian.Uint64(raw[len(ttlHeader):]))
+        raw = raw[ttlHeaderLen:]
+    }
+
+    var expiresAt time.Time
+    if expiry != 0 {
+        expiresAt = time.Unix(0, expiry)
+    }
+    return raw, expiresAt, version, contentType, nil
+}
+
+// load reads and decodes key, treating an expired entry as missing and
+// deleting it. Callers must hold key's lock; a read lock is enough because
+// writers are excluded and a concurrent lazy delete of the same key is
+// harmless.
+func (k *KV) load(ctx context.Context, key string) ([]byte, error) {
+    value, _, err := k.loadVersioned(ctx, key)
+    return value, err
+}
+
+// loadVersioned is load that also returns the stored version.
+func (k *KV) loadVersioned(ctx context.Context, key string) ([]byte, uint64, error) {
+    value, version, _, err := k.loadTyped(ctx, key)
+    return value, version, err
+}
+
+// loadTyped is loadVersioned that also returns the content type, or "" for
+// a value stored without one.
+func (k *KV) loadTyped(ctx context.Context, key string) ([]byte, uint64, string, error) {
+    value, _, version, contentType, err := k.loadRecord(ctx, key)
+    return value, version, contentType, err
+}
+
+// loadRecord is loadTyped that also returns when the value expires, or the
+// zero Time if it never does, for writes that have to keep the expiry.
+func (k *KV) loadRecord(ctx context.Context, key string) ([]byte, time.Time, uint64, string, error) {
+    raw, err := k.store.Get(ctx, key)
+    if err != nil {
+        return nil, time.Time{}, 0, "", err
+    }
+    value, expiresAt, version, contentType, err := decodeTypedValue(raw)
+    if err != nil {
+        return nil, time.Time{}, 0, "", fmt.Errorf("%q: %w", key, err)
+    }
+    if !expiresAt.IsZero() && !k.now().Before(expiresAt) {
+        k.log(ctx).Debug("🗄️⌛ dropping expired value", "key", key, "expired_at", expiresAt)
+        switch err := k.store.Delete(ctx, key); {
+        case err == nil:
+            k.publish(ctx, shared.Event{Op: shared.EventDelete, Key: key})
+        case !errors.Is(err, shared.ErrKeyNotFound):
+            k.log(ctx).Warn("🗄️⚠️ failed to delete expired value", "key", key, "error", err)
+        }
+        return nil, time.Time{}, 0, "", fmt.Errorf("%w: %q", shared.ErrKeyNotFound, key)
+    }
+    return value, expiresAt, version, contentType, nil
+}
+
+// live reports whether key holds a value that hasn't expired. Like load, it
+// deletes an expired one, so callers listing keys must hold their locks.
+func (k *KV) live(ctx context.Context, key string) (bool, error) {
+    _, err := k.load(ctx, key)
+    if errors.Is(err, shared.ErrKeyNotFound) {
+        return false, nil
+    }
+    if err != nil {
+        return false, fmt.Errorf("listing %q: %w", key, err)
+    }
+    return true, nil
+}
+
+// PutWithTTL stores value so that it reads as missing once ttl has passed.
+// A ttl of zero or less behaves like Put.
+func (k *KV) PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    defer k.locks.lock(key)()
+
+    if key == "" {
+        return nil
+    }
+
+    if err := validateKey(key); err != nil {
+        return err
+    }
+    if err := shared.CheckValueSize(key, value, k.valueLimit()); err != nil {
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    var expiresAt time.Time
+    if ttl > 0 {
+        expiresAt = k.now().Add(ttl)
+    }
+
+    k.log(ctx).Debug("🗄️📤 putting value with ttl",
+        "key", key,
+        "value_length", len(value),
+        "ttl", ttl)
+
+    if _, err := k.save(ctx, key, value, expiresAt); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: value})
+    return nil
+}
+
+// SweepExpired deletes every expired key, in every namespace, and returns
+// how many were removed.
+func (k *KV) SweepExpired(ctx context.Context) (int, error) {
+    defer k.locks.lockAll()()
+
+    ctx = withAllNamespaces(ctx)
+
+    keys, err := k.store.List(ctx, "")
+    if err != nil {
+        return 0, err
+    }
+
+    removed := 0
+    for _, key := range keys {
+        if err := ctx.Err(); err != nil {
+            return removed, err
+        }
+        if _, err := k.load(ctx, key); errors.Is(err, shared.ErrKeyNotFound) {
+            removed++
+        }
+    }
+    return removed, nil
+}
+
+// RunSweeper calls SweepExpired every interval until ctx is cancelled.
+func (k *KV) RunSweeper(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            removed, err := k.SweepExpired(ctx)
+            if err != nil && ctx.Err() == nil {
+                k.log(ctx).Warn("🗄️⚠️ expiry sweep failed", "error", err)
+                continue
+            }
+            if removed > 0 {
+                k.log(ctx).Debug("🗄️⌛ expiry sweep removed keys", "count", removed)
+            }
+        }
+    }
+}
+
+// sweepIntervalFromEnv reads PLUGIN_KV_SWEEP_INTERVAL as a Go duration.
+func sweepIntervalFromEnv() (time.Duration, error) {
+    value := os.Getenv("PLUGIN_KV_SWEEP_INTERVAL")
+    if value == "" {
+        return defaultSweepInterval, nil
+    }
+    interval, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_SWEEP_INTERVAL %q: %w", value, err)
+    }
+    if interval <= 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_SWEEP_INTERVAL must be positive, got %s", interval)
+    }
+    return interval, nil
+}
@@ -0,0 +1,239 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/ttl_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// fakeClock is a manually advanced time source for KV.now.
+type fakeClock struct {
+    t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTTLTestKV(store Store) (*KV, *fakeClock) {
+    clock := &fakeClock{t: time.Unix(1700000000, 0)}
+    kv := NewKV(store, nil)
+    kv.now = clock.now
+    return kv, clock
+}
+
+func TestKVPutWithTTLExpires(t *testing.T) {
+    ctx := context.Background()
+    store := newMemStore()
+    kv, clock := newTTLTestKV(store)
+
+    if err := kv.PutWithTTL(ctx, "session", []byte("token"), 50*time.Millisecond); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+
+    got, err := kv.Get(ctx, "session")
+    if err != nil {
+        t.Fatalf("Get before expiry failed: %v", err)
+    }
+    if string(got) != "token" {
+        t.Fatalf("Get before expiry = %q, want %q", got, "token")
+    }
+
+    clock.advance(50 * time.Millisecond)
+
+    if _, err := kv.Get(ctx, "session"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("Get after expiry = %v, want ErrKeyNotFound", err)
+    }
+    if exists, _ := store.Exists(ctx, "session"); exists {
+        t.Fatal("expired key was not deleted from the store on read")
+    }
+    if exists, err := kv.Exists(ctx, "session"); err != nil || exists {
+        t.Fatalf("Exists after expiry = %v, %v; want false, nil", exists, err)
+    }
+}
+
+func TestKVPutClearsTTL(t *testing.T) {
+    ctx := context.Background()
+    kv, clock := newTTLTestKV(newMemStore())
+
+    if err := kv.PutWithTTL(ctx, "k", []byte("short-lived"), time.Second); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    if err := kv.Put(ctx, "k", []byte("permanent")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    clock.advance(time.Hour)
+
+    got, err := kv.Get(ctx, "k")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if string(got) != "permanent" {
+        t.Fatalf("Get = %q, want %q", got, "permanent")
+    }
+}
+
+func TestKVConditionalWritesKeepTTLAndContentType(t *testing.T) {
+    writes := map[string]func(ctx context.Context, kv *KV) error{
+        "CompareAndSwap": func(ctx context.Context, kv *KV) error {
+            swapped, err := kv.CompareAndSwap(ctx, "k", []byte("1"), []byte("2"))
+            if err == nil && !swapped {
+                err = errors.New("value not swapped")
+            }
+            return err
+        },
+        "PutIfVersion": func(ctx context.Context, kv *KV) error {
+            return kv.PutIfVersion(ctx, "k", []byte("2"), 1)
+        },
+        "Increment": func(ctx context.Context, kv *KV) error {
+            _, err := kv.Increment(ctx, "k", 1)
+            return err
+        },
+    }
+    for name, write := range writes {
+        t.Run(name, func(t *testing.T) {
+            ctx := context.Background()
+            kv, clock := newTTLTestKV(newMemStore())
+            if err := kv.PutWithContentType(ctx, "k", []byte("1"), "text/plain", time.Minute); err != nil {
+                t.Fatalf("PutWithContentType failed: %v", err)
+            }
+            if err := write(ctx, kv); err != nil {
+                t.Fatalf("%s failed: %v", name, err)
+            }
+
+            value, contentType, err := kv.GetWithContentType(ctx, "k")
+            if err != nil || string(value) != "2" || contentType != "text/plain" {
+                t.Fatalf("GetWithContentType = %q, %q, %v; want 2 as text/plain", value, contentType, err)
+            }
+            clock.advance(2 * time.Minute)
+            if _, err := kv.Get(ctx, "k"); !errors.Is(err, shared.ErrKeyNotFound) {
+                t.Fatalf("Get after the TTL = %v, want ErrKeyNotFound", err)
+            }
+        })
+    }
+}
+
+func TestKVListSkipsExpired(t *testing.T) {
+    ctx := context.Background()
+    kv, clock := newTTLTestKV(newMemStore())
+    for _, key := range []string{"a", "c"} {
+        if err := kv.PutWithTTL(ctx, key, []byte("v"), time.Minute); err != nil {
+            t.Fatalf("PutWithTTL(%q) failed: %v", key, err)
+        }
+    }
+    for _, key := range []string{"b", "d"} {
+        if err := kv.Put(ctx, key, []byte("v")); err != nil {
+            t.Fatalf("Put(%q) failed: %v", key, err)
+        }
+    }
+    clock.advance(2 * time.Minute)
+
+    if keys, err := kv.List(ctx, ""); err != nil || strings.Join(keys, ",") != "b,d" {
+        t.Fatalf("List() = %v, %v; want [b d]", keys, err)
+    }
+
+    keys, token, err := kv.ListPage(ctx, "", "", 1)
+    if err != nil || strings.Join(keys, ",") != "b" || token == "" {
+        t.Fatalf("first ListPage = %v, %q, %v; want [b] and a token", keys, token, err)
+    }
+    keys, token, err = kv.ListPage(ctx, "", token, 1)
+    if err != nil || strings.Join(keys, ",") != "d" || token != "" {
+        t.Fatalf("second ListPage = %v, %q, %v; want [d] and no token", keys, token, err)
+    }
+    // The expired keys after the last live one don't make another page
+    if err := kv.PutWithTTL(ctx, "e", []byte("v"), time.Minute); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    clock.advance(2 * time.Minute)
+    keys, token, err = kv.ListPage(ctx, "", "", 2)
+    if err != nil || strings.Join(keys, ",") != "b,d" || token != "" {
+        t.Fatalf("ListPage of the live keys = %v, %q, %v; want [b d] and no token", keys, token, err)
+    }
+}
+
+func TestKVSweepExpired(t *testing.T) {
+    ctx := context.Background()
+    store := newMemStore()
+    kv, clock := newTTLTestKV(store)
+
+    if err := kv.PutWithTTL(ctx, "temp", []byte("1"), time.Second); err != nil {
+        t.Fatalf("PutWithTTL failed: %v", err)
+    }
+    if err := kv.Put(ctx, "keep", []byte("2")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    if removed, err := kv.SweepExpired(ctx); err != nil || removed != 0 {
+        t.Fatalf("SweepExpired before expiry = %d, %v; want 0, nil", removed, err)
+    }
+
+    clock.advance(2 * time.Second)
+
+    if removed, err := kv.SweepExpired(ctx); err != nil || removed != 1 {
+        t.Fatalf("SweepExpired after expiry = %d, %v; want 1, nil", removed, err)
+    }
+    keys, err := store.List(ctx, "")
+    if err != nil {
+        t.Fatalf("List failed: %v", err)
+    }
+    if len(keys) != 1 || keys[0] != "keep" {
+        t.Fatalf("keys after sweep = %v, want [keep]", keys)
+    }
+}
+
+func TestEncodeValueRoundTrip(t *testing.T) {
+    expiresAt := time.Unix(1700000000, 42)
+    tests := []struct {
+        name      string
+        value     []byte
+        expiresAt time.Time
+        version   uint64
+    }{
+        {"plain", []byte("hello"), time.Time{}, 1},
+        {"empty", []byte{}, time.Time{}, 1},
+        {"with expiry", []byte("hello"), expiresAt, 1},
+        {"later version", []byte("hello"), time.Time{}, 7},
+        {"looks like a header", append(append([]byte{}, recordHeader...), "payload"...), time.Time{}, 1},
+        {"looks like an old header", append(append([]byte{}, ttlHeader...), "payload"...), time.Time{}, 1},
+        {"looks like a typed header", append(append([]byte{}, typedRecordHeader...), "payload"...), time.Time{}, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            value, gotExpiry, gotVersion, err := decodeValue(encodeValue(tt.value, tt.expiresAt, tt.version))
+            if err != nil {
+                t.Fatalf("decodeValue failed: %v", err)
+            }
+            if !bytes.Equal(value, tt.value) {
+                t.Fatalf("value = %q, want %q", value, tt.value)
+            }
+            if !gotExpiry.Equal(tt.expiresAt) {
+                t.Fatalf("expiry = %v, want %v", gotExpiry, tt.expiresAt)
+            }
+            if gotVersion != tt.version {
+                t.Fatalf("version = %d, want %d", gotVersion, tt.version)
+            }
+        })
+    }
+}
+
+func TestDecodeValueBeforeVersioning(t *testing.T) {
+    expiresAt := time.Unix(1700000000, 0)
+    legacy := binary.BigEndian.AppendUint64(append([]byte{}, ttlHeader...), uint64(expiresAt.UnixNano()))
+    legacy = append(legacy, "old"...)
+
+    value, gotExpiry, version, err := decodeValue(legacy)
+    if err != nil || string(value) != "old" || !gotExpiry.Equal(expiresAt) || version != 1 {
+        t.Fatalf("decodeValue(ttl-only value) = %q, %v, %d, %v; want \"old\", %v, 1, nil",
+            value, gotExpiry, version, err, expiresAt)
+    }
+}
@@ -0,0 +1,24 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/validate.go
+
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+)
+
+// validateOnlyFromEnv reads PLUGIN_KV_VALIDATE_ONLY. When it is true the
+// server runs its whole setup, reports the result and exits without
+// serving, so a deployment's configuration can be checked on its own.
+func validateOnlyFromEnv() (bool, error) {
+    value := os.Getenv("PLUGIN_KV_VALIDATE_ONLY")
+    if value == "" {
+        return false, nil
+    }
+    validateOnly, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, fmt.Errorf("invalid PLUGIN_KV_VALIDATE_ONLY %q: %w", value, err)
+    }
+    return validateOnly, nil
+}
@@ -0,0 +1,81 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/validate_test.go
+
+package main
+
+import (
+    "errors"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// runValidation runs the server in validate-only mode with only env set and
+// returns its exit code and log.
+func runValidation(t *testing.T, env ...string) (int, string) {
+    t.Helper()
+    cmd := exec.Command(os.Args[0])
+    cmd.Env = append([]string{runMainEnv + "=1", "PLUGIN_KV_VALIDATE_ONLY=true"}, env...)
+    out, err := cmd.CombinedOutput()
+    var exitErr *exec.ExitError
+    switch {
+    case err == nil:
+        return 0, string(out)
+    case errors.As(err, &exitErr):
+        return exitErr.ExitCode(), string(out)
+    default:
+        t.Fatalf("running the server failed: %v", err)
+        return 0, ""
+    }
+}
+
+func TestValidateOnly(t *testing.T) {
+    clientCert, _, err := shared.GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    dataDir := filepath.Join(t.TempDir(), "nested", "data")
+    good := []string{
+        "PLUGIN_CLIENT_CERT=" + string(clientCert),
+        "PLUGIN_KV_DATA_DIR=" + dataDir,
+    }
+
+    code, log := runValidation(t, good...)
+    if code != 0 || !strings.Contains(log, "configuration is valid") {
+        t.Fatalf("valid configuration exited %d:\n%s", code, log)
+    }
+    if strings.Contains(log, "starting plugin server") {
+        t.Fatalf("validate-only mode started serving:\n%s", log)
+    }
+    if info, err := os.Stat(dataDir); err != nil || !info.IsDir() {
+        t.Fatalf("data directory was not created: %v", err)
+    }
+
+    notADir := filepath.Join(t.TempDir(), "file")
+    if err := os.WriteFile(notADir, nil, 0600); err != nil {
+        t.Fatalf("WriteFile failed: %v", err)
+    }
+    for name, tt := range map[string]struct {
+        env  []string
+        want string
+    }{
+        "bad scan limit":      {append(good, "PLUGIN_KV_SCAN_LIMIT=lots"), "invalid PLUGIN_KV_SCAN_LIMIT"},
+        "undecodable cert":    {[]string{"PLUGIN_CLIENT_CERT=not a pem", "PLUGIN_KV_DATA_DIR=" + dataDir}, "Invalid client certificate"},
+        "missing cert":        {[]string{"PLUGIN_KV_DATA_DIR=" + dataDir}, "no client certificate was provided"},
+        "bad encryption key":  {append(good, "PLUGIN_KV_ENCRYPTION_KEY=zz"), "PLUGIN_KV_ENCRYPTION_KEY is not valid hex"},
+        "bad socket mode":     {append(good, "PLUGIN_KV_SOCKET_MODE=0400"), "must give the owner read and write access"},
+        "data dir under file": {append(good, "PLUGIN_KV_DATA_DIR="+filepath.Join(notADir, "data")), "creating data directory"},
+    } {
+        code, log := runValidation(t, tt.env...)
+        if code == 0 || !strings.Contains(log, tt.want) {
+            t.Fatalf("%s exited %d, want a failure mentioning %q:\n%s", name, code, tt.want, log)
+        }
+    }
+
+    if code, _ := runValidation(t, append(good, "PLUGIN_KV_VALIDATE_ONLY=maybe")...); code == 0 {
+        t.Fatalf("an invalid PLUGIN_KV_VALIDATE_ONLY exited 0")
+    }
+}
@@ -0,0 +1,83 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/version.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// save writes value as the next version of key and returns that version.
+// Callers must hold key's write lock.
+func (k *KV) save(ctx context.Context, key string, value []byte, expiresAt time.Time) (uint64, error) {
+    return k.saveTyped(ctx, key, value, expiresAt, "")
+}
+
+// saveTyped is save for a value with a content type.
+func (k *KV) saveTyped(ctx context.Context, key string, value []byte, expiresAt time.Time, contentType string) (uint64, error) {
+    _, version, err := k.loadVersioned(ctx, key)
+    if err != nil && !errors.Is(err, shared.ErrKeyNotFound) {
+        return 0, err
+    }
+    version++
+
+    if err := k.store.Put(ctx, key, encodeTypedValue(value, expiresAt, version, contentType)); err != nil {
+        return 0, err
+    }
+    return version, nil
+}
+
+// GetVersioned returns the value of key and the version it was written at.
+func (k *KV) GetVersioned(ctx context.Context, key string) ([]byte, uint64, error) {
+    defer k.locks.rlock(key)()
+
+    if err := validateKey(key); err != nil {
+        return nil, 0, err
+    }
+    if err := ctx.Err(); err != nil {
+        return nil, 0, err
+    }
+
+    k.log(ctx).Debug("🗄️📥 getting versioned value", "key", key)
+    return k.loadVersioned(ctx, key)
+}
+
+// PutIfVersion holds key's write lock across the version check and the write,
+// so two callers that read the same version can't both succeed. The new
+// value keeps key's expiry and content type.
+func (k *KV) PutIfVersion(ctx context.Context, key string, value []byte, expectedVersion uint64) error {
+    defer k.locks.lock(key)()
+
+    if err := validateKey(key); err != nil {
+        return err
+    }
+    if err := shared.CheckValueSize(key, value, k.valueLimit()); err != nil {
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    k.log(ctx).Debug("🗄️🔁 putting value if version matches",
+        "key", key,
+        "value_length", len(value),
+        "expected_version", expectedVersion)
+
+    _, expiresAt, current, contentType, err := k.loadRecord(ctx, key)
+    if err != nil && !errors.Is(err, shared.ErrKeyNotFound) {
+        return err
+    }
+    if current != expectedVersion {
+        return fmt.Errorf("%w: %q is at version %d, not %d", shared.ErrVersionConflict, key, current, expectedVersion)
+    }
+
+    if err := k.store.Put(ctx, key, encodeTypedValue(value, expiresAt, current+1, contentType)); err != nil {
+        return err
+    }
+    k.publish(ctx, shared.Event{Op: shared.EventPut, Key: key, Value: value})
+    return nil
+}
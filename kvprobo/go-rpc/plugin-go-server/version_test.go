@@ -0,0 +1,104 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/version_test.go
+
+package main
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+func TestVersionIncrementsOnEachPut(t *testing.T) {
+    ctx := context.Background()
+    kv := NewKV(newFileStore(t.TempDir()), nil)
+
+    if _, _, err := kv.GetVersioned(ctx, "counter"); !errors.Is(err, shared.ErrKeyNotFound) {
+        t.Fatalf("GetVersioned(missing) = %v, want ErrKeyNotFound", err)
+    }
+
+    writes := []func() error{
+        func() error { return kv.Put(ctx, "counter", []byte("a")) },
+        func() error { return kv.PutWithTTL(ctx, "counter", []byte("b"), time.Hour) },
+        func() error { return kv.BatchPut(ctx, map[string][]byte{"counter": []byte("c")}) },
+        func() error {
+            _, err := kv.CompareAndSwap(ctx, "counter", []byte("c"), []byte("d"))
+            return err
+        },
+        func() error { return kv.PutIfVersion(ctx, "counter", []byte("e"), 4) },
+    }
+    for i, write := range writes {
+        if err := write(); err != nil {
+            t.Fatalf("write %d failed: %v", i+1, err)
+        }
+        _, version, err := kv.GetVersioned(ctx, "counter")
+        if err != nil {
+            t.Fatalf("GetVersioned after write %d failed: %v", i+1, err)
+        }
+        if version != uint64(i+1) {
+            t.Fatalf("version after write %d = %d, want %d", i+1, version, i+1)
+        }
+    }
+
+    // Versioning doesn't change what plain reads see
+    if value, err := kv.Get(ctx, "counter"); err != nil || string(value) != "e" {
+        t.Fatalf("Get = %q, %v; want \"e\", nil", value, err)
+    }
+
+    // A deleted key starts over
+    if err := kv.Delete(ctx, "counter"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+    if err := kv.Put(ctx, "counter", []byte("f")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, version, _ := kv.GetVersioned(ctx, "counter"); version != 1 {
+        t.Fatalf("version after delete and put = %d, want 1", version)
+    }
+}
+
+func TestPutIfVersionRejectsStaleVersion(t *testing.T) {
+    ctx := context.Background()
+    client := serveKV(t, NewKV(newMemStore(), nil))
+
+    if err := client.PutIfVersion(ctx, "doc", []byte("created"), 0); err != nil {
+        t.Fatalf("PutIfVersion(version 0) on a missing key failed: %v", err)
+    }
+    if err := client.PutIfVersion(ctx, "doc", []byte("again"), 0); !errors.Is(err, shared.ErrVersionConflict) {
+        t.Fatalf("PutIfVersion(version 0) on an existing key = %v, want ErrVersionConflict", err)
+    }
+
+    value, version, err := client.GetVersioned(ctx, "doc")
+    if err != nil || string(value) != "created" || version != 1 {
+        t.Fatalf("GetVersioned = %q, %d, %v; want \"created\", 1, nil", value, version, err)
+    }
+
+    // Another writer gets in first
+    if err := client.Put(ctx, "doc", []byte("theirs")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    err = client.PutIfVersion(ctx, "doc", []byte("mine"), version)
+    if !errors.Is(err, shared.ErrVersionConflict) || status.Code(err) != codes.Aborted {
+        t.Fatalf("PutIfVersion with a stale version = %v, want ErrVersionConflict with code Aborted", err)
+    }
+    if value, _ := client.Get(ctx, "doc"); string(value) != "theirs" {
+        t.Fatalf("value after rejected put = %q, want %q", value, "theirs")
+    }
+
+    // Retrying from a fresh read succeeds
+    _, version, err = client.GetVersioned(ctx, "doc")
+    if err != nil {
+        t.Fatalf("GetVersioned failed: %v", err)
+    }
+    if err := client.PutIfVersion(ctx, "doc", []byte("mine"), version); err != nil {
+        t.Fatalf("PutIfVersion with the current version failed: %v", err)
+    }
+    if _, got, _ := client.GetVersioned(ctx, "doc"); got != version+1 {
+        t.Fatalf("version after PutIfVersion = %d, want %d", got, version+1)
+    }
+}
@@ -0,0 +1,97 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/watch.go
+
+package main
+
+import (
+    "context"
+    "strings"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// watchBuffer is how many events a watcher may fall behind before further
+// events for it are dropped.
+const watchBuffer = 64
+
+// watcher receives events for keys under prefix in namespace.
+type watcher struct {
+    namespace string
+    prefix    string
+    events    chan shared.Event
+}
+
+// Watch streams changes to keys starting with prefix until ctx is done, at
+// which point the watcher is unregistered and the channel closed. Writers
+// never block on a slow watcher; events it has no room for are dropped.
+// Only writes made through this process are seen, not changes another
+// plugin instance makes to a shared data directory, and only those in the
+// namespace ctx names.
+func (k *KV) Watch(ctx context.Context, prefix string) (<-chan shared.Event, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    if _, err := keyPrefix(ctx); err != nil {
+        return nil, err
+    }
+
+    w := &watcher{
+        namespace: shared.NamespaceFromContext(ctx),
+        prefix:    prefix,
+        events:    make(chan shared.Event, watchBuffer),
+    }
+
+    k.watchMu.Lock()
+    if k.watchers == nil {
+        k.watchers = make(map[*watcher]struct{})
+    }
+    k.watchers[w] = struct{}{}
+    k.watchMu.Unlock()
+
+    k.log(ctx).Debug("🗄️👀 watcher registered", "prefix", prefix)
+
+    go func() {
+        <-ctx.Done()
+
+        k.watchMu.Lock()
+        delete(k.watchers, w)
+        close(w.events)
+        k.watchMu.Unlock()
+
+        k.log(ctx).Debug("🗄️👀 watcher unregistered", "prefix", prefix)
+    }()
+
+    return w.events, nil
+}
+
+// publish delivers event to every watcher in the namespace of ctx whose
+// prefix matches its key.
+func (k *KV) publish(ctx context.Context, event shared.Event) {
+    namespace := shared.NamespaceFromContext(ctx)
+    if allNamespaces(ctx) {
+        namespace, event.Key = splitNamespace(event.Key)
+    }
+
+    k.watchMu.Lock()
+    defer k.watchMu.Unlock()
+
+    copied := false
+    for w := range k.watchers {
+        if w.namespace != namespace || !strings.HasPrefix(event.Key, w.prefix) {
+            continue
+        }
+        // Watchers read the value after the write returns, so don't share
+        // the caller's buffer.
+        if !copied && event.Value != nil {
+            event.Value = append([]byte{}, event.Value...)
+            copied = true
+        }
+        select {
+        case w.events <- event:
+        default:
+            k.log(ctx).Warn("🗄️⚠️ watcher is falling behind, dropping event",
+                "prefix", w.prefix,
+                "key", event.Key,
+                "op", event.Op)
+        }
+    }
+}
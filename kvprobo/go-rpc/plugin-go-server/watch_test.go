@@ -0,0 +1,86 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/watch_test.go
+
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// nextEvent waits briefly for the next event on events.
+func nextEvent(t *testing.T, events <-chan shared.Event) shared.Event {
+    t.Helper()
+
+    select {
+    case event, ok := <-events:
+        if !ok {
+            t.Fatal("watch channel closed unexpectedly")
+        }
+        return event
+    case <-time.After(5 * time.Second):
+        t.Fatal("timed out waiting for a watch event")
+    }
+    return shared.Event{}
+}
+
+func TestWatchStreamsChanges(t *testing.T) {
+    kv := NewKV(newMemStore(), nil)
+    client := serveKV(t, kv)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    events, err := client.Watch(ctx, "app.")
+    if err != nil {
+        t.Fatalf("Watch failed: %v", err)
+    }
+
+    if err := client.Put(ctx, "other", []byte("ignored")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := client.Put(ctx, "app.name", []byte("kv")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := client.Delete(ctx, "app.name"); err != nil {
+        t.Fatalf("Delete failed: %v", err)
+    }
+
+    if got := nextEvent(t, events); got.Op != shared.EventPut || got.Key != "app.name" || string(got.Value) != "kv" {
+        t.Fatalf("first event = %+v, want put app.name=kv", got)
+    }
+    if got := nextEvent(t, events); got.Op != shared.EventDelete || got.Key != "app.name" {
+        t.Fatalf("second event = %+v, want delete app.name", got)
+    }
+}
+
+func TestWatchUnregistersOnDisconnect(t *testing.T) {
+    kv := NewKV(newMemStore(), nil)
+    client := serveKV(t, kv)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    events, err := client.Watch(ctx, "")
+    if err != nil {
+        t.Fatalf("Watch failed: %v", err)
+    }
+    cancel()
+
+    // The client channel closes and the server drops its watcher
+    for range events {
+    }
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        kv.watchMu.Lock()
+        remaining := len(kv.watchers)
+        kv.watchMu.Unlock()
+        if remaining == 0 {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("%d watchers still registered after the client disconnected", remaining)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
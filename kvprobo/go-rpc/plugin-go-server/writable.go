@@ -0,0 +1,79 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/writable.go
+
+package main
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// writableCheckInterval is how often the data directory is probed.
+const writableCheckInterval = 10 * time.Second
+
+// writableProbePattern names the probe directories. Like fileStoreTempDir
+// it contains "..", so it can never collide with a key, and List skips it
+// because it is a directory.
+const writableProbePattern = "..kv-probe-*"
+
+// storeDataDir returns the directory store keeps its data in, looking
+// through the wrappers newStoreFromEnv adds, or "" for a store with none.
+func storeDataDir(store Store) string {
+    for {
+        switch s := store.(type) {
+        case *fileStore:
+            return s.dir
+        case *boltStore:
+            return filepath.Dir(s.db.Path())
+        case *encryptedStore:
+            store = s.Store
+        case *compressedStore:
+            store = s.Store
+        case *cachedStore:
+            store = s.Store
+        default:
+            return ""
+        }
+    }
+}
+
+// probeWritable creates a directory in dir holding one fsynced byte, then
+// removes it, which fails once dir loses write permission or fills up.
+func probeWritable(dir string) error {
+    probeDir, err := os.MkdirTemp(dir, writableProbePattern)
+    if err != nil {
+        return err
+    }
+    defer os.RemoveAll(probeDir)
+    return writeFileSync(filepath.Join(probeDir, "probe"), []byte{0}, 0600)
+}
+
+// watchWritable probes dir every interval until ctx is cancelled, marking
+// h unwritable while the probes fail.
+func watchWritable(ctx context.Context, dir string, interval time.Duration, h *kvHealth, logger hclog.Logger) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    var failing error
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            err := probeWritable(dir)
+            switch {
+            case err != nil && failing == nil:
+                logger.Error("🗄️❌ data directory is not writable, refusing writes", "path", dir, "error", err)
+            case err == nil && failing != nil:
+                logger.Info("🗄️✅ data directory is writable again", "path", dir)
+            }
+            if (err == nil) != (failing == nil) {
+                h.setUnwritable(err)
+            }
+            failing = err
+        }
+    }
+}
@@ -0,0 +1,155 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/plugin-go-server/writable_test.go
+
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/shared"
+)
+
+// revokeWrites makes dir unwritable until the returned func restores it.
+// Permission bits don't stop root, so there dir is moved away instead.
+func revokeWrites(t *testing.T, dir string) func() {
+    t.Helper()
+    if err := os.Chmod(dir, 0500); err != nil {
+        t.Fatalf("Chmod failed: %v", err)
+    }
+    if probeWritable(dir) != nil {
+        return func() { os.Chmod(dir, 0700) }
+    }
+    os.Chmod(dir, 0700)
+    moved := dir + ".moved"
+    if err := os.Rename(dir, moved); err != nil {
+        t.Fatalf("Rename failed: %v", err)
+    }
+    return func() { os.Rename(moved, dir) }
+}
+
+// syncBuffer is a bytes.Buffer safe to log to from the watcher goroutine.
+type syncBuffer struct {
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.buf.String()
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) {
+            t.Fatalf("timed out waiting for %s", what)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+func TestUnwritableDataDirStopsWrites(t *testing.T) {
+    dir := filepath.Join(t.TempDir(), "data")
+    if err := os.Mkdir(dir, 0700); err != nil {
+        t.Fatalf("Mkdir failed: %v", err)
+    }
+    h := newKVHealth()
+    h.setServing(true)
+    server := newGRPCServer(nil, serverOptions{health: h}, hclog.NewNullLogger())
+    // Stand in for the Health service go-plugin registers
+    healthpb.RegisterHealthServer(server, health.NewServer())
+    client := serveKVOn(t, server, NewKV(newFileStore(dir), nil))
+
+    var logs syncBuffer
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go watchWritable(ctx, dir, 10*time.Millisecond, h, hclog.New(&hclog.LoggerOptions{Output: &logs}))
+
+    if err := client.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    restore := revokeWrites(t, dir)
+    defer restore()
+
+    waitFor(t, "health to report NOT_SERVING", func() bool {
+        return errors.Is(client.HealthCheck(ctx), shared.ErrNotServing)
+    })
+    if err := client.Put(ctx, "k", []byte("v2")); status.Code(err) != codes.Unavailable {
+        t.Fatalf("Put to an unwritable data directory = %v, want Unavailable", err)
+    }
+    if !strings.Contains(logs.String(), "data directory is not writable") {
+        t.Fatalf("watcher didn't log the failure:\n%s", logs.String())
+    }
+
+    // Recovery brings writes back
+    restore()
+    waitFor(t, "health to report SERVING", func() bool {
+        return client.HealthCheck(ctx) == nil
+    })
+    if err := client.Put(ctx, "k", []byte("v3")); err != nil {
+        t.Fatalf("Put after recovery failed: %v", err)
+    }
+}
+
+func TestUnwritableStoreStillServesReads(t *testing.T) {
+    h := newKVHealth()
+    h.setServing(true)
+    kv := NewKV(newMemStore(), nil)
+    client := serveKVOn(t, newGRPCServer(nil, serverOptions{health: h}, hclog.NewNullLogger()), kv)
+
+    ctx := context.Background()
+    if err := client.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    h.setUnwritable(errors.New("disk full"))
+    if value, err := client.Get(ctx, "k"); err != nil || string(value) != "v" {
+        t.Fatalf("Get() while unwritable = %q, %v, want v", value, err)
+    }
+    if err := client.Delete(ctx, "k"); status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "disk full") {
+        t.Fatalf("Delete while unwritable = %v, want Unavailable naming the cause", err)
+    }
+}
+
+func TestStoreDataDir(t *testing.T) {
+    dir := t.TempDir()
+    bolt, err := newBoltStore(filepath.Join(dir, "kv.db"))
+    if err != nil {
+        t.Fatalf("newBoltStore failed: %v", err)
+    }
+    defer bolt.Close()
+    for _, tt := range []struct {
+        store Store
+        want  string
+    }{
+        {newFileStore(dir), dir},
+        {newCachedStore(newCompressedStore(newFileStore(dir), true), 10), dir},
+        {bolt, dir},
+        {newMemStore(), ""},
+    } {
+        if got := storeDataDir(tt.store); got != tt.want {
+            t.Fatalf("storeDataDir(%T) = %q, want %q", tt.store, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,50 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/certpin.go
+
+package shared
+
+import (
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// ErrCertPinMismatch is returned when the plugin presents a certificate
+// other than the one PLUGIN_SERVER_CERT_PIN names.
+var ErrCertPinMismatch = errors.New("certificate pin mismatch")
+
+// CertPinFromEnv reads PLUGIN_SERVER_CERT_PIN, the SHA-256 fingerprint of
+// the only certificate the plugin may present, in the form
+// CertificateFingerprint prints. Colons and case are optional. It returns
+// "" when no pin is set.
+func CertPinFromEnv() (string, error) {
+    value := os.Getenv("PLUGIN_SERVER_CERT_PIN")
+    if value == "" {
+        return "", nil
+    }
+    digest, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(value), ":", ""))
+    if err != nil || len(digest) != sha256.Size {
+        return "", fmt.Errorf("invalid PLUGIN_SERVER_CERT_PIN %q: want a SHA-256 fingerprint such as openssl x509 -fingerprint -sha256 prints", value)
+    }
+    return colonHex(digest), nil
+}
+
+// PinPeerCertificate makes config refuse a peer whose leaf certificate
+// doesn't have fingerprint pin, on top of the usual chain verification, so
+// a certificate that merely chains to a trusted CA isn't enough.
+func PinPeerCertificate(config *tls.Config, pin string) {
+    config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+        if len(rawCerts) == 0 {
+            return fmt.Errorf("%w: no certificate presented", ErrCertPinMismatch)
+        }
+        sum := sha256.Sum256(rawCerts[0])
+        if got := colonHex(sum[:]); got != pin {
+            return fmt.Errorf("%w: got %s, want %s", ErrCertPinMismatch, got, pin)
+        }
+        return nil
+    }
+}
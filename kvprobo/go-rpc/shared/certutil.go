@@ -3,15 +3,21 @@ package shared
 
 import (
     "bytes"
+    "crypto"
     "crypto/ecdsa"
+    "crypto/ed25519"
     "crypto/elliptic"
     "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
     "crypto/tls"
     "crypto/x509"
     "crypto/x509/pkix"
     "encoding/pem"
     "fmt"
     "math/big"
+    "net"
+    "os"
     "errors"
     "time"
 
@@ -20,14 +26,37 @@ import (
     "github.com/hashicorp/go-hclog"
 )
 
+var (
+    // ErrCertNotYetValid is returned when a certificate's NotBefore is in the future
+    ErrCertNotYetValid = errors.New("certificate is not yet valid")
+    // ErrCertExpired is returned when a certificate's NotAfter has passed
+    ErrCertExpired = errors.New("certificate has expired")
+)
+
+// KeyType selects the private key algorithm used when generating certificates
+type KeyType string
+
+const (
+    // KeyTypeECDSA generates an ECDSA P-521 key
+    KeyTypeECDSA KeyType = "ecdsa"
+    // KeyTypeRSA generates an RSA key of CertificateConfig.KeySize bits
+    KeyTypeRSA KeyType = "rsa"
+    // KeyTypeEd25519 generates an Ed25519 key, stored as PKCS#8
+    KeyTypeEd25519 KeyType = "ed25519"
+)
+
 // CertificateConfig holds the configuration for generating TLS certificates
 type CertificateConfig struct {
     CommonName  string
     ValidFor    time.Duration
+    KeyType     KeyType
     KeySize     int
     IsCA        bool
     ServerName  string
     DNSNames    []string
+    IPAddresses []net.IP
+    // IPAddressStrings are parsed with net.ParseIP and added to IPAddresses
+    IPAddressStrings []string
 }
 
 // DefaultCertificateConfig returns a default configuration for local development
@@ -35,6 +64,7 @@ func DefaultCertificateConfig() *CertificateConfig {
     return &CertificateConfig{
         CommonName:  "localhost",
         ValidFor:    24 * time.Hour,
+        KeyType:     KeyTypeECDSA,
         KeySize:     2048,
         IsCA:        true,
         ServerName:  "localhost",
@@ -42,75 +72,249 @@ func DefaultCertificateConfig() *CertificateConfig {
     }
 }
 
-// GenerateCert generates a temporary certificate for plugin authentication.
+// generatePrivateKey creates a private key of the configured type and
+// returns it together with its PEM block.
+func generatePrivateKey(cfg *CertificateConfig, logger hclog.Logger) (crypto.Signer, *pem.Block, error) {
+    switch cfg.KeyType {
+    case KeyTypeRSA:
+        keySize := cfg.KeySize
+        if keySize == 0 {
+            keySize = 2048
+        }
+
+        key, err := rsa.GenerateKey(rand.Reader, keySize)
+        if err != nil {
+            return nil, nil, err
+        }
+        logger.Debug("🔐✅ generated RSA private key", "bits", keySize)
+
+        return key, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}, nil
+
+    case KeyTypeECDSA, "":
+        key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+        if err != nil {
+            return nil, nil, err
+        }
+        logger.Debug("🔐✅ generated ECDSA P-521 private key")
+
+        keyBytes, err := x509.MarshalECPrivateKey(key)
+        if err != nil {
+            return nil, nil, err
+        }
+        return key, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}, nil
+
+    case KeyTypeEd25519:
+        _, key, err := ed25519.GenerateKey(rand.Reader)
+        if err != nil {
+            return nil, nil, err
+        }
+        logger.Debug("🔐✅ generated Ed25519 private key")
+
+        keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+        if err != nil {
+            return nil, nil, err
+        }
+        return key, &pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}, nil
+
+    default:
+        return nil, nil, fmt.Errorf("unsupported key type %q", cfg.KeyType)
+    }
+}
+
+// GenerateCert generates a temporary self-signed certificate for plugin
+// authentication from cfg, falling back to DefaultCertificateConfig when cfg
+// is nil. Returns the certificate and private key in PEM format.
+//
+// The result acts as both CA and serving certificate; prefer GenerateCA and
+// GenerateSignedCert when the two roles can be separated.
+func GenerateCert(cfg *CertificateConfig, logger hclog.Logger) ([]byte, []byte, error) {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+    if cfg == nil {
+        cfg = DefaultCertificateConfig()
+    }
+
+    logger.Debug("🔐 generating temporary certificate", "key_type", cfg.KeyType)
+
+    key, keyBlock, err := generatePrivateKey(cfg, logger)
+    if err != nil {
+        logger.Error("🔐❌ private key generation failed", "error", err)
+        return nil, nil, err
+    }
+
+    template, err := newCertificateTemplate(cfg, logger)
+    if err != nil {
+        return nil, nil, err
+    }
+    template.KeyUsage = x509.KeyUsageDigitalSignature
+    // Ed25519 keys can only sign
+    if _, ok := key.(ed25519.PrivateKey); !ok {
+        template.KeyUsage |= x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement
+    }
+    if cfg.IsCA {
+        template.KeyUsage |= x509.KeyUsageCertSign
+    }
+    template.ExtKeyUsage = []x509.ExtKeyUsage{
+        x509.ExtKeyUsageClientAuth,
+        x509.ExtKeyUsageServerAuth,
+    }
+    template.IsCA = cfg.IsCA
+
+    return signCertificate(template, template, key.Public(), key, keyBlock, logger)
+}
+
+// GenerateCA generates a self-signed CA certificate that can only be used to
+// issue other certificates. Returns the certificate and private key in PEM format.
+func GenerateCA(cfg *CertificateConfig, logger hclog.Logger) ([]byte, []byte, error) {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+    if cfg == nil {
+        cfg = DefaultCertificateConfig()
+    }
+
+    logger.Debug("🔐🏛️ generating CA certificate", "key_type", cfg.KeyType)
+
+    key, keyBlock, err := generatePrivateKey(cfg, logger)
+    if err != nil {
+        logger.Error("🔐❌ private key generation failed", "error", err)
+        return nil, nil, err
+    }
+
+    template, err := newCertificateTemplate(cfg, logger)
+    if err != nil {
+        return nil, nil, err
+    }
+    template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+    template.IsCA = true
+    template.MaxPathLenZero = true
+
+    return signCertificate(template, template, key.Public(), key, keyBlock, logger)
+}
+
+// GenerateSignedCert issues a leaf certificate for cfg signed by ca. The leaf
+// is never a CA and carries only client/server authentication usages.
 // Returns the certificate and private key in PEM format.
-func GenerateCert(logger hclog.Logger) ([]byte, []byte, error) {
+func GenerateSignedCert(ca *x509.Certificate, caKey crypto.Signer, cfg *CertificateConfig, logger hclog.Logger) ([]byte, []byte, error) {
     if logger == nil {
         logger = hclog.NewNullLogger()
     }
+    if cfg == nil {
+        cfg = DefaultCertificateConfig()
+    }
+    if ca == nil || caKey == nil {
+        return nil, nil, errors.New("a CA certificate and key are required")
+    }
+    if !ca.IsCA {
+        return nil, nil, fmt.Errorf("certificate %q is not a CA", ca.Subject.CommonName)
+    }
 
-    logger.Debug("🔐 generating temporary certificate")
+    logger.Debug("🔐📜 generating CA-signed certificate",
+        "key_type", cfg.KeyType,
+        "issuer", ca.Subject.CommonName)
 
-    // Generate ECDSA private key using P-521 curve
-    key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+    key, keyBlock, err := generatePrivateKey(cfg, logger)
     if err != nil {
         logger.Error("🔐❌ private key generation failed", "error", err)
         return nil, nil, err
     }
-    logger.Debug("🔐✅ generated ECDSA P-521 private key")
 
+    template, err := newCertificateTemplate(cfg, logger)
+    if err != nil {
+        return nil, nil, err
+    }
+    template.KeyUsage = x509.KeyUsageDigitalSignature
+    if _, ok := key.(*rsa.PrivateKey); ok {
+        template.KeyUsage |= x509.KeyUsageKeyEncipherment
+    }
+    template.ExtKeyUsage = []x509.ExtKeyUsage{
+        x509.ExtKeyUsageClientAuth,
+        x509.ExtKeyUsageServerAuth,
+    }
+    template.IsCA = false
+
+    // A leaf must not outlive the CA that vouches for it
+    if template.NotAfter.After(ca.NotAfter) {
+        template.NotAfter = ca.NotAfter
+    }
+
+    return signCertificate(template, ca, key.Public(), caKey, keyBlock, logger)
+}
+
+// newCertificateTemplate fills in the identity, SANs, serial number and
+// validity window from cfg. Callers set the key usages for their role.
+func newCertificateTemplate(cfg *CertificateConfig, logger hclog.Logger) (*x509.Certificate, error) {
     // Generate serial number
     serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
     serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
     if err != nil {
         logger.Error("🔐❌ serial number generation failed", "error", err)
-        return nil, nil, err
+        return nil, err
     }
 
     logger.Debug("🔐✅ generated serial number", "serial", serialNumber)
 
-    host := "localhost"
-    template := &x509.Certificate{
+    commonName := cfg.CommonName
+    if commonName == "" {
+        commonName = cfg.ServerName
+    }
+
+    // Make sure the expected server name verifies against the SANs
+    dnsNames := append([]string{}, cfg.DNSNames...)
+    if cfg.ServerName != "" && !containsString(dnsNames, cfg.ServerName) {
+        dnsNames = append(dnsNames, cfg.ServerName)
+    }
+
+    ipAddresses, err := cfg.ipAddresses()
+    if err != nil {
+        logger.Error("🔐❌ invalid IP SAN", "error", err)
+        return nil, err
+    }
+
+    validFor := cfg.ValidFor
+    if validFor <= 0 {
+        validFor = DefaultCertificateConfig().ValidFor
+    }
+
+    notBefore := time.Now().Add(-30 * time.Second)
+    return &x509.Certificate{
         Subject: pkix.Name{
-            CommonName:   host,
+            CommonName:   commonName,
             Organization: []string{"HashiCorp"},
         },
-        DNSNames: []string{host},
-        ExtKeyUsage: []x509.ExtKeyUsage{
-            x509.ExtKeyUsageClientAuth,
-            x509.ExtKeyUsageServerAuth,
-        },
-        KeyUsage: x509.KeyUsageDigitalSignature |
-            x509.KeyUsageKeyEncipherment |
-            x509.KeyUsageKeyAgreement |
-            x509.KeyUsageCertSign,
+        DNSNames:              dnsNames,
+        IPAddresses:           ipAddresses,
         BasicConstraintsValid: true,
-        SerialNumber:         serialNumber,
-        NotBefore:           time.Now().Add(-30 * time.Second),
-        NotAfter:            time.Now().Add(262980 * time.Hour), // 30 years
-        IsCA:                true,
-    }
-
-    serialBytes := template.SerialNumber.Bytes()
-    serialHex := make([]string, len(serialBytes))
-    for i, b := range serialBytes {
-        serialHex[i] = fmt.Sprintf("%02x", b)
-    }
+        SerialNumber:          serialNumber,
+        NotBefore:             notBefore,
+        NotAfter:              notBefore.Add(validFor),
+    }, nil
+}
 
-    logger.Debug("   🔢 Serial Number: " + strings.Join(serialHex, ":"))
+// signCertificate creates template signed by parent/signer and returns the
+// certificate and keyBlock as PEM.
+func signCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer, keyBlock *pem.Block, logger hclog.Logger) ([]byte, []byte, error) {
+    logger.Debug("   🔢 Serial Number: " + colonHex(template.SerialNumber.Bytes()))
 
     logger.Debug("🔐📝 created certificate template",
         "common_name", template.Subject.CommonName,
         "organization", template.Subject.Organization,
-        "dns_names", template.DNSNames)
+        "dns_names", template.DNSNames,
+        "ip_addresses", template.IPAddresses,
+        "not_after", template.NotAfter,
+        "is_ca", template.IsCA)
 
-    // Create self-signed certificate
-    der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+    der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
     if err != nil {
         logger.Error("🔐❌ certificate creation failed", "error", err)
         return nil, nil, err
     }
-    logger.Debug("🔐✅ created self-signed certificate")
+    if template == parent {
+        logger.Debug("🔐✅ created self-signed certificate")
+    } else {
+        logger.Debug("🔐✅ created certificate", "issuer", parent.Subject.CommonName)
+    }
 
     // PEM encode the certificate
     var certOut bytes.Buffer
@@ -119,16 +323,9 @@ func GenerateCert(logger hclog.Logger) ([]byte, []byte, error) {
         return nil, nil, err
     }
 
-    // Marshal the private key
-    keyBytes, err := x509.MarshalECPrivateKey(key)
-    if err != nil {
-        logger.Error("🔐❌ private key marshaling failed", "error", err)
-        return nil, nil, err
-    }
-
     // PEM encode the private key
     var keyOut bytes.Buffer
-    if err := pem.Encode(&keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}); err != nil {
+    if err := pem.Encode(&keyOut, keyBlock); err != nil {
         logger.Error("🔐❌ private key PEM encoding failed", "error", err)
         return nil, nil, err
     }
@@ -137,6 +334,29 @@ func GenerateCert(logger hclog.Logger) ([]byte, []byte, error) {
     return certOut.Bytes(), keyOut.Bytes(), nil
 }
 
+// ipAddresses returns the configured IP SANs, parsing any string forms.
+func (cfg *CertificateConfig) ipAddresses() ([]net.IP, error) {
+    ips := append([]net.IP{}, cfg.IPAddresses...)
+    for _, raw := range cfg.IPAddressStrings {
+        ip := net.ParseIP(strings.TrimSpace(raw))
+        if ip == nil {
+            return nil, fmt.Errorf("invalid IP address %q", raw)
+        }
+        ips = append(ips, ip)
+    }
+    return ips, nil
+}
+
+// containsString reports whether values contains s.
+func containsString(values []string, s string) bool {
+    for _, v := range values {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
+
 // ParseCertificate parses a PEM encoded certificate and returns the x509 certificate
 func ParseCertificate(certPEM []byte, logger hclog.Logger) (*x509.Certificate, error) {
     if logger == nil {
@@ -166,8 +386,8 @@ func ParseCertificate(certPEM []byte, logger hclog.Logger) (*x509.Certificate, e
     return cert, nil
 }
 
-// ParsePrivateKey parses a PEM encoded ECDSA private key
-func ParsePrivateKey(keyPEM []byte, logger hclog.Logger) (*ecdsa.PrivateKey, error) {
+// ParsePrivateKey parses a PEM encoded EC, RSA or PKCS#8 private key
+func ParsePrivateKey(keyPEM []byte, logger hclog.Logger) (crypto.Signer, error) {
     if logger == nil {
         logger = hclog.NewNullLogger()
     }
@@ -180,18 +400,127 @@ func ParsePrivateKey(keyPEM []byte, logger hclog.Logger) (*ecdsa.PrivateKey, err
         return nil, fmt.Errorf("failed to decode PEM block")
     }
 
-    key, err := x509.ParseECPrivateKey(block.Bytes)
+    var key crypto.Signer
+    var err error
+    switch block.Type {
+    case "EC PRIVATE KEY":
+        key, err = x509.ParseECPrivateKey(block.Bytes)
+    case "RSA PRIVATE KEY":
+        key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+    case "PRIVATE KEY":
+        key, err = parsePKCS8Signer(block.Bytes)
+    default:
+        err = fmt.Errorf("unsupported private key PEM type %q", block.Type)
+    }
     if err != nil {
         logger.Error("🔍❌ private key parsing failed", "error", err)
         return nil, err
     }
 
-    logger.Debug("🔍✅ private key parsed successfully")
+    logger.Debug("🔍✅ private key parsed successfully", "pem_type", block.Type)
+    return key, nil
+}
+
+// parsePKCS8Signer parses a PKCS#8 key, as written for Ed25519, and checks
+// it can sign.
+func parsePKCS8Signer(der []byte) (crypto.Signer, error) {
+    parsed, err := x509.ParsePKCS8PrivateKey(der)
+    if err != nil {
+        return nil, err
+    }
+    key, ok := parsed.(crypto.Signer)
+    if !ok {
+        return nil, fmt.Errorf("PKCS#8 private key of type %T cannot sign", parsed)
+    }
     return key, nil
 }
 
+// colonHex formats b as lowercase colon-delimited hex pairs, e.g. "0a:1b:2c".
+func colonHex(b []byte) string {
+    pairs := make([]string, len(b))
+    for i, v := range b {
+        pairs[i] = fmt.Sprintf("%02x", v)
+    }
+    return strings.Join(pairs, ":")
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of cert's DER
+// encoding as lowercase colon-delimited hex.
+func CertificateFingerprint(cert *x509.Certificate) string {
+    sum := sha256.Sum256(cert.Raw)
+    return colonHex(sum[:])
+}
+
+// CheckCertValidity reports whether cert is usable at now, returning an error
+// wrapping ErrCertNotYetValid or ErrCertExpired when it is outside its validity window.
+func CheckCertValidity(cert *x509.Certificate, now time.Time) error {
+    if cert == nil {
+        return errors.New("no certificate provided")
+    }
+
+    if now.Before(cert.NotBefore) {
+        return fmt.Errorf("%w: %q is valid from %s (now %s)",
+            ErrCertNotYetValid, cert.Subject.CommonName,
+            cert.NotBefore.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+    }
+    if now.After(cert.NotAfter) {
+        return fmt.Errorf("%w: %q expired at %s (now %s)",
+            ErrCertExpired, cert.Subject.CommonName,
+            cert.NotAfter.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+    }
+    return nil
+}
+
+// LoadCertificate reads a PEM encoded certificate and private key from disk and
+// returns them as a tls.Certificate, failing if the key does not match the certificate.
+func LoadCertificate(certPath, keyPath string, logger hclog.Logger) (tls.Certificate, error) {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+
+    logger.Debug("📂 loading certificate from disk", "cert_path", certPath, "key_path", keyPath)
+
+    certPEM, err := os.ReadFile(certPath)
+    if err != nil {
+        logger.Error("📂❌ failed to read certificate file", "path", certPath, "error", err)
+        return tls.Certificate{}, fmt.Errorf("reading certificate %q: %w", certPath, err)
+    }
+
+    keyPEM, err := os.ReadFile(keyPath)
+    if err != nil {
+        logger.Error("📂❌ failed to read private key file", "path", keyPath, "error", err)
+        return tls.Certificate{}, fmt.Errorf("reading private key %q: %w", keyPath, err)
+    }
+
+    // Parse both halves first so malformed files get a specific error
+    if _, err := ParseCertificate(certPEM, logger); err != nil {
+        return tls.Certificate{}, fmt.Errorf("parsing certificate %q: %w", certPath, err)
+    }
+    if _, err := ParsePrivateKey(keyPEM, logger); err != nil {
+        return tls.Certificate{}, fmt.Errorf("parsing private key %q: %w", keyPath, err)
+    }
+
+    cert, err := tls.X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        logger.Error("📂❌ certificate and private key do not match", "error", err)
+        return tls.Certificate{}, fmt.Errorf("certificate %q and private key %q do not match: %w", certPath, keyPath, err)
+    }
+
+    logger.Debug("📂✅ certificate loaded successfully",
+        "subject", cert.Leaf.Subject.CommonName,
+        "not_after", cert.Leaf.NotAfter)
+    return cert, nil
+}
+
 // CreateTLSConfig creates a TLS configuration suitable for client or server
-func CreateTLSConfig(cert *x509.Certificate, key *ecdsa.PrivateKey, certPool *x509.CertPool, isServer bool, logger hclog.Logger) *tls.Config {
+// use with the default TLSPolicy.
+func CreateTLSConfig(cert *x509.Certificate, key crypto.Signer, certPool *x509.CertPool, isServer bool, logger hclog.Logger) *tls.Config {
+    return CreateTLSConfigWithPolicy(cert, key, certPool, isServer, TLSPolicy{}, logger)
+}
+
+// CreateTLSConfigWithPolicy is CreateTLSConfig restricted to the versions
+// and cipher suites in policy.
+func CreateTLSConfigWithPolicy(cert *x509.Certificate, key crypto.Signer, certPool *x509.CertPool, isServer bool, policy TLSPolicy, logger hclog.Logger) *tls.Config {
     if logger == nil {
         logger = hclog.NewNullLogger()
     }
@@ -203,10 +532,11 @@ func CreateTLSConfig(cert *x509.Certificate, key *ecdsa.PrivateKey, certPool *x5
             {
                 Certificate: [][]byte{cert.Raw},
                 PrivateKey:  key,
+                Leaf:        cert,
             },
         },
-        MinVersion: tls.VersionTLS12,
     }
+    policy.Apply(config)
 
     if isServer {
         config.ClientAuth = tls.RequireAndVerifyClientCert
@@ -217,7 +547,7 @@ func CreateTLSConfig(cert *x509.Certificate, key *ecdsa.PrivateKey, certPool *x5
 
     logger.Debug("🔒✅ TLS config created",
         "is_server", isServer,
-        "min_version", "TLS1.2")
+        "min_version", tls.VersionName(config.MinVersion))
 
     return config
 }
@@ -291,34 +621,31 @@ func generateCert() (cert []byte, privateKey []byte, err error) {
 // Returns:
 // - *x509.Certificate representing the parsed certificate.
 // - error if decoding or parsing fails.
-//func DecodeAndLogCertificate(certPEM string, logger hclog.Logger) (*x509.Certificate, error) {
-func DecodeAndLogCertificate(certPEM string, logger hclog.Logger) error {
+func DecodeAndLogCertificate(certPEM string, logger hclog.Logger) (*x509.Certificate, error) {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+
     block, _ := pem.Decode([]byte(certPEM))
     if block == nil {
         logger.Error("❌ Failed to decode certificate PEM")
-        return errors.New("failed to decode certificate PEM")
+        return nil, errors.New("failed to decode certificate PEM")
     }
 
     cert, err := x509.ParseCertificate(block.Bytes)
     if err != nil {
-        logger.Error("❌ Error parsing certificate: %v", err)
-        return errors.New("Error parsing certificate.")
-    }
-
-    // Format serial number as colon-delimited hex
-    serialBytes := cert.SerialNumber.Bytes()
-    serialHex := make([]string, len(serialBytes))
-    for i, b := range serialBytes {
-        serialHex[i] = fmt.Sprintf("%02x", b)
+        logger.Error("❌ Error parsing certificate", "error", err)
+        return nil, fmt.Errorf("error parsing certificate: %w", err)
     }
 
     logger.Debug("📜 Certificate Information:")
-    logger.Debug("   🔢 Serial Number: " + strings.Join(serialHex, ":"))
+    logger.Debug("   🔢 Serial Number: " + colonHex(cert.SerialNumber.Bytes()))
+    logger.Debug("   🧬 SHA-256 Fingerprint: " + CertificateFingerprint(cert))
     logger.Debug("   🏷️  Subject: " + cert.Subject.String())
     logger.Debug("   🏢 Organization: " + strings.Join(cert.Subject.Organization, ", "))
     logger.Debug("   🌐 Common Name: " + cert.Subject.CommonName)
     logger.Debug("   📆 Valid From: " + cert.NotBefore.String())
     logger.Debug("   📆 Valid To: " + cert.NotAfter.String())
 
-    return nil
+    return cert, nil
 }
@@ -0,0 +1,30 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/contenttype.go
+
+package shared
+
+import (
+    "fmt"
+    "mime"
+)
+
+// DefaultContentType is the content type of a value stored without one.
+const DefaultContentType = "application/octet-stream"
+
+// MaxContentTypeBytes is the longest content type a value can be stored with.
+const MaxContentTypeBytes = 255
+
+// CheckContentType returns ErrInvalidContentType unless contentType is
+// empty or a media type, with optional parameters, of at most
+// MaxContentTypeBytes.
+func CheckContentType(contentType string) error {
+    if contentType == "" {
+        return nil
+    }
+    if len(contentType) > MaxContentTypeBytes {
+        return fmt.Errorf("%w: %d bytes, limit is %d", ErrInvalidContentType, len(contentType), MaxContentTypeBytes)
+    }
+    if _, _, err := mime.ParseMediaType(contentType); err != nil {
+        return fmt.Errorf("%w: %q: %v", ErrInvalidContentType, contentType, err)
+    }
+    return nil
+}
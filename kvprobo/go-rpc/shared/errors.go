@@ -0,0 +1,202 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/errors.go
+
+package shared
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "google.golang.org/genproto/googleapis/rpc/errdetails"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+)
+
+// ErrKeyNotFound is returned when an operation targets a key that has no stored value.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrKeyExists is returned when a write that mustn't overwrite finds its key
+// already holding a value: by KV.Rename without overwrite, and by the
+// plugin's Store.Create and Store.Rename. KV.PutIfAbsent gets it from
+// Store.Create, and reports it to callers as false rather than an error.
+var ErrKeyExists = errors.New("key already exists")
+
+// ErrInvalidKey is returned when a key cannot be safely mapped to a backing file.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ErrInvalidNamespace is returned when the kv-namespace header names a
+// namespace that can't be stored.
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+// ErrInvalidPageToken is returned by ListPage for a page token it didn't
+// issue for the same prefix.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ErrInvalidContentType is returned for a content type that isn't a valid
+// media type.
+var ErrInvalidContentType = errors.New("invalid content type")
+
+// ErrInvalidTTL is returned for a TTL that is negative or too long to
+// represent, rather than storing a wrapped-around expiry.
+var ErrInvalidTTL = errors.New("invalid ttl")
+
+// ErrValueTooLarge is returned when a value exceeds the configured size limit.
+var ErrValueTooLarge = errors.New("value too large")
+
+// ErrStorageFailure is returned when the storage backend fails for a reason
+// other than a missing or invalid key.
+var ErrStorageFailure = errors.New("storage failure")
+
+// ErrDecryptionFailed is returned when a stored value can't be decrypted,
+// usually because it was written with a different encryption key.
+var ErrDecryptionFailed = errors.New("decryption failed")
+
+// ErrNotServing is returned by HealthCheck when the plugin reports it
+// cannot take requests.
+var ErrNotServing = errors.New("plugin is not serving")
+
+// ErrVersionConflict is returned by PutIfVersion when the key has been
+// written since the caller read it.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNotANumber is returned by Increment when the stored value is not a
+// base-10 integer.
+var ErrNotANumber = errors.New("value is not a number")
+
+// ErrOutOfRange is returned by Increment when adding the delta would take
+// the total outside int64.
+var ErrOutOfRange = errors.New("value out of range")
+
+// ErrReadOnly is returned for a write to a server running in read-only mode.
+var ErrReadOnly = errors.New("server is read-only")
+
+// ErrPermissionDenied is returned for a request the server's access list
+// doesn't allow the client's certificate to make.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrCompareFailed is returned when a transaction's compare-and-swap finds
+// the key missing or holding a different value.
+var ErrCompareFailed = errors.New("compare-and-swap condition not met")
+
+// TxError reports which operation made a Transaction fail. Nothing in the
+// transaction was applied.
+type TxError struct {
+    Index int
+    Err   error
+}
+
+func (e *TxError) Error() string { return fmt.Sprintf("transaction op %d: %v", e.Index, e.Err) }
+func (e *TxError) Unwrap() error { return e.Err }
+
+// txErrorReason tags the ErrorInfo detail that carries a TxError's index
+// across gRPC.
+const txErrorReason = "KV_TX_OP_FAILED"
+
+// toStatus converts an error from a KV implementation into a gRPC status
+// error. Backend failures are reported as ErrStorageFailure so raw
+// filesystem details stay in the server log.
+func toStatus(err error) error {
+    if err == nil {
+        return nil
+    }
+    if _, ok := status.FromError(err); ok {
+        return err
+    }
+    var txErr *TxError
+    if errors.As(err, &txErr) {
+        s := status.Convert(toStatus(txErr.Err))
+        detailed, detailErr := s.WithDetails(&errdetails.ErrorInfo{
+            Reason:   txErrorReason,
+            Metadata: map[string]string{"op_index": strconv.Itoa(txErr.Index)},
+        })
+        if detailErr != nil {
+            return s.Err()
+        }
+        return detailed.Err()
+    }
+
+    switch {
+    case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+        return status.FromContextError(err).Err()
+    case errors.Is(err, ErrKeyNotFound):
+        return status.Error(codes.NotFound, err.Error())
+    case errors.Is(err, ErrKeyExists):
+        return status.Error(codes.AlreadyExists, err.Error())
+    case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrValueTooLarge), errors.Is(err, ErrInvalidNamespace),
+        errors.Is(err, ErrInvalidPageToken), errors.Is(err, ErrInvalidContentType), errors.Is(err, ErrInvalidTTL):
+        return status.Error(codes.InvalidArgument, err.Error())
+    case errors.Is(err, ErrNotANumber), errors.Is(err, ErrReadOnly):
+        return status.Error(codes.FailedPrecondition, err.Error())
+    case errors.Is(err, ErrOutOfRange):
+        return status.Error(codes.OutOfRange, err.Error())
+    case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrCompareFailed):
+        return status.Error(codes.Aborted, err.Error())
+    case errors.Is(err, ErrDecryptionFailed):
+        return status.Error(codes.DataLoss, err.Error())
+    case errors.Is(err, ErrPermissionDenied):
+        return status.Error(codes.PermissionDenied, err.Error())
+    default:
+        return status.Error(codes.Internal, ErrStorageFailure.Error())
+    }
+}
+
+// statusError is a gRPC status error that also matches the sentinel it was
+// mapped from, so callers can use errors.Is on the client side.
+type statusError struct {
+    status   *status.Status
+    sentinel error
+}
+
+func (e *statusError) Error() string              { return e.status.Message() }
+func (e *statusError) Unwrap() error              { return e.sentinel }
+func (e *statusError) GRPCStatus() *status.Status { return e.status }
+
+// sentinelsByCode lists the sentinels toStatus can produce for each code.
+// When several share a code, the one the message starts with wins; the
+// first is the fallback.
+var sentinelsByCode = map[codes.Code][]error{
+    codes.NotFound:           {ErrKeyNotFound},
+    codes.AlreadyExists:      {ErrKeyExists},
+    codes.InvalidArgument:    {ErrInvalidKey, ErrValueTooLarge, ErrInvalidNamespace, ErrInvalidPageToken, ErrInvalidContentType, ErrInvalidTTL},
+    codes.FailedPrecondition: {ErrNotANumber, ErrReadOnly},
+    codes.OutOfRange:         {ErrOutOfRange},
+    codes.Aborted:            {ErrVersionConflict, ErrCompareFailed},
+    codes.DataLoss:           {ErrDecryptionFailed},
+    codes.PermissionDenied:   {ErrPermissionDenied},
+    codes.Internal:           {ErrStorageFailure},
+}
+
+// fromStatus reverses toStatus for errors received by GRPCClient, including
+// the op index of a failed transaction. Errors with other codes are
+// returned unchanged.
+func fromStatus(err error) error {
+    s, ok := status.FromError(err)
+    if !ok {
+        return err
+    }
+
+    candidates, ok := sentinelsByCode[s.Code()]
+    if !ok {
+        return err
+    }
+    sentinel := candidates[0]
+    for _, candidate := range candidates[1:] {
+        if strings.HasPrefix(s.Message(), candidate.Error()) {
+            sentinel = candidate
+        }
+    }
+    mapped := error(&statusError{status: s, sentinel: sentinel})
+
+    for _, detail := range s.Details() {
+        info, ok := detail.(*errdetails.ErrorInfo)
+        if !ok || info.Reason != txErrorReason {
+            continue
+        }
+        if index, err := strconv.Atoi(info.Metadata["op_index"]); err == nil {
+            return &TxError{Index: index, Err: mapped}
+        }
+    }
+    return mapped
+}
@@ -0,0 +1,139 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/eventsink.go
+
+package shared
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// notifyTimeout bounds each callback the plugin makes into the host's
+// EventSink, so a wedged host can't stall event delivery forever.
+const notifyTimeout = 5 * time.Second
+
+// ErrNoBroker is returned when an EventSink is registered over a connection
+// that has no go-plugin broker, such as one dialled directly in tests.
+var ErrNoBroker = errors.New("no plugin broker on this connection")
+
+// EventSink receives notifications the plugin pushes to the host.
+type EventSink interface {
+    Notify(ctx context.Context, event Event) error
+}
+
+// SetEventSink serves sink to the plugin over the go-plugin broker and asks
+// the plugin to send it every change it makes from now on. The sink stays
+// registered until either side of the plugin connection goes away or
+// Notify returns an error.
+func (m *GRPCClient) SetEventSink(ctx context.Context, sink EventSink) error {
+    if m.broker == nil {
+        return ErrNoBroker
+    }
+
+    id := m.broker.NextId()
+    go m.broker.AcceptAndServe(id, func(opts []grpc.ServerOption) *grpc.Server {
+        s := grpc.NewServer(opts...)
+        proto.RegisterEventSinkServer(s, &eventSinkServer{sink: sink, logger: m.logger})
+        return s
+    })
+
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📣 registering event sink", "broker_id", id)
+
+    if _, err := m.client.RegisterEventSink(ctx, &proto.RegisterEventSinkRequest{BrokerId: id}); err != nil {
+        m.log(ctx).Error("🌐❌ RegisterEventSink request failed", "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ event sink registered", "broker_id", id)
+    return nil
+}
+
+// eventSinkServer is the host-side gRPC server the plugin calls back into.
+type eventSinkServer struct {
+    proto.UnimplementedEventSinkServer
+    sink   EventSink
+    logger hclog.Logger
+}
+
+func (s *eventSinkServer) Notify(ctx context.Context, req *proto.Event) (*proto.Empty, error) {
+    event := Event{
+        Op:    eventOpFromProto(req.Op),
+        Key:   req.Key,
+        Value: req.Value,
+    }
+    if err := s.sink.Notify(ctx, event); err != nil {
+        return nil, toStatus(err)
+    }
+    return &proto.Empty{}, nil
+}
+
+// RegisterEventSink dials the EventSink the host serves on req.BrokerId and
+// forwards every event from Impl.Watch to it until a Notify fails. The
+// watch is registered before returning, so writes made after the call are
+// never missed.
+func (m *GRPCServer) RegisterEventSink(ctx context.Context, req *proto.RegisterEventSinkRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡📣 handling RegisterEventSink request", "broker_id", req.BrokerId)
+
+    if m.broker == nil {
+        return nil, status.Error(codes.FailedPrecondition, ErrNoBroker.Error())
+    }
+
+    conn, err := m.broker.Dial(req.BrokerId)
+    if err != nil {
+        m.log(ctx).Error("📡❌ dialling event sink failed",
+            "broker_id", req.BrokerId,
+            "error", err)
+        return nil, status.Errorf(codes.Unavailable, "dialling event sink: %v", err)
+    }
+
+    // The watch outlives this request, so it can't use the request context,
+    // but it keeps the metadata so it watches the caller's namespace
+    watchCtx, cancel := context.WithCancel(context.Background())
+    if md, ok := metadata.FromIncomingContext(ctx); ok {
+        watchCtx = metadata.NewIncomingContext(watchCtx, md)
+    }
+    events, err := m.Impl.Watch(watchCtx, "")
+    if err != nil {
+        cancel()
+        conn.Close()
+        m.log(ctx).Error("📡❌ watching for event sink failed", "error", err)
+        return nil, toStatus(err)
+    }
+
+    go m.forwardEvents(events, proto.NewEventSinkClient(conn), conn, cancel)
+    return &proto.Empty{}, nil
+}
+
+// forwardEvents delivers events to sink in order, giving up on the sink and
+// ending the watch the first time a Notify fails.
+func (m *GRPCServer) forwardEvents(events <-chan Event, sink proto.EventSinkClient, conn *grpc.ClientConn, cancel context.CancelFunc) {
+    defer conn.Close()
+    defer cancel()
+
+    for event := range events {
+        ctx, cancelNotify := context.WithTimeout(context.Background(), notifyTimeout)
+        _, err := sink.Notify(ctx, &proto.Event{
+            Op:    eventOpToProto(event.Op),
+            Key:   event.Key,
+            Value: event.Value,
+        })
+        cancelNotify()
+        if err != nil {
+            m.logger.Warn("📡⚠️ event sink stopped answering, dropping it",
+                "key", event.Key,
+                "error", err)
+            return
+        }
+    }
+}
@@ -5,30 +5,76 @@ package shared
 
 import (
     "context"
+    "errors"
     "fmt"
+    "io"
+    "math"
+    "os"
+    "strings"
+    "sync"
+    "time"
 
     //"crypto/tls"
-    //"crypto/x509"
+    "crypto/x509"
 
     "github.com/hashicorp/go-hclog"
     "github.com/hashicorp/go-plugin"
     "google.golang.org/grpc"
-    //"google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/encoding/gzip"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/metadata"
+    "google.golang.org/grpc/peer"
+    "google.golang.org/grpc/status"
 
     "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
 )
 
+// getStreamChunkSize is the largest slice of a value sent in one GetStream
+// message, kept well under gRPC's default 4MB message limit.
+const getStreamChunkSize = 1 << 20
+
+// DefaultRequestTimeout bounds each RPC made by a GRPCClient unless
+// RequestTimeout is changed.
+const DefaultRequestTimeout = 30 * time.Second
+
 // GRPCClient is an implementation of KV that talks over RPC.
 type GRPCClient struct {
     client proto.KVClient
+    health healthpb.HealthClient
     logger hclog.Logger
+    broker *plugin.GRPCBroker
+
+    // conn is the connection Close closes, nil when go-plugin owns it.
+    conn      *grpc.ClientConn
+    closeOnce sync.Once
+    closeErr  error
+
+    // RequestTimeout bounds every call so a wedged server can't block the
+    // client forever. Zero or negative disables the limit.
+    RequestTimeout time.Duration
+
+    // ProtocolVersion is the KV protocol version negotiated with the
+    // plugin. RPCs added in a later version fail with ErrVersionMismatch
+    // without being sent. Zero means ProtocolVersion.
+    ProtocolVersion int
+}
+
+// namedLogger returns p.Logger named name, or a logger of its own, set up
+// from PLUGIN_LOG_LEVEL and PLUGIN_LOG_JSON at Debug by default, when p has
+// none.
+func (p *KVGRPCPlugin) namedLogger(name string) hclog.Logger {
+    if p.Logger != nil {
+        return p.Logger.Named(name)
+    }
+    // The main reports an invalid setting; here it just means the defaults
+    logger, _ := NewLoggerFromEnv(name, os.Stderr, hclog.Debug)
+    return logger
 }
 
 func (p *KVGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
-    logger := hclog.New(&hclog.LoggerOptions{
-        Name:  "🔌🌐 kv-grpc-client",
-        Level: hclog.Debug,
-    })
+    logger := p.namedLogger("🔌🌐 kv-grpc-client")
 
     if c == nil {
         logger.Error("🌐❌ received nil gRPC connection")
@@ -39,66 +85,848 @@ func (p *KVGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker
         "connection_state", c.GetState().String(),
         "target", c.Target())
 
-    grpcClient := &GRPCClient{
-        client: proto.NewKVClient(c),
-        logger: logger,
-    }
+    grpcClient := NewGRPCClient(c, logger)
+    grpcClient.broker = broker
+    // go-plugin closes the connection when the plugin client is killed
+    grpcClient.conn = nil
 
     logger.Debug("🌐✨ GRPCClient wrapper initialized successfully",
         "client_implementation", fmt.Sprintf("%T", grpcClient))
     return grpcClient, nil
 }
 
-func (m *GRPCClient) Put(key string, value []byte) error {
-    m.logger.Debug("🌐📤 initiating Put request",
+// NewGRPCClient wraps a connection to a KV server that go-plugin didn't
+// launch, such as one running as a standalone daemon. Without go-plugin
+// there is no broker, so SetEventSink fails with ErrNoBroker. The client
+// takes ownership of conn, which Close closes.
+func NewGRPCClient(conn *grpc.ClientConn, logger hclog.Logger) *GRPCClient {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+    client := &GRPCClient{
+        health:         healthpb.NewHealthClient(conn),
+        logger:         logger,
+        conn:           conn,
+        RequestTimeout: DefaultRequestTimeout,
+    }
+    client.client = proto.NewKVClient(versionGate{ClientConnInterface: conn, client: client})
+    return client
+}
+
+// protocolVersion returns the version RPCs are checked against.
+func (m *GRPCClient) protocolVersion() int {
+    if m.ProtocolVersion <= 0 {
+        return ProtocolVersion
+    }
+    return m.ProtocolVersion
+}
+
+// Close closes the connection the client was created with, ending its
+// transport goroutines and releasing its socket. A client go-plugin
+// dispensed leaves the connection to go-plugin, which closes it when the
+// plugin client is killed. Calling Close more than once is safe; later
+// calls return the first call's result.
+func (m *GRPCClient) Close() error {
+    m.closeOnce.Do(func() {
+        if m.conn == nil {
+            return
+        }
+        m.logger.Debug("🌐🧹 closing gRPC connection", "target", m.conn.Target())
+        m.closeErr = m.conn.Close()
+    })
+    return m.closeErr
+}
+
+// log returns the client's logger tagged with ctx's request ID.
+func (m *GRPCClient) log(ctx context.Context) hclog.Logger {
+    return RequestLogger(ctx, m.logger)
+}
+
+// requestContext derives the context for a single RPC, applying RequestTimeout.
+func (m *GRPCClient) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+    if m.RequestTimeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+    return context.WithTimeout(ctx, m.RequestTimeout)
+}
+
+func (m *GRPCClient) Put(ctx context.Context, key string, value []byte) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📤 initiating Put request",
         "key", key,
         "value_size", len(value))
 
-    _, err := m.client.Put(context.Background(), &proto.PutRequest{
+    _, err := m.client.Put(ctx, &proto.PutRequest{
         Key:   key,
         Value: value,
     })
 
     if err != nil {
-        m.logger.Error("🌐❌ Put request failed",
+        m.log(ctx).Error("🌐❌ Put request failed",
             "key", key,
             "error", err)
-        return err
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Put request completed successfully",
+        "key", key)
+    return nil
+}
+
+// PutWithTTL sends ttl as whole seconds, rounding up so a short TTL never
+// becomes "no expiry".
+func (m *GRPCClient) PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    var ttlSeconds int64
+    if ttl > 0 {
+        ttlSeconds = int64(math.Ceil(ttl.Seconds()))
+    }
+
+    m.log(ctx).Debug("🌐📤 initiating Put request with ttl",
+        "key", key,
+        "value_size", len(value),
+        "ttl_seconds", ttlSeconds)
+
+    _, err := m.client.Put(ctx, &proto.PutRequest{
+        Key:        key,
+        Value:      value,
+        TtlSeconds: ttlSeconds,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Put request with ttl failed",
+            "key", key,
+            "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Put request with ttl completed successfully",
+        "key", key)
+    return nil
+}
+
+// PutWithContentType sends contentType and ttl with the value, with ttl
+// rounded up to whole seconds as PutWithTTL does.
+func (m *GRPCClient) PutWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    var ttlSeconds int64
+    if ttl > 0 {
+        ttlSeconds = int64(math.Ceil(ttl.Seconds()))
     }
 
-    m.logger.Debug("🌐✅ Put request completed successfully",
+    m.log(ctx).Debug("🌐📤 initiating Put request with content type",
+        "key", key,
+        "value_size", len(value),
+        "content_type", contentType,
+        "ttl_seconds", ttlSeconds)
+
+    _, err := m.client.Put(ctx, &proto.PutRequest{
+        Key:         key,
+        Value:       value,
+        TtlSeconds:  ttlSeconds,
+        ContentType: contentType,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Put request with content type failed",
+            "key", key,
+            "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Put request with content type completed successfully",
         "key", key)
     return nil
 }
 
-func (m *GRPCClient) Get(key string) ([]byte, error) {
-    m.logger.Debug("🌐📥 initiating Get request", "key", key)
+func (m *GRPCClient) Get(ctx context.Context, key string) ([]byte, error) {
+    value, _, err := m.GetWithContentType(ctx, key)
+    return value, err
+}
+
+// GetWithContentType is Get that also returns the value's content type.
+// A plugin that predates content types is reported as DefaultContentType.
+func (m *GRPCClient) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📥 initiating Get request", "key", key)
 
     // Perform the Get operation
-    resp, err := m.client.Get(context.Background(), &proto.GetRequest{
+    resp, err := m.client.Get(ctx, &proto.GetRequest{
+        Key:      key,
+        SoftMiss: true,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Get request failed", "key", key, "error", err)
+        return nil, "", fromStatus(err)
+    }
+    // A plugin that predates soft misses still fails with NOT_FOUND above.
+    // A soft miss is returned as if it had, so callers checking the status
+    // code see no difference.
+    if resp.ErrorCode == proto.GetErrorCode_GET_ERROR_CODE_KEY_NOT_FOUND {
+        m.log(ctx).Debug("🌐📥 Get found no value", "key", key)
+        return nil, "", fromStatus(toStatus(fmt.Errorf("%w: %q", ErrKeyNotFound, key)))
+    }
+
+    contentType := resp.ContentType
+    if contentType == "" {
+        contentType = DefaultContentType
+    }
+    m.log(ctx).Debug("🌐✅ Get request completed successfully", "key", key, "value_size", len(resp.Value), "content_type", contentType)
+    return resp.Value, contentType, nil
+}
+
+// GetConditional fetches the value for key only if it was written after
+// ifModifiedAfter, so unchanged values aren't transferred again.
+func (m *GRPCClient) GetConditional(ctx context.Context, key string, ifModifiedAfter time.Time) ([]byte, bool, time.Time, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📥 initiating GetConditional request", "key", key, "if_modified_after", ifModifiedAfter)
+
+    resp, err := m.client.GetConditional(ctx, &proto.GetConditionalRequest{
+        Key:                     key,
+        IfModifiedAfterUnixNano: unixNano(ifModifiedAfter),
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ GetConditional request failed", "key", key, "error", err)
+        return nil, false, time.Time{}, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ GetConditional request completed successfully",
+        "key", key,
+        "modified", resp.Modified,
+        "value_size", len(resp.Value))
+    return resp.Value, resp.Modified, fromUnixNano(resp.ModTimeUnixNano), nil
+}
+
+// unixNano converts t for the wire, where zero means no time.
+func unixNano(t time.Time) int64 {
+    if t.IsZero() {
+        return 0
+    }
+    return t.UnixNano()
+}
+
+// fromUnixNano reverses unixNano.
+func fromUnixNano(n int64) time.Time {
+    if n == 0 {
+        return time.Time{}
+    }
+    return time.Unix(0, n)
+}
+
+// maxTTLSeconds is the longest TTL a request can carry; more seconds than
+// this overflow time.Duration.
+const maxTTLSeconds = int64(math.MaxInt64 / time.Second)
+
+// ttlFromSeconds converts a request's ttl_seconds, which a client in
+// another language can set to anything, refusing values that would wrap
+// around into a TTL nobody asked for.
+func ttlFromSeconds(key string, seconds int64) (time.Duration, error) {
+    if seconds < 0 || seconds > maxTTLSeconds {
+        return 0, fmt.Errorf("%w: %q has ttl_seconds %d, want 0 to %d", ErrInvalidTTL, key, seconds, maxTTLSeconds)
+    }
+    return time.Duration(seconds) * time.Second, nil
+}
+
+// GetStream fetches the value for key in chunks and writes them to w, so
+// values larger than the gRPC message limit can be read.
+func (m *GRPCClient) GetStream(ctx context.Context, key string, w io.Writer) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📥 initiating GetStream request", "key", key)
+
+    stream, err := m.client.GetStream(ctx, &proto.GetRequest{
         Key: key,
     })
     if err != nil {
-        m.logger.Error("🌐❌ Get request failed", "key", key, "error", err)
-        return nil, err
+        m.log(ctx).Error("🌐❌ GetStream request failed", "key", key, "error", err)
+        return fromStatus(err)
+    }
+
+    total := 0
+    for {
+        chunk, err := stream.Recv()
+        if errors.Is(err, io.EOF) {
+            break
+        }
+        if err != nil {
+            m.log(ctx).Error("🌐❌ GetStream receive failed", "key", key, "error", err)
+            return fromStatus(err)
+        }
+        n, err := w.Write(chunk.Data)
+        total += n
+        if err != nil {
+            return fmt.Errorf("writing streamed value for %q: %w", key, err)
+        }
+    }
+
+    m.log(ctx).Debug("🌐✅ GetStream request completed successfully", "key", key, "value_size", total)
+    return nil
+}
+
+// GetBatchStream fetches keys over one stream. Every key is sent without
+// waiting for the values before it, so a slow link costs about one
+// round-trip for the whole batch rather than one per key. Results arrive in
+// the order of keys, and the channel is closed after the last one. A batch
+// cut short, by an error or by ctx or RequestTimeout ending, always ends with
+// a result whose Err says why, so a drained channel is a complete batch.
+// Callers must call stop when they are done with the channel, even after
+// draining it; results not yet read are only dropped once stop is called.
+func (m *GRPCClient) GetBatchStream(ctx context.Context, keys []string) (results <-chan BatchResult, stop func(), err error) {
+    ctx, cancel := m.requestContext(ctx)
+    // Stopping is tracked apart from ctx, so a deadline can't drop the
+    // result that reports it
+    stopped := make(chan struct{})
+    var once sync.Once
+    stop = func() {
+        once.Do(func() {
+            close(stopped)
+            cancel()
+        })
+    }
+
+    m.log(ctx).Debug("🌐📥 initiating GetBatchStream request", "key_count", len(keys))
+
+    stream, err := m.client.GetBatchStream(ctx)
+    if err != nil {
+        stop()
+        m.log(ctx).Error("🌐❌ GetBatchStream request failed", "key_count", len(keys), "error", err)
+        return nil, nil, fromStatus(err)
+    }
+
+    go func() {
+        for _, key := range keys {
+            // A failed send ends the stream, and Recv reports why
+            if err := stream.Send(&proto.GetBatchStreamRequest{Key: key}); err != nil {
+                return
+            }
+        }
+        stream.CloseSend()
+    }()
+
+    out := make(chan BatchResult)
+    go func() {
+        defer cancel()
+        defer close(out)
+        received := 0
+        for {
+            msg, err := stream.Recv()
+            if errors.Is(err, io.EOF) {
+                m.log(ctx).Debug("🌐✅ GetBatchStream request completed successfully", "key_count", received)
+                return
+            }
+            var result BatchResult
+            if err != nil {
+                m.log(ctx).Error("🌐❌ GetBatchStream receive failed", "received", received, "error", err)
+                result = BatchResult{Err: fromStatus(err)}
+            } else {
+                received++
+                result = BatchResult{Key: msg.Key, Found: msg.Found}
+                if msg.Found {
+                    result.Value = msg.Value
+                }
+            }
+            select {
+            case out <- result:
+            case <-stopped:
+                return
+            }
+            if result.Err != nil {
+                return
+            }
+        }
+    }()
+    return out, stop, nil
+}
+
+func (m *GRPCClient) Delete(ctx context.Context, key string) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🗑️ initiating Delete request", "key", key)
+
+    _, err := m.client.Delete(ctx, &proto.DeleteRequest{
+        Key: key,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Delete request failed", "key", key, "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Delete request completed successfully", "key", key)
+    return nil
+}
+
+func (m *GRPCClient) List(ctx context.Context, prefix string) ([]string, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📋 initiating List request", "prefix", prefix)
+
+    resp, err := m.client.List(ctx, &proto.ListRequest{
+        Prefix: prefix,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ List request failed", "prefix", prefix, "error", err)
+        return nil, fromStatus(err)
     }
 
-    m.logger.Debug("🌐✅ Get request completed successfully", "key", key, "value_size", len(resp.Value))
+    m.log(ctx).Debug("🌐✅ List request completed successfully", "prefix", prefix, "key_count", len(resp.Keys))
+    return resp.Keys, nil
+}
+
+func (m *GRPCClient) ListPage(ctx context.Context, prefix string, pageToken string, pageSize int) ([]string, string, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📋 initiating ListPage request", "prefix", prefix, "page_size", pageSize)
+
+    if pageSize > math.MaxInt32 {
+        pageSize = math.MaxInt32
+    }
+    resp, err := m.client.ListPage(ctx, &proto.ListPageRequest{
+        Prefix:    prefix,
+        PageToken: pageToken,
+        PageSize:  int32(pageSize),
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ ListPage request failed", "prefix", prefix, "error", err)
+        return nil, "", fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ ListPage request completed successfully",
+        "prefix", prefix,
+        "key_count", len(resp.Keys),
+        "more", resp.NextPageToken != "")
+    return resp.Keys, resp.NextPageToken, nil
+}
+
+func (m *GRPCClient) BatchPut(ctx context.Context, items map[string][]byte) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📤 initiating BatchPut request", "item_count", len(items))
+
+    _, err := m.client.BatchPut(ctx, &proto.BatchPutRequest{
+        Items: items,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ BatchPut request failed", "item_count", len(items), "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ BatchPut request completed successfully", "item_count", len(items))
+    return nil
+}
+
+func (m *GRPCClient) BatchGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📥 initiating BatchGet request", "key_count", len(keys))
+
+    resp, err := m.client.BatchGet(ctx, &proto.BatchGetRequest{
+        Keys: keys,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ BatchGet request failed", "key_count", len(keys), "error", err)
+        return nil, fromStatus(err)
+    }
+
+    values := resp.Values
+    if values == nil {
+        values = map[string][]byte{}
+    }
+
+    m.log(ctx).Debug("🌐✅ BatchGet request completed successfully",
+        "found", len(values),
+        "missing", len(resp.Missing))
+    return values, nil
+}
+
+func (m *GRPCClient) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🔁 initiating CompareAndSwap request", "key", key)
+
+    resp, err := m.client.CompareAndSwap(ctx, &proto.CasRequest{
+        Key:      key,
+        OldValue: old,
+        NewValue: new,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ CompareAndSwap request failed", "key", key, "error", err)
+        return false, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ CompareAndSwap request completed successfully", "key", key, "swapped", resp.Swapped)
+    return resp.Swapped, nil
+}
+
+func (m *GRPCClient) PutIfAbsent(ctx context.Context, key string, value []byte) (bool, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📤 initiating PutIfAbsent request", "key", key, "value_size", len(value))
+
+    resp, err := m.client.PutIfAbsent(ctx, &proto.PutIfAbsentRequest{
+        Key:   key,
+        Value: value,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ PutIfAbsent request failed", "key", key, "error", err)
+        return false, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ PutIfAbsent request completed successfully", "key", key, "created", resp.Created)
+    return resp.Created, nil
+}
+
+func (m *GRPCClient) Rename(ctx context.Context, oldKey, newKey string, overwrite bool) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🚚 initiating Rename request", "old_key", oldKey, "new_key", newKey, "overwrite", overwrite)
+
+    _, err := m.client.Rename(ctx, &proto.RenameRequest{
+        OldKey:    oldKey,
+        NewKey:    newKey,
+        Overwrite: overwrite,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Rename request failed", "old_key", oldKey, "new_key", newKey, "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Rename request completed successfully", "old_key", oldKey, "new_key", newKey)
+    return nil
+}
+
+func (m *GRPCClient) Exists(ctx context.Context, key string) (bool, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🔎 initiating Exists request", "key", key)
+
+    resp, err := m.client.Exists(ctx, &proto.ExistsRequest{
+        Key: key,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Exists request failed", "key", key, "error", err)
+        return false, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Exists request completed successfully", "key", key, "exists", resp.Exists)
+    return resp.Exists, nil
+}
+
+func (m *GRPCClient) GetVersioned(ctx context.Context, key string) ([]byte, uint64, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📥 initiating GetVersioned request", "key", key)
+
+    resp, err := m.client.GetVersioned(ctx, &proto.GetRequest{
+        Key: key,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ GetVersioned request failed", "key", key, "error", err)
+        return nil, 0, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ GetVersioned request completed successfully",
+        "key", key,
+        "value_length", len(resp.Value),
+        "version", resp.Version)
+    return resp.Value, resp.Version, nil
+}
+
+func (m *GRPCClient) PutIfVersion(ctx context.Context, key string, value []byte, expectedVersion uint64) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🔁 initiating PutIfVersion request",
+        "key", key,
+        "value_length", len(value),
+        "expected_version", expectedVersion)
+
+    _, err := m.client.PutIfVersion(ctx, &proto.PutIfVersionRequest{
+        Key:             key,
+        Value:           value,
+        ExpectedVersion: expectedVersion,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ PutIfVersion request failed", "key", key, "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ PutIfVersion request completed successfully", "key", key)
+    return nil
+}
+
+func (m *GRPCClient) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐➕ initiating Increment request", "key", key, "delta", delta)
+
+    resp, err := m.client.Increment(ctx, &proto.IncrementRequest{
+        Key:   key,
+        Delta: delta,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Increment request failed", "key", key, "error", err)
+        return 0, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Increment request completed successfully", "key", key, "value", resp.Value)
     return resp.Value, nil
 }
 
+func (m *GRPCClient) Transaction(ctx context.Context, ops []TxOp) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🧾 initiating Transaction request", "op_count", len(ops))
+
+    req := &proto.TransactionRequest{Ops: make([]*proto.TxOp, 0, len(ops))}
+    for i, op := range ops {
+        msg, err := txOpToProto(op)
+        if err != nil {
+            return &TxError{Index: i, Err: err}
+        }
+        req.Ops = append(req.Ops, msg)
+    }
+
+    if _, err := m.client.Transaction(ctx, req); err != nil {
+        m.log(ctx).Error("🌐❌ Transaction request failed", "op_count", len(ops), "error", err)
+        return fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Transaction request completed successfully", "op_count", len(ops))
+    return nil
+}
+
+// Scan fetches every key under prefix with its value in one request. The
+// map is never nil; truncated reports that the server capped the result.
+func (m *GRPCClient) Scan(ctx context.Context, prefix string) (map[string][]byte, bool, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🧺 initiating Scan request", "prefix", prefix)
+
+    resp, err := m.client.Scan(ctx, &proto.ScanRequest{Prefix: prefix})
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Scan request failed", "prefix", prefix, "error", err)
+        return nil, false, fromStatus(err)
+    }
+
+    values := resp.Values
+    if values == nil {
+        values = map[string][]byte{}
+    }
+    m.log(ctx).Debug("🌐✅ Scan request completed successfully",
+        "prefix", prefix,
+        "key_count", len(values),
+        "truncated", resp.Truncated)
+    return values, resp.Truncated, nil
+}
+
+// Stats fetches the number of stored keys and the total size of their
+// values.
+func (m *GRPCClient) Stats(ctx context.Context) (int64, int64, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐📊 initiating Stats request")
+
+    resp, err := m.client.Stats(ctx, &proto.Empty{})
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Stats request failed", "error", err)
+        return 0, 0, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Stats request completed successfully",
+        "key_count", resp.KeyCount,
+        "total_bytes", resp.TotalBytes)
+    return resp.KeyCount, resp.TotalBytes, nil
+}
+
+// Watch streams changes under prefix until ctx is done or the stream breaks.
+// RequestTimeout does not apply, since a watch is expected to stay open. Once
+// Watch returns, the server has registered the watcher, so no later change
+// is missed.
+func (m *GRPCClient) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+    m.log(ctx).Debug("🌐👀 initiating Watch request", "prefix", prefix)
+
+    stream, err := m.client.Watch(ctx, &proto.WatchRequest{
+        Prefix: prefix,
+    })
+    if err == nil {
+        // The server sends headers once the watcher is registered
+        _, err = stream.Header()
+    }
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Watch request failed", "prefix", prefix, "error", err)
+        return nil, fromStatus(err)
+    }
+
+    events := make(chan Event)
+    go func() {
+        defer close(events)
+        for {
+            msg, err := stream.Recv()
+            if err != nil {
+                if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+                    m.log(ctx).Error("🌐❌ Watch stream ended", "prefix", prefix, "error", err)
+                }
+                return
+            }
+            event := Event{Op: eventOpFromProto(msg.Op), Key: msg.Key, Value: msg.Value}
+            select {
+            case events <- event:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+
+    m.log(ctx).Debug("🌐✅ Watch established", "prefix", prefix)
+    return events, nil
+}
+
+// CompressionFromEnv returns the compressor named by PLUGIN_KV_COMPRESSION,
+// or "" when compression is off. Importing this package registers gzip, so
+// a server can always decompress requests and compresses its replies to
+// match; uncompressed clients keep working.
+func CompressionFromEnv() (string, error) {
+    switch value := strings.ToLower(os.Getenv("PLUGIN_KV_COMPRESSION")); value {
+    case "", "none":
+        return "", nil
+    case gzip.Name:
+        return gzip.Name, nil
+    default:
+        return "", fmt.Errorf("unsupported PLUGIN_KV_COMPRESSION %q (use \"gzip\" or \"none\")", value)
+    }
+}
+
+// HealthCheck asks the plugin whether the KV service is serving, returning
+// an error wrapping ErrNotServing if it is not.
+func (m *GRPCClient) HealthCheck(ctx context.Context) error {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🩺 initiating HealthCheck request")
+
+    resp, err := m.health.Check(ctx, &healthpb.HealthCheckRequest{
+        Service: proto.KV_ServiceDesc.ServiceName,
+    })
+    if err != nil {
+        m.log(ctx).Error("🌐❌ HealthCheck request failed", "error", err)
+        return err
+    }
+    if resp.Status != healthpb.HealthCheckResponse_SERVING {
+        m.log(ctx).Warn("🌐⚠️ plugin is not serving", "status", resp.Status)
+        return fmt.Errorf("%w: %s", ErrNotServing, resp.Status)
+    }
+
+    m.log(ctx).Debug("🌐✅ HealthCheck request completed successfully", "status", resp.Status)
+    return nil
+}
+
+// Ping sends payload to the plugin and returns the echo along with the
+// plugin's clock, in Unix nanoseconds, when it answered. It doesn't touch
+// the store, so it measures only the connection and the plugin's RPC layer.
+func (m *GRPCClient) Ping(ctx context.Context, payload []byte) ([]byte, int64, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    m.log(ctx).Debug("🌐🏓 initiating Ping request", "payload_size", len(payload))
+
+    resp, err := m.client.Ping(ctx, &proto.PingRequest{Payload: payload})
+    if err != nil {
+        m.log(ctx).Error("🌐❌ Ping request failed", "error", err)
+        return nil, 0, fromStatus(err)
+    }
+
+    m.log(ctx).Debug("🌐✅ Ping request completed successfully", "server_time", resp.ServerTimeUnixNano)
+    return resp.Payload, resp.ServerTimeUnixNano, nil
+}
+
+// PeerCertificate returns the certificate the plugin presented for the
+// connection, or nil when the connection doesn't use TLS.
+func (m *GRPCClient) PeerCertificate(ctx context.Context) (*x509.Certificate, error) {
+    ctx, cancel := m.requestContext(ctx)
+    defer cancel()
+
+    // The handshake has happened once a call succeeds, so ping for the peer
+    var p peer.Peer
+    if _, err := m.client.Ping(ctx, &proto.PingRequest{}, grpc.Peer(&p)); err != nil {
+        m.log(ctx).Error("🌐❌ Ping for the peer certificate failed", "error", err)
+        return nil, fromStatus(err)
+    }
+    tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+    if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+        return nil, nil
+    }
+    return tlsInfo.State.PeerCertificates[0], nil
+}
+
+// LoggingUnaryInterceptor logs the method, duration and status code of every
+// unary call handled by a server.
+func LoggingUnaryInterceptor(logger hclog.Logger) grpc.UnaryServerInterceptor {
+    return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+        start := time.Now()
+        resp, err := handler(ctx, req)
+        ServerRequestLogger(ctx, logger).Debug("📡⏱️ handled RPC",
+            "method", info.FullMethod,
+            "duration", time.Since(start),
+            "code", status.Code(err))
+        return resp, err
+    }
+}
+
+// LoggingUnaryClientInterceptor logs the method, duration and status code of
+// every unary call made by a client.
+func LoggingUnaryClientInterceptor(logger hclog.Logger) grpc.UnaryClientInterceptor {
+    return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+        start := time.Now()
+        err := invoker(ctx, method, req, reply, cc, opts...)
+        RequestLogger(ctx, logger).Debug("🌐⏱️ completed RPC",
+            "method", method,
+            "duration", time.Since(start),
+            "code", status.Code(err))
+        return err
+    }
+}
+
 // GRPCServer is the gRPC server that GRPCClient talks to.
 type GRPCServer struct {
     proto.UnimplementedKVServer
-    Impl   KV
-    logger hclog.Logger
+    Impl          KV
+    maxValueBytes int
+    logger        hclog.Logger
+    broker        *plugin.GRPCBroker
+}
+
+// log returns the server's logger tagged with the request ID the client
+// sent, so every line about one RPC can be found from the client's logs,
+// and with the subject of the client's certificate.
+func (m *GRPCServer) log(ctx context.Context) hclog.Logger {
+    return ServerRequestLogger(ctx, m.logger)
 }
 
 func (p *KVGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
-    logger := hclog.New(&hclog.LoggerOptions{
-        Name:  "🔌📡 kv-grpc-server",
-        Level: hclog.Debug,
-    })
+    logger := p.namedLogger("🔌📡 kv-grpc-server")
 
     logger.Debug("📡🔄 initializing gRPC server registration")
 
@@ -108,8 +936,10 @@ func (p *KVGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) err
     }
 
     server := &GRPCServer{
-        Impl:   p.Impl,
-        logger: logger,
+        Impl:          p.Impl,
+        maxValueBytes: p.MaxValueBytes,
+        logger:        logger,
+        broker:        broker,
     }
 
     proto.RegisterKVServer(s, server)
@@ -119,36 +949,571 @@ func (p *KVGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) err
 }
 
 func (m *GRPCServer) Put(ctx context.Context, req *proto.PutRequest) (*proto.Empty, error) {
-    m.logger.Debug("📡📤 handling Put request",
+    m.log(ctx).Debug("📡📤 handling Put request",
         "key", req.Key,
-        "value_size", len(req.Value))
+        "value_size", len(req.Value),
+        "ttl_seconds", req.TtlSeconds,
+        "content_type", req.ContentType)
 
-    if err := m.Impl.Put(req.Key, req.Value); err != nil {
-        m.logger.Error("📡❌ Put operation failed",
+    ttl, err := ttlFromSeconds(req.Key, req.TtlSeconds)
+    if err == nil {
+        err = CheckValueSize(req.Key, req.Value, m.maxValueBytes)
+    }
+    if err == nil {
+        err = CheckContentType(req.ContentType)
+    }
+    if err == nil && req.ContentType != "" {
+        err = m.Impl.PutWithContentType(ctx, req.Key, req.Value, req.ContentType, ttl)
+    } else if err == nil && req.TtlSeconds > 0 {
+        err = m.Impl.PutWithTTL(ctx, req.Key, req.Value, ttl)
+    } else if err == nil {
+        err = m.Impl.Put(ctx, req.Key, req.Value)
+    }
+    if err != nil {
+        m.log(ctx).Error("📡❌ Put operation failed",
             "key", req.Key,
             "error", err)
-        return nil, err
+        return nil, toStatus(err)
     }
 
-    m.logger.Debug("📡✅ Put operation completed successfully",
+    m.log(ctx).Debug("📡✅ Put operation completed successfully",
         "key", req.Key)
     return &proto.Empty{}, nil
 }
 
 func (m *GRPCServer) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
-    m.logger.Debug("📡📥 handling Get request",
+    m.log(ctx).Debug("📡📥 handling Get request",
         "key", req.Key)
 
-    v, err := m.Impl.Get(req.Key)
+    v, contentType, err := m.Impl.GetWithContentType(ctx, req.Key)
+    if req.SoftMiss && errors.Is(err, ErrKeyNotFound) {
+        m.log(ctx).Debug("📡📥 Get found no value",
+            "key", req.Key)
+        return &proto.GetResponse{ErrorCode: proto.GetErrorCode_GET_ERROR_CODE_KEY_NOT_FOUND}, nil
+    }
+    if err != nil {
+        m.log(ctx).Error("📡❌ Get operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+    if contentType == "" {
+        contentType = DefaultContentType
+    }
+
+    m.log(ctx).Debug("📡✅ Get operation completed successfully",
+        "key", req.Key,
+        "value_size", len(v),
+        "content_type", contentType)
+    return &proto.GetResponse{Value: v, ContentType: contentType, Found: true}, nil
+}
+
+func (m *GRPCServer) GetConditional(ctx context.Context, req *proto.GetConditionalRequest) (*proto.GetConditionalResponse, error) {
+    m.log(ctx).Debug("📡📥 handling GetConditional request",
+        "key", req.Key,
+        "if_modified_after_unix_nano", req.IfModifiedAfterUnixNano)
+
+    v, modified, modTime, err := m.Impl.GetConditional(ctx, req.Key, fromUnixNano(req.IfModifiedAfterUnixNano))
     if err != nil {
-        m.logger.Error("📡❌ Get operation failed",
+        m.log(ctx).Error("📡❌ GetConditional operation failed",
             "key", req.Key,
             "error", err)
-        return nil, err
+        return nil, toStatus(err)
     }
 
-    m.logger.Debug("📡✅ Get operation completed successfully",
+    m.log(ctx).Debug("📡✅ GetConditional operation completed successfully",
         "key", req.Key,
+        "modified", modified,
         "value_size", len(v))
-    return &proto.GetResponse{Value: v}, nil
+    return &proto.GetConditionalResponse{Value: v, Modified: modified, ModTimeUnixNano: unixNano(modTime)}, nil
+}
+
+func (m *GRPCServer) GetStream(req *proto.GetRequest, stream proto.KV_GetStreamServer) error {
+    m.log(stream.Context()).Debug("📡📥 handling GetStream request",
+        "key", req.Key)
+
+    v, err := m.Impl.Get(stream.Context(), req.Key)
+    if err != nil {
+        m.log(stream.Context()).Error("📡❌ GetStream operation failed",
+            "key", req.Key,
+            "error", err)
+        return toStatus(err)
+    }
+
+    chunks := 0
+    for offset := 0; offset < len(v); offset += getStreamChunkSize {
+        end := min(offset+getStreamChunkSize, len(v))
+        if err := stream.Send(&proto.GetChunk{Data: v[offset:end]}); err != nil {
+            m.log(stream.Context()).Error("📡❌ GetStream send failed",
+                "key", req.Key,
+                "error", err)
+            return toStatus(err)
+        }
+        chunks++
+    }
+
+    m.log(stream.Context()).Debug("📡✅ GetStream operation completed successfully",
+        "key", req.Key,
+        "value_size", len(v),
+        "chunks", chunks)
+    return nil
+}
+
+// GetBatchStream answers each key as it arrives, so the client can keep
+// sending keys while earlier ones are looked up.
+func (m *GRPCServer) GetBatchStream(stream proto.KV_GetBatchStreamServer) error {
+    ctx := stream.Context()
+    m.log(ctx).Debug("📡📥 handling GetBatchStream request")
+
+    found, missing := 0, 0
+    for {
+        req, err := stream.Recv()
+        if errors.Is(err, io.EOF) {
+            break
+        }
+        if err != nil {
+            m.log(ctx).Error("📡❌ GetBatchStream receive failed", "error", err)
+            return toStatus(err)
+        }
+
+        resp := &proto.GetBatchStreamResponse{Key: req.Key}
+        v, err := m.Impl.Get(ctx, req.Key)
+        switch {
+        case err == nil:
+            resp.Value, resp.Found = v, true
+            found++
+        case errors.Is(err, ErrKeyNotFound):
+            missing++
+        default:
+            m.log(ctx).Error("📡❌ GetBatchStream operation failed",
+                "key", req.Key,
+                "error", err)
+            return toStatus(err)
+        }
+        if err := stream.Send(resp); err != nil {
+            m.log(ctx).Error("📡❌ GetBatchStream send failed",
+                "key", req.Key,
+                "error", err)
+            return toStatus(err)
+        }
+    }
+
+    m.log(ctx).Debug("📡✅ GetBatchStream operation completed successfully",
+        "found", found,
+        "missing", missing)
+    return nil
+}
+
+func (m *GRPCServer) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡🗑️ handling Delete request",
+        "key", req.Key)
+
+    if err := m.Impl.Delete(ctx, req.Key); err != nil {
+        m.log(ctx).Error("📡❌ Delete operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Delete operation completed successfully",
+        "key", req.Key)
+    return &proto.Empty{}, nil
+}
+
+func (m *GRPCServer) List(ctx context.Context, req *proto.ListRequest) (*proto.ListResponse, error) {
+    m.log(ctx).Debug("📡📋 handling List request",
+        "prefix", req.Prefix)
+
+    keys, err := m.Impl.List(ctx, req.Prefix)
+    if err != nil {
+        m.log(ctx).Error("📡❌ List operation failed",
+            "prefix", req.Prefix,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ List operation completed successfully",
+        "prefix", req.Prefix,
+        "key_count", len(keys))
+    return &proto.ListResponse{Keys: keys}, nil
+}
+
+func (m *GRPCServer) ListPage(ctx context.Context, req *proto.ListPageRequest) (*proto.ListPageResponse, error) {
+    m.log(ctx).Debug("📡📋 handling ListPage request",
+        "prefix", req.Prefix,
+        "page_size", req.PageSize)
+
+    keys, next, err := m.Impl.ListPage(ctx, req.Prefix, req.PageToken, int(req.PageSize))
+    if err != nil {
+        m.log(ctx).Error("📡❌ ListPage operation failed",
+            "prefix", req.Prefix,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ ListPage operation completed successfully",
+        "prefix", req.Prefix,
+        "key_count", len(keys),
+        "more", next != "")
+    return &proto.ListPageResponse{Keys: keys, NextPageToken: next}, nil
+}
+
+func (m *GRPCServer) BatchPut(ctx context.Context, req *proto.BatchPutRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡📤 handling BatchPut request",
+        "item_count", len(req.Items))
+
+    if err := m.Impl.BatchPut(ctx, req.Items); err != nil {
+        m.log(ctx).Error("📡❌ BatchPut operation failed",
+            "item_count", len(req.Items),
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ BatchPut operation completed successfully",
+        "item_count", len(req.Items))
+    return &proto.Empty{}, nil
+}
+
+func (m *GRPCServer) BatchGet(ctx context.Context, req *proto.BatchGetRequest) (*proto.BatchGetResponse, error) {
+    m.log(ctx).Debug("📡📥 handling BatchGet request",
+        "key_count", len(req.Keys))
+
+    values, err := m.Impl.BatchGet(ctx, req.Keys)
+    if err != nil {
+        m.log(ctx).Error("📡❌ BatchGet operation failed",
+            "key_count", len(req.Keys),
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    var missing []string
+    for _, key := range req.Keys {
+        if _, ok := values[key]; !ok {
+            missing = append(missing, key)
+        }
+    }
+
+    m.log(ctx).Debug("📡✅ BatchGet operation completed successfully",
+        "found", len(values),
+        "missing", len(missing))
+    return &proto.BatchGetResponse{Values: values, Missing: missing}, nil
+}
+
+func (m *GRPCServer) CompareAndSwap(ctx context.Context, req *proto.CasRequest) (*proto.CasResponse, error) {
+    m.log(ctx).Debug("📡🔁 handling CompareAndSwap request",
+        "key", req.Key)
+
+    swapped, err := m.Impl.CompareAndSwap(ctx, req.Key, req.OldValue, req.NewValue)
+    if err != nil {
+        m.log(ctx).Error("📡❌ CompareAndSwap operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ CompareAndSwap operation completed successfully",
+        "key", req.Key,
+        "swapped", swapped)
+    return &proto.CasResponse{Swapped: swapped}, nil
+}
+
+func (m *GRPCServer) PutIfAbsent(ctx context.Context, req *proto.PutIfAbsentRequest) (*proto.PutIfAbsentResponse, error) {
+    m.log(ctx).Debug("📡📤 handling PutIfAbsent request",
+        "key", req.Key,
+        "value_size", len(req.Value))
+
+    var created bool
+    err := CheckValueSize(req.Key, req.Value, m.maxValueBytes)
+    if err == nil {
+        created, err = m.Impl.PutIfAbsent(ctx, req.Key, req.Value)
+    }
+    if err != nil {
+        m.log(ctx).Error("📡❌ PutIfAbsent operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ PutIfAbsent operation completed successfully",
+        "key", req.Key,
+        "created", created)
+    return &proto.PutIfAbsentResponse{Created: created}, nil
+}
+
+func (m *GRPCServer) Rename(ctx context.Context, req *proto.RenameRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡🚚 handling Rename request",
+        "old_key", req.OldKey,
+        "new_key", req.NewKey,
+        "overwrite", req.Overwrite)
+
+    if err := m.Impl.Rename(ctx, req.OldKey, req.NewKey, req.Overwrite); err != nil {
+        m.log(ctx).Error("📡❌ Rename operation failed",
+            "old_key", req.OldKey,
+            "new_key", req.NewKey,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Rename operation completed successfully",
+        "old_key", req.OldKey,
+        "new_key", req.NewKey)
+    return &proto.Empty{}, nil
+}
+
+func (m *GRPCServer) Exists(ctx context.Context, req *proto.ExistsRequest) (*proto.ExistsResponse, error) {
+    m.log(ctx).Debug("📡🔎 handling Exists request",
+        "key", req.Key)
+
+    exists, err := m.Impl.Exists(ctx, req.Key)
+    if err != nil {
+        m.log(ctx).Error("📡❌ Exists operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Exists operation completed successfully",
+        "key", req.Key,
+        "exists", exists)
+    return &proto.ExistsResponse{Exists: exists}, nil
+}
+
+func (m *GRPCServer) GetVersioned(ctx context.Context, req *proto.GetRequest) (*proto.GetVersionedResponse, error) {
+    m.log(ctx).Debug("📡📥 handling GetVersioned request",
+        "key", req.Key)
+
+    value, version, err := m.Impl.GetVersioned(ctx, req.Key)
+    if err != nil {
+        m.log(ctx).Error("📡❌ GetVersioned operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ GetVersioned operation completed successfully",
+        "key", req.Key,
+        "version", version)
+    return &proto.GetVersionedResponse{Value: value, Version: version}, nil
+}
+
+func (m *GRPCServer) PutIfVersion(ctx context.Context, req *proto.PutIfVersionRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡🔁 handling PutIfVersion request",
+        "key", req.Key,
+        "expected_version", req.ExpectedVersion)
+
+    if err := m.Impl.PutIfVersion(ctx, req.Key, req.Value, req.ExpectedVersion); err != nil {
+        m.log(ctx).Error("📡❌ PutIfVersion operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ PutIfVersion operation completed successfully",
+        "key", req.Key)
+    return &proto.Empty{}, nil
+}
+
+func (m *GRPCServer) Increment(ctx context.Context, req *proto.IncrementRequest) (*proto.IncrementResponse, error) {
+    m.log(ctx).Debug("📡➕ handling Increment request",
+        "key", req.Key,
+        "delta", req.Delta)
+
+    value, err := m.Impl.Increment(ctx, req.Key, req.Delta)
+    if err != nil {
+        m.log(ctx).Error("📡❌ Increment operation failed",
+            "key", req.Key,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Increment operation completed successfully",
+        "key", req.Key,
+        "value", value)
+    return &proto.IncrementResponse{Value: value}, nil
+}
+
+func (m *GRPCServer) Transaction(ctx context.Context, req *proto.TransactionRequest) (*proto.Empty, error) {
+    m.log(ctx).Debug("📡🧾 handling Transaction request",
+        "op_count", len(req.Ops))
+
+    ops := make([]TxOp, 0, len(req.Ops))
+    for i, msg := range req.Ops {
+        op, err := txOpFromProto(msg)
+        if err != nil {
+            return nil, toStatus(&TxError{Index: i, Err: err})
+        }
+        if err := CheckValueSize(op.Key, op.Value, m.maxValueBytes); err != nil {
+            return nil, toStatus(&TxError{Index: i, Err: err})
+        }
+        ops = append(ops, op)
+    }
+
+    if err := m.Impl.Transaction(ctx, ops); err != nil {
+        m.log(ctx).Error("📡❌ Transaction operation failed",
+            "op_count", len(ops),
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Transaction operation completed successfully",
+        "op_count", len(ops))
+    return &proto.Empty{}, nil
+}
+
+func (m *GRPCServer) Scan(ctx context.Context, req *proto.ScanRequest) (*proto.ScanResponse, error) {
+    m.log(ctx).Debug("📡🧺 handling Scan request",
+        "prefix", req.Prefix)
+
+    values, truncated, err := m.Impl.Scan(ctx, req.Prefix)
+    if err != nil {
+        m.log(ctx).Error("📡❌ Scan operation failed",
+            "prefix", req.Prefix,
+            "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Scan operation completed successfully",
+        "prefix", req.Prefix,
+        "key_count", len(values),
+        "truncated", truncated)
+    return &proto.ScanResponse{Values: values, Truncated: truncated}, nil
+}
+
+func (m *GRPCServer) Stats(ctx context.Context, req *proto.Empty) (*proto.StatsResponse, error) {
+    m.log(ctx).Debug("📡📊 handling Stats request")
+
+    keyCount, totalBytes, err := m.Impl.Stats(ctx)
+    if err != nil {
+        m.log(ctx).Error("📡❌ Stats operation failed", "error", err)
+        return nil, toStatus(err)
+    }
+
+    m.log(ctx).Debug("📡✅ Stats operation completed successfully",
+        "key_count", keyCount,
+        "total_bytes", totalBytes)
+    return &proto.StatsResponse{KeyCount: keyCount, TotalBytes: totalBytes}, nil
+}
+
+// Ping echoes the payload with the server's clock. It is answered here
+// rather than by Impl.
+func (m *GRPCServer) Ping(ctx context.Context, req *proto.PingRequest) (*proto.PingResponse, error) {
+    m.log(ctx).Debug("📡🏓 handling Ping request", "payload_size", len(req.Payload))
+    return &proto.PingResponse{
+        Payload:            req.Payload,
+        ServerTimeUnixNano: time.Now().UnixNano(),
+    }, nil
+}
+
+func (m *GRPCServer) Watch(req *proto.WatchRequest, stream proto.KV_WatchServer) error {
+    ctx := stream.Context()
+    m.log(stream.Context()).Debug("📡👀 handling Watch request",
+        "prefix", req.Prefix)
+
+    events, err := m.Impl.Watch(ctx, req.Prefix)
+    if err != nil {
+        m.log(stream.Context()).Error("📡❌ Watch operation failed",
+            "prefix", req.Prefix,
+            "error", err)
+        return toStatus(err)
+    }
+    // Tell the client the watcher is registered
+    if err := stream.SendHeader(metadata.MD{}); err != nil {
+        return toStatus(err)
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            m.log(stream.Context()).Debug("📡👀 Watch client disconnected",
+                "prefix", req.Prefix)
+            return nil
+        case event, ok := <-events:
+            if !ok {
+                m.log(stream.Context()).Debug("📡✅ Watch stream completed",
+                    "prefix", req.Prefix)
+                return nil
+            }
+            err := stream.Send(&proto.Event{
+                Op:    eventOpToProto(event.Op),
+                Key:   event.Key,
+                Value: event.Value,
+            })
+            if err != nil {
+                m.log(stream.Context()).Error("📡❌ Watch send failed",
+                    "prefix", req.Prefix,
+                    "error", err)
+                return toStatus(err)
+            }
+        }
+    }
+}
+
+func eventOpToProto(op EventOp) proto.EventOp {
+    switch op {
+    case EventPut:
+        return proto.EventOp_EVENT_OP_PUT
+    case EventDelete:
+        return proto.EventOp_EVENT_OP_DELETE
+    default:
+        return proto.EventOp_EVENT_OP_UNSPECIFIED
+    }
+}
+
+func eventOpFromProto(op proto.EventOp) EventOp {
+    switch op {
+    case proto.EventOp_EVENT_OP_PUT:
+        return EventPut
+    case proto.EventOp_EVENT_OP_DELETE:
+        return EventDelete
+    default:
+        return 0
+    }
+}
+
+func txOpToProto(op TxOp) (*proto.TxOp, error) {
+    switch op.Kind {
+    case TxPut:
+        put := &proto.PutRequest{Key: op.Key, Value: op.Value}
+        if op.TTL > 0 {
+            put.TtlSeconds = int64(math.Ceil(op.TTL.Seconds()))
+        }
+        return &proto.TxOp{Op: &proto.TxOp_Put{Put: put}}, nil
+    case TxDelete:
+        return &proto.TxOp{Op: &proto.TxOp_Delete{Delete: &proto.DeleteRequest{Key: op.Key}}}, nil
+    case TxCompareAndSwap:
+        return &proto.TxOp{Op: &proto.TxOp_CompareAndSwap{CompareAndSwap: &proto.CasRequest{
+            Key:      op.Key,
+            OldValue: op.Old,
+            NewValue: op.Value,
+        }}}, nil
+    default:
+        return nil, fmt.Errorf("unknown transaction op kind %d", op.Kind)
+    }
+}
+
+func txOpFromProto(msg *proto.TxOp) (TxOp, error) {
+    switch op := msg.GetOp().(type) {
+    case *proto.TxOp_Put:
+        ttl, err := ttlFromSeconds(op.Put.GetKey(), op.Put.GetTtlSeconds())
+        if err != nil {
+            return TxOp{}, err
+        }
+        return TxOp{
+            Kind:  TxPut,
+            Key:   op.Put.GetKey(),
+            Value: op.Put.GetValue(),
+            TTL:   ttl,
+        }, nil
+    case *proto.TxOp_Delete:
+        return TxOp{Kind: TxDelete, Key: op.Delete.GetKey()}, nil
+    case *proto.TxOp_CompareAndSwap:
+        return TxOp{
+            Kind:  TxCompareAndSwap,
+            Key:   op.CompareAndSwap.GetKey(),
+            Value: op.CompareAndSwap.GetNewValue(),
+            Old:   op.CompareAndSwap.GetOldValue(),
+        }, nil
+    default:
+        return TxOp{}, status.Error(codes.InvalidArgument, "transaction op has no operation set")
+    }
 }
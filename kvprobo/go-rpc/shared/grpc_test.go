@@ -0,0 +1,657 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/grpc_test.go
+
+package shared
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "net"
+    "os"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "go.uber.org/goleak"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/encoding/gzip"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/status"
+    "google.golang.org/grpc/test/bufconn"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// blockingKV never answers Get until the request context is done.
+type blockingKV struct {
+    kvImpl
+}
+
+func (*blockingKV) Get(ctx context.Context, key string) ([]byte, error) {
+    <-ctx.Done()
+    return nil, ctx.Err()
+}
+
+func (b *blockingKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := b.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+// valueKV serves a single fixed value for every Get.
+type valueKV struct {
+    kvImpl
+    value []byte
+}
+
+func (v *valueKV) Get(ctx context.Context, key string) ([]byte, error) {
+    return v.value, nil
+}
+
+func (v *valueKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := v.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+// mapKV is a minimal in-memory KV for round-trip tests.
+type mapKV struct {
+    kvImpl
+    mu   sync.Mutex
+    data map[string][]byte
+}
+
+func (m *mapKV) Put(ctx context.Context, key string, value []byte) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.data == nil {
+        m.data = map[string][]byte{}
+    }
+    m.data[key] = value
+    return nil
+}
+
+func (m *mapKV) Get(ctx context.Context, key string) ([]byte, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    value, ok := m.data[key]
+    if !ok {
+        return nil, ErrKeyNotFound
+    }
+    return value, nil
+}
+
+func (m *mapKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := m.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+// errKV fails every Get with err.
+type errKV struct {
+    kvImpl
+    err error
+}
+
+func (e *errKV) Get(ctx context.Context, key string) ([]byte, error) {
+    return nil, e.err
+}
+
+func (e *errKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := e.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+// newTestGRPCClient serves impl over an in-memory listener and returns a
+// GRPCClient connected to it.
+func newTestGRPCClient(t *testing.T, impl KV) *GRPCClient {
+    t.Helper()
+    return newTestGRPCClientWithOptions(t, impl, nil)
+}
+
+// newTestGRPCClientWithOptions is newTestGRPCClient with extra server and
+// dial options.
+func newTestGRPCClientWithOptions(t *testing.T, impl KV, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) *GRPCClient {
+    t.Helper()
+
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(serverOpts...)
+    proto.RegisterKVServer(server, &GRPCServer{Impl: impl, logger: hclog.NewNullLogger()})
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    dialOpts = append([]grpc.DialOption{
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()),
+    }, dialOpts...)
+    conn, err := grpc.NewClient("passthrough:///bufconn", dialOpts...)
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    return &GRPCClient{
+        client: proto.NewKVClient(conn),
+        health: healthpb.NewHealthClient(conn),
+        logger: hclog.NewNullLogger(),
+    }
+}
+
+func TestGRPCClientHonorsCancelledContext(t *testing.T) {
+    client := newTestGRPCClient(t, &blockingKV{})
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    start := time.Now()
+    _, err := client.Get(ctx, "hung")
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("Get with a cancelled context took %v", elapsed)
+    }
+    if status.Code(err) != codes.Canceled {
+        t.Fatalf("Get error = %v, want code Canceled", err)
+    }
+}
+
+func TestGRPCClientHonorsDeadline(t *testing.T) {
+    client := newTestGRPCClient(t, &blockingKV{})
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    start := time.Now()
+    _, err := client.Get(ctx, "hung")
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("Get past its deadline took %v", elapsed)
+    }
+    if status.Code(err) != codes.DeadlineExceeded {
+        t.Fatalf("Get error = %v, want code DeadlineExceeded", err)
+    }
+}
+
+func TestGRPCClientRequestTimeout(t *testing.T) {
+    client := newTestGRPCClient(t, &blockingKV{})
+    client.RequestTimeout = 50 * time.Millisecond
+
+    start := time.Now()
+    _, err := client.Get(context.Background(), "hung")
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Fatalf("Get against a slow server took %v despite RequestTimeout", elapsed)
+    }
+    if status.Code(err) != codes.DeadlineExceeded {
+        t.Fatalf("Get error = %v, want code DeadlineExceeded", err)
+    }
+}
+
+func TestGRPCClientGetStreamLargeValue(t *testing.T) {
+    value := bytes.Repeat([]byte("0123456789abcdef"), 10<<20/16)
+    client := newTestGRPCClient(t, &valueKV{value: value})
+
+    if _, err := client.Get(context.Background(), "big"); status.Code(err) != codes.ResourceExhausted {
+        t.Fatalf("unary Get of a %d byte value = %v, want code ResourceExhausted", len(value), err)
+    }
+
+    var buf bytes.Buffer
+    if err := client.GetStream(context.Background(), "big", &buf); err != nil {
+        t.Fatalf("GetStream failed: %v", err)
+    }
+    if !bytes.Equal(buf.Bytes(), value) {
+        t.Fatalf("GetStream returned %d bytes, want the original %d", buf.Len(), len(value))
+    }
+}
+
+func TestGRPCClientGetBatchStream(t *testing.T) {
+    ctx := context.Background()
+    kv := &mapKV{}
+    var keys []string
+    for i := 0; i < 100; i++ {
+        key := fmt.Sprintf("key-%d", i)
+        keys = append(keys, key)
+        // Every third key is missing
+        if i%3 != 0 {
+            kv.Put(ctx, key, []byte("value-"+key))
+        }
+    }
+    client := newTestGRPCClient(t, kv)
+
+    results, stop, err := client.GetBatchStream(ctx, keys)
+    if err != nil {
+        t.Fatalf("GetBatchStream failed: %v", err)
+    }
+    defer stop()
+    i := 0
+    for result := range results {
+        if result.Err != nil {
+            t.Fatalf("result %d failed: %v", i, result.Err)
+        }
+        if i >= len(keys) {
+            t.Fatalf("got more results than the %d keys sent", len(keys))
+        }
+        wantFound := i%3 != 0
+        if result.Key != keys[i] || result.Found != wantFound {
+            t.Fatalf("result %d = %q found %v, want %q found %v", i, result.Key, result.Found, keys[i], wantFound)
+        }
+        if wantFound && string(result.Value) != "value-"+keys[i] {
+            t.Fatalf("value of %q = %q, want %q", result.Key, result.Value, "value-"+keys[i])
+        }
+        if !wantFound && result.Value != nil {
+            t.Fatalf("missing key %q came with value %q", result.Key, result.Value)
+        }
+        i++
+    }
+    if i != len(keys) {
+        t.Fatalf("got %d results, want %d", i, len(keys))
+    }
+}
+
+func TestGRPCClientGetBatchStreamError(t *testing.T) {
+    client := newTestGRPCClient(t, &errKV{err: ErrInvalidKey})
+
+    results, stop, err := client.GetBatchStream(context.Background(), []string{"a", "b"})
+    if err != nil {
+        t.Fatalf("GetBatchStream failed: %v", err)
+    }
+    defer stop()
+    var got []BatchResult
+    for result := range results {
+        got = append(got, result)
+    }
+    if len(got) != 1 || !errors.Is(got[0].Err, ErrInvalidKey) {
+        t.Fatalf("results = %+v, want one carrying ErrInvalidKey", got)
+    }
+}
+
+func TestGRPCClientGetBatchStreamTimeout(t *testing.T) {
+    client := newTestGRPCClient(t, &blockingKV{})
+    client.RequestTimeout = 50 * time.Millisecond
+
+    results, stop, err := client.GetBatchStream(context.Background(), []string{"a", "b"})
+    if err != nil {
+        t.Fatalf("GetBatchStream failed: %v", err)
+    }
+    defer stop()
+    // Read only once the deadline has passed, so the result reporting it
+    // has to wait for the caller
+    time.Sleep(150 * time.Millisecond)
+    var last BatchResult
+    n := 0
+    for result := range results {
+        last = result
+        n++
+    }
+    if n == 0 || last.Err == nil {
+        t.Fatalf("a batch cut short by RequestTimeout ended with %+v after %d results, want an error", last, n)
+    }
+    if !errors.Is(last.Err, context.DeadlineExceeded) && status.Code(last.Err) != codes.DeadlineExceeded {
+        t.Fatalf("last result's error = %v, want DeadlineExceeded", last.Err)
+    }
+}
+
+func TestGRPCClientGetBatchStreamAbandoned(t *testing.T) {
+    kv := &mapKV{}
+    var keys []string
+    for i := 0; i < 100; i++ {
+        keys = append(keys, fmt.Sprintf("key-%d", i))
+    }
+    client := newTestGRPCClient(t, kv)
+    // Connect first, so the connection's goroutines aren't counted as leaks
+    if err := client.Put(context.Background(), keys[0], []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    ignore := goleak.IgnoreCurrent()
+
+    // No RequestTimeout, so only stop ends the stream
+    results, stop, err := client.GetBatchStream(context.Background(), keys)
+    if err != nil {
+        t.Fatalf("GetBatchStream failed: %v", err)
+    }
+    if result := <-results; result.Err != nil || result.Key != keys[0] {
+        t.Fatalf("first result = %+v, want %q", result, keys[0])
+    }
+    stop()
+
+    goleak.VerifyNone(t, ignore)
+}
+
+func TestGRPCServerRejectsOutOfRangeTTL(t *testing.T) {
+    ctx := context.Background()
+    kv := &mapKV{}
+    client := newTestGRPCClient(t, kv)
+
+    for _, seconds := range []int64{-1, maxTTLSeconds + 1, math.MaxInt64} {
+        _, err := client.client.Put(ctx, &proto.PutRequest{Key: "k", Value: []byte("v"), TtlSeconds: seconds})
+        if status.Code(err) != codes.InvalidArgument || !errors.Is(fromStatus(err), ErrInvalidTTL) {
+            t.Fatalf("Put with ttl_seconds %d = %v, want ErrInvalidTTL", seconds, err)
+        }
+        _, err = client.client.Transaction(ctx, &proto.TransactionRequest{Ops: []*proto.TxOp{
+            {Op: &proto.TxOp_Put{Put: &proto.PutRequest{Key: "k", Value: []byte("v"), TtlSeconds: seconds}}},
+        }})
+        if status.Code(err) != codes.InvalidArgument || !errors.Is(fromStatus(err), ErrInvalidTTL) {
+            t.Fatalf("transaction put with ttl_seconds %d = %v, want ErrInvalidTTL", seconds, err)
+        }
+    }
+    if _, err := kv.Get(ctx, "k"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fatalf("a refused put was stored: Get = %v", err)
+    }
+
+    if _, err := client.client.Put(ctx, &proto.PutRequest{Key: "k", Value: []byte("v"), TtlSeconds: maxTTLSeconds}); err != nil {
+        t.Fatalf("Put with the longest ttl_seconds failed: %v", err)
+    }
+}
+
+func TestGRPCErrorsRoundTrip(t *testing.T) {
+    tests := []struct {
+        name     string
+        err      error
+        code     codes.Code
+        sentinel error
+    }{
+        {"not found", fmt.Errorf("%w: %q", ErrKeyNotFound, "k"), codes.NotFound, ErrKeyNotFound},
+        {"invalid key", fmt.Errorf("%w: %q contains a path separator", ErrInvalidKey, "a/b"), codes.InvalidArgument, ErrInvalidKey},
+        {"invalid ttl", fmt.Errorf("%w: %q has ttl_seconds %d", ErrInvalidTTL, "k", -1), codes.InvalidArgument, ErrInvalidTTL},
+        {"version conflict", fmt.Errorf("%w: %q is at version %d, not %d", ErrVersionConflict, "k", 3, 2), codes.Aborted, ErrVersionConflict},
+        {"not a number", fmt.Errorf("%w: %q holds %q", ErrNotANumber, "k", "abc"), codes.FailedPrecondition, ErrNotANumber},
+        {"out of range", fmt.Errorf("%w: %q is %d, adding %d overflows int64", ErrOutOfRange, "k", int64(1), int64(2)), codes.OutOfRange, ErrOutOfRange},
+        {"decryption failed", fmt.Errorf("%w: %q was written with a different key or is corrupt", ErrDecryptionFailed, "k"), codes.DataLoss, ErrDecryptionFailed},
+        {"storage failure", &os.PathError{Op: "open", Path: "/tmp/kv-data-x/k", Err: os.ErrPermission}, codes.Internal, ErrStorageFailure},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            client := newTestGRPCClient(t, &errKV{err: tt.err})
+
+            _, err := client.Get(context.Background(), "k")
+            if !errors.Is(err, tt.sentinel) {
+                t.Fatalf("Get error = %v, want errors.Is %v", err, tt.sentinel)
+            }
+            if status.Code(err) != tt.code {
+                t.Fatalf("Get error code = %v, want %v", status.Code(err), tt.code)
+            }
+            if strings.Contains(err.Error(), "/tmp/") {
+                t.Fatalf("Get error %q leaks a server path", err)
+            }
+        })
+    }
+}
+
+func TestGetSoftMiss(t *testing.T) {
+    ctx := context.Background()
+    impl := &mapKV{data: map[string][]byte{"present": []byte("value")}}
+    server := &GRPCServer{Impl: impl, logger: hclog.NewNullLogger()}
+
+    resp, err := server.Get(ctx, &proto.GetRequest{Key: "present", SoftMiss: true})
+    if err != nil || !resp.Found || resp.ErrorCode != proto.GetErrorCode_GET_ERROR_CODE_UNSPECIFIED || string(resp.Value) != "value" {
+        t.Fatalf("Get(present) = %v, %v; want the value with found set", resp, err)
+    }
+    resp, err = server.Get(ctx, &proto.GetRequest{Key: "missing", SoftMiss: true})
+    if err != nil || resp.Found || resp.ErrorCode != proto.GetErrorCode_GET_ERROR_CODE_KEY_NOT_FOUND {
+        t.Fatalf("soft Get(missing) = %v, %v; want found unset and KEY_NOT_FOUND", resp, err)
+    }
+    // Callers that don't ask for soft misses still get NOT_FOUND
+    if _, err := server.Get(ctx, &proto.GetRequest{Key: "missing"}); status.Code(err) != codes.NotFound {
+        t.Fatalf("Get(missing) without soft_miss = %v, want NotFound", err)
+    }
+
+    client := newTestGRPCClient(t, impl)
+    if value, err := client.Get(ctx, "present"); err != nil || string(value) != "value" {
+        t.Fatalf("client Get(present) = %q, %v; want value", value, err)
+    }
+    _, err = client.Get(ctx, "missing")
+    if !errors.Is(err, ErrKeyNotFound) || status.Code(err) != codes.NotFound {
+        t.Fatalf("client Get(missing) = %v, want ErrKeyNotFound with code NotFound", err)
+    }
+}
+
+func TestGRPCServerRejectsLargeValue(t *testing.T) {
+    impl := &mapKV{}
+    server := &GRPCServer{Impl: impl, maxValueBytes: 4, logger: hclog.NewNullLogger()}
+
+    _, err := server.Put(context.Background(), &proto.PutRequest{Key: "k", Value: []byte("12345")})
+    if status.Code(err) != codes.InvalidArgument {
+        t.Fatalf("Put of 5 bytes with a 4-byte limit = %v, want code InvalidArgument", err)
+    }
+    if err := fromStatus(err); !errors.Is(err, ErrValueTooLarge) {
+        t.Fatalf("client-side error = %v, want ErrValueTooLarge", err)
+    }
+    if len(impl.data) != 0 {
+        t.Fatalf("oversized value reached the implementation: %q", impl.data)
+    }
+
+    if _, err := server.Put(context.Background(), &proto.PutRequest{Key: "k", Value: []byte("1234")}); err != nil {
+        t.Fatalf("Put of 4 bytes with a 4-byte limit = %v, want nil", err)
+    }
+}
+
+func TestMaxValueBytesFromEnv(t *testing.T) {
+    tests := []struct {
+        value   string
+        want    int
+        wantErr bool
+    }{
+        {"", DefaultMaxValueBytes, false},
+        {"1024", 1024, false},
+        {"0", 0, false},
+        {"lots", 0, true},
+        {"-1", 0, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.value, func(t *testing.T) {
+            t.Setenv("PLUGIN_KV_MAX_VALUE_BYTES", tt.value)
+            got, err := MaxValueBytesFromEnv()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("MaxValueBytesFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Fatalf("MaxValueBytesFromEnv() = %d, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestLoggingInterceptors(t *testing.T) {
+    var serverLog, clientLog bytes.Buffer
+    newLogger := func(w *bytes.Buffer) hclog.Logger {
+        return hclog.New(&hclog.LoggerOptions{Output: w, Level: hclog.Trace})
+    }
+
+    client := newTestGRPCClientWithOptions(t, &valueKV{value: []byte("v")},
+        []grpc.ServerOption{grpc.UnaryInterceptor(LoggingUnaryInterceptor(newLogger(&serverLog)))},
+        grpc.WithUnaryInterceptor(LoggingUnaryClientInterceptor(newLogger(&clientLog))))
+
+    ctx := context.Background()
+    if _, err := client.Get(ctx, "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if err := client.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+
+    for name, log := range map[string]string{"server": serverLog.String(), "client": clientLog.String()} {
+        lines := strings.Split(strings.TrimSpace(log), "\n")
+        if len(lines) != 2 {
+            t.Fatalf("%s logged %d entries, want one per call:\n%s", name, len(lines), log)
+        }
+        for i, method := range []string{"/proto.KV/Get", "/proto.KV/Put"} {
+            if !strings.Contains(lines[i], "method="+method) || !strings.Contains(lines[i], "code=OK") {
+                t.Fatalf("%s entry %d = %q, want method %s with code OK", name, i, lines[i], method)
+            }
+        }
+    }
+}
+
+func TestGRPCClientGzipRoundTrip(t *testing.T) {
+    t.Setenv("PLUGIN_KV_COMPRESSION", "gzip")
+    compressor, err := CompressionFromEnv()
+    if err != nil || compressor != gzip.Name {
+        t.Fatalf("CompressionFromEnv = %q, %v; want %q", compressor, err, gzip.Name)
+    }
+
+    client := newTestGRPCClientWithOptions(t, &mapKV{}, nil,
+        grpc.WithDefaultCallOptions(grpc.UseCompressor(compressor)))
+
+    ctx := context.Background()
+    value := bytes.Repeat([]byte("compress me "), (1<<20)/12)
+    if err := client.Put(ctx, "big", value); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    got, err := client.Get(ctx, "big")
+    if err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if !bytes.Equal(got, value) {
+        t.Fatalf("Get returned %d bytes that differ from the %d put", len(got), len(value))
+    }
+}
+
+func TestCompressionFromEnvRejectsUnknown(t *testing.T) {
+    t.Setenv("PLUGIN_KV_COMPRESSION", "brotli")
+    if _, err := CompressionFromEnv(); err == nil {
+        t.Fatal("CompressionFromEnv accepted an unsupported compressor")
+    }
+}
+
+func TestGRPCPing(t *testing.T) {
+    client := newTestGRPCClient(t, &kvImpl{})
+
+    payload := []byte("ping\x00\xff")
+    before := time.Now().UnixNano()
+    echo, serverTime, err := client.Ping(context.Background(), payload)
+    if err != nil {
+        t.Fatalf("Ping failed: %v", err)
+    }
+    if !bytes.Equal(echo, payload) {
+        t.Fatalf("Ping echoed %q, want %q", echo, payload)
+    }
+    if serverTime < before || serverTime > time.Now().UnixNano() {
+        t.Fatalf("Ping server time %d is outside the call", serverTime)
+    }
+}
+
+func TestKVGRPCPluginUsesCallerLogger(t *testing.T) {
+    var logs bytes.Buffer
+    logger := hclog.New(&hclog.LoggerOptions{Name: "host", Output: &logs, Level: hclog.Trace})
+    p := &KVGRPCPlugin{Impl: &valueKV{value: []byte("v")}, Logger: logger}
+
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer()
+    if err := p.GRPCServer(nil, server); err != nil {
+        t.Fatalf("GRPCServer failed: %v", err)
+    }
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufconn",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    raw, err := p.GRPCClient(context.Background(), nil, conn)
+    if err != nil {
+        t.Fatalf("GRPCClient failed: %v", err)
+    }
+    if _, err := raw.(KV).Get(context.Background(), "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+
+    for _, want := range []string{
+        "host.🔌🌐 kv-grpc-client: 🌐📥 initiating Get request",
+        "host.🔌📡 kv-grpc-server: 📡📥 handling Get request",
+    } {
+        if !strings.Contains(logs.String(), want) {
+            t.Fatalf("caller's logger is missing %q:\n%s", want, logs.String())
+        }
+    }
+
+    // Raising the caller's level quiets the RPC logs too
+    logger.SetLevel(hclog.Warn)
+    logs.Reset()
+    if _, err := raw.(KV).Get(context.Background(), "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if logs.Len() != 0 {
+        t.Fatalf("RPC logs ignored the caller's level:\n%s", logs.String())
+    }
+}
+
+// openFDs counts the process's open file descriptors, or returns -1 where
+// /proc/self/fd doesn't exist.
+func openFDs() int {
+    entries, err := os.ReadDir("/proc/self/fd")
+    if err != nil {
+        return -1
+    }
+    return len(entries)
+}
+
+func TestGRPCClientCloseLeaksNothing(t *testing.T) {
+    server := grpc.NewServer()
+    proto.RegisterKVServer(server, &GRPCServer{Impl: &mapKV{}, logger: hclog.NewNullLogger()})
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Listen failed: %v", err)
+    }
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    // Anything running now belongs to the server or the test runner
+    ignore := goleak.IgnoreCurrent()
+    fdsBefore := openFDs()
+
+    conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    client := NewGRPCClient(conn, nil)
+    if err := client.Put(context.Background(), "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if err := client.Close(); err != nil {
+        t.Fatalf("Close failed: %v", err)
+    }
+    if err := client.Close(); err != nil {
+        t.Fatalf("second Close = %v, want nil", err)
+    }
+
+    // The server notices the closed connection in its own time
+    goleak.VerifyNone(t, ignore)
+    if fdsBefore < 0 {
+        return
+    }
+    deadline := time.Now().Add(5 * time.Second)
+    for openFDs() > fdsBefore {
+        if time.Now().After(deadline) {
+            t.Fatalf("%d file descriptors open after Close, want %d", openFDs(), fdsBefore)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+func TestDispensedGRPCClientCloseLeavesConnection(t *testing.T) {
+    conn, err := grpc.NewClient("passthrough:///unused", grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    defer conn.Close()
+    p := &KVGRPCPlugin{Logger: hclog.NewNullLogger()}
+    raw, err := p.GRPCClient(context.Background(), nil, conn)
+    if err != nil {
+        t.Fatalf("GRPCClient failed: %v", err)
+    }
+    if err := raw.(*GRPCClient).Close(); err != nil {
+        t.Fatalf("Close failed: %v", err)
+    }
+    // go-plugin still owns the connection, so it must be left open
+    if err := conn.Close(); err != nil {
+        t.Fatalf("connection was already closed: %v", err)
+    }
+}
@@ -0,0 +1,38 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/handshake.go
+
+package shared
+
+import (
+    "errors"
+    "fmt"
+    "os"
+)
+
+// ErrCookieMismatch is returned when a process expects a different magic
+// cookie than the one in Handshake, which usually means the wrong binary
+// was launched.
+var ErrCookieMismatch = errors.New("handshake cookie mismatch — is this the right plugin binary?")
+
+// go-plugin returns one of these errors, depending on which it notices
+// first, when the plugin quits before printing its handshake line. A plugin
+// that rejects our cookie exits this way.
+var pluginExitedMessages = []string{
+    "plugin exited before we could connect",
+    "Failed to read any lines from plugin's stdout",
+}
+
+// CheckMagicCookie confirms the host launched this process with the cookie
+// in Handshake. go-plugin checks this too, but only prints a generic
+// message to stderr.
+func CheckMagicCookie() error {
+    value, ok := os.LookupEnv(Handshake.MagicCookieKey)
+    switch {
+    case !ok:
+        return fmt.Errorf("%w: %s is not set; this binary is started by the KV client, not run directly",
+            ErrCookieMismatch, Handshake.MagicCookieKey)
+    case value != Handshake.MagicCookieValue:
+        return fmt.Errorf("%w: %s is %q, want %q",
+            ErrCookieMismatch, Handshake.MagicCookieKey, value, Handshake.MagicCookieValue)
+    }
+    return nil
+}
@@ -0,0 +1,82 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/handshake_test.go
+
+package shared
+
+import (
+    "errors"
+    "os"
+    "testing"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+func TestCheckMagicCookie(t *testing.T) {
+    t.Setenv(Handshake.MagicCookieKey, Handshake.MagicCookieValue)
+    if err := CheckMagicCookie(); err != nil {
+        t.Fatalf("CheckMagicCookie() with the right cookie = %v", err)
+    }
+
+    t.Setenv(Handshake.MagicCookieKey, "goodbye")
+    if err := CheckMagicCookie(); !errors.Is(err, ErrCookieMismatch) {
+        t.Fatalf("CheckMagicCookie() with the wrong cookie = %v, want ErrCookieMismatch", err)
+    }
+
+    os.Unsetenv(Handshake.MagicCookieKey)
+    if err := CheckMagicCookie(); !errors.Is(err, ErrCookieMismatch) {
+        t.Fatalf("CheckMagicCookie() with no cookie = %v, want ErrCookieMismatch", err)
+    }
+}
+
+func TestHandshakeError(t *testing.T) {
+    err := HandshakeError(errors.New("Incompatible API version with plugin. Plugin version: 3, Client versions: [2 1]"))
+    var mismatch *VersionMismatchError
+    if !errors.As(err, &mismatch) || mismatch.PluginVersion != 3 ||
+        len(mismatch.Supported) != 2 || mismatch.Supported[0] != 1 || mismatch.Supported[1] != 2 {
+        t.Fatalf("HandshakeError(version) = %#v, want plugin version 3 and supported [1 2]", err)
+    }
+
+    for _, exited := range pluginExitedMessages {
+        if err := HandshakeError(errors.New(exited)); !errors.Is(err, ErrCookieMismatch) {
+            t.Fatalf("HandshakeError(%q) = %v, want ErrCookieMismatch", exited, err)
+        }
+    }
+
+    other := errors.New("timeout while waiting for plugin to start")
+    if err := HandshakeError(other); err != other {
+        t.Fatalf("HandshakeError(other) = %v, want it unchanged", err)
+    }
+}
+
+func TestRPCProtocolVersions(t *testing.T) {
+    // The RPCs protocol version 1 shipped with; every later one needs an
+    // entry in rpcProtocolVersions
+    v1 := map[string]bool{
+        "Get": true, "GetStream": true, "Put": true, "Delete": true, "List": true,
+        "BatchPut": true, "BatchGet": true, "CompareAndSwap": true, "Exists": true,
+        "Watch": true, "GetVersioned": true, "PutIfVersion": true, "Increment": true,
+        "Transaction": true,
+    }
+    var names []string
+    for _, method := range proto.KV_ServiceDesc.Methods {
+        names = append(names, method.MethodName)
+    }
+    for _, stream := range proto.KV_ServiceDesc.Streams {
+        names = append(names, stream.StreamName)
+    }
+    for _, name := range names {
+        method := "/" + proto.KV_ServiceDesc.ServiceName + "/" + name
+        if _, listed := rpcProtocolVersions[method]; listed == v1[name] {
+            t.Errorf("%s: listed in rpcProtocolVersions = %t, but added after version 1 = %t", name, listed, !v1[name])
+        }
+    }
+
+    if err := checkRPCVersion(proto.KV_Rename_FullMethodName, 1); !errors.Is(err, ErrVersionMismatch) {
+        t.Fatalf("checkRPCVersion(Rename, 1) = %v, want ErrVersionMismatch", err)
+    }
+    if err := checkRPCVersion(proto.KV_Rename_FullMethodName, ProtocolVersion); err != nil {
+        t.Fatalf("checkRPCVersion(Rename, %d) = %v, want nil", ProtocolVersion, err)
+    }
+    if err := checkRPCVersion(proto.KV_Get_FullMethodName, 1); err != nil {
+        t.Fatalf("checkRPCVersion(Get, 1) = %v, want nil", err)
+    }
+}
@@ -0,0 +1,41 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/idempotency.go
+
+package shared
+
+import (
+    "context"
+
+    "github.com/google/uuid"
+    "google.golang.org/grpc/metadata"
+)
+
+// IdempotencyKeyMetadataKey is the gRPC metadata header naming a Put, so
+// the plugin can recognise a repeat of one it has already applied.
+const IdempotencyKeyMetadataKey = "kv-idempotency-key"
+
+// NewIdempotencyKey returns a random key for one Put.
+func NewIdempotencyKey() string {
+    return uuid.NewString()
+}
+
+// WithIdempotencyKey returns a context whose Put sends key in the
+// kv-idempotency-key header. A plugin that has seen key recently returns
+// the original result without writing again, so the call is also marked
+// safe to retry. Use a fresh key for every Put.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+    ctx = metadata.AppendToOutgoingContext(ctx, IdempotencyKeyMetadataKey, key)
+    return WithRetry(ctx)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key a server-side
+// request carried, or "" when it had none.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+    md, ok := metadata.FromIncomingContext(ctx)
+    if !ok {
+        return ""
+    }
+    if values := md.Get(IdempotencyKeyMetadataKey); len(values) > 0 {
+        return values[0]
+    }
+    return ""
+}
@@ -0,0 +1,113 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/inprocess.go
+
+package shared
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "net"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/test/bufconn"
+)
+
+// inProcessBufferSize is how many bytes an in-process connection buffers
+// in each direction before a write blocks.
+const inProcessBufferSize = 1 << 20
+
+// InProcessConfig configures ServeInProcess. The zero value serves without
+// TLS, which is safe as the connection never leaves the process.
+type InProcessConfig struct {
+    // ServerTLS and ClientTLS layer TLS over the in-memory pipe, for code
+    // that authorizes requests by the client's certificate. Set both or
+    // neither; the client verifies the server as "localhost" unless
+    // ClientTLS names another ServerName.
+    ServerTLS *tls.Config
+    ClientTLS *tls.Config
+
+    // ServerOptions and DialOptions are added to those ServeInProcess
+    // builds, for interceptors and the like.
+    ServerOptions []grpc.ServerOption
+    DialOptions   []grpc.DialOption
+
+    // Logger is used by both ends; nil discards their logs.
+    Logger hclog.Logger
+}
+
+// ServeInProcess serves impl over an in-memory listener and returns a
+// client connected to it, for a host and plugin built into one binary that
+// want the plugin's protocol without a socket. stop closes the client and
+// stops the server.
+func ServeInProcess(impl KV, config InProcessConfig) (client *GRPCClient, stop func(), err error) {
+    if (config.ServerTLS == nil) != (config.ClientTLS == nil) {
+        return nil, nil, errors.New("in-process TLS needs both ServerTLS and ClientTLS")
+    }
+    logger := config.Logger
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+
+    serverCreds, clientCreds := insecure.NewCredentials(), insecure.NewCredentials()
+    if config.ServerTLS != nil {
+        clientTLS := config.ClientTLS.Clone()
+        if clientTLS.ServerName == "" {
+            clientTLS.ServerName = manualTLSServerName
+        }
+        serverCreds, clientCreds = credentials.NewTLS(config.ServerTLS), credentials.NewTLS(clientTLS)
+    }
+
+    listener := bufconn.Listen(inProcessBufferSize)
+    server := grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(serverCreds)}, config.ServerOptions...)...)
+    plugin := &KVGRPCPlugin{Impl: impl, Logger: logger}
+    if err := plugin.GRPCServer(nil, server); err != nil {
+        return nil, nil, err
+    }
+    go server.Serve(listener)
+
+    dialOptions := append([]grpc.DialOption{
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(clientCreds),
+    }, config.DialOptions...)
+    conn, err := grpc.NewClient("passthrough:///bufconn", dialOptions...)
+    if err != nil {
+        server.Stop()
+        return nil, nil, err
+    }
+    client = NewGRPCClient(conn, logger.Named("🔌🌐 kv-grpc-client"))
+    stop = func() {
+        client.Close()
+        server.Stop()
+    }
+    return client, stop, nil
+}
+
+// NewInProcessKV serves impl with ServeInProcess and returns a client for
+// it, so tests of code built on KV go through the real client, server and
+// wire format without launching the plugin as a subprocess. Both ends are
+// shut down when the test ends.
+//
+// The plugin's own KV, backed by its in-memory store, lives in the
+// plugin-go-server command, whose tests use this harness to serve it.
+func NewInProcessKV(t testing.TB, impl KV) *GRPCClient {
+    t.Helper()
+    return NewInProcessKVWithConfig(t, impl, InProcessConfig{})
+}
+
+// NewInProcessKVWithConfig is NewInProcessKV with config, such as TLS, for
+// the in-process connection.
+func NewInProcessKVWithConfig(t testing.TB, impl KV, config InProcessConfig) *GRPCClient {
+    t.Helper()
+    client, stop, err := ServeInProcess(impl, config)
+    if err != nil {
+        t.Fatalf("serving the in-process KV failed: %v", err)
+    }
+    t.Cleanup(stop)
+    return client
+}
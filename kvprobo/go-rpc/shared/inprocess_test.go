@@ -0,0 +1,98 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/inprocess_test.go
+
+package shared
+
+import (
+    "context"
+    "crypto/tls"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestInProcessKV(t *testing.T) {
+    kv := NewInProcessKV(t, &mapKV{})
+
+    ctx := context.Background()
+    if err := kv.Put(ctx, "greeting", []byte("hello")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if value, err := kv.Get(ctx, "greeting"); err != nil || string(value) != "hello" {
+        t.Fatalf("Get() = %q, %v, want hello", value, err)
+    }
+    // Errors cross the connection as they would from the plugin
+    if _, err := kv.Get(ctx, "missing"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fatalf("Get() of a missing key = %v, want ErrKeyNotFound", err)
+    }
+}
+
+// openSockets returns how many sockets the process has open, or -1 where
+// /proc isn't available.
+func openSockets() int {
+    entries, err := os.ReadDir("/proc/self/fd")
+    if err != nil {
+        return -1
+    }
+    sockets := 0
+    for _, entry := range entries {
+        target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name()))
+        if err == nil && strings.HasPrefix(target, "socket:") {
+            sockets++
+        }
+    }
+    return sockets
+}
+
+func TestServeInProcessOpensNoSocket(t *testing.T) {
+    before := openSockets()
+    if before < 0 {
+        t.Skip("/proc/self/fd isn't available")
+    }
+    kv, stop, err := ServeInProcess(&mapKV{}, InProcessConfig{})
+    if err != nil {
+        t.Fatalf("ServeInProcess failed: %v", err)
+    }
+    defer stop()
+
+    ctx := context.Background()
+    if err := kv.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if value, err := kv.Get(ctx, "k"); err != nil || string(value) != "v" {
+        t.Fatalf("Get() = %q, %v, want v", value, err)
+    }
+    if after := openSockets(); after != before {
+        t.Fatalf("a put and get in process opened sockets: %d before, %d after", before, after)
+    }
+}
+
+func TestServeInProcessWithMTLS(t *testing.T) {
+    serverCert, serverPool := testKeyPair(t, "localhost")
+    clientCert, clientPool := testKeyPair(t, "in-process-host")
+    impl := &peerKV{}
+    kv := NewInProcessKVWithConfig(t, impl, InProcessConfig{
+        ServerTLS: &tls.Config{
+            Certificates: []tls.Certificate{serverCert},
+            ClientAuth:   tls.RequireAndVerifyClientCert,
+            ClientCAs:    clientPool,
+        },
+        ClientTLS: &tls.Config{
+            Certificates: []tls.Certificate{clientCert},
+            RootCAs:      serverPool,
+        },
+    })
+
+    if err := kv.Put(context.Background(), "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if got := strings.Join(impl.names, ","); got != "in-process-host" {
+        t.Fatalf("Put saw client common name %q, want in-process-host", got)
+    }
+
+    // TLS on one end only is refused rather than failing on the first call
+    if _, _, err := ServeInProcess(&mapKV{}, InProcessConfig{ClientTLS: &tls.Config{}}); err == nil {
+        t.Fatal("ServeInProcess with only ClientTLS succeeded, want an error")
+    }
+}
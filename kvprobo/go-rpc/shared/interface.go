@@ -2,27 +2,206 @@
 package shared
 
 import (
+    "context"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
     "github.com/hashicorp/go-plugin"
 )
 
 // Handshake is a common handshake that is shared by plugin and host.
 var Handshake = plugin.HandshakeConfig{
-    ProtocolVersion:  1,
+    ProtocolVersion:  ProtocolVersion,
     MagicCookieKey:   "BASIC_PLUGIN",
     MagicCookieValue: "hello",
 }
 
-// KV is the interface that we're exposing as a plugin.
+// EventOp identifies the kind of change a watch Event reports.
+type EventOp int
+
+const (
+    EventPut EventOp = iota + 1
+    EventDelete
+)
+
+func (op EventOp) String() string {
+    switch op {
+    case EventPut:
+        return "put"
+    case EventDelete:
+        return "delete"
+    default:
+        return "unknown"
+    }
+}
+
+// Event describes a change to a watched key. Value is nil for deletes.
+type Event struct {
+    Op    EventOp
+    Key   string
+    Value []byte
+}
+
+// BatchResult is one key's answer from GRPCClient.GetBatchStream. Found is
+// false, and Value nil, for a key that holds no value. A result with Err
+// set ends the stream early, and carries no key.
+type BatchResult struct {
+    Key   string
+    Value []byte
+    Found bool
+    Err   error
+}
+
+// TxOpKind identifies what a transaction operation does.
+type TxOpKind int
+
+const (
+    TxPut TxOpKind = iota + 1
+    TxDelete
+    TxCompareAndSwap
+)
+
+func (kind TxOpKind) String() string {
+    switch kind {
+    case TxPut:
+        return "put"
+    case TxDelete:
+        return "delete"
+    case TxCompareAndSwap:
+        return "compare-and-swap"
+    default:
+        return "unknown"
+    }
+}
+
+// TxOp is one step of a Transaction.
+type TxOp struct {
+    Kind TxOpKind
+    Key  string
+    // Value is written by TxPut and TxCompareAndSwap.
+    Value []byte
+    // Old is the value TxCompareAndSwap expects key to hold.
+    Old []byte
+    // TTL makes a TxPut expire; zero or less never expires.
+    TTL time.Duration
+}
+
+// KV is the interface that we're exposing as a plugin. Every call takes a
+// context so callers can bound or cancel requests to a slow plugin.
 type KV interface {
-    Put(key string, value []byte) error
-    Get(key string) ([]byte, error)
+    Put(ctx context.Context, key string, value []byte) error
+    Get(ctx context.Context, key string) ([]byte, error)
+    // GetConditional returns the value of key only if it was written after
+    // ifModifiedAfter, along with when it was. Otherwise modified is false
+    // and value is empty. A zero ifModifiedAfter always returns the value,
+    // and so does a plugin that doesn't know when key was written, in which
+    // case modTime is zero.
+    GetConditional(ctx context.Context, key string, ifModifiedAfter time.Time) (value []byte, modified bool, modTime time.Time, err error)
+    // PutWithTTL stores value so that it reads as missing once ttl has
+    // passed. A ttl of zero or less never expires.
+    PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
+    // PutWithContentType stores value labelled with contentType, a media
+    // type such as "application/json"; empty means DefaultContentType. A
+    // ttl of zero or less never expires. Other writes store the value as
+    // DefaultContentType.
+    PutWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error
+    // GetWithContentType returns the value of key with the content type it
+    // was stored with.
+    GetWithContentType(ctx context.Context, key string) (value []byte, contentType string, err error)
+    Delete(ctx context.Context, key string) error
+    List(ctx context.Context, prefix string) ([]string, error)
+    // ListPage returns up to pageSize keys under prefix, in sorted order,
+    // starting after the page pageToken came from; pass "" for the first
+    // page. nextPageToken is "" once there are no more keys. A pageSize of
+    // zero or less lets the plugin choose.
+    ListPage(ctx context.Context, prefix string, pageToken string, pageSize int) (keys []string, nextPageToken string, err error)
+
+    // BatchPut stores every item in one round-trip.
+    BatchPut(ctx context.Context, items map[string][]byte) error
+    // BatchGet returns the values for the keys that exist. Missing keys are
+    // left out of the map rather than reported as an error.
+    BatchGet(ctx context.Context, keys []string) (map[string][]byte, error)
+
+    // CompareAndSwap replaces the value of key with new only if it currently
+    // equals old, reporting whether the swap happened. A missing key never
+    // matches. The new value keeps the key's TTL and content type.
+    CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error)
+
+    // PutIfAbsent stores value only if key holds no value, reporting whether
+    // it did. An existing value is left untouched and isn't an error.
+    PutIfAbsent(ctx context.Context, key string, value []byte) (created bool, err error)
+
+    // Rename moves the value of oldKey, with its TTL and content type, to
+    // newKey in one step, failing with ErrKeyNotFound if oldKey holds no
+    // value. A value already under newKey makes it fail with ErrKeyExists
+    // unless overwrite is set. The move counts as a write to newKey, whose
+    // version ends up above both keys' previous versions.
+    Rename(ctx context.Context, oldKey, newKey string, overwrite bool) error
+
+    // Exists reports whether key holds a value, which may be empty.
+    Exists(ctx context.Context, key string) (bool, error)
+
+    // Watch streams changes to keys starting with prefix. The channel is
+    // closed once ctx is done or the watch ends.
+    Watch(ctx context.Context, prefix string) (<-chan Event, error)
+
+    // GetVersioned returns the value of key with its version, which starts
+    // at 1 and goes up by one on every write. A deleted or expired key
+    // starts again at 1.
+    GetVersioned(ctx context.Context, key string) ([]byte, uint64, error)
+    // PutIfVersion writes value only if key is still at expectedVersion,
+    // returning ErrVersionConflict otherwise. An expectedVersion of 0 means
+    // the key must not exist. The new value keeps the key's TTL and content
+    // type.
+    PutIfVersion(ctx context.Context, key string, value []byte, expectedVersion uint64) error
+
+    // Increment atomically adds delta to the base-10 integer stored at key
+    // and returns the new total. A missing key counts as 0; a value that is
+    // not an integer fails with ErrNotANumber, and a total that doesn't fit
+    // in an int64 with ErrOutOfRange. Use a negative delta to decrement.
+    // The new total keeps the key's TTL and content type.
+    Increment(ctx context.Context, key string, delta int64) (int64, error)
+
+    // Transaction applies ops in order as a unit: either every op takes
+    // effect or none does. Each op sees the effects of the ones before it.
+    // A failing op is reported as a *TxError carrying its index.
+    Transaction(ctx context.Context, ops []TxOp) error
+
+    // Scan returns every key under prefix with its value in one call. The
+    // plugin may cap how many it returns, in which case truncated is true
+    // and the keys returned are the first ones in sorted order.
+    Scan(ctx context.Context, prefix string) (values map[string][]byte, truncated bool, err error)
+
+    // Stats reports how many keys are stored and the total size of their
+    // values in bytes. Expired keys are not counted.
+    Stats(ctx context.Context) (keyCount int64, totalBytes int64, err error)
 }
 
 // kvImpl provides a default no-op implementation
 type kvImpl struct{}
 
-func (*kvImpl) Put(key string, value []byte) error { return nil }
-func (*kvImpl) Get(key string) ([]byte, error)     { return nil, nil }
+func (*kvImpl) Put(ctx context.Context, key string, value []byte) error                                                       { return nil }
+func (*kvImpl) Get(ctx context.Context, key string) ([]byte, error)                                                           { return nil, nil }
+func (*kvImpl) GetConditional(ctx context.Context, key string, ifModifiedAfter time.Time) ([]byte, bool, time.Time, error)    { return nil, false, time.Time{}, nil }
+func (*kvImpl) PutWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error                             { return nil }
+func (*kvImpl) PutWithContentType(ctx context.Context, key string, value []byte, contentType string, ttl time.Duration) error { return nil }
+func (*kvImpl) GetWithContentType(ctx context.Context, key string) ([]byte, string, error)                                    { return nil, "", nil }
+func (*kvImpl) Delete(ctx context.Context, key string) error                                                                  { return nil }
+func (*kvImpl) List(ctx context.Context, prefix string) ([]string, error)                                                     { return nil, nil }
+func (*kvImpl) ListPage(ctx context.Context, prefix, pageToken string, pageSize int) ([]string, string, error)                { return nil, "", nil }
+func (*kvImpl) BatchPut(ctx context.Context, items map[string][]byte) error                                                   { return nil }
+func (*kvImpl) BatchGet(ctx context.Context, keys []string) (map[string][]byte, error)                                        { return nil, nil }
+func (*kvImpl) CompareAndSwap(ctx context.Context, key string, old, new []byte) (bool, error)                                 { return false, nil }
+func (*kvImpl) PutIfAbsent(ctx context.Context, key string, value []byte) (bool, error)                                       { return false, nil }
+func (*kvImpl) Rename(ctx context.Context, oldKey, newKey string, overwrite bool) error                                       { return nil }
+func (*kvImpl) Exists(ctx context.Context, key string) (bool, error)                                                          { return false, nil }
+func (*kvImpl) Watch(ctx context.Context, prefix string) (<-chan Event, error)                                                { return nil, nil }
+func (*kvImpl) GetVersioned(ctx context.Context, key string) ([]byte, uint64, error)                                          { return nil, 0, nil }
+func (*kvImpl) PutIfVersion(ctx context.Context, key string, value []byte, expectedVersion uint64) error                      { return nil }
+func (*kvImpl) Increment(ctx context.Context, key string, delta int64) (int64, error)                                         { return 0, nil }
+func (*kvImpl) Transaction(ctx context.Context, ops []TxOp) error                                                             { return nil }
+func (*kvImpl) Scan(ctx context.Context, prefix string) (map[string][]byte, bool, error)                                      { return nil, false, nil }
+func (*kvImpl) Stats(ctx context.Context) (int64, int64, error)                                                               { return 0, 0, nil }
 
 // KVPlugin is the implementation of plugin.GRPCPlugin so we can serve/consume this.
 type KVGRPCPlugin struct {
@@ -31,6 +210,13 @@ type KVGRPCPlugin struct {
     // Concrete implementation, written in Go. This is only used for plugins
     // that are written in Go.
     Impl KV
+    // MaxValueBytes makes the server reject larger Put values before they
+    // reach Impl. Zero allows any size. Size the server's receive limit with
+    // MaxRecvMsgSize, or gRPC refuses values over 4MB first.
+    MaxValueBytes int
+    // Logger receives the RPC logs of both sides, so they follow the level
+    // and output the host or plugin configured. Nil logs at Debug to stderr.
+    Logger hclog.Logger
 }
 
 // Add this method
@@ -0,0 +1,67 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/keepalive.go
+
+package shared
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/keepalive"
+)
+
+// DefaultKeepaliveInterval is how long a connection may sit idle before it
+// is pinged when PLUGIN_KV_KEEPALIVE_INTERVAL is unset.
+const DefaultKeepaliveInterval = 30 * time.Second
+
+// KeepaliveIntervalFromEnv reads PLUGIN_KV_KEEPALIVE_INTERVAL as a Go
+// duration. Zero disables keepalive pings.
+func KeepaliveIntervalFromEnv() (time.Duration, error) {
+    value := os.Getenv("PLUGIN_KV_KEEPALIVE_INTERVAL")
+    if value == "" {
+        return DefaultKeepaliveInterval, nil
+    }
+    interval, err := time.ParseDuration(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_KEEPALIVE_INTERVAL %q: %w", value, err)
+    }
+    if interval < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_KEEPALIVE_INTERVAL must not be negative, got %s", interval)
+    }
+    return interval, nil
+}
+
+// KeepaliveServerOptions pings idle clients every interval and drops any
+// that don't answer within another interval. The enforcement policy lets
+// clients using the same interval ping freely, even with no RPC in flight.
+func KeepaliveServerOptions(interval time.Duration) []grpc.ServerOption {
+    if interval <= 0 {
+        return nil
+    }
+    return []grpc.ServerOption{
+        grpc.KeepaliveParams(keepalive.ServerParameters{
+            Time:    interval,
+            Timeout: interval,
+        }),
+        grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+            MinTime:             interval / 2,
+            PermitWithoutStream: true,
+        }),
+    }
+}
+
+// KeepaliveDialOptions is the client side of KeepaliveServerOptions. gRPC
+// raises client ping intervals below 10s to 10s.
+func KeepaliveDialOptions(interval time.Duration) []grpc.DialOption {
+    if interval <= 0 {
+        return nil
+    }
+    return []grpc.DialOption{
+        grpc.WithKeepaliveParams(keepalive.ClientParameters{
+            Time:                interval,
+            Timeout:             interval,
+            PermitWithoutStream: true,
+        }),
+    }
+}
@@ -0,0 +1,130 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/keepalive_test.go
+
+package shared
+
+import (
+    "context"
+    "net"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/test/bufconn"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// pingCounter wraps the client end of a connection and counts the HTTP/2
+// PING frames (excluding acks) that the server sends on it.
+type pingCounter struct {
+    net.Conn
+
+    mu    sync.Mutex
+    buf   []byte
+    pings int
+}
+
+func (c *pingCounter) Read(p []byte) (int, error) {
+    n, err := c.Conn.Read(p)
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.buf = append(c.buf, p[:n]...)
+    for len(c.buf) >= 9 {
+        length := int(c.buf[0])<<16 | int(c.buf[1])<<8 | int(c.buf[2])
+        if len(c.buf) < 9+length {
+            break
+        }
+        const framePing, flagAck = 0x6, 0x1
+        if c.buf[3] == framePing && c.buf[4]&flagAck == 0 {
+            c.pings++
+        }
+        c.buf = c.buf[9+length:]
+    }
+    return n, err
+}
+
+func (c *pingCounter) count() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.pings
+}
+
+func TestKeepaliveIntervalFromEnv(t *testing.T) {
+    tests := []struct {
+        value   string
+        want    time.Duration
+        wantErr bool
+    }{
+        {"", DefaultKeepaliveInterval, false},
+        {"15s", 15 * time.Second, false},
+        {"0", 0, false},
+        {"soon", 0, true},
+        {"-1s", 0, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.value, func(t *testing.T) {
+            t.Setenv("PLUGIN_KV_KEEPALIVE_INTERVAL", tt.value)
+            got, err := KeepaliveIntervalFromEnv()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("KeepaliveIntervalFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Fatalf("KeepaliveIntervalFromEnv() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+    if opts := KeepaliveServerOptions(0); opts != nil {
+        t.Fatalf("KeepaliveServerOptions(0) = %v, want none", opts)
+    }
+}
+
+func TestKeepalivePingsIdleConnection(t *testing.T) {
+    // gRPC won't ping more often than once a second
+    const interval = time.Second
+
+    listener := bufconn.Listen(1 << 20)
+    // A fixed window turns off the BDP pings gRPC otherwise sends
+    serverOpts := append(KeepaliveServerOptions(interval),
+        grpc.InitialWindowSize(1<<20),
+        grpc.InitialConnWindowSize(1<<20))
+    server := grpc.NewServer(serverOpts...)
+    proto.RegisterKVServer(server, &GRPCServer{Impl: &valueKV{value: []byte("v")}, logger: hclog.NewNullLogger()})
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    var counter *pingCounter
+    conn, err := grpc.NewClient("passthrough:///bufconn",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            c, err := listener.DialContext(ctx)
+            if err != nil {
+                return nil, err
+            }
+            counter = &pingCounter{Conn: c}
+            return counter, nil
+        }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    client := &GRPCClient{client: proto.NewKVClient(conn), logger: hclog.NewNullLogger()}
+    if _, err := client.Get(context.Background(), "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    idleSince := time.Now()
+
+    deadline := idleSince.Add(3 * interval)
+    for counter.count() == 0 {
+        if time.Now().After(deadline) {
+            t.Fatalf("no keepalive ping within %v of the connection going idle", 3*interval)
+        }
+        time.Sleep(50 * time.Millisecond)
+    }
+    t.Logf("first keepalive ping after %v idle", time.Since(idleSince).Round(time.Millisecond))
+}
@@ -0,0 +1,59 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/limits.go
+
+package shared
+
+import (
+    "fmt"
+    "math"
+    "os"
+    "strconv"
+)
+
+// DefaultMaxValueBytes is the largest value accepted when
+// PLUGIN_KV_MAX_VALUE_BYTES is unset.
+const DefaultMaxValueBytes = 16 << 20
+
+// messageOverheadBytes is room in a request message for the key and other
+// fields that travel alongside a value.
+const messageOverheadBytes = 64 << 10
+
+// grpcDefaultMaxRecvMsgSize is the receive limit gRPC applies when a server
+// sets none.
+const grpcDefaultMaxRecvMsgSize = 4 << 20
+
+// MaxRecvMsgSize returns the receive limit, for grpc.MaxRecvMsgSize, a server
+// needs so a Put of a value around maxValueBytes reaches CheckValueSize,
+// and is refused with ErrValueTooLarge, instead of failing in the transport
+// with ResourceExhausted. It never goes below gRPC's 4MB default, and a
+// maxValueBytes of zero lifts the transport's limit too.
+func MaxRecvMsgSize(maxValueBytes int) int {
+    if maxValueBytes <= 0 {
+        return math.MaxInt32
+    }
+    return max(maxValueBytes+messageOverheadBytes, grpcDefaultMaxRecvMsgSize)
+}
+
+// MaxValueBytesFromEnv reads PLUGIN_KV_MAX_VALUE_BYTES. Zero turns the limit off.
+func MaxValueBytesFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_MAX_VALUE_BYTES")
+    if value == "" {
+        return DefaultMaxValueBytes, nil
+    }
+    limit, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_MAX_VALUE_BYTES %q: %w", value, err)
+    }
+    if limit < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_MAX_VALUE_BYTES must not be negative, got %d", limit)
+    }
+    return limit, nil
+}
+
+// CheckValueSize returns ErrValueTooLarge if value for key exceeds limit.
+// A limit of zero or less allows any size.
+func CheckValueSize(key string, value []byte, limit int) error {
+    if limit > 0 && len(value) > limit {
+        return fmt.Errorf("%w: %q is %d bytes, limit is %d", ErrValueTooLarge, key, len(value), limit)
+    }
+    return nil
+}
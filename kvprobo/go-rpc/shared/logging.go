@@ -0,0 +1,55 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/logging.go
+
+package shared
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// DefaultLogLevel is how much the client and server log when
+// PLUGIN_LOG_LEVEL is unset.
+const DefaultLogLevel = hclog.Trace
+
+// LogOptionsFromEnv reads PLUGIN_LOG_LEVEL, one of trace, debug, info, warn,
+// error or off, and PLUGIN_LOG_JSON, which switches to one JSON object per
+// line for log aggregators. The plugin inherits the host's environment, and
+// go-plugin re-logs JSON lines from the plugin at their own level, so the
+// host controls both sides.
+func LogOptionsFromEnv(defaultLevel hclog.Level) (hclog.Level, bool, error) {
+    level := defaultLevel
+    if value := os.Getenv("PLUGIN_LOG_LEVEL"); value != "" {
+        level = hclog.LevelFromString(value)
+        if level == hclog.NoLevel {
+            return defaultLevel, false, fmt.Errorf("invalid PLUGIN_LOG_LEVEL %q: want trace, debug, info, warn, error or off", value)
+        }
+    }
+
+    jsonFormat := false
+    if value := os.Getenv("PLUGIN_LOG_JSON"); value != "" {
+        enabled, err := strconv.ParseBool(value)
+        if err != nil {
+            return level, false, fmt.Errorf("invalid PLUGIN_LOG_JSON %q: %w", value, err)
+        }
+        jsonFormat = enabled
+    }
+    return level, jsonFormat, nil
+}
+
+// NewLoggerFromEnv returns a logger named name writing to output, configured
+// by LogOptionsFromEnv with defaultLevel. When the environment is invalid
+// the logger uses the defaults and the error says why, so callers can still
+// log it.
+func NewLoggerFromEnv(name string, output io.Writer, defaultLevel hclog.Level) (hclog.Logger, error) {
+    level, jsonFormat, err := LogOptionsFromEnv(defaultLevel)
+    return hclog.New(&hclog.LoggerOptions{
+        Name:       name,
+        Level:      level,
+        Output:     output,
+        JSONFormat: jsonFormat,
+    }), err
+}
@@ -0,0 +1,84 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/logging_test.go
+
+package shared
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+func TestNewLoggerFromEnvJSON(t *testing.T) {
+    t.Setenv("PLUGIN_LOG_JSON", "true")
+    t.Setenv("PLUGIN_LOG_LEVEL", "")
+
+    var out bytes.Buffer
+    logger, err := NewLoggerFromEnv("test", &out, hclog.Trace)
+    if err != nil {
+        t.Fatalf("NewLoggerFromEnv failed: %v", err)
+    }
+    logger.Info("📡 first line", "key", "greeting")
+    logger.Named("rpc").Debug("📡⏱️ handled RPC", "code", "OK")
+
+    lines := 0
+    scanner := bufio.NewScanner(&out)
+    for scanner.Scan() {
+        lines++
+        var entry map[string]interface{}
+        if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+            t.Fatalf("log line %q is not a JSON object: %v", scanner.Text(), err)
+        }
+        if _, ok := entry["@message"]; !ok {
+            t.Fatalf("log line %q has no @message", scanner.Text())
+        }
+    }
+    if lines != 2 {
+        t.Fatalf("logged %d lines, want 2", lines)
+    }
+}
+
+func TestLogOptionsFromEnv(t *testing.T) {
+    for _, tc := range []struct {
+        level, json string
+        wantLevel   hclog.Level
+        wantJSON    bool
+        wantErr     bool
+    }{
+        {"", "", hclog.Trace, false, false},
+        {"INFO", "", hclog.Info, false, false},
+        {"warn", "1", hclog.Warn, true, false},
+        {"loud", "", hclog.Trace, false, true},
+        {"", "sometimes", hclog.Trace, false, true},
+    } {
+        t.Setenv("PLUGIN_LOG_LEVEL", tc.level)
+        t.Setenv("PLUGIN_LOG_JSON", tc.json)
+        level, jsonFormat, err := LogOptionsFromEnv(hclog.Trace)
+        if (err != nil) != tc.wantErr {
+            t.Fatalf("LogOptionsFromEnv() with level %q, json %q: err = %v, want error %t", tc.level, tc.json, err, tc.wantErr)
+        }
+        if err == nil && (level != tc.wantLevel || jsonFormat != tc.wantJSON) {
+            t.Fatalf("LogOptionsFromEnv() with level %q, json %q = %s, %t; want %s, %t",
+                tc.level, tc.json, level, jsonFormat, tc.wantLevel, tc.wantJSON)
+        }
+    }
+}
+
+func TestNewLoggerFromEnvLevel(t *testing.T) {
+    t.Setenv("PLUGIN_LOG_JSON", "")
+    t.Setenv("PLUGIN_LOG_LEVEL", "warn")
+
+    var out bytes.Buffer
+    logger, err := NewLoggerFromEnv("test", &out, hclog.Trace)
+    if err != nil {
+        t.Fatalf("NewLoggerFromEnv failed: %v", err)
+    }
+    logger.Info("hidden")
+    logger.Warn("shown")
+    if got := out.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+        t.Fatalf("PLUGIN_LOG_LEVEL=warn logged %q", got)
+    }
+}
@@ -0,0 +1,149 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/mtls.go
+
+package shared
+
+import (
+    "crypto"
+    "crypto/tls"
+    "crypto/x509"
+    "fmt"
+    "net"
+    "os"
+    "strconv"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// Manual mTLS, used when PLUGIN_AUTO_MTLS=false, reads PEM certificates and
+// keys from the environment. The plugin inherits the host's environment, so
+// one set of variables configures both sides:
+//
+//   PLUGIN_CLIENT_CERT, PLUGIN_CLIENT_KEY  the host's certificate and key
+//   PLUGIN_SERVER_CERT, PLUGIN_SERVER_KEY  the plugin's certificate and key
+//   PLUGIN_CA_CERT                         optional CA that signed both
+//   PLUGIN_SERVER_CERT_PIN                 optional SHA-256 fingerprint the
+//                                          plugin's certificate must have
+//
+// Each side verifies its peer against PLUGIN_CA_CERT when it is set, and
+// otherwise trusts the peer's certificate itself, as AutoMTLS does.
+
+// manualTLSServerName is the name the host expects in the plugin's
+// certificate. go-plugin dials an address rather than a host name, and
+// AutoMTLS certificates are issued for localhost.
+const manualTLSServerName = "localhost"
+
+// SetUnixSocketServerName makes config verify a plugin listening on the
+// Unix socket addr against localhost, which certificates generated here and
+// by AutoMTLS carry as a SAN. A socket's address is a path that can't match
+// a certificate, and skipping verification instead would accept any
+// certificate at all. A ServerName already set is kept. It reports whether
+// config changed.
+func SetUnixSocketServerName(config *tls.Config, addr net.Addr) bool {
+    if config == nil || addr == nil || addr.Network() != "unix" || config.ServerName != "" {
+        return false
+    }
+    config.ServerName = manualTLSServerName
+    return true
+}
+
+// AutoMTLSFromEnv reads PLUGIN_AUTO_MTLS. AutoMTLS stays on unless it is
+// set to a false value; an unparsable value is logged and also leaves it
+// on, so a typo never turns TLS off.
+func AutoMTLSFromEnv(logger hclog.Logger) bool {
+    value := os.Getenv("PLUGIN_AUTO_MTLS")
+    if value == "" {
+        return true
+    }
+    enabled, err := strconv.ParseBool(value)
+    if err != nil {
+        logger.Warn("🔐⚠️ invalid PLUGIN_AUTO_MTLS value, defaulting to enabled",
+            "value", value,
+            "error", err)
+        return true
+    }
+    return enabled
+}
+
+// PeerCertPool returns the certificates a peer is verified against:
+// PLUGIN_CA_CERT if set, otherwise the PEM certificate in peerEnv.
+func PeerCertPool(peerEnv string) (*x509.CertPool, error) {
+    source := "PLUGIN_CA_CERT"
+    certPEM := os.Getenv(source)
+    if certPEM == "" {
+        source = peerEnv
+        certPEM = os.Getenv(source)
+    }
+    if certPEM == "" {
+        return nil, fmt.Errorf("PLUGIN_CA_CERT or %s must be set to verify the peer", peerEnv)
+    }
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM([]byte(certPEM)) {
+        return nil, fmt.Errorf("%s contains no PEM certificates", source)
+    }
+    return pool, nil
+}
+
+// CertificateFromEnv parses the PEM certificate and private key in certEnv
+// and keyEnv, failing if either is missing or they don't belong together.
+func CertificateFromEnv(certEnv, keyEnv string, logger hclog.Logger) (*x509.Certificate, crypto.Signer, error) {
+    certPEM, keyPEM := os.Getenv(certEnv), os.Getenv(keyEnv)
+    if certPEM == "" || keyPEM == "" {
+        return nil, nil, fmt.Errorf("%s and %s must both be set for manual mTLS", certEnv, keyEnv)
+    }
+    if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+        return nil, nil, fmt.Errorf("%s and %s do not match: %w", certEnv, keyEnv, err)
+    }
+    cert, err := ParseCertificate([]byte(certPEM), logger)
+    if err != nil {
+        return nil, nil, fmt.Errorf("parsing %s: %w", certEnv, err)
+    }
+    key, err := ParsePrivateKey([]byte(keyPEM), logger)
+    if err != nil {
+        return nil, nil, fmt.Errorf("parsing %s: %w", keyEnv, err)
+    }
+    return cert, key, nil
+}
+
+// ClientTLSConfigFromEnv builds the host side of manual mTLS under policy:
+// it presents PLUGIN_CLIENT_CERT and verifies the plugin against
+// PLUGIN_CA_CERT or PLUGIN_SERVER_CERT, and against PLUGIN_SERVER_CERT_PIN
+// when it is set.
+func ClientTLSConfigFromEnv(policy TLSPolicy, logger hclog.Logger) (*tls.Config, error) {
+    if logger == nil {
+        logger = hclog.NewNullLogger()
+    }
+    cert, key, err := CertificateFromEnv("PLUGIN_CLIENT_CERT", "PLUGIN_CLIENT_KEY", logger)
+    if err != nil {
+        return nil, err
+    }
+    pool, err := PeerCertPool("PLUGIN_SERVER_CERT")
+    if err != nil {
+        return nil, err
+    }
+    pin, err := CertPinFromEnv()
+    if err != nil {
+        return nil, err
+    }
+    config := CreateTLSConfigWithPolicy(cert, key, pool, false, policy, logger)
+    config.ServerName = manualTLSServerName
+    if pin != "" {
+        logger.Debug("🔐📌 pinning the plugin's certificate", "fingerprint", pin)
+        PinPeerCertificate(config, pin)
+    }
+    return config, nil
+}
+
+// ServerTLSConfigFromEnv is the plugin side of ClientTLSConfigFromEnv: it
+// presents PLUGIN_SERVER_CERT and requires a client certificate that
+// verifies against PLUGIN_CA_CERT or PLUGIN_CLIENT_CERT.
+func ServerTLSConfigFromEnv(policy TLSPolicy, logger hclog.Logger) (*tls.Config, error) {
+    cert, key, err := CertificateFromEnv("PLUGIN_SERVER_CERT", "PLUGIN_SERVER_KEY", logger)
+    if err != nil {
+        return nil, err
+    }
+    pool, err := PeerCertPool("PLUGIN_CLIENT_CERT")
+    if err != nil {
+        return nil, err
+    }
+    return CreateTLSConfigWithPolicy(cert, key, pool, true, policy, logger), nil
+}
@@ -0,0 +1,173 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/mtls_test.go
+
+package shared
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "net"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+func TestAutoMTLSFromEnv(t *testing.T) {
+    for value, want := range map[string]bool{
+        "":      true,
+        "true":  true,
+        "false": false,
+        "0":     false,
+        "nope":  true,
+    } {
+        t.Setenv("PLUGIN_AUTO_MTLS", value)
+        if got := AutoMTLSFromEnv(hclog.NewNullLogger()); got != want {
+            t.Fatalf("AutoMTLSFromEnv() with PLUGIN_AUTO_MTLS=%q = %t, want %t", value, got, want)
+        }
+    }
+}
+
+func TestCertificateFromEnv(t *testing.T) {
+    certPEM, keyPEM, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    _, otherKeyPEM, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+
+    t.Setenv("PLUGIN_SERVER_CERT", string(certPEM))
+    t.Setenv("PLUGIN_SERVER_KEY", string(keyPEM))
+    if _, _, err := CertificateFromEnv("PLUGIN_SERVER_CERT", "PLUGIN_SERVER_KEY", nil); err != nil {
+        t.Fatalf("CertificateFromEnv() with a matching pair = %v", err)
+    }
+
+    t.Setenv("PLUGIN_SERVER_KEY", string(otherKeyPEM))
+    if _, _, err := CertificateFromEnv("PLUGIN_SERVER_CERT", "PLUGIN_SERVER_KEY", nil); err == nil {
+        t.Fatalf("CertificateFromEnv() accepted a key from another certificate")
+    }
+
+    t.Setenv("PLUGIN_SERVER_KEY", "")
+    if _, _, err := CertificateFromEnv("PLUGIN_SERVER_CERT", "PLUGIN_SERVER_KEY", nil); err == nil {
+        t.Fatalf("CertificateFromEnv() accepted a missing key")
+    }
+}
+
+func TestPeerCertPool(t *testing.T) {
+    t.Setenv("PLUGIN_CA_CERT", "")
+    t.Setenv("PLUGIN_CLIENT_CERT", "")
+    if _, err := PeerCertPool("PLUGIN_CLIENT_CERT"); err == nil {
+        t.Fatalf("PeerCertPool() with nothing set succeeded")
+    }
+
+    t.Setenv("PLUGIN_CLIENT_CERT", "not a certificate")
+    if _, err := PeerCertPool("PLUGIN_CLIENT_CERT"); err == nil {
+        t.Fatalf("PeerCertPool() accepted a non-PEM certificate")
+    }
+
+    certPEM, _, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    t.Setenv("PLUGIN_CA_CERT", string(certPEM))
+    if _, err := PeerCertPool("PLUGIN_CLIENT_CERT"); err != nil {
+        t.Fatalf("PeerCertPool() preferring PLUGIN_CA_CERT = %v", err)
+    }
+}
+
+func TestCertPinFromEnv(t *testing.T) {
+    certPEM, _, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    cert, err := ParseCertificate(certPEM, nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    fingerprint := CertificateFingerprint(cert)
+
+    for value, want := range map[string]string{
+        "":                                       "",
+        fingerprint:                              fingerprint,
+        strings.ToUpper(fingerprint):             fingerprint,
+        strings.ReplaceAll(fingerprint, ":", ""): fingerprint,
+    } {
+        t.Setenv("PLUGIN_SERVER_CERT_PIN", value)
+        if got, err := CertPinFromEnv(); err != nil || got != want {
+            t.Fatalf("CertPinFromEnv() with %q = %q, %v; want %q", value, got, err, want)
+        }
+    }
+    for _, value := range []string{"ab:cd", "not hex", fingerprint + ":00"} {
+        t.Setenv("PLUGIN_SERVER_CERT_PIN", value)
+        if _, err := CertPinFromEnv(); err == nil {
+            t.Fatalf("CertPinFromEnv() accepted %q", value)
+        }
+    }
+}
+
+func TestSetUnixSocketServerName(t *testing.T) {
+    certPEM, keyPEM, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    cert, err := tls.X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        t.Fatalf("X509KeyPair failed: %v", err)
+    }
+    pool := x509.NewCertPool()
+    pool.AppendCertsFromPEM(certPEM)
+
+    // Keep the path short enough for a socket address
+    dir, err := os.MkdirTemp("", "kv")
+    if err != nil {
+        t.Fatalf("MkdirTemp failed: %v", err)
+    }
+    t.Cleanup(func() { os.RemoveAll(dir) })
+    ln, err := tls.Listen("unix", filepath.Join(dir, "plugin.sock"), &tls.Config{Certificates: []tls.Certificate{cert}})
+    if err != nil {
+        t.Fatalf("Listen failed: %v", err)
+    }
+    t.Cleanup(func() { ln.Close() })
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            conn.(*tls.Conn).Handshake()
+            conn.Close()
+        }
+    }()
+
+    // Without a server name the socket path is checked against the SANs
+    config := &tls.Config{RootCAs: pool}
+    if conn, err := tls.Dial("unix", ln.Addr().String(), config); err == nil {
+        conn.Close()
+        t.Fatalf("handshake verified the socket path against the certificate")
+    }
+
+    if !SetUnixSocketServerName(config, ln.Addr()) || config.ServerName != "localhost" {
+        t.Fatalf("SetUnixSocketServerName left ServerName = %q", config.ServerName)
+    }
+    conn, err := tls.Dial("unix", ln.Addr().String(), config)
+    if err != nil {
+        t.Fatalf("verified handshake over the Unix socket failed: %v", err)
+    }
+    conn.Close()
+
+    // Verification is still on: an unknown CA fails
+    config = &tls.Config{RootCAs: x509.NewCertPool()}
+    SetUnixSocketServerName(config, ln.Addr())
+    if conn, err := tls.Dial("unix", ln.Addr().String(), config); err == nil {
+        conn.Close()
+        t.Fatalf("handshake accepted a certificate from an untrusted CA")
+    }
+
+    tcpAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+    if SetUnixSocketServerName(&tls.Config{}, tcpAddr) {
+        t.Fatalf("SetUnixSocketServerName changed the config for a TCP address")
+    }
+}
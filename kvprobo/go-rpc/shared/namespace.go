@@ -0,0 +1,36 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/namespace.go
+
+package shared
+
+import (
+    "context"
+
+    "google.golang.org/grpc/metadata"
+)
+
+// NamespaceMetadataKey is the gRPC metadata header that selects which
+// namespace a request's keys live in.
+const NamespaceMetadataKey = "kv-namespace"
+
+// DefaultNamespace is used by requests that don't name a namespace.
+const DefaultNamespace = "default"
+
+// WithNamespace returns a context whose RPCs read and write keys in
+// namespace, isolated from the same keys in every other namespace.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+    return metadata.AppendToOutgoingContext(ctx, NamespaceMetadataKey, namespace)
+}
+
+// NamespaceFromContext returns the namespace a server-side request asked
+// for, or DefaultNamespace when it didn't send the header.
+func NamespaceFromContext(ctx context.Context) string {
+    md, ok := metadata.FromIncomingContext(ctx)
+    if !ok {
+        return DefaultNamespace
+    }
+    values := md.Get(NamespaceMetadataKey)
+    if len(values) == 0 || values[0] == "" {
+        return DefaultNamespace
+    }
+    return values[0]
+}
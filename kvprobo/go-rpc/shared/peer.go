@@ -0,0 +1,47 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/peer.go
+
+package shared
+
+import (
+    "context"
+    "crypto/x509"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/peer"
+)
+
+// PeerCertificateFromContext returns the certificate the client of the RPC
+// handled under ctx presented and the TLS handshake verified, or nil when
+// the connection isn't TLS or the client's certificate wasn't verified.
+// Handlers and Impl methods can use it to decide what the client may do.
+func PeerCertificateFromContext(ctx context.Context) *x509.Certificate {
+    p, ok := peer.FromContext(ctx)
+    if !ok {
+        return nil
+    }
+    tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+    if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+        return nil
+    }
+    return tlsInfo.State.VerifiedChains[0][0]
+}
+
+// PeerSubject returns the subject of the client's verified certificate,
+// such as "CN=host,O=HashiCorp", or "" when there is none.
+func PeerSubject(ctx context.Context) string {
+    if cert := PeerCertificateFromContext(ctx); cert != nil {
+        return cert.Subject.String()
+    }
+    return ""
+}
+
+// ServerRequestLogger is RequestLogger for a server, also tagging every
+// line with the subject of the client's certificate.
+func ServerRequestLogger(ctx context.Context, logger hclog.Logger) hclog.Logger {
+    logger = RequestLogger(ctx, logger)
+    if subject := PeerSubject(ctx); subject != "" {
+        return logger.With("peer", subject)
+    }
+    return logger
+}
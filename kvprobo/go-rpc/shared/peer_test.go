@@ -0,0 +1,137 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/peer_test.go
+
+package shared
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "net"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/test/bufconn"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// peerKV records the common name of the client certificate each Put and
+// Get was made with.
+type peerKV struct {
+    kvImpl
+    mu    sync.Mutex
+    names []string
+}
+
+func (p *peerKV) record(ctx context.Context) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    name := "<none>"
+    if cert := PeerCertificateFromContext(ctx); cert != nil {
+        name = cert.Subject.CommonName
+    }
+    p.names = append(p.names, name)
+}
+
+func (p *peerKV) Put(ctx context.Context, key string, value []byte) error {
+    p.record(ctx)
+    return nil
+}
+
+func (p *peerKV) Get(ctx context.Context, key string) ([]byte, error) {
+    p.record(ctx)
+    return []byte("v"), nil
+}
+
+func (p *peerKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := p.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+// testKeyPair generates a self-signed certificate for commonName and
+// returns it with a pool trusting it.
+func testKeyPair(t *testing.T, commonName string) (tls.Certificate, *x509.CertPool) {
+    t.Helper()
+    cfg := DefaultCertificateConfig()
+    cfg.CommonName = commonName
+    certPEM, keyPEM, err := GenerateCert(cfg, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    cert, err := tls.X509KeyPair(certPEM, keyPEM)
+    if err != nil {
+        t.Fatalf("X509KeyPair failed: %v", err)
+    }
+    pool := x509.NewCertPool()
+    pool.AppendCertsFromPEM(certPEM)
+    return cert, pool
+}
+
+func TestHandlersSeePeerCertificate(t *testing.T) {
+    serverCert, serverPool := testKeyPair(t, "localhost")
+    clientCert, clientPool := testKeyPair(t, "reporting-job")
+
+    var logs bytes.Buffer
+    logger := hclog.New(&hclog.LoggerOptions{Output: &logs, Level: hclog.Debug})
+    impl := &peerKV{}
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(
+        grpc.Creds(credentials.NewTLS(&tls.Config{
+            Certificates: []tls.Certificate{serverCert},
+            ClientAuth:   tls.RequireAndVerifyClientCert,
+            ClientCAs:    clientPool,
+        })),
+        grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(logger)))
+    proto.RegisterKVServer(server, &GRPCServer{Impl: impl, logger: logger})
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufconn",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+            Certificates: []tls.Certificate{clientCert},
+            RootCAs:      serverPool,
+            ServerName:   "localhost",
+        })))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    client := NewGRPCClient(conn, nil)
+    defer client.Close()
+
+    ctx := context.Background()
+    if err := client.Put(ctx, "k", []byte("v")); err != nil {
+        t.Fatalf("Put failed: %v", err)
+    }
+    if _, err := client.Get(ctx, "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    if got := strings.Join(impl.names, ","); got != "reporting-job,reporting-job" {
+        t.Fatalf("Put and Get saw client common names %q, want reporting-job for both", got)
+    }
+
+    for _, want := range []string{
+        "📡📤 handling Put request: peer=\"CN=reporting-job,O=HashiCorp\"",
+        "📡⏱️ handled RPC: peer=\"CN=reporting-job,O=HashiCorp\"",
+    } {
+        if !strings.Contains(logs.String(), want) {
+            t.Fatalf("server log is missing %q:\n%s", want, logs.String())
+        }
+    }
+}
+
+func TestPeerCertificateWithoutTLS(t *testing.T) {
+    if cert := PeerCertificateFromContext(context.Background()); cert != nil {
+        t.Fatalf("PeerCertificateFromContext() without a peer = %v, want nil", cert)
+    }
+    if subject := PeerSubject(context.Background()); subject != "" {
+        t.Fatalf("PeerSubject() without a peer = %q, want empty", subject)
+    }
+}
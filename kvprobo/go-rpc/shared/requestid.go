@@ -0,0 +1,54 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/requestid.go
+
+package shared
+
+import (
+    "context"
+
+    "github.com/google/uuid"
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc/metadata"
+)
+
+// RequestIDMetadataKey is the gRPC metadata header carrying the ID the
+// client gave a command, so its log lines and the plugin's can be matched.
+const RequestIDMetadataKey = "kv-request-id"
+
+// requestIDKey holds a request ID in a context on the client side.
+type requestIDKey struct{}
+
+// NewRequestID returns a random ID for one command.
+func NewRequestID() string {
+    return uuid.NewString()
+}
+
+// WithRequestID returns a context whose RPCs send id in the kv-request-id
+// header and whose log lines, through RequestLogger, carry it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+    ctx = context.WithValue(ctx, requestIDKey{}, id)
+    return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+}
+
+// RequestIDFromContext returns the request ID set by WithRequestID on the
+// client, or received in the kv-request-id header on the server. It is ""
+// when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+    if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+        return id
+    }
+    if md, ok := metadata.FromIncomingContext(ctx); ok {
+        if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+            return values[0]
+        }
+    }
+    return ""
+}
+
+// RequestLogger returns logger with ctx's request ID attached to every
+// line, or logger itself when ctx has none.
+func RequestLogger(ctx context.Context, logger hclog.Logger) hclog.Logger {
+    if id := RequestIDFromContext(ctx); id != "" {
+        return logger.With("request_id", id)
+    }
+    return logger
+}
@@ -0,0 +1,71 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/requestid_test.go
+
+package shared
+
+import (
+    "bytes"
+    "context"
+    "net"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/test/bufconn"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+func TestRequestIDReachesBothLogs(t *testing.T) {
+    var clientLog, serverLog bytes.Buffer
+    clientLogger := hclog.New(&hclog.LoggerOptions{Output: &clientLog, Level: hclog.Debug})
+    serverLogger := hclog.New(&hclog.LoggerOptions{Output: &serverLog, Level: hclog.Debug})
+
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor(serverLogger)))
+    proto.RegisterKVServer(server, &GRPCServer{Impl: &valueKV{value: []byte("v")}, logger: serverLogger})
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufconn",
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+            return listener.DialContext(ctx)
+        }),
+        grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithChainUnaryInterceptor(LoggingUnaryClientInterceptor(clientLogger)))
+    if err != nil {
+        t.Fatalf("grpc.NewClient failed: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    client := NewGRPCClient(conn, clientLogger)
+
+    id := NewRequestID()
+    if _, err := client.Get(WithRequestID(context.Background(), id), "k"); err != nil {
+        t.Fatalf("Get failed: %v", err)
+    }
+    for side, log := range map[string]string{"client": clientLog.String(), "server": serverLog.String()} {
+        lines := strings.Split(strings.TrimSpace(log), "\n")
+        tagged := 0
+        for _, line := range lines {
+            if strings.Contains(line, "request_id="+id) {
+                tagged++
+            }
+        }
+        // The request, its completion and the RPC timing
+        if tagged < 3 {
+            t.Errorf("%s logged %d lines with request_id=%s, want at least 3:\n%s", side, tagged, id, log)
+        }
+    }
+
+    if other := NewRequestID(); other == id {
+        t.Fatalf("NewRequestID returned %s twice", id)
+    }
+}
+
+func TestRequestLoggerWithoutID(t *testing.T) {
+    logger := hclog.NewNullLogger()
+    if got := RequestLogger(context.Background(), logger); got != logger {
+        t.Fatalf("RequestLogger without a request ID wrapped the logger")
+    }
+}
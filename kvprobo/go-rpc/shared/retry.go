@@ -0,0 +1,121 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/retry.go
+
+package shared
+
+import (
+    "context"
+    "fmt"
+    "math/rand/v2"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// DefaultMaxRetries is how many times a failed idempotent call is retried
+// when PLUGIN_KV_MAX_RETRIES is unset.
+const DefaultMaxRetries = 3
+
+// retryBaseDelay and retryMaxDelay bound the backoff between attempts. The
+// delay doubles from the base on every retry, then a random part of its
+// upper half is dropped so clients retrying together spread out.
+var (
+    retryBaseDelay = 100 * time.Millisecond
+    retryMaxDelay  = 2 * time.Second
+)
+
+// idempotentMethods are retried without the caller opting in. Running any
+// of them twice has the same effect as running it once. Every unary RPC
+// without side effects belongs here; TestIdempotentMethodsCoverReads checks.
+var idempotentMethods = map[string]bool{
+    proto.KV_Get_FullMethodName:            true,
+    proto.KV_GetConditional_FullMethodName: true,
+    proto.KV_GetVersioned_FullMethodName:   true,
+    proto.KV_BatchGet_FullMethodName:       true,
+    proto.KV_List_FullMethodName:           true,
+    proto.KV_ListPage_FullMethodName:       true,
+    proto.KV_Exists_FullMethodName:         true,
+    proto.KV_Scan_FullMethodName:           true,
+    proto.KV_Stats_FullMethodName:          true,
+    proto.KV_Ping_FullMethodName:           true,
+}
+
+type retryKey struct{}
+
+// WithRetry marks calls made with ctx as safe to retry, so writes like Put
+// are retried on the same failures as reads. Only use it when repeating the
+// call can't do harm.
+func WithRetry(ctx context.Context) context.Context {
+    return context.WithValue(ctx, retryKey{}, true)
+}
+
+// MaxRetriesFromEnv reads PLUGIN_KV_MAX_RETRIES. Zero turns retries off.
+func MaxRetriesFromEnv() (int, error) {
+    value := os.Getenv("PLUGIN_KV_MAX_RETRIES")
+    if value == "" {
+        return DefaultMaxRetries, nil
+    }
+    retries, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid PLUGIN_KV_MAX_RETRIES %q: %w", value, err)
+    }
+    if retries < 0 {
+        return 0, fmt.Errorf("PLUGIN_KV_MAX_RETRIES must not be negative, got %d", retries)
+    }
+    return retries, nil
+}
+
+// RetryUnaryClientInterceptor retries a unary call up to maxRetries times
+// when it fails with Unavailable or DeadlineExceeded, as it does while the
+// plugin is still starting. Only idempotent methods and calls made with a
+// WithRetry context are retried, and never once ctx is done.
+func RetryUnaryClientInterceptor(maxRetries int, logger hclog.Logger) grpc.UnaryClientInterceptor {
+    baseDelay := retryBaseDelay
+    return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+        err := invoker(ctx, method, req, reply, cc, opts...)
+        if !idempotentMethods[method] && ctx.Value(retryKey{}) == nil {
+            return err
+        }
+
+        delay := baseDelay
+        for attempt := 1; attempt <= maxRetries && retryable(err); attempt++ {
+            wait := delay/2 + rand.N(delay/2+1)
+            RequestLogger(ctx, logger).Debug("🌐🔄 retrying RPC",
+                "method", method,
+                "attempt", attempt,
+                "wait", wait,
+                "code", status.Code(err))
+
+            timer := time.NewTimer(wait)
+            select {
+            case <-ctx.Done():
+                timer.Stop()
+                return err
+            case <-timer.C:
+            }
+            if ctx.Err() != nil {
+                return err
+            }
+
+            err = invoker(ctx, method, req, reply, cc, opts...)
+            delay = min(delay*2, retryMaxDelay)
+        }
+        return err
+    }
+}
+
+// retryable reports whether err is a failure that may go away on its own.
+func retryable(err error) bool {
+    switch status.Code(err) {
+    case codes.Unavailable, codes.DeadlineExceeded:
+        return true
+    default:
+        return false
+    }
+}
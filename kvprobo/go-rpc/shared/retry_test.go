@@ -0,0 +1,144 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/retry_test.go
+
+package shared
+
+import (
+    "context"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/hashicorp/go-hclog"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// flakyKV fails the first failures calls to Get and Put as unavailable,
+// then succeeds.
+type flakyKV struct {
+    kvImpl
+    failures int32
+    calls    atomic.Int32
+}
+
+func (f *flakyKV) fail() error {
+    if f.calls.Add(1) <= f.failures {
+        return status.Error(codes.Unavailable, "still starting")
+    }
+    return nil
+}
+
+func (f *flakyKV) Get(ctx context.Context, key string) ([]byte, error) {
+    if err := f.fail(); err != nil {
+        return nil, err
+    }
+    return []byte("ok"), nil
+}
+
+func (f *flakyKV) GetWithContentType(ctx context.Context, key string) ([]byte, string, error) {
+    value, err := f.Get(ctx, key)
+    return value, DefaultContentType, err
+}
+
+func (f *flakyKV) Put(ctx context.Context, key string, value []byte) error {
+    return f.fail()
+}
+
+// newRetryingClient serves impl to a client that retries up to maxRetries times.
+func newRetryingClient(t *testing.T, impl KV, maxRetries int) *GRPCClient {
+    t.Helper()
+    defer func(base time.Duration) { retryBaseDelay = base }(retryBaseDelay)
+    retryBaseDelay = time.Millisecond
+    return newTestGRPCClientWithOptions(t, impl, nil,
+        grpc.WithChainUnaryInterceptor(RetryUnaryClientInterceptor(maxRetries, hclog.NewNullLogger())))
+}
+
+func TestRetryRecoversFromTransientFailures(t *testing.T) {
+    impl := &flakyKV{failures: 2}
+    client := newRetryingClient(t, impl, 3)
+
+    value, err := client.Get(context.Background(), "k")
+    if err != nil {
+        t.Fatalf("Get() = %v, want success after retries", err)
+    }
+    if string(value) != "ok" || impl.calls.Load() != 3 {
+        t.Fatalf("Get() = %q after %d calls, want \"ok\" after 3", value, impl.calls.Load())
+    }
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+    impl := &flakyKV{failures: 5}
+    client := newRetryingClient(t, impl, 2)
+
+    if _, err := client.Get(context.Background(), "k"); status.Code(err) != codes.Unavailable {
+        t.Fatalf("Get() = %v, want Unavailable", err)
+    }
+    if got := impl.calls.Load(); got != 3 {
+        t.Fatalf("server saw %d calls, want 1 plus 2 retries", got)
+    }
+}
+
+func TestRetrySkipsPutUnlessAskedTo(t *testing.T) {
+    impl := &flakyKV{failures: 1}
+    client := newRetryingClient(t, impl, 3)
+
+    if err := client.Put(context.Background(), "k", []byte("v")); status.Code(err) != codes.Unavailable {
+        t.Fatalf("Put() = %v, want Unavailable without a retry", err)
+    }
+    if got := impl.calls.Load(); got != 1 {
+        t.Fatalf("server saw %d calls, want 1", got)
+    }
+
+    impl.calls.Store(0)
+    if err := client.Put(WithRetry(context.Background()), "k", []byte("v")); err != nil {
+        t.Fatalf("Put() with WithRetry = %v, want success", err)
+    }
+    if got := impl.calls.Load(); got != 2 {
+        t.Fatalf("server saw %d calls, want 2", got)
+    }
+
+    impl.calls.Store(0)
+    if err := client.Put(WithIdempotencyKey(context.Background(), "put-1"), "k", []byte("v")); err != nil {
+        t.Fatalf("Put() with an idempotency key = %v, want success", err)
+    }
+    if got := impl.calls.Load(); got != 2 {
+        t.Fatalf("server saw %d calls with an idempotency key, want 2", got)
+    }
+}
+
+func TestIdempotentMethodsCoverReads(t *testing.T) {
+    // The unary RPCs that change something; every other one only reads,
+    // so it is safe to retry
+    writes := map[string]bool{
+        "Put": true, "Delete": true, "BatchPut": true, "CompareAndSwap": true,
+        "PutIfAbsent": true, "Rename": true, "PutIfVersion": true,
+        "Increment": true, "Transaction": true, "RegisterEventSink": true,
+    }
+    for _, method := range proto.KV_ServiceDesc.Methods {
+        fullName := "/" + proto.KV_ServiceDesc.ServiceName + "/" + method.MethodName
+        if idempotentMethods[fullName] == writes[method.MethodName] {
+            t.Errorf("%s: retried = %t, but writes = %t; add new RPCs to idempotentMethods or to writes here",
+                method.MethodName, idempotentMethods[fullName], writes[method.MethodName])
+        }
+    }
+}
+
+func TestMaxRetriesFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_MAX_RETRIES", "")
+    if retries, err := MaxRetriesFromEnv(); err != nil || retries != DefaultMaxRetries {
+        t.Fatalf("MaxRetriesFromEnv() unset = %d, %v", retries, err)
+    }
+    t.Setenv("PLUGIN_KV_MAX_RETRIES", "0")
+    if retries, err := MaxRetriesFromEnv(); err != nil || retries != 0 {
+        t.Fatalf("MaxRetriesFromEnv() = %d, %v; want 0", retries, err)
+    }
+    for _, value := range []string{"many", "-1"} {
+        t.Setenv("PLUGIN_KV_MAX_RETRIES", value)
+        if _, err := MaxRetriesFromEnv(); err == nil {
+            t.Fatalf("MaxRetriesFromEnv() accepted %q", value)
+        }
+    }
+}
@@ -0,0 +1,85 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/tlspolicy.go
+
+package shared
+
+import (
+    "crypto/tls"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// TLSPolicy restricts the protocol versions and cipher suites a TLS
+// connection may use. The zero value allows TLS 1.2 and later with Go's
+// default suites.
+type TLSPolicy struct {
+    // MinVersion is the oldest TLS version accepted, such as
+    // tls.VersionTLS13. Zero means TLS 1.2.
+    MinVersion uint16
+
+    // CipherSuites limits the TLS 1.2 suites offered and accepted. TLS 1.3
+    // suites are not configurable in Go. Nil keeps Go's defaults.
+    CipherSuites []uint16
+}
+
+// TLSPolicyFromEnv reads PLUGIN_KV_MIN_TLS_VERSION ("1.2" or "1.3") and
+// PLUGIN_KV_TLS_CIPHER_SUITES, a comma-separated list of Go cipher suite
+// names such as TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384.
+func TLSPolicyFromEnv() (TLSPolicy, error) {
+    var policy TLSPolicy
+    if value := os.Getenv("PLUGIN_KV_MIN_TLS_VERSION"); value != "" {
+        version, err := parseTLSVersion(value)
+        if err != nil {
+            return TLSPolicy{}, fmt.Errorf("invalid PLUGIN_KV_MIN_TLS_VERSION: %w", err)
+        }
+        policy.MinVersion = version
+    }
+    if value := os.Getenv("PLUGIN_KV_TLS_CIPHER_SUITES"); value != "" {
+        suites, err := parseCipherSuites(value)
+        if err != nil {
+            return TLSPolicy{}, fmt.Errorf("invalid PLUGIN_KV_TLS_CIPHER_SUITES: %w", err)
+        }
+        policy.CipherSuites = suites
+    }
+    return policy, nil
+}
+
+// Apply sets config's minimum version and cipher suites from the policy.
+func (p TLSPolicy) Apply(config *tls.Config) {
+    config.MinVersion = tls.VersionTLS12
+    if p.MinVersion != 0 {
+        config.MinVersion = p.MinVersion
+    }
+    if p.CipherSuites != nil {
+        config.CipherSuites = p.CipherSuites
+    }
+}
+
+func parseTLSVersion(value string) (uint16, error) {
+    switch strings.TrimPrefix(strings.ToUpper(value), "TLS") {
+    case "1.2":
+        return tls.VersionTLS12, nil
+    case "1.3":
+        return tls.VersionTLS13, nil
+    }
+    return 0, fmt.Errorf("unknown TLS version %q, want 1.2 or 1.3", value)
+}
+
+// parseCipherSuites accepts only the suites Go considers secure.
+func parseCipherSuites(value string) ([]uint16, error) {
+    byName := map[string]uint16{}
+    for _, suite := range tls.CipherSuites() {
+        byName[suite.Name] = suite.ID
+    }
+
+    var suites []uint16
+    for _, name := range strings.Split(value, ",") {
+        name = strings.TrimSpace(name)
+        id, ok := byName[name]
+        if !ok {
+            return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+        }
+        suites = append(suites, id)
+    }
+    return suites, nil
+}
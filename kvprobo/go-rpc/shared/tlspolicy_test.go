@@ -0,0 +1,98 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/tlspolicy_test.go
+
+package shared
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "net"
+    "testing"
+)
+
+func TestTLSPolicyFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_MIN_TLS_VERSION", "")
+    t.Setenv("PLUGIN_KV_TLS_CIPHER_SUITES", "")
+    policy, err := TLSPolicyFromEnv()
+    if err != nil || policy.MinVersion != 0 || policy.CipherSuites != nil {
+        t.Fatalf("TLSPolicyFromEnv() with nothing set = %+v, %v; want the zero policy", policy, err)
+    }
+
+    for value, want := range map[string]uint16{
+        "1.2":    tls.VersionTLS12,
+        "1.3":    tls.VersionTLS13,
+        "TLS1.3": tls.VersionTLS13,
+        "tls1.2": tls.VersionTLS12,
+    } {
+        t.Setenv("PLUGIN_KV_MIN_TLS_VERSION", value)
+        policy, err := TLSPolicyFromEnv()
+        if err != nil || policy.MinVersion != want {
+            t.Fatalf("TLSPolicyFromEnv() with PLUGIN_KV_MIN_TLS_VERSION=%q = %+v, %v; want %s",
+                value, policy, err, tls.VersionName(want))
+        }
+    }
+
+    for _, value := range []string{"1.1", "1.0", "SSLv3", "modern"} {
+        t.Setenv("PLUGIN_KV_MIN_TLS_VERSION", value)
+        if _, err := TLSPolicyFromEnv(); err == nil {
+            t.Fatalf("TLSPolicyFromEnv() accepted PLUGIN_KV_MIN_TLS_VERSION=%q", value)
+        }
+    }
+
+    t.Setenv("PLUGIN_KV_MIN_TLS_VERSION", "")
+    t.Setenv("PLUGIN_KV_TLS_CIPHER_SUITES", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256")
+    policy, err = TLSPolicyFromEnv()
+    if err != nil || len(policy.CipherSuites) != 2 ||
+        policy.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 {
+        t.Fatalf("TLSPolicyFromEnv() with two suites = %+v, %v", policy, err)
+    }
+
+    // RC4 is known to Go but insecure
+    t.Setenv("PLUGIN_KV_TLS_CIPHER_SUITES", "TLS_RSA_WITH_RC4_128_SHA")
+    if _, err := TLSPolicyFromEnv(); err == nil {
+        t.Fatalf("TLSPolicyFromEnv() accepted an insecure cipher suite")
+    }
+}
+
+func TestTLS13PolicyRejectsTLS12Client(t *testing.T) {
+    certPEM, keyPEM, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    cert, err := ParseCertificate(certPEM, nil)
+    if err != nil {
+        t.Fatalf("ParseCertificate failed: %v", err)
+    }
+    key, err := ParsePrivateKey(keyPEM, nil)
+    if err != nil {
+        t.Fatalf("ParsePrivateKey failed: %v", err)
+    }
+    pool := x509.NewCertPool()
+    pool.AddCert(cert)
+
+    handshake := func(clientMax uint16) error {
+        serverConfig := CreateTLSConfigWithPolicy(cert, key, pool, true, TLSPolicy{MinVersion: tls.VersionTLS13}, nil)
+        clientConfig := CreateTLSConfig(cert, key, pool, false, nil)
+        clientConfig.ServerName = "localhost"
+        clientConfig.MaxVersion = clientMax
+
+        serverConn, clientConn := net.Pipe()
+        defer serverConn.Close()
+        defer clientConn.Close()
+        serverErr := make(chan error, 1)
+        go func() { serverErr <- tls.Server(serverConn, serverConfig).Handshake() }()
+        clientErr := tls.Client(clientConn, clientConfig).Handshake()
+        // Unblock the server if the client gave up first
+        clientConn.Close()
+        if err := <-serverErr; err != nil {
+            return err
+        }
+        return clientErr
+    }
+
+    if err := handshake(tls.VersionTLS13); err != nil {
+        t.Fatalf("TLS 1.3 client rejected by a TLS 1.3 server: %v", err)
+    }
+    if err := handshake(tls.VersionTLS12); err == nil {
+        t.Fatalf("TLS 1.2 client accepted by a TLS 1.3-only server")
+    }
+}
@@ -0,0 +1,150 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/tracing.go
+
+package shared
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+
+    "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/stats"
+)
+
+// KeyAttribute is the span attribute that carries the key an RPC targets.
+const KeyAttribute = attribute.Key("kv.key")
+
+// redactedKey replaces the key in spans when RedactKeys is set.
+const redactedKey = "<redacted>"
+
+// Tracing configures OpenTelemetry tracing of KV RPCs. The zero value
+// disables it.
+type Tracing struct {
+    // Endpoint is the OTLP/gRPC collector spans are exported to. A URL such
+    // as http://localhost:4317 picks the scheme; a bare host:port uses TLS.
+    Endpoint string
+
+    // RedactKeys records KeyAttribute as a placeholder instead of the key.
+    RedactKeys bool
+
+    // provider overrides the global tracer provider, for tests.
+    provider trace.TracerProvider
+}
+
+// TracingFromEnv reads PLUGIN_KV_OTEL_ENDPOINT and
+// PLUGIN_KV_OTEL_REDACT_KEYS. Tracing is off when the endpoint is unset.
+func TracingFromEnv() (Tracing, error) {
+    t := Tracing{Endpoint: os.Getenv("PLUGIN_KV_OTEL_ENDPOINT")}
+    if value := os.Getenv("PLUGIN_KV_OTEL_REDACT_KEYS"); value != "" {
+        redact, err := strconv.ParseBool(value)
+        if err != nil {
+            return Tracing{}, fmt.Errorf("invalid PLUGIN_KV_OTEL_REDACT_KEYS %q: %w", value, err)
+        }
+        t.RedactKeys = redact
+    }
+    return t, nil
+}
+
+// Enabled reports whether spans should be recorded.
+func (t Tracing) Enabled() bool {
+    return t.Endpoint != "" || t.provider != nil
+}
+
+// Start installs a global tracer provider that exports to Endpoint under
+// serviceName. The returned function flushes and stops the exporter.
+func (t Tracing) Start(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+    if t.Endpoint == "" {
+        return func(context.Context) error { return nil }, nil
+    }
+
+    var endpoint otlptracegrpc.Option
+    if strings.Contains(t.Endpoint, "://") {
+        endpoint = otlptracegrpc.WithEndpointURL(t.Endpoint)
+    } else {
+        endpoint = otlptracegrpc.WithEndpoint(t.Endpoint)
+    }
+    exporter, err := otlptracegrpc.New(ctx, endpoint)
+    if err != nil {
+        return nil, fmt.Errorf("creating OTLP exporter for %q: %w", t.Endpoint, err)
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+    )
+    otel.SetTracerProvider(provider)
+    otel.SetTextMapPropagator(propagation.TraceContext{})
+    return provider.Shutdown, nil
+}
+
+// ServerOptions creates a span for every RPC the server handles, continuing
+// any trace the caller sent in its metadata.
+func (t Tracing) ServerOptions() []grpc.ServerOption {
+    if !t.Enabled() {
+        return nil
+    }
+    return []grpc.ServerOption{
+        grpc.StatsHandler(keyAnnotator{Handler: otelgrpc.NewServerHandler(t.handlerOptions()...), tracing: t}),
+    }
+}
+
+// DialOptions is the client side of ServerOptions. The span's trace context
+// is sent to the plugin so its spans join the host's trace.
+func (t Tracing) DialOptions() []grpc.DialOption {
+    if !t.Enabled() {
+        return nil
+    }
+    return []grpc.DialOption{
+        grpc.WithStatsHandler(keyAnnotator{Handler: otelgrpc.NewClientHandler(t.handlerOptions()...), tracing: t}),
+    }
+}
+
+func (t Tracing) handlerOptions() []otelgrpc.Option {
+    opts := []otelgrpc.Option{otelgrpc.WithPropagators(propagation.TraceContext{})}
+    if t.provider != nil {
+        opts = append(opts, otelgrpc.WithTracerProvider(t.provider))
+    }
+    return opts
+}
+
+// keyAnnotator adds KeyAttribute to the span otelgrpc opened for an RPC
+// once its request message, sent by the client or received by the server,
+// is seen. Interceptors can't do this on the client, where they run before
+// the span exists.
+type keyAnnotator struct {
+    stats.Handler
+    tracing Tracing
+}
+
+func (a keyAnnotator) HandleRPC(ctx context.Context, s stats.RPCStats) {
+    var req interface{}
+    switch p := s.(type) {
+    case *stats.OutPayload:
+        if p.Client {
+            req = p.Payload
+        }
+    case *stats.InPayload:
+        if !p.Client {
+            req = p.Payload
+        }
+    }
+    if keyed, ok := req.(interface{ GetKey() string }); ok {
+        key := keyed.GetKey()
+        if a.tracing.RedactKeys {
+            key = redactedKey
+        }
+        trace.SpanFromContext(ctx).SetAttributes(KeyAttribute.String(key))
+    }
+    a.Handler.HandleRPC(ctx, s)
+}
@@ -0,0 +1,106 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/tracing_test.go
+
+package shared
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingRecordsPutSpan(t *testing.T) {
+    for _, tt := range []struct {
+        name   string
+        redact bool
+        want   string
+    }{
+        {"plain", false, "greeting"},
+        {"redacted", true, redactedKey},
+    } {
+        t.Run(tt.name, func(t *testing.T) {
+            // Separate providers stand in for the host and plugin processes
+            hostSpans := tracetest.NewInMemoryExporter()
+            hostProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(hostSpans))
+            pluginSpans := tracetest.NewInMemoryExporter()
+            pluginProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(pluginSpans))
+
+            host := Tracing{RedactKeys: tt.redact, provider: hostProvider}
+            plugin := Tracing{RedactKeys: tt.redact, provider: pluginProvider}
+            client := newTestGRPCClientWithOptions(t, &mapKV{}, plugin.ServerOptions(), host.DialOptions()...)
+
+            ctx, parent := hostProvider.Tracer("test").Start(context.Background(), "kv put")
+            err := client.Put(ctx, "greeting", []byte("hello"))
+            parent.End()
+            if err != nil {
+                t.Fatalf("Put failed: %v", err)
+            }
+
+            serverSpan := findSpan(t, pluginSpans, "proto.KV/Put")
+            if got := spanKey(serverSpan); got != tt.want {
+                t.Fatalf("server span %s = %q, want %q", KeyAttribute, got, tt.want)
+            }
+            if got := spanKey(findSpan(t, hostSpans, "proto.KV/Put")); got != tt.want {
+                t.Fatalf("client span %s = %q, want %q", KeyAttribute, got, tt.want)
+            }
+            if serverSpan.Parent.TraceID() != parent.SpanContext().TraceID() {
+                t.Fatalf("server span trace = %s, want the host's trace %s",
+                    serverSpan.Parent.TraceID(), parent.SpanContext().TraceID())
+            }
+        })
+    }
+}
+
+func TestTracingFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_KV_OTEL_ENDPOINT", "")
+    t.Setenv("PLUGIN_KV_OTEL_REDACT_KEYS", "")
+    tracing, err := TracingFromEnv()
+    if err != nil || tracing.Enabled() {
+        t.Fatalf("TracingFromEnv() with nothing set = %+v, %v; want disabled", tracing, err)
+    }
+    if opts := tracing.ServerOptions(); opts != nil {
+        t.Fatalf("ServerOptions() while disabled = %v, want none", opts)
+    }
+
+    t.Setenv("PLUGIN_KV_OTEL_ENDPOINT", "http://localhost:4317")
+    t.Setenv("PLUGIN_KV_OTEL_REDACT_KEYS", "true")
+    tracing, err = TracingFromEnv()
+    if err != nil || !tracing.Enabled() || !tracing.RedactKeys {
+        t.Fatalf("TracingFromEnv() = %+v, %v; want enabled with redaction", tracing, err)
+    }
+
+    t.Setenv("PLUGIN_KV_OTEL_REDACT_KEYS", "sometimes")
+    if _, err := TracingFromEnv(); err == nil {
+        t.Fatalf("TracingFromEnv() accepted PLUGIN_KV_OTEL_REDACT_KEYS=sometimes")
+    }
+}
+
+// findSpan returns the span called name, failing the test if none is
+// recorded. The server ends its span after replying, so it may arrive late.
+func findSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        spans := exporter.GetSpans()
+        for _, span := range spans {
+            if span.Name == name {
+                return span
+            }
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("no %q span among %d recorded", name, len(spans))
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+func spanKey(span tracetest.SpanStub) string {
+    for _, attr := range span.Attributes {
+        if attr.Key == KeyAttribute {
+            return attr.Value.AsString()
+        }
+    }
+    return ""
+}
@@ -1,51 +1,128 @@
 package shared
 
 import (
+    "fmt"
     "os"
-
-    "strings"
     "strconv"
+    "strings"
 
     "github.com/hashicorp/go-hclog"
 )
 
+// SecretEnvVars names variables whose values are always redacted, whatever
+// they look like: an encryption key is plain hex, not PEM.
+var SecretEnvVars = []string{
+    "PLUGIN_CA_CERT",
+    "PLUGIN_CLIENT_CERT",
+    "PLUGIN_CLIENT_KEY",
+    "PLUGIN_SERVER_CERT",
+    "PLUGIN_SERVER_KEY",
+    "PLUGIN_KV_ENCRYPTION_KEY",
+}
+
+// EnvDisplay controls which environment variables DisplayEnv prints.
+type EnvDisplay struct {
+    // Show turns the display on.
+    Show bool
+    // Prefixes selects the variables to print. Matching ignores case.
+    Prefixes []string
+    // Exclude names variables that are never printed, even when a prefix
+    // matches. Matching ignores case.
+    Exclude []string
+}
+
+// EnvDisplayFromEnv builds an EnvDisplay from the environment:
+// - `PLUGIN_SHOW_ENV`: set to a false value to turn the display off.
+// - `PLUGIN_ENV_FILTER`: comma-separated prefixes, replacing `defaultPrefixes`.
+// - `PLUGIN_ENV_EXCLUDE`: comma-separated variable names to leave out.
+func EnvDisplayFromEnv(defaultPrefixes []string) EnvDisplay {
+    display := EnvDisplay{Show: true, Prefixes: defaultPrefixes}
+    if value := os.Getenv("PLUGIN_SHOW_ENV"); value != "" {
+        display.Show, _ = strconv.ParseBool(strings.ToLower(value))
+    }
+    if value := os.Getenv("PLUGIN_ENV_FILTER"); value != "" {
+        display.Prefixes = splitEnvList(value)
+    }
+    if value := os.Getenv("PLUGIN_ENV_EXCLUDE"); value != "" {
+        display.Exclude = splitEnvList(value)
+    }
+    return display
+}
+
+// splitEnvList splits a comma-separated list, dropping empty entries.
+func splitEnvList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
 // DisplayFilteredEnv shows filtered environment variables if the PLUGIN_SHOW_ENV is enabled.
 // - `logger`: Logger instance for logging environment variables.
 // - `defaultFilter`: A default filter applied if PLUGIN_ENV_FILTER is not set.
 func DisplayFilteredEnv(logger hclog.Logger, defaultFilter []string) {
+    DisplayEnv(logger, EnvDisplayFromEnv(defaultFilter))
+}
 
-       // Default showing the environment variables to off.
-    showEnv := false
-    showEnvValue := os.Getenv("PLUGIN_SHOW_ENV")
-    if showEnvValue != "" {
-        showEnv, _ = strconv.ParseBool(strings.ToLower(showEnvValue))
+// DisplayEnv logs the environment variables display selects. Values of
+// SecretEnvVars and values that look like PEM certificates or keys are
+// redacted.
+func DisplayEnv(logger hclog.Logger, display EnvDisplay) {
+    if !display.Show {
+        logger.Debug("🔕 Environment variable display is disabled.")
+        return
+    }
 
-        if !showEnv {
-            logger.Debug("🔕 Environment variable display is disabled.")
-            return
+    logger.Info("📡🌍 Displaying Environment Variables:")
+    for _, env := range os.Environ() {
+        key, value, _ := strings.Cut(env, "=")
+        if display.matches(key) {
+            logger.Info("🔑 " + key + "=" + RedactEnv(key, value))
         }
     }
+}
 
-    // Retrieve filter list from PLUGIN_ENV_FILTER or use the default
-    rawFilter := os.Getenv("PLUGIN_ENV_FILTER")
-    var filters []string
-    if rawFilter != "" {
-        filters = strings.Split(rawFilter, ",")
-    } else {
-        filters = defaultFilter
+// matches reports whether key starts with one of the prefixes and isn't
+// excluded.
+func (display EnvDisplay) matches(key string) bool {
+    for _, excluded := range display.Exclude {
+        if strings.EqualFold(key, excluded) {
+            return false
+        }
+    }
+    upper := strings.ToUpper(key)
+    for _, prefix := range display.Prefixes {
+        if strings.HasPrefix(upper, strings.ToUpper(prefix)) {
+            return true
+        }
     }
+    return false
+}
 
-    logger.Info("📡🌍 Displaying Environment Variables:")
-    for _, env := range os.Environ() {
-        keyValue := strings.SplitN(env, "=", 2)
-        key := keyValue[0]
-
-        // Check if the key matches any filter in the list
-        for _, filter := range filters {
-            if strings.Contains(key, filter) {
-                logger.Info("🔑 " + key + "=" + keyValue[1])
-                break
-            }
+// RedactEnv returns the value of the environment variable key as it may be
+// logged.
+func RedactEnv(key, value string) string {
+    for _, secret := range SecretEnvVars {
+        if strings.EqualFold(key, secret) {
+            return redacted(value)
         }
     }
-}
\ No newline at end of file
+    return RedactValue(value)
+}
+
+// RedactValue replaces a value that looks like PEM-encoded material with a
+// marker giving only its length, so certificates and keys stay out of logs.
+func RedactValue(value string) string {
+    if strings.Contains(value, "-----BEGIN") {
+        return redacted(value)
+    }
+    return value
+}
+
+// redacted is the marker logged in place of value.
+func redacted(value string) string {
+    return fmt.Sprintf("<redacted:%d bytes>", len(value))
+}
@@ -0,0 +1,112 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/utils_test.go
+
+package shared
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/go-hclog"
+)
+
+// displayedEnv returns what DisplayEnv logs for display.
+func displayedEnv(display EnvDisplay) string {
+    var out bytes.Buffer
+    DisplayEnv(hclog.New(&hclog.LoggerOptions{Output: &out, Level: hclog.Trace}), display)
+    return out.String()
+}
+
+func TestDisplayEnvRedactsPEMValues(t *testing.T) {
+    certPEM, _, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    t.Setenv("KVTEST_CERT", string(certPEM))
+    t.Setenv("KVTEST_MODE", "benign")
+
+    out := displayedEnv(EnvDisplay{Show: true, Prefixes: []string{"kvtest_"}})
+    if !strings.Contains(out, "KVTEST_MODE=benign") {
+        t.Fatalf("benign variable not shown in full:\n%s", out)
+    }
+    if strings.Contains(out, "BEGIN") {
+        t.Fatalf("certificate leaked into the display:\n%s", out)
+    }
+    if !strings.Contains(out, "KVTEST_CERT=<redacted:") {
+        t.Fatalf("certificate variable not redacted:\n%s", out)
+    }
+}
+
+func TestDisplayEnvFiltering(t *testing.T) {
+    t.Setenv("KVTEST_SHOWN", "1")
+    t.Setenv("KVTEST_HIDDEN", "1")
+    t.Setenv("OTHER_KVTEST", "1")
+
+    out := displayedEnv(EnvDisplay{
+        Show:     true,
+        Prefixes: []string{"KvTest"},
+        Exclude:  []string{"kvtest_hidden"},
+    })
+    if !strings.Contains(out, "KVTEST_SHOWN=1") {
+        t.Fatalf("prefix match should ignore case:\n%s", out)
+    }
+    if strings.Contains(out, "KVTEST_HIDDEN") {
+        t.Fatalf("excluded variable shown:\n%s", out)
+    }
+    if strings.Contains(out, "OTHER_KVTEST") {
+        t.Fatalf("variable matching only mid-name shown:\n%s", out)
+    }
+
+    if out := displayedEnv(EnvDisplay{Prefixes: []string{"KVTEST"}}); strings.Contains(out, "KVTEST") {
+        t.Fatalf("display shown while turned off:\n%s", out)
+    }
+}
+
+func TestEnvDisplayFromEnv(t *testing.T) {
+    t.Setenv("PLUGIN_SHOW_ENV", "")
+    t.Setenv("PLUGIN_ENV_FILTER", "")
+    t.Setenv("PLUGIN_ENV_EXCLUDE", "")
+    display := EnvDisplayFromEnv([]string{"PLUGIN"})
+    if !display.Show || len(display.Prefixes) != 1 || display.Exclude != nil {
+        t.Fatalf("EnvDisplayFromEnv() defaults = %+v", display)
+    }
+
+    t.Setenv("PLUGIN_SHOW_ENV", "false")
+    t.Setenv("PLUGIN_ENV_FILTER", "GRPC, go")
+    t.Setenv("PLUGIN_ENV_EXCLUDE", "PLUGIN_CLIENT_CERT,")
+    display = EnvDisplayFromEnv([]string{"PLUGIN"})
+    if display.Show {
+        t.Fatalf("PLUGIN_SHOW_ENV=false left the display on")
+    }
+    if strings.Join(display.Prefixes, "|") != "GRPC|go" || strings.Join(display.Exclude, "|") != "PLUGIN_CLIENT_CERT" {
+        t.Fatalf("EnvDisplayFromEnv() = %+v", display)
+    }
+}
+
+func TestDisplayFilteredEnvRedactsSecretVars(t *testing.T) {
+    certPEM, keyPEM, err := GenerateCert(nil, nil)
+    if err != nil {
+        t.Fatalf("GenerateCert failed: %v", err)
+    }
+    blob := string(certPEM) + string(keyPEM)
+    t.Setenv("PLUGIN_SHOW_ENV", "true")
+    t.Setenv("PLUGIN_ENV_FILTER", "")
+    t.Setenv("PLUGIN_ENV_EXCLUDE", "")
+    t.Setenv("PLUGIN_CLIENT_CERT", blob)
+    t.Setenv("PLUGIN_KV_ENCRYPTION_KEY", "00112233445566778899aabbccddeeff")
+
+    var out bytes.Buffer
+    DisplayFilteredEnv(hclog.New(&hclog.LoggerOptions{Output: &out, Level: hclog.Trace}), []string{"PLUGIN"})
+    logged := out.String()
+
+    if want := "PLUGIN_CLIENT_CERT=" + redacted(blob); !strings.Contains(logged, want) {
+        t.Fatalf("display missing %q:\n%s", want, logged)
+    }
+    keyBody := strings.Split(strings.TrimSpace(string(keyPEM)), "\n")[1]
+    if strings.Contains(logged, keyBody) || strings.Contains(logged, "PRIVATE KEY") {
+        t.Fatalf("private key bytes leaked into the display:\n%s", logged)
+    }
+    if strings.Contains(logged, "00112233445566778899aabbccddeeff") {
+        t.Fatalf("encryption key leaked into the display:\n%s", logged)
+    }
+}
@@ -0,0 +1,158 @@
+// pyvider-rpcplugin/examples/kvprobo/go-plugin/shared/version.go
+
+package shared
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/hashicorp/go-plugin"
+    "google.golang.org/grpc"
+
+    "github.com/provide-io/pyvider-rpcplugin/examples/kvprobo/go-plugin/proto"
+)
+
+// ProtocolVersion is the newest KV protocol version this build speaks.
+// Bump it, and add an entry to VersionedPlugins, when a change would break
+// peers built against the previous version, or adds RPCs the client has to
+// know the plugin serves. Version 2 added GetBatchStream, GetConditional,
+// ListPage, PutIfAbsent, Rename, Ping, Scan, Stats and RegisterEventSink;
+// list each RPC a version adds in rpcProtocolVersions.
+const ProtocolVersion = 2
+
+// PluginName is the name the KV plugin is dispensed under.
+const PluginName = "kv_grpc"
+
+// ErrVersionMismatch is returned when the host and plugin share no protocol
+// version.
+var ErrVersionMismatch = errors.New("plugin protocol version mismatch")
+
+// VersionedPlugins returns the plugin set for every protocol version this
+// build speaks, all served by p. go-plugin picks the newest version both
+// sides share. Later versions only add RPCs, so one plugin serves them all.
+func VersionedPlugins(p *KVGRPCPlugin) map[int]plugin.PluginSet {
+    return map[int]plugin.PluginSet{
+        1: {PluginName: p},
+        2: {PluginName: p},
+    }
+}
+
+// rpcProtocolVersions maps the KV RPCs added after protocol version 1 to
+// the version that added them. Unlisted RPCs work with every version.
+var rpcProtocolVersions = map[string]int{
+    proto.KV_GetBatchStream_FullMethodName:    2,
+    proto.KV_GetConditional_FullMethodName:    2,
+    proto.KV_ListPage_FullMethodName:          2,
+    proto.KV_PutIfAbsent_FullMethodName:       2,
+    proto.KV_Rename_FullMethodName:            2,
+    proto.KV_Ping_FullMethodName:              2,
+    proto.KV_Scan_FullMethodName:              2,
+    proto.KV_Stats_FullMethodName:             2,
+    proto.KV_RegisterEventSink_FullMethodName: 2,
+}
+
+// checkRPCVersion refuses method when the negotiated protocol version is too
+// old to serve it, rather than letting it fail with Unimplemented.
+func checkRPCVersion(method string, negotiated int) error {
+    if required := rpcProtocolVersions[method]; negotiated < required {
+        return fmt.Errorf("%w: %s needs KV protocol version %d, but the plugin speaks version %d",
+            ErrVersionMismatch, path.Base(method), required, negotiated)
+    }
+    return nil
+}
+
+// versionGate is the connection a GRPCClient's calls go through. It checks
+// every RPC against the client's protocol version before sending it, so
+// each one is covered however a command reaches it.
+type versionGate struct {
+    grpc.ClientConnInterface
+    client *GRPCClient
+}
+
+func (g versionGate) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+    if err := checkRPCVersion(method, g.client.protocolVersion()); err != nil {
+        return err
+    }
+    return g.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (g versionGate) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+    if err := checkRPCVersion(method, g.client.protocolVersion()); err != nil {
+        return nil, err
+    }
+    return g.ClientConnInterface.NewStream(ctx, desc, method, opts...)
+}
+
+// VersionMismatchError reports a plugin that speaks none of the protocol
+// versions this build supports.
+type VersionMismatchError struct {
+    PluginVersion int
+    Supported     []int
+}
+
+func (e *VersionMismatchError) Error() string {
+    return fmt.Sprintf("plugin speaks KV protocol version %d, but this client supports %s; rebuild the plugin and client from the same release",
+        e.PluginVersion, formatVersions(e.Supported))
+}
+
+func (e *VersionMismatchError) Is(target error) bool { return target == ErrVersionMismatch }
+
+func formatVersions(versions []int) string {
+    parts := make([]string, len(versions))
+    for i, version := range versions {
+        parts[i] = strconv.Itoa(version)
+    }
+    if len(parts) == 1 {
+        return "only version " + parts[0]
+    }
+    return "versions " + strings.Join(parts, ", ")
+}
+
+// go-plugin reports a handshake with no common version as
+// "Incompatible API version with plugin. Plugin version: 1, Client versions: [2]".
+const (
+    pluginVersionLabel  = "Incompatible API version with plugin. Plugin version: "
+    clientVersionsLabel = ", Client versions: "
+)
+
+// HandshakeError turns go-plugin's protocol version error into a
+// VersionMismatchError, and a plugin that exits mid-handshake into
+// ErrCookieMismatch. Other errors are returned unchanged.
+func HandshakeError(err error) error {
+    if err == nil {
+        return nil
+    }
+    for _, exited := range pluginExitedMessages {
+        if strings.Contains(err.Error(), exited) {
+            return fmt.Errorf("%w: the plugin exited before completing the handshake; it must accept %s=%s",
+                ErrCookieMismatch, Handshake.MagicCookieKey, Handshake.MagicCookieValue)
+        }
+    }
+    _, rest, ok := strings.Cut(err.Error(), pluginVersionLabel)
+    if !ok {
+        return err
+    }
+    pluginPart, clientPart, ok := strings.Cut(rest, clientVersionsLabel)
+    if !ok {
+        return err
+    }
+    pluginVersion, convErr := strconv.Atoi(pluginPart)
+    if convErr != nil {
+        return err
+    }
+    var supported []int
+    for _, field := range strings.Fields(strings.Trim(clientPart, "[]")) {
+        version, convErr := strconv.Atoi(field)
+        if convErr != nil {
+            return err
+        }
+        supported = append(supported, version)
+    }
+    sort.Ints(supported)
+    return &VersionMismatchError{PluginVersion: pluginVersion, Supported: supported}
+}
@@ -3,6 +3,10 @@
 export PLUGIN_CLIENT_PATH="$(pwd)/bin/kv-go-client"
 export PLUGIN_SERVER_PATH="$(pwd)/bin/kv-go-server"
 
+# Share one data directory across invocations; each server process would
+# otherwise get its own temporary directory.
+export PLUGIN_KV_DATA_DIR="${PLUGIN_KV_DATA_DIR:-${TMPDIR:-/tmp}/kv-go-data}"
+
 file ${PLUGIN_CLIENT_PATH}
 file ${PLUGIN_SERVER_PATH}
 